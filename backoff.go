@@ -0,0 +1,38 @@
+package fxfer
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// withDefaults returns the config with the default values of the fields which are not set.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetryAttempts <= 0 {
+		c.MaxRetryAttempts = defaultMaxRetryAttempts
+	}
+	if c.InitialDelay <= 0 {
+		c.InitialDelay = defaultInitialDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaultMaxDelay
+	}
+	if c.Multiplier <= 0 {
+		c.Multiplier = defaultRetryMultiplier
+	}
+	return c
+}
+
+// backOffDelay is the delay before the retry following the n-th failed attempt (zero-based):
+// InitialDelay grown by Multiplier after each retry up to MaxDelay, randomized between its
+// half and itself unless DisableJitter is set.
+func (c RetryConfig) backOffDelay(n uint) time.Duration {
+	delay := float64(c.InitialDelay) * math.Pow(max(c.Multiplier, 1), float64(n))
+	if c.MaxDelay > 0 {
+		delay = min(delay, float64(c.MaxDelay))
+	}
+	if !c.DisableJitter {
+		delay = delay/2 + rand.Float64()*delay/2
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,71 @@
+package fxfer
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryConfig backoff", func() {
+	config := RetryConfig{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   3,
+		// the delays are only deterministic without jitter
+		DisableJitter: true,
+	}
+
+	It("should grow the delay by the multiplier up to the max delay", func() {
+		var delays []time.Duration
+		for n := range uint(5) {
+			delays = append(delays, config.backOffDelay(n))
+		}
+		Expect(delays).To(Equal([]time.Duration{
+			100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second,
+		}))
+	})
+
+	It("should vary the delay between its half and itself with jitter", func() {
+		config := config
+		config.DisableJitter = false
+		for n, maxDelay := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond} {
+			delays := make(map[time.Duration]struct{})
+			for range 20 {
+				delay := config.backOffDelay(uint(n))
+				Expect(delay).To(BeNumerically(">=", maxDelay/2))
+				Expect(delay).To(BeNumerically("<=", maxDelay))
+				delays[delay] = struct{}{}
+			}
+			Expect(len(delays)).To(BeNumerically(">", 1))
+		}
+	})
+
+	It("should retry with a jittered exponential backoff by default", func() {
+		tfr := NewTransfer(GinkgoLogr).(*transfer)
+		Expect(tfr.retryConfig.Multiplier).To(Equal(float64(2)))
+		Expect(tfr.retryConfig.DisableJitter).To(BeFalse())
+
+		// the ranges of successive delays do not overlap with a multiplier of 2 or more
+		var previous time.Duration
+		for n := range uint(4) {
+			delay := tfr.retryDelay(n, errRetryable, nil)
+			Expect(delay).To(BeNumerically(">=", previous))
+			previous = delay
+		}
+	})
+
+	It("should not jitter the delay once disabled, even with the default multiplier", func() {
+		tfr := NewTransfer(GinkgoLogr, WithRetryConfig(RetryConfig{
+			InitialDelay:  100 * time.Millisecond,
+			DisableJitter: true,
+		})).(*transfer)
+		var delays []time.Duration
+		for n := range uint(3) {
+			delays = append(delays, tfr.retryDelay(n, errRetryable, nil))
+		}
+		Expect(delays).To(Equal([]time.Duration{
+			100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		}))
+	})
+})
@@ -0,0 +1,87 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// ErrCollisionUnsupported is returned when the destination cannot check whether a file
+// occupies the destination path (see WithCollisionSuffixing and storage.ExistenceChecker).
+var ErrCollisionUnsupported = errors.New("collision: the destination cannot check whether a file exists")
+
+// ErrCollisionUnresolved is returned when all the suffixed destination paths are occupied
+// (see WithCollisionSuffixing).
+var ErrCollisionUnresolved = errors.New("collision: no free destination path")
+
+// maxCollisionSuffix is the last suffix tried for a destination path.
+const maxCollisionSuffix = 1000
+
+// avoidCollision replaces the destination path with the first path free of another file, the
+// path itself or the path suffixed with a number before its extension, e.g. "file (1).txt"
+// (see WithCollisionSuffixing).
+func (t *transfer) avoidCollision(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	dest DestinationConfig,
+) (resolved DestinationConfig, err error) {
+	resolved = dest
+	if !t.collisionSuffixing {
+		return
+	}
+	checker, ok := dest.Storage.(storage.ExistenceChecker)
+	if !ok {
+		err = ErrCollisionUnsupported
+		return
+	}
+
+	for n := 0; n <= maxCollisionSuffix; n++ {
+		resolved.FilePath = collisionPath(dest.FilePath, n)
+		var occupied bool
+		if occupied, err = t.isPathOccupied(ctx, checker, srcInfo, resolved); err != nil || !occupied {
+			return
+		}
+		t.logger.Info("destination path is occupied by another file, suffixing it",
+			"dstPath", resolved.FilePath)
+	}
+	err = fmt.Errorf("%w: %s", ErrCollisionUnresolved, dest.FilePath)
+	return
+}
+
+// isPathOccupied reports whether another file occupies the destination path: a file which has
+// not been transferred by the destination, or a finished file of another source. An unfinished
+// file is resumed and an identical file is skipped, as without suffixing.
+func (t *transfer) isPathOccupied(
+	ctx context.Context,
+	checker storage.ExistenceChecker,
+	srcInfo xferfile.Info,
+	dest DestinationConfig,
+) (occupied bool, err error) {
+	var exists bool
+	if exists, err = checker.FileExists(ctx, dest.FilePath, dest.Client); err != nil || !exists {
+		return
+	}
+	var destInfo xferfile.Info
+	if destInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
+		if errors.Is(err, xferfile.ErrFileNotExists) || errors.Is(err, storage.ErrFileInfoMismatch) {
+			return true, nil
+		}
+		return
+	}
+	return !destInfo.FinishTime.IsZero() && !t.isDestinationFinished(srcInfo, destInfo), nil
+}
+
+// collisionPath returns the path suffixed with the number before its extension, so that the
+// extension is preserved, the path itself for 0.
+func collisionPath(filePath string, n int) string {
+	if n == 0 {
+		return filePath
+	}
+	ext := filepath.Ext(filepath.Base(filePath))
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(filePath, ext), n, ext)
+}
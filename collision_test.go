@@ -0,0 +1,86 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithCollisionSuffixing", func() {
+	noopCallback := func(fxfer.Progress) {}
+
+	var (
+		content    string
+		destDir    string
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 100)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+		destDir = filepath.Join(tempDir, "dest")
+		Expect(os.MkdirAll(destDir, 0755)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(destDir, "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should transfer to the suffixed path when another file occupies the path", func(ctx context.Context) {
+		Expect(os.WriteFile(destConfig.FilePath, []byte("another file"), 0644)).To(Succeed())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithCollisionSuffixing())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo("another file"))
+		Expect(os.ReadFile(filepath.Join(destDir, "content (1).txt"))).To(BeEquivalentTo(content))
+		Expect(filepath.Join(destDir, "content (1).txt.info")).To(BeAnExistingFile())
+	}, NodeTimeout(10*time.Second))
+
+	It("should pick the first free suffixed path", func(ctx context.Context) {
+		Expect(os.WriteFile(destConfig.FilePath, []byte("another file"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(destDir, "content (1).txt"), []byte("another file"), 0644)).To(Succeed())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithCollisionSuffixing())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		Expect(os.ReadFile(filepath.Join(destDir, "content (2).txt"))).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should skip the identical file transferred from the same source", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithCollisionSuffixing())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Skipped).To(BeTrue())
+		Expect(filepath.Join(destDir, "content (1).txt")).ToNot(BeAnExistingFile())
+	}, NodeTimeout(10*time.Second))
+
+	It("should return error when the destination cannot check whether a file exists", func(ctx context.Context) {
+		destConfig.Storage = struct{ storage.Destination }{destConfig.Storage}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithCollisionSuffixing())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(MatchError(fxfer.ErrCollisionUnsupported))
+	}, NodeTimeout(10*time.Second))
+})
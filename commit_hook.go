@@ -0,0 +1,41 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCommitFailed is returned when the commit hook of a transfer fails, its finalized destination
+// file is then deleted (see WithCommitHook).
+var ErrCommitFailed = errors.New("commit: the commit hook failed")
+
+// CommitHook commits the transfer to an external state (e.g. writes a database record or sends
+// an event) once its destination file is finalized, the result is the one of the transfer but
+// its S3Operations.
+type CommitHook func(ctx context.Context, result TransferResult) error
+
+// commit calls the commit hook with the result of the finalized transfer, and rolls the transfer
+// back by deleting its destination file, along with its sidecar (see WithSidecar), if it fails.
+func (t *transfer) commit(
+	ctx context.Context,
+	dest DestinationConfig,
+	result TransferResult,
+) (err error) {
+	if t.commitHook == nil || isSidecarTransfer(ctx) {
+		return
+	}
+	if err = t.commitHook(ctx, result); err == nil {
+		return
+	}
+	t.logger.Info("commit hook failed, deleting the finalized destination file",
+		"dstPath", dest.FilePath, "errorMessage", err.Error())
+	errs := []error{
+		fmt.Errorf("%w: %w", ErrCommitFailed, err),
+		dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client),
+	}
+	if sc := sidecarFromContext(ctx); sc != nil && sc.finalized {
+		errs = append(errs, sc.dest.Storage.DeleteFile(ctx, sc.dest.FilePath, sc.dest.Client))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with a commit hook", func() {
+	var (
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+		committed  []fxfer.TransferResult
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		committed = nil
+	})
+
+	It("should keep the finalized file once committed", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithCommitHook(func(_ context.Context, result fxfer.TransferResult) error {
+			Expect(destConfig.FilePath).To(BeARegularFile())
+			committed = append(committed, result)
+			return nil
+		}))
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(committed).To(HaveExactElements(HaveField("FinishedAt", Equal(result.FinishedAt))))
+		Expect(os.ReadFile(destConfig.FilePath)).To(HaveLen(1000))
+	}, NodeTimeout(10*time.Second))
+
+	It("should delete the finalized file when the commit fails", func(ctx context.Context) {
+		hookErr := errors.New("record not written")
+		var progresses []fxfer.Progress
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithCommitHook(func(_ context.Context, result fxfer.TransferResult) error {
+			committed = append(committed, result)
+			return hookErr
+		}))
+		err := tfr.Transfer(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			progresses = append(progresses, progress)
+		})
+		Expect(err).To(MatchError(fxfer.ErrCommitFailed))
+		Expect(err).To(MatchError(hookErr))
+
+		Expect(committed).To(HaveLen(1))
+		Expect(destConfig.FilePath).ToNot(BeAnExistingFile())
+		Expect(progresses[len(progresses)-1].Status).To(Equal(fxfer.ProgressStatusInError))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,124 @@
+package fxfer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/derektruong/fxfer/storage"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionMeta is the metadata key of the destination file info recording the compression codec
+const compressionMeta = "compression"
+
+// CompressionCodec defines the supported codecs for compressing the transferred content.
+type CompressionCodec int
+
+const (
+	// NoneCompressionCodec is the default codec, the content is transferred as is.
+	NoneCompressionCodec CompressionCodec = iota
+	// CompressionCodecGzip is the gzip codec, it is widely supported (moderate speed and ratio).
+	CompressionCodecGzip
+	// CompressionCodecZstd is the Zstandard codec (fast, better ratio).
+	CompressionCodecZstd
+)
+
+// String returns the name of the codec, as recorded in the destination file info.
+func (c CompressionCodec) String() string {
+	switch c {
+	case CompressionCodecGzip:
+		return "gzip"
+	case CompressionCodecZstd:
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// NewDecompressReader returns a reader decompressing the content of r, which has
+// been compressed with the codec (see WithCompression).
+func NewDecompressReader(r io.Reader, codec CompressionCodec) (rc io.ReadCloser, err error) {
+	switch codec {
+	case NoneCompressionCodec:
+		rc = io.NopCloser(r)
+	case CompressionCodecGzip:
+		rc, err = gzip.NewReader(r)
+	case CompressionCodecZstd:
+		var decoder *zstd.Decoder
+		if decoder, err = zstd.NewReader(r); err != nil {
+			return
+		}
+		rc = decoder.IOReadCloser()
+	default:
+		err = fmt.Errorf("unsupported compression codec: %d", codec)
+	}
+	return
+}
+
+// compressReader is a reader compressing the content of the underlying reader. The bytes
+// confirmed by the destination (see storage.ConfirmedSizeTracker) are compressed bytes, which do
+// not map to the bytes of the source, so the content is only confirmed to the tracker as a whole,
+// once the destination has confirmed every compressed byte.
+type compressReader struct {
+	pr      *io.PipeReader
+	tracker storage.ConfirmedSizeTracker
+
+	// readSize is the number of source bytes compressed, compressedSize the number of compressed
+	// bytes read, and confirmedSize the number of compressed bytes confirmed by the destination
+	readSize, compressedSize, confirmedSize atomic.Int64
+	eof                                     atomic.Bool
+}
+
+// newCompressReader creates a new compressReader, the content of r is compressed
+// with the codec in a separate goroutine while being read.
+func newCompressReader(r io.Reader, codec CompressionCodec) (c *compressReader, err error) {
+	pr, pw := io.Pipe()
+	var w io.WriteCloser
+	switch codec {
+	case CompressionCodecGzip:
+		w = gzip.NewWriter(pw)
+	case CompressionCodecZstd:
+		if w, err = zstd.NewWriter(pw); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("unsupported compression codec: %d", codec)
+		return
+	}
+
+	c = &compressReader{pr: pr}
+	c.tracker, _ = r.(storage.ConfirmedSizeTracker)
+	go func() {
+		n, copyErr := io.Copy(w, r)
+		c.readSize.Store(n)
+		pw.CloseWithError(errors.Join(copyErr, w.Close()))
+	}()
+	return
+}
+
+// Read reads the compressed content.
+func (c *compressReader) Read(data []byte) (n int, err error) {
+	n, err = c.pr.Read(data)
+	c.compressedSize.Add(int64(n))
+	if err == io.EOF {
+		c.eof.Store(true)
+	}
+	return
+}
+
+// AddConfirmedSize implements the storage.ConfirmedSizeTracker interface, the source bytes are
+// confirmed once the whole compressed content is.
+func (c *compressReader) AddConfirmedSize(n int64) {
+	confirmed := c.confirmedSize.Add(n)
+	if c.tracker != nil && n > 0 && c.eof.Load() && confirmed == c.compressedSize.Load() {
+		c.tracker.AddConfirmedSize(c.readSize.Load())
+	}
+}
+
+// Close stops the compression, the underlying reader is not closed.
+func (c *compressReader) Close() error {
+	return c.pr.Close()
+}
@@ -0,0 +1,141 @@
+package fxfer_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with compression", func() {
+	var (
+		content    string
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+		callback   fxfer.ProgressUpdatedCallback
+		progresses func() []fxfer.Progress
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat(gofakeit.Sentence(20), 100)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt.gz"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		// the progress is also reported by a goroutine, so it is recorded per spec under a lock
+		var mu sync.Mutex
+		var recorded []fxfer.Progress
+		callback = func(progress fxfer.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded = append(recorded, progress)
+		}
+		progresses = func() []fxfer.Progress {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]fxfer.Progress(nil), recorded...)
+		}
+	})
+
+	readDestContent := func(codec fxfer.CompressionCodec) string {
+		GinkgoHelper()
+		f, err := os.Open(destConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		reader, err := fxfer.NewDecompressReader(f, codec)
+		Expect(err).ToNot(HaveOccurred())
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		return string(data)
+	}
+
+	DescribeTable("should compress the content stored by the destination",
+		func(ctx context.Context, codec fxfer.CompressionCodec) {
+			tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithCompression(codec))
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+
+			By("assert the destination is compressed")
+			stat, err := os.Stat(destConfig.FilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stat.Size()).To(BeNumerically("<", len(content)))
+			Expect(readDestContent(codec)).To(Equal(content))
+
+			By("assert the progress reports the uncompressed size")
+			Expect(progresses()).To(ContainElement(HaveField("Status", fxfer.ProgressStatusFinished)))
+			for _, progress := range progresses() {
+				if progress.Status == fxfer.ProgressStatusInProgress || progress.Status == fxfer.ProgressStatusFinalizing {
+					Expect(progress.TotalSize).To(Equal(int64(len(content))))
+				}
+			}
+
+			By("assert the destination confirms the uncompressed size once the compressed content is written")
+			Eventually(progresses).Should(ContainElement(And(
+				HaveField("Status", fxfer.ProgressStatusFinished),
+				HaveField("ConfirmedSize", int64(len(content))),
+			)))
+
+			By("assert a finished compressed destination is not compressed twice")
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(os.Stat(destConfig.FilePath)).To(HaveField("Size()", stat.Size()))
+			Expect(readDestContent(codec)).To(Equal(content))
+		},
+		Entry("gzip", fxfer.CompressionCodecGzip),
+		Entry("zstd", fxfer.CompressionCodecZstd),
+	)
+
+	It("should restart an interrupted compressed transfer from the beginning", func(ctx context.Context) {
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage := destConfig.Storage.(*local.Destination)
+		Expect(destStorage.CreateFileWithMetadata(
+			ctx, destConfig.FilePath, fxfer.SizeUnknown, stat.ModTime(),
+			map[string]string{"compression": "gzip"}, destConfig.Client,
+		)).To(Succeed())
+		_, err = destStorage.TransferFileChunk(
+			ctx, destConfig.FilePath, bytes.NewReader([]byte("partial")), 0, destConfig.Client,
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithCompression(fxfer.CompressionCodecGzip),
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		Expect(readDestContent(fxfer.CompressionCodecGzip)).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should re-create a compressed destination when transferring without compression", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithCompression(fxfer.CompressionCodecGzip),
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+
+		tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		Expect(readDestContent(fxfer.NoneCompressionCodec)).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+})
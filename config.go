@@ -44,3 +44,17 @@ func (src SourceConfig) Validate(ctx context.Context) error {
 func (dest DestinationConfig) Validate(ctx context.Context) error {
 	return validate.StructCtx(ctx, dest)
 }
+
+// Preflight verifies up front that the destination file can be written, e.g. that the
+// credentials are granted the permissions required by the transfer, the error names the
+// missing permission (see storage.Preflighter). It succeeds without any check if the
+// storage does not implement storage.Preflighter.
+func (dest DestinationConfig) Preflight(ctx context.Context) (err error) {
+	if err = dest.Validate(ctx); err != nil {
+		return
+	}
+	if preflighter, ok := dest.Storage.(storage.Preflighter); ok {
+		return preflighter.Preflight(ctx, dest.FilePath, dest.Client)
+	}
+	return
+}
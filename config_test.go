@@ -7,10 +7,14 @@ import (
 
 	"github.com/brianvoe/gofakeit/v7"
 	fxfer "github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
 	s3protoc "github.com/derektruong/fxfer/protoc/s3"
+	"github.com/derektruong/fxfer/storage/local"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
 	"github.com/derektruong/fxfer/storage/s3"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
 )
 
 var _ = Describe("Config", func() {
@@ -24,6 +28,24 @@ var _ = Describe("Config", func() {
 		Expect(cmd.Validate(ctx)).To(Succeed())
 	}, NodeTimeout(10*time.Second))
 
+	It("should preflight destination config without a preflighter", func(ctx context.Context) {
+		cmd := destinationConfigFactory(func(cmd *fxfer.DestinationConfig) {
+			cmd.Storage = mock_storage.NewMockDestination(gomock.NewController(GinkgoT()))
+		})
+		Expect(cmd.Preflight(ctx)).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+
+	It("should preflight local destination config", func(ctx context.Context) {
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		cmd := destinationConfigFactory(func(cmd *fxfer.DestinationConfig) {
+			cmd.FilePath = GinkgoT().TempDir() + "/nested/test.txt"
+			cmd.Storage = localDest
+			cmd.Client = local_protoc.NewIO()
+		})
+		Expect(cmd.Preflight(ctx)).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+
 	DescribeTable(
 		"Validate source config matches with validation",
 		func(ctx context.Context, sourceConfig fxfer.SourceConfig, expectedMsg string) {
@@ -101,9 +123,11 @@ func sourceConfigFactory(editFn func(*fxfer.SourceConfig)) fxfer.SourceConfig {
 }
 
 func destinationConfigFactory(editFn func(*fxfer.DestinationConfig)) fxfer.DestinationConfig {
+	destStorage, err := s3.NewDestination(GinkgoLogr)
+	Expect(err).ToNot(HaveOccurred())
 	cmd := &fxfer.DestinationConfig{
 		FilePath: fmt.Sprintf("%s/%s.%s", gofakeit.Word(), gofakeit.Word(), gofakeit.FileExtension()),
-		Storage:  s3.NewDestination(GinkgoLogr),
+		Storage:  destStorage,
 		Client:   s3protoc.NewClient("http://localhost:9000", "bucket", "us-east-1", "minioadmin", "minioadmin"),
 	}
 	if editFn != nil {
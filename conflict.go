@@ -0,0 +1,51 @@
+package fxfer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+)
+
+// ErrDestinationNewer is returned when the finalized destination file was transferred from
+// a newer version of the source file than the current one (see DestinationNewerPolicy).
+var ErrDestinationNewer = errors.New("destination file is newer than the source file")
+
+// DestinationNewerPolicy defines the behavior of the transfer when the finalized destination
+// file was transferred from a newer version of the source file than the current one, e.g. the
+// destination has been updated by another writer of a multi-writer sync.
+type DestinationNewerPolicy int
+
+const (
+	// DestinationNewerReject is the default policy, the transfer is rejected with
+	// ErrDestinationNewer rather than overwriting the newer content with the stale one.
+	DestinationNewerReject DestinationNewerPolicy = iota
+	// DestinationNewerSkip skips the transfer, the destination file is kept as is.
+	DestinationNewerSkip
+	// DestinationNewerOverwrite re-creates the destination file from the current source file.
+	DestinationNewerOverwrite
+)
+
+// isDestinationNewer reports whether the finalized destination file records a modification
+// time of the source file later than the current one, beyond the tolerance (see
+// WithModTimeTolerance).
+func (t *transfer) isDestinationNewer(srcInfo xferfile.Info, destInfo xferfile.Info) bool {
+	return !destInfo.FinishTime.IsZero() && destInfo.ModTime.Sub(srcInfo.ModTime) > t.modTimeTolerance
+}
+
+// checkDestinationNewer applies the destination newer policy, skip reports whether the
+// transfer is skipped.
+func (t *transfer) checkDestinationNewer(srcInfo xferfile.Info, destInfo xferfile.Info) (skip bool, err error) {
+	if !t.isDestinationNewer(srcInfo, destInfo) {
+		return
+	}
+	switch t.destinationNewerPolicy {
+	case DestinationNewerSkip:
+		skip = true
+	case DestinationNewerReject:
+		err = fmt.Errorf("%w: %s > %s", ErrDestinationNewer,
+			destInfo.ModTime.Format(time.RFC3339), srcInfo.ModTime.Format(time.RFC3339))
+	}
+	return
+}
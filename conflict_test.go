@@ -0,0 +1,110 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer to a destination newer than the source", func() {
+	const newerContent = "the content written from the newer source"
+
+	var (
+		modTime    time.Time
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+		writeSrc   func(content string, modTime time.Time)
+		skipped    func() bool
+		callback   fxfer.ProgressUpdatedCallback
+	)
+
+	BeforeEach(func(ctx context.Context) {
+		tempDir := GinkgoT().TempDir()
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{
+			FilePath: filepath.Join(tempDir, "src", "content.txt"),
+			Storage:  srcStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		writeSrc = func(content string, modTime time.Time) {
+			Expect(os.MkdirAll(filepath.Dir(srcConfig.FilePath), 0755)).To(Succeed())
+			Expect(os.WriteFile(srcConfig.FilePath, []byte(content), 0644)).To(Succeed())
+			Expect(os.Chtimes(srcConfig.FilePath, modTime, modTime)).To(Succeed())
+		}
+		var wasSkipped bool
+		callback = func(progress fxfer.Progress) {
+			if progress.Skipped {
+				wasSkipped = true
+			}
+		}
+		skipped = func() bool { return wasSkipped }
+
+		By("transfer the newer source to the destination")
+		modTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+		writeSrc(newerContent, modTime)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+	})
+
+	Context("when the source is older than the destination", func() {
+		const olderContent = "the stale content"
+
+		BeforeEach(func() {
+			writeSrc(olderContent, modTime.Add(-time.Hour))
+		})
+
+		It("should reject the transfer by default", func(ctx context.Context) {
+			tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(fxfer.ErrDestinationNewer))
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(newerContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should skip the transfer with the skip policy", func(ctx context.Context) {
+			tfr := fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(), fxfer.WithDestinationNewerPolicy(fxfer.DestinationNewerSkip))
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(skipped()).To(BeTrue())
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(newerContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should overwrite the destination with the overwrite policy", func(ctx context.Context) {
+			tfr := fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(), fxfer.WithDestinationNewerPolicy(fxfer.DestinationNewerOverwrite))
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(skipped()).To(BeFalse())
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(olderContent))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	It("should transfer the source again when it is newer than the destination", func(ctx context.Context) {
+		const newestContent = "the newest content"
+		writeSrc(newestContent, modTime.Add(time.Hour))
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		Expect(skipped()).To(BeFalse())
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(newestContent))
+	}, NodeTimeout(10*time.Second))
+
+	It("should skip the identical destination when the source is as old as it", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		Expect(skipped()).To(BeTrue())
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(newerContent))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,99 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with the content type preserved", func() {
+	var (
+		destStorage *metadataRecordingDestination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.xml")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte("<content/>"), 0644)).To(Succeed())
+
+		localSrc, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &metadataRecordingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{
+			FilePath: srcPath,
+			Storage:  &contentTypeSource{Source: localSrc, contentType: "application/vnd.fxfer+xml"},
+			Client:   local_protoc.NewIO(),
+		}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.xml"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should declare the content type of the source for the destination file", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithPreserveContentType())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(destStorage.metadata).To(HaveKeyWithValue(storage.ContentTypeMeta, "application/vnd.fxfer+xml"))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo("<content/>"))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not declare the content type of the source by default", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(destStorage.metadata).ToNot(HaveKey(storage.ContentTypeMeta))
+	}, NodeTimeout(10*time.Second))
+})
+
+// contentTypeSource is a local source recording the content type of the files in their info.
+type contentTypeSource struct {
+	*local.Source
+	contentType string
+}
+
+func (s *contentTypeSource) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	client protoc.Client,
+) (info xferfile.Info, err error) {
+	if info, err = s.Source.GetFileInfo(ctx, filePath, client); err != nil {
+		return
+	}
+	if info.Metadata == nil {
+		info.Metadata = make(map[string]string)
+	}
+	info.Metadata[storage.ContentTypeMeta] = s.contentType
+	return
+}
+
+// metadataRecordingDestination is a local destination recording the metadata of the files it creates.
+type metadataRecordingDestination struct {
+	*local.Destination
+	metadata map[string]string
+}
+
+func (d *metadataRecordingDestination) CreateFileWithMetadata(
+	ctx context.Context,
+	path string, size int64, modTime time.Time, metadata map[string]string,
+	client protoc.Client,
+) error {
+	d.metadata = metadata
+	return d.Destination.CreateFileWithMetadata(ctx, path, size, modTime, metadata, client)
+}
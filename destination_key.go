@@ -0,0 +1,27 @@
+package fxfer
+
+import "errors"
+
+var ErrDestinationKeyEmpty = errors.New("destination key: the key function returned an empty path")
+
+// DestinationKeyFunc derives the path of the destination file (e.g. an S3 object key) from
+// the path of the source file (see WithDestinationKeyFunc), e.g. to add a date prefix, to
+// lowercase the path or to strip a leading directory. It must be deterministic, so that
+// a resumed transfer targets the same destination file.
+type DestinationKeyFunc func(srcPath string) (destPath string)
+
+// resolveDestination replaces the path of the destination file with the path derived
+// from the source file (if any).
+func (t *transfer) resolveDestination(
+	src SourceConfig,
+	dest DestinationConfig,
+) (resolved DestinationConfig, err error) {
+	resolved = dest
+	if t.destinationKeyFunc == nil {
+		return
+	}
+	if resolved.FilePath = t.destinationKeyFunc(src.FilePath); resolved.FilePath == "" {
+		err = ErrDestinationKeyEmpty
+	}
+	return
+}
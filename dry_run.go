@@ -0,0 +1,92 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+)
+
+// DryRunAction is an enum that represents the action a transfer would take
+type DryRunAction int
+
+const (
+	// DryRunActionStart is the action when the destination file does not exist,
+	// it would be created and transferred from the beginning
+	DryRunActionStart DryRunAction = iota
+	// DryRunActionResume is the action when the destination file is incomplete,
+	// it would be resumed from DryRunResult.Offset
+	DryRunActionResume
+	// DryRunActionRestart is the action when the source file has been modified,
+	// the destination file would be re-created and transferred from the beginning
+	DryRunActionRestart
+	// DryRunActionSkip is the action when the destination file is already finished
+	DryRunActionSkip
+)
+
+// DryRunResult is a struct that contains the report of a dry-run transfer
+type DryRunResult struct {
+	// Action is the action the transfer would take
+	Action DryRunAction
+
+	// DestinationPath is the path of the destination file that would be written
+	DestinationPath string
+
+	// TotalSize is the total number of bytes of the source file
+	TotalSize int64
+
+	// Offset is the offset in bytes the transfer would start from
+	Offset int64
+}
+
+// processDryRun computes the action the transfer would take and reports it via the
+// callback, it must not mutate the destination state.
+func (t *transfer) processDryRun(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	result := DryRunResult{
+		Action:          DryRunActionStart,
+		DestinationPath: dest.FilePath,
+		TotalSize:       srcInfo.Size,
+	}
+
+	var destInfo xferfile.Info
+	if destInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
+		if !errors.Is(err, xferfile.ErrFileNotExists) {
+			return
+		}
+		err = nil
+	} else {
+		var skipNewer bool
+		if skipNewer, err = t.checkDestinationNewer(srcInfo, destInfo); err != nil {
+			return
+		}
+		switch {
+		case t.isDestinationFinished(srcInfo, destInfo), skipNewer:
+			result.Action = DryRunActionSkip
+			result.Offset = destInfo.Offset
+		case t.isSourceModified(srcInfo, destInfo),
+			!t.isResumableCompression(destInfo),
+			!t.isResumableEncryption(destInfo):
+			result.Action = DryRunActionRestart
+		default:
+			result.Action = DryRunActionResume
+			result.Offset = destInfo.Offset
+		}
+	}
+
+	t.logger.Info("dry-run file transfer",
+		"srcPath", srcInfo.Path, "dstPath", dest.FilePath,
+		"action", result.Action, "offset", result.Offset, "totalSize", result.TotalSize,
+	)
+	cb(Progress{
+		Status:          ProgressStatusDryRun,
+		TotalSize:       result.TotalSize,
+		TransferredSize: result.Offset,
+		DryRun:          &result,
+	})
+	return
+}
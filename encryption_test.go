@@ -0,0 +1,137 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with client encryption", func() {
+	var (
+		key         []byte
+		content     string
+		tempDir     string
+		srcStorage  *local.Source
+		destStorage *local.Destination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		callback    fxfer.ProgressUpdatedCallback
+	)
+
+	BeforeEach(func() {
+		var err error
+		key = []byte(gofakeit.LetterN(crypt.KeySize))
+		tempDir = GinkgoT().TempDir()
+		content = gofakeit.Sentence(200)
+		srcPath := filepath.Join(tempDir, "plain", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err = local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "encrypted", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		callback = func(progress fxfer.Progress) {}
+	})
+
+	// decryptDestination transfers the encrypted destination through a decrypting
+	// source to a plain destination and returns its content
+	decryptDestination := func(ctx context.Context) string {
+		GinkgoHelper()
+		info, err := destStorage.GetFileInfo(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		iv, err := crypt.DecodeIV(info.Metadata)
+		Expect(err).ToNot(HaveOccurred())
+		decryptSource, err := crypt.NewSource(GinkgoLogr, srcStorage, key, iv)
+		Expect(err).ToNot(HaveOccurred())
+
+		decryptedPath := filepath.Join(tempDir, "decrypted", "content.txt")
+		Expect(fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry()).Transfer(ctx,
+			fxfer.SourceConfig{FilePath: destConfig.FilePath, Storage: decryptSource, Client: local_protoc.NewIO()},
+			fxfer.DestinationConfig{FilePath: decryptedPath, Storage: destStorage, Client: local_protoc.NewIO()},
+			callback,
+		)).To(Succeed())
+		data, err := os.ReadFile(decryptedPath)
+		Expect(err).ToNot(HaveOccurred())
+		return string(data)
+	}
+
+	It("should round-trip the file through an encrypting destination and a decrypting source", func(ctx context.Context) {
+		// the progress is also reported by a goroutine, so it is recorded under a lock
+		var mu sync.Mutex
+		var finished []fxfer.Progress
+		recordFinished := func(progress fxfer.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if progress.Status == fxfer.ProgressStatusFinished {
+				finished = append(finished, progress)
+			}
+		}
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithClientEncryption(key))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, recordFinished)).To(Succeed())
+
+		By("assert the destination confirms the encrypted bytes")
+		Eventually(func() []fxfer.Progress {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]fxfer.Progress(nil), finished...)
+		}).Should(ContainElement(HaveField("ConfirmedSize", int64(len(content)))))
+
+		By("assert the destination is encrypted")
+		encrypted, err := os.ReadFile(destConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encrypted).To(HaveLen(len(content)))
+		Expect(string(encrypted)).ToNot(Equal(content))
+
+		By("assert the decrypted content")
+		Expect(decryptDestination(ctx)).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should resume an encrypted transfer by continuing the keystream", func(ctx context.Context) {
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		iv, err := crypt.NewIV()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(destStorage.CreateFileWithMetadata(
+			ctx, destConfig.FilePath, stat.Size(), stat.ModTime(),
+			map[string]string{crypt.IVMeta: crypt.EncodeIV(iv)}, destConfig.Client,
+		)).To(Succeed())
+
+		By("transfer the first encrypted bytes")
+		partial, err := srcStorage.GetFileFromOffset(ctx, srcConfig.FilePath, 0, srcConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		defer partial.Close()
+		encryptedReader, err := crypt.NewReader(partial, key, iv, 0)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = destStorage.TransferFileChunk(ctx, destConfig.FilePath,
+			io.LimitReader(encryptedReader, 37), 0, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("resume the transfer")
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithClientEncryption(key))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		Expect(decryptDestination(ctx)).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should return error if the key is not an AES-256 key", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithClientEncryption(key[:16]))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(crypt.ErrInvalidKey))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,43 @@
+package fxfer
+
+import (
+	"errors"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+// ErrEstimateSizeUnknown is returned when estimating the transfer of a source of unknown size
+var ErrEstimateSizeUnknown = errors.New("estimate: cannot estimate a transfer of unknown size")
+
+// TransferEstimate is the estimated cost of a transfer (see EstimateTransfer)
+type TransferEstimate = storage.TransferEstimate
+
+// EstimateTransfer estimates the cost (requests, bytes and temporary disk usage) of
+// transferring the source file to the destination, without issuing any request. It
+// is useful for dashboards and capacity planning.
+//
+// The file rules of the options are checked against the source file info first. The
+// destination storage must implement storage.Estimator (e.g. s3.Destination),
+// otherwise errors.ErrUnsupported is returned.
+func EstimateTransfer(
+	srcInfo xferfile.Info,
+	dest DestinationConfig,
+	opts ...TransferOption,
+) (estimate TransferEstimate, err error) {
+	t := NewTransfer(logr.Discard(), opts...).(*transfer)
+	if err = t.fileRule.Check(srcInfo); err != nil {
+		return
+	}
+	if srcInfo.Size == xferfile.SizeUnknown {
+		err = ErrEstimateSizeUnknown
+		return
+	}
+	estimator, ok := dest.Storage.(storage.Estimator)
+	if !ok {
+		err = errors.ErrUnsupported
+		return
+	}
+	return estimator.EstimateTransfer(srcInfo.Size)
+}
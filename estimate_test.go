@@ -0,0 +1,58 @@
+package fxfer_test
+
+import (
+	"errors"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/internal/xferfile/xferfiletest"
+	"github.com/derektruong/fxfer/storage/local"
+	"github.com/derektruong/fxfer/storage/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EstimateTransfer", func() {
+	var srcInfo xferfile.Info
+
+	BeforeEach(func() {
+		srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+			i.Extension = "txt"
+			i.Size = 120 * 1024 * 1024
+		})
+	})
+
+	It("should estimate the transfer to a S3 destination", func() {
+		destConfig := destinationConfigFactory(nil)
+		estimate, err := fxfer.EstimateTransfer(srcInfo, destConfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		expected, err := destConfig.Storage.(*s3.Destination).EstimateTransfer(srcInfo.Size)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(estimate).To(Equal(expected))
+		Expect(estimate.TransferredBytes).To(Equal(srcInfo.Size))
+	})
+
+	It("should return error if the source file does not satisfy the file rules", func() {
+		_, err := fxfer.EstimateTransfer(
+			srcInfo, destinationConfigFactory(nil),
+			fxfer.WithMaxFileSize(1024),
+		)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if the size of the source file is unknown", func() {
+		srcInfo.Size = xferfile.SizeUnknown
+		_, err := fxfer.EstimateTransfer(srcInfo, destinationConfigFactory(nil))
+		Expect(err).To(MatchError(fxfer.ErrEstimateSizeUnknown))
+	})
+
+	It("should return error if the destination does not support estimation", func() {
+		destLocal, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = fxfer.EstimateTransfer(srcInfo, destinationConfigFactory(func(cmd *fxfer.DestinationConfig) {
+			cmd.Storage = destLocal
+		}))
+		Expect(errors.Is(err, errors.ErrUnsupported)).To(BeTrue())
+	})
+})
@@ -27,7 +27,8 @@ func ExtractPathsViaCliArgs(args []string, logger logr.Logger) (src, dest, srcPa
 		logger.Info(
 			fmt.Sprintf(
 				"invalid cli args, expected: %s <source> <destination> <source_path> <destination_path>."+
-					" Where <source> and <destination> are one of: [local, ftp, sftp, s3]",
+					" Where <source> and <destination> are one of: [local, ftp, sftp, s3, webdav],"+
+					" <source> may also be one of: [http, https, stdin]",
 				args[0],
 			),
 			"args", args,
@@ -5,17 +5,23 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"log/slog"
 
 	fxfer "github.com/derektruong/fxfer"
 	"github.com/derektruong/fxfer/examples"
 	"github.com/derektruong/fxfer/protoc"
+	httpsrcprotoc "github.com/derektruong/fxfer/protoc/httpsrc"
 	localio "github.com/derektruong/fxfer/protoc/local"
 	s3protoc "github.com/derektruong/fxfer/protoc/s3"
+	webdavprotoc "github.com/derektruong/fxfer/protoc/webdav"
 	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/httpsrc"
 	"github.com/derektruong/fxfer/storage/local"
 	"github.com/derektruong/fxfer/storage/s3"
+	"github.com/derektruong/fxfer/storage/stream"
+	"github.com/derektruong/fxfer/storage/webdav"
 	"github.com/go-logr/logr"
 )
 
@@ -54,6 +60,18 @@ func main() {
 	case "s3":
 		srcClient = s3protoc.NewClient(s3Endpoint, s3Bucket, s3Region, s3AccessKey, s3SecretKey)
 		srcStorage = s3.NewSource(logger)
+	case "webdav":
+		srcClient = newWebDAVClient()
+		srcStorage = webdav.NewSource(logger)
+	case "http", "https":
+		// e.g. `go run simple/main.go https local https://example.com/file.zip <dst_file>`,
+		// HTTP_BEARER_TOKEN is sent if set
+		srcClient = httpsrcprotoc.NewClient(httpsrcprotoc.WithBearerToken(os.Getenv("HTTP_BEARER_TOKEN")))
+		srcStorage = httpsrc.NewSource(logger)
+	case "stdin":
+		// e.g. `cat file | go run simple/main.go stdin s3 - <dst_file>`
+		srcClient = localio.NewIO()
+		srcStorage = stream.NewSource(logger, os.Stdin, fxfer.SizeUnknown, time.Now())
 	default:
 		panic("invalid source storage")
 	}
@@ -70,7 +88,14 @@ func main() {
 		}
 	case "s3":
 		destClient = s3protoc.NewClient(s3Endpoint, s3Bucket, s3Region, s3AccessKey, s3SecretKey)
-		destStorage = s3.NewDestination(logger)
+		destStorage, err = s3.NewDestination(logger)
+		if err != nil {
+			logger.Error(err, "failed to setup s3 destination storage")
+			return
+		}
+	case "webdav":
+		destClient = newWebDAVClient()
+		destStorage = webdav.NewDestination(logger)
 	default:
 		panic("invalid destination storage")
 	}
@@ -96,3 +121,13 @@ func main() {
 		return
 	}
 }
+
+// newWebDAVClient creates the WebDAV client from the envs, e.g. of a Nextcloud server
+// (WEBDAV_ENDPOINT=https://cloud.example.com/remote.php/dav/files/<user>).
+func newWebDAVClient() *webdavprotoc.Client {
+	return webdavprotoc.NewClient(
+		examples.MustGetEnv("WEBDAV_ENDPOINT"),
+		examples.MustGetEnv("WEBDAV_USERNAME"),
+		examples.MustGetEnv("WEBDAV_PASSWORD"),
+	)
+}
@@ -0,0 +1,47 @@
+package fxfer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/xferfile"
+)
+
+var ErrExtensionMismatch = func(srcExt, destExt string) error {
+	return fmt.Errorf("file extension of the destination does not match the source: %s != %s", destExt, srcExt)
+}
+
+// ExtensionMismatchPolicy defines the behavior of the transfer when the extension
+// of the destination path differs from the extension of the source file.
+type ExtensionMismatchPolicy int
+
+const (
+	// ExtensionMismatchAllow is the default policy, the file is transferred and the
+	// extension of the source file is recorded in the destination file info.
+	ExtensionMismatchAllow ExtensionMismatchPolicy = iota
+	// ExtensionMismatchReject rejects the transfer with ErrExtensionMismatch.
+	ExtensionMismatchReject
+)
+
+// sourceExtension returns the extension of the source file when it differs from
+// the extension of the destination path (case-insensitive), ok is false otherwise.
+func sourceExtension(srcInfo xferfile.Info, dest DestinationConfig) (srcExt string, ok bool) {
+	_, destExt := fileutils.SplitFileName(dest.FilePath)
+	if strings.EqualFold(srcInfo.Extension, destExt) {
+		return
+	}
+	return srcInfo.Extension, true
+}
+
+// checkExtension applies the extension mismatch policy to the source file.
+func (t *transfer) checkExtension(srcInfo xferfile.Info, dest DestinationConfig) (err error) {
+	if t.extensionMismatchPolicy != ExtensionMismatchReject {
+		return
+	}
+	if srcExt, ok := sourceExtension(srcInfo, dest); ok {
+		_, destExt := fileutils.SplitFileName(dest.FilePath)
+		return ErrExtensionMismatch(srcExt, destExt)
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package fxfer_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with mismatched extensions", func() {
+	noopCallback := func(fxfer.Progress) {}
+
+	var (
+		tempDir     string
+		destStorage *local.Destination
+		sourceOf    func(name, content string) fxfer.SourceConfig
+		destOf      func(name string) fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+
+		sourceOf = func(name, content string) fxfer.SourceConfig {
+			srcPath := filepath.Join(tempDir, "src", name)
+			Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+			Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+			return fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		}
+		destOf = func(name string) fxfer.DestinationConfig {
+			return fxfer.DestinationConfig{
+				FilePath: filepath.Join(tempDir, "dest", name),
+				Storage:  destStorage,
+				Client:   local_protoc.NewIO(),
+			}
+		}
+	})
+
+	It("should record the source extension in the destination file info", func(ctx context.Context) {
+		content := gofakeit.Sentence(20)
+		srcConfig, destConfig := sourceOf("content.txt", content), destOf("content.md")
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		info, err := destStorage.GetFileInfo(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Extension).To(Equal("txt"))
+		Expect(info.Name).To(Equal("content"))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not collide with a file differing only by extension", func(ctx context.Context) {
+		txtContent, mdContent := gofakeit.Sentence(20), gofakeit.Sentence(30)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, sourceOf("content.txt", txtContent), destOf("content.txt"), noopCallback)).To(Succeed())
+		Expect(tfr.Transfer(ctx, sourceOf("content.md", mdContent), destOf("content.md"), noopCallback)).To(Succeed())
+
+		for name, content := range map[string]string{"content.txt": txtContent, "content.md": mdContent} {
+			destConfig := destOf(name)
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+			info, err := destStorage.GetFileInfo(ctx, destConfig.FilePath, destConfig.Client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Path).To(Equal(destConfig.FilePath))
+			Expect(info.Size).To(Equal(int64(len(content))))
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should resume an interrupted transfer to a mismatched extension", func(ctx context.Context) {
+		content := strings.Repeat(gofakeit.Sentence(20), 10)
+		srcConfig, destConfig := sourceOf("content.txt", content), destOf("content.md")
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("simulate an interrupted transfer")
+		Expect(destStorage.CreateFileWithMetadata(
+			ctx, destConfig.FilePath, int64(len(content)), stat.ModTime(),
+			map[string]string{storage.SourceExtensionMeta: "txt"}, destConfig.Client,
+		)).To(Succeed())
+		half := len(content) / 2
+		_, err = destStorage.TransferFileChunk(
+			ctx, destConfig.FilePath, bytes.NewReader([]byte(content[:half])), 0, destConfig.Client,
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("resume the transfer from the offset of the destination")
+		src := &offsetRecordingSource{Source: srcConfig.Storage}
+		srcConfig.Storage = src
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+		Expect(src.Offsets()).To(Equal([]int64{int64(half)}))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+
+		info, err := destStorage.GetFileInfo(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Extension).To(Equal("txt"))
+		Expect(info.FinishTime).ToNot(BeZero())
+	}, NodeTimeout(10*time.Second))
+
+	It("should reject the transfer with the reject policy", func(ctx context.Context) {
+		srcConfig, destConfig := sourceOf("content.txt", gofakeit.Sentence(20)), destOf("content.md")
+
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithExtensionMismatchPolicy(fxfer.ExtensionMismatchReject),
+		)
+		err := tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).To(MatchError(fxfer.ErrExtensionMismatch("txt", "md")))
+		Expect(destConfig.FilePath).ToNot(BeAnExistingFile())
+
+		By("assert matching extensions are still transferred")
+		Expect(tfr.Transfer(ctx, srcConfig, destOf("content.TXT"), noopCallback)).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,308 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"golang.org/x/sync/errgroup"
+)
+
+// errFanOutDropped is returned when every destination of a fan-out stopped reading the source.
+var errFanOutDropped = errors.New("fan-out: all destinations are dropped")
+
+// fanOutDestination is a destination of a fan-out transfer (see Transfer.TransferFanOut).
+type fanOutDestination struct {
+	config DestinationConfig
+	info   xferfile.Info
+	proxy  *proxyReader
+}
+
+func (t *transfer) TransferFanOut(
+	ctx context.Context,
+	src SourceConfig,
+	dests []DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	ctx, span := t.startSpan(ctx, transferSpanName, srcPathAttributeKey.String(src.FilePath))
+	defer func() { endSpan(span, err) }()
+
+	if err = src.Validate(ctx); err != nil {
+		return
+	}
+	for _, dest := range dests {
+		if err = dest.Validate(ctx); err != nil {
+			return
+		}
+	}
+	if t.encryptionKey != nil && len(t.encryptionKey) != crypt.KeySize {
+		err = crypt.ErrInvalidKey
+		return
+	}
+	if err = t.pauseGate.wait(ctx); err != nil {
+		return
+	}
+
+	var srcInfo xferfile.Info
+	if srcInfo, err = t.getSourceFileInfo(ctx, src); err != nil {
+		return
+	}
+	if err = t.fileRule.Check(srcInfo); err != nil {
+		return
+	}
+
+	// the destinations kept as is are not written, the source is read for the others only
+	fanOutDests := make([]*fanOutDestination, 0, len(dests))
+	var skippedInfo xferfile.Info
+	for _, dest := range dests {
+		if dest, err = t.resolveDestination(src, dest); err != nil {
+			return
+		}
+		if err = t.checkExtension(srcInfo, dest); err != nil {
+			return
+		}
+		if err = t.validate(ctx, srcInfo, dest); err != nil {
+			return
+		}
+		if t.dryRun {
+			if err = t.processDryRun(ctx, srcInfo, dest, cb); err != nil {
+				return
+			}
+			continue
+		}
+
+		var (
+			destInfo xferfile.Info
+			skip     bool
+		)
+		if destInfo, skip, err = t.prepareDestination(ctx, srcInfo, src, dest); err != nil {
+			err = fmt.Errorf("failed to transfer to %s: %w", dest.FilePath, err)
+			return
+		}
+		if skip {
+			skippedInfo = destInfo
+			continue
+		}
+		fanOutDests = append(fanOutDests, &fanOutDestination{config: dest, info: destInfo})
+	}
+	if t.dryRun {
+		return
+	}
+	if len(fanOutDests) == 0 {
+		cb(skippedProgress(skippedInfo))
+		return
+	}
+	return t.fanOut(ctx, srcInfo, src, fanOutDests, cb)
+}
+
+// fanOut reads the source file once from the lowest offset of the destinations and streams
+// its content to all of them at once, each destination skips the content it already has.
+func (t *transfer) fanOut(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	src SourceConfig,
+	dests []*fanOutDestination,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	// the first failed destination cancels the others, unless the transfer continues on error
+	destCtx := ctx
+	eg := new(errgroup.Group)
+	if !t.continueOnError {
+		eg, destCtx = errgroup.WithContext(ctx)
+	}
+
+	offset := dests[0].info.Offset
+	for _, dest := range dests[1:] {
+		offset = min(offset, dest.info.Offset)
+	}
+	var reader io.ReadCloser
+	if reader, err = getSourceFromOffset(destCtx, src, srcInfo, offset); err != nil {
+		return
+	}
+	if t.sourceReadRetries > 0 {
+		reader = newReopeningReader(destCtx, t.logger, src, srcInfo, reader, offset, t.sourceReadRetries)
+	}
+	// the source is closed before the copy is waited for, which unblocks a pending read
+	copyDone := make(chan struct{})
+	defer func() {
+		_ = reader.Close()
+		<-copyDone
+	}()
+
+	writer := new(fanOutWriter)
+	for _, dest := range dests {
+		pipeReader, pipeWriter := newFanOutPipe(t.fanOutBufferSize)
+		dest.proxy = newProxyReader(pipeReader, dest.info.Offset)
+		writer.pipes = append(writer.pipes, &fanOutPipe{fanOutPipeWriter: pipeWriter, skip: dest.info.Offset - offset})
+	}
+
+	t.logger.Info("starting fan-out file transfer",
+		"srcPath", src.FilePath, "destinations", len(dests),
+		"fromOffset", offset, "totalSize", srcInfo.Size)
+
+	startAt := time.Now()
+	stopProgress := make(chan struct{})
+	var progressWg sync.WaitGroup
+	progressWg.Add(1)
+	go func() {
+		defer progressWg.Done()
+		ticker := time.NewTicker(t.refreshProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopProgress:
+				return
+			case <-ticker.C:
+				cb(fanOutProgress(dests, offset, srcInfo.Size, startAt))
+			}
+		}
+	}()
+
+	// the destinations close their pipe once done, the source is read as long as one of them reads it
+	go func() {
+		defer close(copyDone)
+		_, copyErr := io.Copy(writer, t.pauseGate.reader(destCtx, applyMiddlewares(reader, t.readerMiddlewares)))
+		writer.close(copyErr)
+	}()
+
+	errs := make([]error, len(dests))
+	for i, dest := range dests {
+		eg.Go(func() (err error) {
+			defer dest.proxy.Close()
+			metricAttrs := transferAttributes(src, dest.config)
+			t.metrics.recordStarted(destCtx, metricAttrs)
+			destStartAt := time.Now()
+			defer func() {
+				t.metrics.recordFinished(destCtx, metricAttrs, destStartAt,
+					dest.proxy.transferReader.TransferredSize()-dest.info.Offset, err)
+			}()
+
+			if err = t.transferChunk(destCtx, dest.config, dest.info, dest.proxy); err == nil {
+				_, err = t.finalizeTransfer(destCtx, dest.config)
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to transfer to %s: %w", dest.config.FilePath, err)
+				errs[i] = err
+			}
+			if t.continueOnError {
+				return nil
+			}
+			return
+		})
+	}
+	err = eg.Wait()
+	close(stopProgress)
+	progressWg.Wait()
+	if err == nil {
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		progress := fanOutProgress(dests, offset, srcInfo.Size, startAt)
+		progress.Status = ProgressStatusInError
+		progress.Error = err
+		cb(progress)
+		return
+	}
+
+	finishAt := time.Now()
+	cb(Progress{
+		Status:     ProgressStatusFinished,
+		Duration:   finishAt.Sub(startAt),
+		StartAt:    startAt,
+		FinishAt:   finishAt,
+		Percentage: finishedProgress,
+	})
+	t.logger.Info("fan-out file transfer is finished",
+		"srcPath", src.FilePath, "destinations", len(dests), "totalSize", srcInfo.Size)
+	return
+}
+
+// fanOutProgress returns the progress of the slowest destination of the fan-out, which read
+// the source from the offset.
+func fanOutProgress(dests []*fanOutDestination, offset int64, totalSize int64, startAt time.Time) Progress {
+	transferredSize := dests[0].proxy.transferReader.TransferredSize()
+	confirmedSize := dests[0].proxy.ConfirmedSize()
+	for _, dest := range dests[1:] {
+		transferredSize = min(transferredSize, dest.proxy.transferReader.TransferredSize())
+		confirmedSize = min(confirmedSize, dest.proxy.ConfirmedSize())
+	}
+
+	status := ProgressStatusInProgress
+	var percentage int
+	if totalSize != xferfile.SizeUnknown && totalSize > 0 {
+		percentage = int(math.Min(
+			finishedProgress,
+			math.Round(float64(transferredSize)/float64(totalSize)*100),
+		))
+	}
+	if percentage == finishedProgress {
+		percentage = finalizingProgress
+		status = ProgressStatusFinalizing
+	}
+	return Progress{
+		Status:          status,
+		TotalSize:       totalSize,
+		TransferredSize: transferredSize,
+		ConfirmedSize:   confirmedSize,
+		Offset:          offset,
+		Percentage:      percentage,
+		Duration:        time.Since(startAt),
+		Speed:           (transferredSize - offset) / int64(math.Max(1, time.Since(startAt).Seconds())),
+		StartAt:         startAt,
+	}
+}
+
+// fanOutWriter writes the content read once from the source to the pipe of each destination,
+// skipping the content the destination already has. A write blocks until each destination has
+// read it, or buffered it (see WithFanOutBuffer). A destination which stops reading its pipe is
+// dropped, the writes fail once all of them are dropped.
+type fanOutWriter struct {
+	pipes []*fanOutPipe
+}
+
+// fanOutPipe is the pipe of a destination of the fan-out.
+type fanOutPipe struct {
+	fanOutPipeWriter
+	// skip is the number of bytes left to skip before the offset of the destination
+	skip    int64
+	dropped bool
+}
+
+func (w *fanOutWriter) Write(data []byte) (n int, err error) {
+	writing := 0
+	for _, pipe := range w.pipes {
+		if pipe.dropped {
+			continue
+		}
+		pipeData := data
+		if pipe.skip > 0 {
+			skipped := min(pipe.skip, int64(len(pipeData)))
+			pipe.skip -= skipped
+			pipeData = pipeData[skipped:]
+		}
+		if len(pipeData) > 0 {
+			if _, err = pipe.Write(pipeData); err != nil {
+				pipe.dropped = true
+				continue
+			}
+		}
+		writing++
+	}
+	if writing == 0 {
+		return 0, errFanOutDropped
+	}
+	return len(data), nil
+}
+
+// close closes the pipes with the error of the source, a nil error is read as io.EOF.
+func (w *fanOutWriter) close(err error) {
+	for _, pipe := range w.pipes {
+		_ = pipe.CloseWithError(err)
+	}
+}
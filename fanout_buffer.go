@@ -0,0 +1,105 @@
+package fxfer
+
+import (
+	"io"
+	"sync"
+)
+
+// fanOutPipeWriter is the writing half of the pipe of a destination of the fan-out, the pipe
+// is closed with the error of the source once it is read, a nil error is read as io.EOF.
+type fanOutPipeWriter interface {
+	io.Writer
+	CloseWithError(err error) error
+}
+
+// newFanOutPipe returns the pipe of a destination of the fan-out, which buffers up to size
+// bytes (see WithFanOutBuffer), or a synchronous io.Pipe if size is 0.
+func newFanOutPipe(size int) (io.ReadCloser, fanOutPipeWriter) {
+	if size <= 0 {
+		return io.Pipe()
+	}
+	p := &bufferedPipe{buf: make([]byte, size)}
+	p.cond = sync.NewCond(&p.mu)
+	return p, p
+}
+
+// bufferedPipe is a pipe buffering up to the size of its ring buffer, a write blocks while the
+// buffer is full and a read blocks while it is empty. Unlike io.Pipe, the writer runs ahead of
+// the reader by the size of the buffer.
+type bufferedPipe struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  []byte
+	// start is the index of the first buffered byte, length the number of buffered bytes
+	start, length int
+	// writeErr is the error the reads fail with once the buffer is drained, set by CloseWithError
+	writeErr    error
+	writeClosed bool
+	readClosed  bool
+}
+
+func (p *bufferedPipe) Write(data []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(data) > 0 {
+		for p.length == len(p.buf) && !p.readClosed {
+			p.cond.Wait()
+		}
+		if p.readClosed {
+			return n, io.ErrClosedPipe
+		}
+		end := (p.start + p.length) % len(p.buf)
+		free := len(p.buf) - p.length
+		if end >= p.start {
+			free = min(free, len(p.buf)-end)
+		}
+		written := copy(p.buf[end:end+free], data)
+		p.length += written
+		data = data[written:]
+		n += written
+		p.cond.Broadcast()
+	}
+	return
+}
+
+func (p *bufferedPipe) Read(data []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.length == 0 && !p.writeClosed && !p.readClosed {
+		p.cond.Wait()
+	}
+	if p.readClosed {
+		return 0, io.ErrClosedPipe
+	}
+	if p.length == 0 {
+		if p.writeErr != nil {
+			return 0, p.writeErr
+		}
+		return 0, io.EOF
+	}
+	n = copy(data, p.buf[p.start:min(p.start+p.length, len(p.buf))])
+	p.start = (p.start + n) % len(p.buf)
+	p.length -= n
+	p.cond.Broadcast()
+	return
+}
+
+// CloseWithError closes the writing half, the buffered bytes are still read before the error.
+func (p *bufferedPipe) CloseWithError(err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.writeClosed {
+		p.writeClosed, p.writeErr = true, err
+		p.cond.Broadcast()
+	}
+	return nil
+}
+
+// Close closes the reading half, the pending and following writes fail with io.ErrClosedPipe.
+func (p *bufferedPipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readClosed = true
+	p.cond.Broadcast()
+	return nil
+}
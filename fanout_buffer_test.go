@@ -0,0 +1,59 @@
+package fxfer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("bufferedPipe", func() {
+	It("should deliver the content written across the wrap of its buffer", func() {
+		reader, writer := newFanOutPipe(7)
+		content := strings.Repeat("0123456789", 10)
+		go func() {
+			defer GinkgoRecover()
+			for i := 0; i < len(content); i += 3 {
+				_, err := writer.Write([]byte(content[i:min(i+3, len(content))]))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(writer.CloseWithError(nil)).To(Succeed())
+		}()
+		Expect(io.ReadAll(reader)).To(BeEquivalentTo(content))
+	})
+
+	It("should block the writes once the buffer is full", func() {
+		_, writer := newFanOutPipe(4)
+		written := make(chan int)
+		go func() {
+			n, _ := writer.Write([]byte("0123456789"))
+			written <- n
+		}()
+		Consistently(written, 50*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should read the buffered content before the error of the writer", func() {
+		reader, writer := newFanOutPipe(16)
+		writeErr := errors.New("source failed")
+		Expect(writer.Write([]byte("abc"))).To(Equal(3))
+		Expect(writer.CloseWithError(writeErr)).To(Succeed())
+
+		content, err := io.ReadAll(reader)
+		Expect(content).To(BeEquivalentTo("abc"))
+		Expect(err).To(MatchError(writeErr))
+	})
+
+	It("should fail the blocked write once the reader is closed", func() {
+		reader, writer := newFanOutPipe(4)
+		writeErr := make(chan error)
+		go func() {
+			_, err := writer.Write([]byte("0123456789"))
+			writeErr <- err
+		}()
+		Expect(reader.Close()).To(Succeed())
+		Eventually(writeErr).Should(Receive(MatchError(io.ErrClosedPipe)))
+	})
+})
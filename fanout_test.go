@@ -0,0 +1,212 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer to several destinations", func() {
+	var (
+		content     string
+		tempDir     string
+		localDest   *local.Destination
+		srcStorage  *countingSource
+		srcConfig   fxfer.SourceConfig
+		destConfigs []fxfer.DestinationConfig
+		progresses  []fxfer.Progress
+		callback    fxfer.ProgressUpdatedCallback
+	)
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 10000)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		localSrc, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcStorage = &countingSource{Source: localSrc}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfigs = nil
+		for _, name := range []string{"a", "b", "c"} {
+			destConfigs = append(destConfigs, fxfer.DestinationConfig{
+				FilePath: filepath.Join(tempDir, name, "content.txt"),
+				Storage:  localDest,
+				Client:   local_protoc.NewIO(),
+			})
+		}
+		progresses = nil
+		callback = func(progress fxfer.Progress) {
+			progresses = append(progresses, progress)
+		}
+	})
+
+	It("should read the source once for all the destinations", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)).To(Succeed())
+
+		Expect(srcStorage.offsets).To(Equal([]int64{0}))
+		for _, destConfig := range destConfigs {
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		}
+		Expect(progresses).ToNot(BeEmpty())
+		Expect(progresses[len(progresses)-1].Status).To(Equal(fxfer.ProgressStatusFinished))
+	}, NodeTimeout(10*time.Second))
+
+	It("should resume each destination from its own offset", func(ctx context.Context) {
+		for i, size := range []int{40000, 0, 70000} {
+			if size == 0 {
+				continue
+			}
+			destConfig := destConfigs[i]
+			Expect(localDest.CreateFile(ctx, destConfig.FilePath, int64(len(content)),
+				fileModTime(srcConfig.FilePath), destConfig.Client)).To(Succeed())
+			_, err := localDest.TransferFileChunk(ctx, destConfig.FilePath, strings.NewReader(content[:size]), 0,
+				destConfig.Client)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)).To(Succeed())
+
+		Expect(srcStorage.offsets).To(Equal([]int64{0}))
+		for _, destConfig := range destConfigs {
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should read the source from the lowest offset of the destinations", func(ctx context.Context) {
+		for i, size := range []int{40000, 20000} {
+			destConfig := destConfigs[i]
+			Expect(localDest.CreateFile(ctx, destConfig.FilePath, int64(len(content)),
+				fileModTime(srcConfig.FilePath), destConfig.Client)).To(Succeed())
+			_, err := localDest.TransferFileChunk(ctx, destConfig.FilePath, strings.NewReader(content[:size]), 0,
+				destConfig.Client)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs[:2], callback)).To(Succeed())
+
+		Expect(srcStorage.offsets).To(Equal([]int64{20000}))
+		for _, destConfig := range destConfigs[:2] {
+			Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should skip the transfer when all the destinations are identical", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)).To(Succeed())
+
+		progresses = nil
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)).To(Succeed())
+		Expect(srcStorage.offsets).To(HaveLen(1))
+		Expect(progresses).To(HaveExactElements(HaveField("Skipped", BeTrue())))
+	}, NodeTimeout(10*time.Second))
+
+	It("should transfer to the other destinations when one fails", func(ctx context.Context) {
+		chunkErr := errors.New("chunk failed")
+		destConfigs[1].Storage = &spanCapturingDestination{Destination: localDest, err: chunkErr}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithContinueOnError())
+		err := tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)
+		Expect(err).To(MatchError(chunkErr))
+		Expect(err).To(MatchError(ContainSubstring(destConfigs[1].FilePath)))
+
+		Expect(os.ReadFile(destConfigs[0].FilePath)).To(BeEquivalentTo(content))
+		Expect(os.ReadFile(destConfigs[2].FilePath)).To(BeEquivalentTo(content))
+		Expect(progresses[len(progresses)-1].Status).To(Equal(fxfer.ProgressStatusInError))
+	}, NodeTimeout(10*time.Second))
+
+	It("should report the progress of the slowest destination", func(ctx context.Context) {
+		slowDest := &slowDestination{Destination: localDest, delay: 20 * time.Millisecond}
+		destConfigs[2].Storage = slowDest
+
+		var (
+			maxTransferredSize atomic.Int64
+			aheadOfSlowest     atomic.Bool
+		)
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithProgressRefreshInterval(10*time.Millisecond))
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, func(progress fxfer.Progress) {
+			if progress.Status == fxfer.ProgressStatusInProgress {
+				maxTransferredSize.Store(max(maxTransferredSize.Load(), progress.TransferredSize))
+				if progress.TransferredSize > slowDest.readSize.Load() {
+					aheadOfSlowest.Store(true)
+				}
+			}
+		})).To(Succeed())
+		Expect(maxTransferredSize.Load()).To(BeNumerically(">", 0))
+		Expect(aheadOfSlowest.Load()).To(BeFalse())
+		Expect(os.ReadFile(destConfigs[2].FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should buffer the source for a slow destination up to the fan-out buffer", func(ctx context.Context) {
+		content = strings.Repeat("0123456789", 100000)
+		Expect(os.WriteFile(srcConfig.FilePath, []byte(content), 0644)).To(Succeed())
+		slowDest := &slowDestination{Destination: localDest, delay: 5 * time.Millisecond}
+		destConfigs = []fxfer.DestinationConfig{destConfigs[0], destConfigs[2]}
+		destConfigs[1].Storage = slowDest
+
+		// the source runs ahead of the slow destination by the bytes buffered for it
+		const bufferSize = 128 << 10
+		var sourceRead, maxAhead atomic.Int64
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithFanOutBuffer(bufferSize),
+			fxfer.WithReaderMiddleware(func(reader io.Reader) io.Reader {
+				return readerFunc(func(p []byte) (n int, err error) {
+					n, err = reader.Read(p)
+					ahead := sourceRead.Add(int64(n)) - slowDest.readSize.Load()
+					maxAhead.Store(max(maxAhead.Load(), ahead))
+					return
+				})
+			}),
+		)
+		Expect(tfr.TransferFanOut(ctx, srcConfig, destConfigs, callback)).To(Succeed())
+
+		Expect(maxAhead.Load()).To(BeNumerically(">", bufferSize/2))
+		Expect(maxAhead.Load()).To(BeNumerically("<=", bufferSize+32<<10))
+		Expect(os.ReadFile(destConfigs[0].FilePath)).To(BeEquivalentTo(content))
+		Expect(os.ReadFile(destConfigs[1].FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+})
+
+// countingSource is a local source which records the offsets the file is read from.
+type countingSource struct {
+	*local.Source
+	offsets []int64
+}
+
+func (s *countingSource) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (io.ReadCloser, error) {
+	s.offsets = append(s.offsets, offset)
+	return s.Source.GetFileFromOffset(ctx, filePath, offset, cli)
+}
+
+// readerFunc is an io.Reader reading with the function.
+type readerFunc func(p []byte) (n int, err error)
+
+func (f readerFunc) Read(p []byte) (n int, err error) {
+	return f(p)
+}
@@ -0,0 +1,157 @@
+package fxfer_test
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer from a file iterator source", func() {
+	var (
+		srcDir, destDir string
+		srcStorage      *iteratingSource
+		srcConfig       fxfer.SourceConfig
+		destConfig      fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcDir, destDir = filepath.Join(tempDir, "src"), filepath.Join(tempDir, "dest")
+		localSrc, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcStorage = &iteratingSource{Source: localSrc}
+		srcConfig = fxfer.SourceConfig{FilePath: srcDir, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{FilePath: destDir, Storage: destStorage, Client: local_protoc.NewIO()}
+	})
+
+	writeFiles := func(names ...string) {
+		GinkgoHelper()
+		for _, name := range names {
+			path := filepath.Join(srcDir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(name), 0644)).To(Succeed())
+		}
+	}
+
+	It("should transfer the files of a directory as they are listed", func(ctx context.Context) {
+		names := make([]string, 1001)
+		for i := range names {
+			names[i] = fmt.Sprintf("%04d.txt", i)
+		}
+		writeFiles(names...)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDryRun())
+
+		var (
+			firstListed int64
+			progresses  []fxfer.BatchProgress
+		)
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			if len(progresses) == 0 {
+				firstListed = srcStorage.listed.Load()
+			}
+			progresses = append(progresses, progress.BatchProgress)
+		})).To(Succeed())
+		Expect(firstListed).To(BeNumerically("<", len(names)))
+		Expect(progresses).To(HaveLen(len(names)))
+		Expect(progresses[0].TotalFiles).To(BeNumerically("<", len(names)))
+		Expect(progresses[len(names)-1]).To(Equal(fxfer.BatchProgress{
+			CurrentFile:    filepath.Join(srcDir, "1000.txt"),
+			FilesCompleted: 1000,
+			TotalFiles:     1001,
+			BytesCompleted: 8000,
+			TotalBytes:     8008,
+		}))
+	}, NodeTimeout(30*time.Second))
+
+	It("should transfer the sidecar listed in the next window along with its file", func(ctx context.Context) {
+		names := make([]string, 999)
+		for i := range names {
+			names[i] = fmt.Sprintf("%04d.txt", i)
+		}
+		writeFiles(append(names, "x.dat", "x.dat.meta")...)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDryRun(), fxfer.WithSidecar(".meta"))
+
+		var currentFiles []string
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			currentFiles = append(currentFiles, progress.BatchProgress.CurrentFile)
+		})).To(Succeed())
+		Expect(currentFiles).To(HaveLen(1000))
+		Expect(currentFiles).ToNot(ContainElement(filepath.Join(srcDir, "x.dat.meta")))
+	}, NodeTimeout(30*time.Second))
+
+	It("should transfer the iterated files matching the pattern", func(ctx context.Context) {
+		writeFiles(
+			filepath.Join("2024-01", "app-1.log"),
+			filepath.Join("2024-01", "nested", "app-2.log"),
+			filepath.Join("2024-02", "app-3.log"),
+			"app-4.log",
+		)
+		srcConfig.FilePath = filepath.Join(srcDir, "2024-*", "app-*")
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+
+		Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+		Expect(filepath.Join(destDir, "2024-01", "app-1.log")).To(BeAnExistingFile())
+		Expect(filepath.Join(destDir, "2024-02", "app-3.log")).To(BeAnExistingFile())
+		Expect(filepath.Join(destDir, "2024-01", "nested", "app-2.log")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(destDir, "app-4.log")).ToNot(BeAnExistingFile())
+	}, NodeTimeout(10*time.Second))
+
+	It("should return error if no iterated file matches the pattern", func(ctx context.Context) {
+		writeFiles("app-1.log")
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+
+		srcConfig.FilePath = filepath.Join(srcDir, "*.txt")
+		Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(fxfer.ErrGlobNoMatch))
+
+		srcConfig.FilePath = filepath.Join(srcDir, "missing", "*.log")
+		Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(fxfer.ErrGlobNoMatch))
+	}, NodeTimeout(10*time.Second))
+})
+
+// iteratingSource is a local source counting the files it iterates over, which fails the
+// test if its files are listed at once.
+type iteratingSource struct {
+	*local.Source
+	listed atomic.Int64
+}
+
+func (s *iteratingSource) IterFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) iter.Seq2[xferfile.Info, error] {
+	return func(yield func(xferfile.Info, error) bool) {
+		for info, err := range s.Source.IterFiles(ctx, dirPath, cli) {
+			s.listed.Add(1)
+			if !yield(info, err) {
+				return
+			}
+		}
+	}
+}
+
+func (s *iteratingSource) ListFiles(context.Context, string, protoc.Client) ([]xferfile.Info, error) {
+	Fail("the files of a file iterator should not be listed")
+	return nil, nil
+}
+
+func (s *iteratingSource) Glob(context.Context, string, protoc.Client) ([]xferfile.Info, error) {
+	Fail("the files of a file iterator should not be globbed")
+	return nil, nil
+}
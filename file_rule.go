@@ -30,6 +30,12 @@ var (
 	ErrModifiedAfter = func(t time.Time) error {
 		return fmt.Errorf("file was modified after the required time: %s", t.Format(time.RFC3339))
 	}
+	ErrMaxAgeExceeded = func(maxAge time.Duration) error {
+		return fmt.Errorf("file is older than the maximum allowed age: %s", maxAge)
+	}
+	ErrMinAgeNotMet = func(minAge time.Duration) error {
+		return fmt.Errorf("file is newer than the minimum required age: %s", minAge)
+	}
 	ErrFileNamePatternMismatch = func(pattern string) error {
 		return fmt.Errorf("file name does not match the required pattern: %s", pattern)
 	}
@@ -49,16 +55,20 @@ type fileRule struct {
 	ModifiedAfter time.Time
 	// ModifiedBefore allows setting a maximum modified time for transfer.
 	ModifiedBefore time.Time
+	// MaxAge allows setting a maximum age of the modified time, relative to the time of the transfer.
+	MaxAge time.Duration
+	// MinAge allows setting a minimum age of the modified time, relative to the time of the transfer.
+	MinAge time.Duration
 	// FileNamePattern allows setting a regular expression pattern for file names.
 	FileNamePattern *regexp.Regexp
 }
 
 func (r *fileRule) Check(fileInfo xferfile.Info) (err error) {
-	// check file size
+	// check file size (a stream of unknown size cannot be checked up front)
 	if r.MaxFileSize > 0 && fileInfo.Size > r.MaxFileSize {
 		return ErrMaxFileSizeExceeded(r.MaxFileSize, fileInfo.Size)
 	}
-	if r.MinFileSize > 0 && fileInfo.Size < r.MinFileSize {
+	if r.MinFileSize > 0 && fileInfo.Size != xferfile.SizeUnknown && fileInfo.Size < r.MinFileSize {
 		return ErrMinFileSizeNotMet(r.MinFileSize, fileInfo.Size)
 	}
 
@@ -80,6 +90,15 @@ func (r *fileRule) Check(fileInfo xferfile.Info) (err error) {
 		return ErrModifiedBefore(r.ModifiedBefore)
 	}
 
+	// check age of the modified time
+	age := time.Since(fileInfo.ModTime)
+	if r.MaxAge > 0 && age > r.MaxAge {
+		return ErrMaxAgeExceeded(r.MaxAge)
+	}
+	if r.MinAge > 0 && age < r.MinAge {
+		return ErrMinAgeNotMet(r.MinAge)
+	}
+
 	// check file name pattern
 	if r.FileNamePattern != nil &&
 		!r.FileNamePattern.MatchString(filepath.Base(fileInfo.Path)) {
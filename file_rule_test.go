@@ -62,6 +62,26 @@ var _ = Describe("FileRule", func() {
 		Expect(err).To(MatchError(ErrModifiedBefore(rule.ModifiedBefore)))
 	})
 
+	It("should return error when file is older than the maximum allowed age", func() {
+		rule.MaxAge = 30 * 24 * time.Hour
+		fileInfo.ModTime = time.Now().Add(-31 * 24 * time.Hour)
+		err := rule.Check(fileInfo)
+		Expect(err).To(MatchError(ErrMaxAgeExceeded(rule.MaxAge)))
+
+		fileInfo.ModTime = time.Now().Add(-29 * 24 * time.Hour)
+		Expect(rule.Check(fileInfo)).To(Succeed())
+	})
+
+	It("should return error when file is newer than the minimum required age", func() {
+		rule.MinAge = time.Hour
+		fileInfo.ModTime = time.Now().Add(-time.Minute)
+		err := rule.Check(fileInfo)
+		Expect(err).To(MatchError(ErrMinAgeNotMet(rule.MinAge)))
+
+		fileInfo.ModTime = time.Now().Add(-2 * time.Hour)
+		Expect(rule.Check(fileInfo)).To(Succeed())
+	})
+
 	It("should return error when file name does not match the required pattern", func() {
 		rule.FileNamePattern = regexp.MustCompile(`^abc$`)
 		err := rule.Check(fileInfo)
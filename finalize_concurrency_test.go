@@ -0,0 +1,72 @@
+package fxfer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// slowFinalizeDestination is a local destination whose finalizations take a while, recording
+// the maximum number of them in flight at once (the counters are shared by its copies).
+type slowFinalizeDestination struct {
+	*local.Destination
+	inFlight, maxInFlight *atomic.Int32
+}
+
+func (d *slowFinalizeDestination) FinalizeTransfer(ctx context.Context, filePath string, cli protoc.Client) error {
+	n := d.inFlight.Add(1)
+	defer d.inFlight.Add(-1)
+	for current := d.maxInFlight.Load(); n > current && !d.maxInFlight.CompareAndSwap(current, n); {
+		current = d.maxInFlight.Load()
+	}
+	time.Sleep(20 * time.Millisecond)
+	return d.Destination.FinalizeTransfer(ctx, filePath, cli)
+}
+
+var _ = Describe("Transfer with bounded concurrent finalizations", func() {
+	It("should finalize no more files at once than the bound", func(ctx context.Context) {
+		tempDir := GinkgoT().TempDir()
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage := &slowFinalizeDestination{
+			Destination: localDest,
+			inFlight:    new(atomic.Int32),
+			maxInFlight: new(atomic.Int32),
+		}
+
+		var pairs []fxfer.TransferPair
+		for i := range 8 {
+			srcPath := filepath.Join(tempDir, "src", fmt.Sprintf("file-%d.txt", i))
+			Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+			Expect(os.WriteFile(srcPath, []byte(fmt.Sprintf("content of %d", i)), 0644)).To(Succeed())
+			pairs = append(pairs, fxfer.TransferPair{
+				Source: fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()},
+				Destination: fxfer.DestinationConfig{
+					FilePath: filepath.Join(tempDir, "dest", fmt.Sprintf("file-%d.txt", i)),
+					Storage:  destStorage,
+					Client:   local_protoc.NewIO(),
+				},
+			})
+		}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithMaxConcurrentFinalizes(2))
+		Expect(tfr.TransferAll(ctx, pairs, len(pairs), func(int, fxfer.Progress) {})).To(Succeed())
+
+		Expect(destStorage.maxInFlight.Load()).To(BeNumerically("<=", 2))
+		for i, pair := range pairs {
+			Expect(os.ReadFile(pair.Destination.FilePath)).To(BeEquivalentTo(fmt.Sprintf("content of %d", i)))
+		}
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,63 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// ErrFinalizedFileNotVisible is returned when the finalized destination file is still not visible
+// with its expected size once the polls are exhausted (see WithFinalizeConsistencyBackoff).
+var ErrFinalizedFileNotVisible = errors.New("finalize consistency: the finalized file is not visible")
+
+// awaitFinalizedFile polls the destination until the finalized file is visible with the expected
+// size, xferfile.SizeUnknown for any size, waiting between the polls as configured by
+// WithFinalizeConsistencyBackoff. It returns at once if the destination cannot report its files
+// (see storage.ObjectStatter).
+func (t *transfer) awaitFinalizedFile(
+	ctx context.Context,
+	dest DestinationConfig,
+	expectedSize int64,
+) (err error) {
+	statter, ok := dest.Storage.(storage.ObjectStatter)
+	if t.finalizeConsistency == nil || !ok {
+		return
+	}
+	config := *t.finalizeConsistency
+	var size int64
+	var exists bool
+	for n := 0; n < config.MaxRetryAttempts; n++ {
+		if n > 0 {
+			timer := time.NewTimer(config.backOffDelay(uint(n - 1)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if size, exists, err = statter.StatObject(ctx, dest.FilePath, dest.Client); err != nil {
+			return
+		}
+		if exists && (expectedSize == xferfile.SizeUnknown || size == expectedSize) {
+			return
+		}
+		t.logger.V(debugLevel).Info("finalized file is not visible yet",
+			"dstPath", dest.FilePath, "exists", exists, "size", size, "expectedSize", expectedSize,
+			"polls", n+1)
+	}
+	return fmt.Errorf("%w: %s after %d polls", ErrFinalizedFileNotVisible, dest.FilePath, config.MaxRetryAttempts)
+}
+
+// finalizedFileSize returns the size of the destination file finalized from the source file,
+// xferfile.SizeUnknown if its content is transformed into an unknown size (e.g. compressed).
+func (t *transfer) finalizedFileSize(srcInfo xferfile.Info) int64 {
+	if t.compressionCodec != NoneCompressionCodec || len(t.writeMiddlewares) > 0 {
+		return xferfile.SizeUnknown
+	}
+	return srcInfo.Size
+}
@@ -0,0 +1,102 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with a finalize consistency backoff", func() {
+	var (
+		destStorage *eventuallyVisibleDestination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		backoff     fxfer.RetryConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &eventuallyVisibleDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		backoff = fxfer.RetryConfig{
+			MaxRetryAttempts: 5,
+			InitialDelay:     10 * time.Millisecond,
+			MaxDelay:         time.Second,
+			Multiplier:       2,
+			DisableJitter:    true,
+		}
+	})
+
+	It("should poll the destination with increasing delays until the file is visible", func(ctx context.Context) {
+		destStorage.invisiblePolls = 3
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithFinalizeConsistencyBackoff(backoff))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(destStorage.polls).To(HaveLen(4))
+		for i, delay := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+			Expect(destStorage.polls[i+1].Sub(destStorage.polls[i])).To(BeNumerically(">=", delay))
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail once the polls are exhausted", func(ctx context.Context) {
+		destStorage.invisiblePolls = 10
+		backoff.MaxRetryAttempts = 3
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithFinalizeConsistencyBackoff(backoff))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(fxfer.ErrFinalizedFileNotVisible))
+		Expect(destStorage.polls).To(HaveLen(3))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not poll the destination without the option", func(ctx context.Context) {
+		destStorage.invisiblePolls = 10
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+		Expect(destStorage.polls).To(BeEmpty())
+	}, NodeTimeout(10*time.Second))
+})
+
+// eventuallyVisibleDestination is a local destination whose files are not visible for the first
+// invisiblePolls polls (see storage.ObjectStatter), it records the time of each poll.
+type eventuallyVisibleDestination struct {
+	*local.Destination
+	invisiblePolls int
+	polls          []time.Time
+}
+
+func (d *eventuallyVisibleDestination) StatObject(
+	_ context.Context,
+	filePath string,
+	_ protoc.Client,
+) (size int64, exists bool, err error) {
+	d.polls = append(d.polls, time.Now())
+	if len(d.polls) <= d.invisiblePolls {
+		return
+	}
+	var fileStat os.FileInfo
+	if fileStat, err = os.Stat(filePath); err != nil {
+		return
+	}
+	return fileStat.Size(), true, nil
+}
@@ -0,0 +1,83 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// ErrGlobNoMatch is returned by Transfer.TransferGlob when no source file matches the pattern.
+var ErrGlobNoMatch = errors.New("glob: no file matches the pattern")
+
+func (t *transfer) TransferGlob(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	if err = src.Validate(ctx); err != nil {
+		return
+	}
+	if err = dest.Validate(ctx); err != nil {
+		return
+	}
+
+	if iterator, ok := src.Storage.(storage.FileIterator); ok {
+		return t.transferIteratedGlob(ctx, iterator, src, dest, cb)
+	}
+	var srcInfos []xferfile.Info
+	if srcInfos, err = src.Storage.Glob(ctx, src.FilePath, src.Client); err != nil {
+		return
+	}
+	if len(srcInfos) == 0 {
+		return fmt.Errorf("%w: %s", ErrGlobNoMatch, src.FilePath)
+	}
+	return t.transferBatch(ctx, srcInfos, globBaseDir(src.FilePath), src, dest, cb)
+}
+
+// transferIteratedGlob transfers the files matching the pattern as they are iterated under the
+// base directory of the pattern (see transferIteratedBatch).
+func (t *transfer) transferIteratedGlob(
+	ctx context.Context,
+	iterator storage.FileIterator,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	pattern, baseDir := src.FilePath, globBaseDir(src.FilePath)
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return
+	}
+	var matches int
+	srcInfos := func(yield func(xferfile.Info, error) bool) {
+		for info, iterErr := range iterator.IterFiles(ctx, baseDir, src.Client) {
+			if iterErr == nil {
+				if matched, _ := filepath.Match(pattern, info.Path); !matched {
+					continue
+				}
+				matches++
+			}
+			if !yield(info, iterErr) {
+				return
+			}
+		}
+	}
+	err = t.transferIteratedBatch(ctx, srcInfos, baseDir, src, dest, cb)
+	// a base directory which does not exist matches no file either
+	if matches == 0 && (err == nil || errors.Is(err, fs.ErrNotExist)) {
+		err = fmt.Errorf("%w: %s", ErrGlobNoMatch, pattern)
+	}
+	return
+}
+
+// globBaseDir returns the deepest directory of the pattern without meta character,
+// e.g. "logs" for "logs/2024-*/app-*.log".
+func globBaseDir(pattern string) string {
+	return filepath.Dir(fileutils.GlobPrefix(pattern))
+}
@@ -0,0 +1,91 @@
+package fxfer
+
+import (
+	"sync"
+	"time"
+)
+
+// heartbeat repeats the last progress of a transfer when no progress has been reported for its
+// interval, e.g. during a long finalization or a slow read (see WithHeartbeat).
+type heartbeat struct {
+	interval time.Duration
+	cb       ProgressUpdatedCallback
+	startAt  time.Time
+
+	// mu is used to protect the last progress and the time it was reported
+	mu       sync.Mutex
+	last     Progress
+	reported time.Time
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+	doneChan chan struct{}
+}
+
+// startHeartbeat starts the heartbeat of the transfer of the source file, cb is the progress
+// callback recording the reported progress, stop ends the heartbeat.
+func (t *transfer) startHeartbeat(
+	totalSize int64,
+	cb ProgressUpdatedCallback,
+) (hbCb ProgressUpdatedCallback, stop func()) {
+	if t.heartbeatInterval <= 0 {
+		return cb, func() {}
+	}
+	now := time.Now()
+	h := &heartbeat{
+		interval: t.heartbeatInterval,
+		cb:       cb,
+		startAt:  now,
+		last:     Progress{Status: ProgressStatusInProgress, TotalSize: totalSize},
+		reported: now,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+	go h.run()
+	return h.report, h.stop
+}
+
+// report records the progress and passes it on to the callback.
+func (h *heartbeat) report(progress Progress) {
+	h.mu.Lock()
+	h.last = progress
+	h.reported = time.Now()
+	h.mu.Unlock()
+	h.cb(progress)
+}
+
+// run repeats the last progress whenever none has been reported for the interval.
+func (h *heartbeat) run() {
+	defer close(h.doneChan)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stopChan:
+			return
+		case now := <-ticker.C:
+			h.mu.Lock()
+			if now.Sub(h.reported) < h.interval {
+				h.mu.Unlock()
+				continue
+			}
+			progress := h.last
+			h.reported = now
+			h.mu.Unlock()
+
+			progress.Heartbeat = true
+			startAt := progress.StartAt
+			if startAt.IsZero() {
+				startAt = h.startAt
+			}
+			progress.Duration = now.Sub(startAt)
+			h.cb(progress)
+		}
+	}
+}
+
+// stop ends the heartbeat, no progress is repeated once it returns.
+func (h *heartbeat) stop() {
+	h.stopOnce.Do(func() { close(h.stopChan) })
+	<-h.doneChan
+}
@@ -0,0 +1,118 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithHeartbeat", func() {
+	var (
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+		pause      fxfer.ReaderMiddleware
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 1000)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+
+		pause = func(reader io.Reader) io.Reader {
+			return &pausingReader{reader: reader, pause: 300 * time.Millisecond}
+		}
+	})
+
+	It("should repeat the progress while the transfer is paused", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithProgressRefreshInterval(time.Hour),
+			fxfer.WithHeartbeat(20*time.Millisecond),
+			fxfer.WithReaderMiddleware(pause),
+		)
+
+		var (
+			mu         sync.Mutex
+			heartbeats []fxfer.Progress
+			finished   bool
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if progress.Heartbeat {
+				Expect(finished).To(BeFalse())
+				heartbeats = append(heartbeats, progress)
+			}
+			finished = finished || progress.Status == fxfer.ProgressStatusFinished
+		})).To(Succeed())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(finished).To(BeTrue())
+		Expect(len(heartbeats)).To(BeNumerically(">=", 5))
+		for i, heartbeat := range heartbeats {
+			Expect(heartbeat.Status).To(Equal(fxfer.ProgressStatusInProgress))
+			Expect(heartbeat.TotalSize).To(Equal(int64(10000)))
+			if i > 0 {
+				Expect(heartbeat.Duration).To(BeNumerically(">", heartbeats[i-1].Duration))
+			}
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should not repeat the progress without the option", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithProgressRefreshInterval(time.Hour),
+			fxfer.WithReaderMiddleware(pause),
+		)
+
+		var mu sync.Mutex
+		var progresses []fxfer.Progress
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			progresses = append(progresses, progress)
+		})).To(Succeed())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(progresses).ToNot(ContainElement(HaveField("Heartbeat", BeTrue())))
+	}, NodeTimeout(10*time.Second))
+})
+
+// pausingReader pauses before its first read, as a source slow to respond.
+type pausingReader struct {
+	reader io.Reader
+	pause  time.Duration
+	paused bool
+}
+
+func (r *pausingReader) Read(p []byte) (n int, err error) {
+	if !r.paused {
+		r.paused = true
+		time.Sleep(r.pause)
+	}
+	return r.reader.Read(p)
+}
@@ -28,3 +28,22 @@ func ExtractFileParts(filePath string) (prefix, fileName, fileExt string, err er
 	}
 	return
 }
+
+// SplitFileName splits the base of the path into the file name and extension,
+// unlike ExtractFileParts, the extension is optional.
+func SplitFileName(filePath string) (fileName, fileExt string) {
+	base := filepath.Base(filePath)
+	fileExt = filepath.Ext(base)
+	fileName = strings.TrimSuffix(base, fileExt)
+	fileExt = strings.TrimPrefix(fileExt, ".")
+	return
+}
+
+// GlobPrefix returns the leading part of the glob pattern which contains no meta
+// character, e.g. "logs/2024-" for "logs/2024-*/app-*.log".
+func GlobPrefix(pattern string) (prefix string) {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
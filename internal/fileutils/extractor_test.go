@@ -77,4 +77,31 @@ var _ = Describe("Extractor", func() {
 			})
 		})
 	})
+
+	Describe("SplitFileName", func() {
+		It("should return file name and extension", func() {
+			fileName, fileExt := fileutils.SplitFileName("sample-prefix/sample-object.tar.gz")
+			Expect(fileName).To(Equal("sample-object.tar"))
+			Expect(fileExt).To(Equal("gz"))
+		})
+
+		It("should return empty extension when the file has no extension", func() {
+			fileName, fileExt := fileutils.SplitFileName("sample-prefix/sample-object")
+			Expect(fileName).To(Equal("sample-object"))
+			Expect(fileExt).To(BeEmpty())
+		})
+	})
+
+	Describe("GlobPrefix", func() {
+		DescribeTable("should return the part before the first meta character",
+			func(pattern, expectedPrefix string) {
+				Expect(fileutils.GlobPrefix(pattern)).To(Equal(expectedPrefix))
+			},
+			Entry("star", "logs/2024-*/app-*.log", "logs/2024-"),
+			Entry("question mark", "logs/app-?.log", "logs/app-"),
+			Entry("character class", "logs/[ab]/app.log", "logs/"),
+			Entry("escape", `logs/\*.log`, "logs/"),
+			Entry("no meta character", "logs/app.log", "logs/app.log"),
+		)
+	})
 })
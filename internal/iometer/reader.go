@@ -75,6 +75,12 @@ func (tr *TransferReader) TransferredSize() int64 {
 	return atomic.LoadInt64(tr.transferredSize)
 }
 
+// AddTransferredSize adds n bytes transferred without being read
+// from the reader (e.g. copied by the storage service itself).
+func (tr *TransferReader) AddTransferredSize(n int64) {
+	atomic.AddInt64(tr.transferredSize, n)
+}
+
 // SetRateLimit sets rate limit (bytes/sec) to the reader.
 func (tr *TransferReader) SetRateLimit(bytesPerSec float64) {
 	tr.limiter = rate.NewLimiter(rate.Limit(bytesPerSec), burstLimit)
@@ -10,6 +10,10 @@ import (
 
 var ErrFileNotExists = errors.New("file path does not exist")
 
+// SizeUnknown is used as Info.Size when the size of the file cannot be determined
+// up front (e.g. a stream), the transfer then reads the source until EOF.
+const SizeUnknown int64 = -1
+
 // Info represents information about the file transfer, this information is stored in the destination file
 type Info struct {
 	// Path is the path of the destination file
@@ -21,7 +25,8 @@ type Info struct {
 	// Name contains the name of the destination file (without extension)
 	Name string `json:"name"`
 
-	// Extension contains the file extension of the file.
+	// Extension contains the file extension of the source file, which may differ
+	// from the extension of the destination path (e.g. "a.txt" transferred to "b.md").
 	Extension string `json:"extension"`
 
 	// ModTime is the modification time of the source file (not destination file)
@@ -44,8 +49,29 @@ type Info struct {
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
-// GenerateInfoPath generates the path of the info file based on the file path
+// IsSizeChanged reports whether the size recorded in the info of the destination file differs
+// from the size of the source file, an unknown size (see SizeUnknown) cannot be compared.
+func IsSizeChanged(srcSize, destSize int64) bool {
+	if srcSize == SizeUnknown || destSize == SizeUnknown {
+		return false
+	}
+	return srcSize != destSize
+}
+
+// GenerateInfoPath generates the path of the info file based on the file path,
+// the extension is kept so that "file.txt" and "file.md" do not share an info file.
 func GenerateInfoPath(filePath string) (infoPath string, err error) {
+	if _, _, _, err = fileutils.ExtractFileParts(filePath); err != nil {
+		return
+	}
+	infoPath = fmt.Sprintf("%s.%s", filePath, "info")
+	return
+}
+
+// GenerateLegacyInfoPath generates the path of the info file in its former format, which
+// dropped the extension of the file path. The info files of the transfers started before the
+// extension was kept are still found under it.
+func GenerateLegacyInfoPath(filePath string) (infoPath string, err error) {
 	var prefix, fileName string
 	if prefix, fileName, _, err = fileutils.ExtractFileParts(filePath); err != nil {
 		return
@@ -11,7 +11,15 @@ var _ = Describe("Info", func() {
 		It("should return info file path", func() {
 			infoPath, err := xferfile.GenerateInfoPath("sample-prefix/sample-object.txt")
 			Expect(err).ToNot(HaveOccurred())
-			Expect(infoPath).To(Equal("sample-prefix/sample-object.info"))
+			Expect(infoPath).To(Equal("sample-prefix/sample-object.txt.info"))
+		})
+
+		It("should not collide for files differing only by extension", func() {
+			txtInfoPath, err := xferfile.GenerateInfoPath("sample-prefix/sample-object.txt")
+			Expect(err).ToNot(HaveOccurred())
+			mdInfoPath, err := xferfile.GenerateInfoPath("sample-prefix/sample-object.md")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txtInfoPath).ToNot(Equal(mdInfoPath))
 		})
 
 		It("should return error when file path is empty", func() {
@@ -19,4 +27,27 @@ var _ = Describe("Info", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	Describe("GenerateLegacyInfoPath", func() {
+		It("should return info file path without the extension", func() {
+			infoPath, err := xferfile.GenerateLegacyInfoPath("sample-prefix/sample-object.txt")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infoPath).To(Equal("sample-prefix/sample-object.info"))
+		})
+
+		It("should return error when file path is empty", func() {
+			_, err := xferfile.GenerateLegacyInfoPath("")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	DescribeTable("IsSizeChanged",
+		func(srcSize, destSize int64, changed bool) {
+			Expect(xferfile.IsSizeChanged(srcSize, destSize)).To(Equal(changed))
+		},
+		Entry("same size", int64(100), int64(100), false),
+		Entry("different size", int64(100), int64(120), true),
+		Entry("unknown source size", xferfile.SizeUnknown, int64(120), false),
+		Entry("unknown destination size", int64(100), xferfile.SizeUnknown, false),
+	)
 })
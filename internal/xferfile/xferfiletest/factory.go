@@ -3,7 +3,6 @@ package xferfiletest
 import (
 	"fmt"
 	"path/filepath"
-	"strings"
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/derektruong/fxfer/internal/xferfile"
@@ -25,7 +24,7 @@ func InfoFactory(editFn func(*xferfile.Info)) xferfile.Info {
 		Offset:     int64(gofakeit.Number(1, 1000000)),
 		Checksum:   gofakeit.ImagePng(10, 10),
 		Metadata: map[string]string{
-			"multipartKey": strings.Replace(path, filepath.Ext(path), ".part", -1),
+			"multipartKey": path + ".part",
 		},
 	}
 	if editFn != nil {
@@ -0,0 +1,110 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer logs", func() {
+	var (
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should log the lifecycle transitions at the Info level only", func(ctx context.Context) {
+		sink := &capturingLogSink{}
+		tfr := fxfer.NewTransfer(logr.New(sink), fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(sink.messages(0)).To(ContainElements("starting file transfer", "file transfer is finished"))
+		Expect(sink.messages(1)).To(BeEmpty())
+	}, NodeTimeout(10*time.Second))
+
+	It("should log the chunks at the debug level with a higher verbosity", func(ctx context.Context) {
+		sink := &capturingLogSink{verbosity: 1}
+		tfr := fxfer.NewTransfer(logr.New(sink), fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(sink.messages(1)).To(ContainElement("file chunk is transferred"))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not log anything when silent", func(ctx context.Context) {
+		sink := &capturingLogSink{verbosity: 1}
+		tfr := fxfer.NewTransfer(logr.New(sink), fxfer.WithDisabledRetry(), fxfer.WithSilent())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(sink.messages(0)).To(BeEmpty())
+		Expect(sink.messages(1)).To(BeEmpty())
+	}, NodeTimeout(10*time.Second))
+})
+
+// capturingLogSink is a logr.LogSink which records the messages of the enabled logs by level,
+// the logs above verbosity are disabled.
+type capturingLogSink struct {
+	verbosity int
+	mu        sync.Mutex
+	logs      map[int][]string
+}
+
+func (s *capturingLogSink) Init(logr.RuntimeInfo) {}
+
+func (s *capturingLogSink) Enabled(level int) bool {
+	return level <= s.verbosity
+}
+
+func (s *capturingLogSink) Info(level int, msg string, _ ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.logs == nil {
+		s.logs = make(map[int][]string)
+	}
+	s.logs[level] = append(s.logs[level], msg)
+}
+
+func (s *capturingLogSink) Error(_ error, msg string, keysAndValues ...any) {
+	s.Info(0, msg, keysAndValues...)
+}
+
+func (s *capturingLogSink) WithValues(...any) logr.LogSink {
+	return s
+}
+
+func (s *capturingLogSink) WithName(string) logr.LogSink {
+	return s
+}
+
+// messages returns the messages logged at the level.
+func (s *capturingLogSink) messages(level int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logs[level]
+}
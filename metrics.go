@@ -0,0 +1,132 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the name of the OpenTelemetry meter of the transfers (see WithMeterProvider).
+const meterName = tracerName
+
+// metric attribute keys of the transfers
+const (
+	srcTypeAttributeKey  = attribute.Key("fxfer.src.type")
+	destTypeAttributeKey = attribute.Key("fxfer.dest.type")
+	outcomeAttributeKey  = attribute.Key("fxfer.outcome")
+)
+
+// outcomes of a transfer attempt, recorded by the duration histogram
+const (
+	outcomeSucceeded = "succeeded"
+	outcomeFailed    = "failed"
+	outcomeCanceled  = "canceled"
+)
+
+// transferMetrics holds the OpenTelemetry instruments of the transfers, a nil transferMetrics
+// records nothing.
+type transferMetrics struct {
+	started     metric.Int64Counter
+	succeeded   metric.Int64Counter
+	failed      metric.Int64Counter
+	retried     metric.Int64Counter
+	transferred metric.Int64Counter
+	duration    metric.Float64Histogram
+}
+
+// newTransferMetrics creates the instruments of the transfers from the meter provider, the
+// errors are reported to the global OpenTelemetry error handler as the instruments are usable
+// regardless.
+func newTransferMetrics(provider metric.MeterProvider) (m *transferMetrics) {
+	meter := provider.Meter(meterName)
+	m = new(transferMetrics)
+	var errs [6]error
+	m.started, errs[0] = meter.Int64Counter("fxfer.transfers.started",
+		metric.WithDescription("Number of transfer attempts which started streaming the content."))
+	m.succeeded, errs[1] = meter.Int64Counter("fxfer.transfers.succeeded",
+		metric.WithDescription("Number of transfer attempts which finalized the destination file."))
+	m.failed, errs[2] = meter.Int64Counter("fxfer.transfers.failed",
+		metric.WithDescription("Number of transfer attempts which failed after they started."))
+	m.retried, errs[3] = meter.Int64Counter("fxfer.transfers.retried",
+		metric.WithDescription("Number of retries of the failed transfer attempts."))
+	m.transferred, errs[4] = meter.Int64Counter("fxfer.transfer.bytes",
+		metric.WithDescription("Number of bytes read from the sources and written to the destinations."),
+		metric.WithUnit("By"))
+	m.duration, errs[5] = meter.Float64Histogram("fxfer.transfer.duration",
+		metric.WithDescription("Duration of the transfer attempts, by outcome."),
+		metric.WithUnit("s"))
+	if err := errors.Join(errs[:]...); err != nil {
+		otel.Handle(err)
+	}
+	return
+}
+
+// recordStarted records a transfer attempt which starts streaming the content.
+func (m *transferMetrics) recordStarted(ctx context.Context, attrs attribute.Set) {
+	if m == nil {
+		return
+	}
+	m.started.Add(ctx, 1, metric.WithAttributeSet(attrs))
+}
+
+// recordRetried records a retry of a failed transfer attempt.
+func (m *transferMetrics) recordRetried(ctx context.Context, attrs attribute.Set) {
+	if m == nil {
+		return
+	}
+	m.retried.Add(ctx, 1, metric.WithAttributeSet(attrs))
+}
+
+// recordFinished records the outcome of a transfer attempt, the bytes it transferred and its
+// duration since startAt. An attempt without error whose context is canceled is canceled.
+func (m *transferMetrics) recordFinished(
+	ctx context.Context,
+	attrs attribute.Set,
+	startAt time.Time,
+	transferredSize int64,
+	err error,
+) {
+	if m == nil {
+		return
+	}
+	outcome := outcomeSucceeded
+	switch {
+	case err != nil:
+		outcome = outcomeFailed
+		m.failed.Add(ctx, 1, metric.WithAttributeSet(attrs))
+	case ctx.Err() != nil:
+		outcome = outcomeCanceled
+	default:
+		m.succeeded.Add(ctx, 1, metric.WithAttributeSet(attrs))
+	}
+	m.transferred.Add(ctx, max(transferredSize, 0), metric.WithAttributeSet(attrs))
+	m.duration.Record(ctx, time.Since(startAt).Seconds(), metric.WithAttributeSet(attrs),
+		metric.WithAttributes(outcomeAttributeKey.String(outcome)))
+}
+
+// transferAttributes returns the metric attributes of the transfer from the source to the destination.
+func transferAttributes(src SourceConfig, dest DestinationConfig) attribute.Set {
+	return attribute.NewSet(
+		srcTypeAttributeKey.String(storageType(src.Storage)),
+		destTypeAttributeKey.String(storageType(dest.Storage)),
+	)
+}
+
+// storageType returns the name of the package of the storage (e.g. "s3", "local"), which
+// keeps the cardinality of the attributes low.
+func storageType(storage any) string {
+	typ := reflect.TypeOf(storage)
+	if typ == nil {
+		return "unknown"
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return path.Base(typ.PkgPath())
+}
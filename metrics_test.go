@@ -0,0 +1,170 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+var _ = Describe("Transfer with a meter provider", func() {
+	var (
+		recorder   *metricRecorder
+		localDest  *local.Destination
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  localDest,
+			Client:   local_protoc.NewIO(),
+		}
+		recorder = new(metricRecorder)
+	})
+
+	It("should record the succeeded transfer", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithMeterProvider(recorder))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(recorder.sums()).To(Equal(map[string]int64{
+			"fxfer.transfers.started":   1,
+			"fxfer.transfers.succeeded": 1,
+			"fxfer.transfer.bytes":      1000,
+		}))
+		Expect(recorder.attributes("fxfer.transfer.duration")).To(ConsistOf(attribute.NewSet(
+			attribute.String("fxfer.src.type", "local"),
+			attribute.String("fxfer.dest.type", "local"),
+			attribute.String("fxfer.outcome", "succeeded"),
+		)))
+	}, NodeTimeout(10*time.Second))
+
+	It("should record the failed and retried transfer attempts", func(ctx context.Context) {
+		destStorage := &spanCapturingDestination{Destination: localDest, err: errors.New("chunk failed")}
+		destConfig.Storage = destStorage
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithMeterProvider(recorder),
+			fxfer.WithRetryConfig(fxfer.RetryConfig{
+				MaxRetryAttempts: 2,
+				InitialDelay:     time.Millisecond,
+				MaxDelay:         time.Millisecond,
+			}),
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(destStorage.err))
+
+		Expect(recorder.sums()).To(Equal(map[string]int64{
+			"fxfer.transfers.started": 2,
+			"fxfer.transfers.failed":  2,
+			"fxfer.transfers.retried": 1,
+			"fxfer.transfer.bytes":    0,
+		}))
+		failedAttrs := attribute.NewSet(
+			attribute.String("fxfer.src.type", "local"),
+			attribute.String("fxfer.dest.type", "fxfer_test"),
+			attribute.String("fxfer.outcome", "failed"),
+		)
+		Expect(recorder.attributes("fxfer.transfer.duration")).To(Equal([]attribute.Set{failedAttrs, failedAttrs}))
+	}, NodeTimeout(10*time.Second))
+})
+
+// metricRecorder is an in-memory metric.MeterProvider which sums the counters and records
+// the attributes of every measurement.
+type metricRecorder struct {
+	embedded.MeterProvider
+	mu           sync.Mutex
+	counters     map[string]int64
+	measurements map[string][]attribute.Set
+}
+
+func (r *metricRecorder) Meter(string, ...metric.MeterOption) metric.Meter {
+	return recordingMeter{recorder: r}
+}
+
+func (r *metricRecorder) record(name string, incr int64, attrs attribute.Set, counter bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counters == nil {
+		r.counters = make(map[string]int64)
+		r.measurements = make(map[string][]attribute.Set)
+	}
+	if counter {
+		r.counters[name] += incr
+	}
+	r.measurements[name] = append(r.measurements[name], attrs)
+}
+
+func (r *metricRecorder) sums() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sums := make(map[string]int64, len(r.counters))
+	for name, sum := range r.counters {
+		sums[name] = sum
+	}
+	return sums
+}
+
+func (r *metricRecorder) attributes(name string) []attribute.Set {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]attribute.Set(nil), r.measurements[name]...)
+}
+
+type recordingMeter struct {
+	noop.Meter
+	recorder *metricRecorder
+}
+
+func (m recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return recordingCounter{name: name, recorder: m.recorder}, nil
+}
+
+func (m recordingMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return recordingHistogram{name: name, recorder: m.recorder}, nil
+}
+
+type recordingCounter struct {
+	embedded.Int64Counter
+	name     string
+	recorder *metricRecorder
+}
+
+func (c recordingCounter) Add(_ context.Context, incr int64, opts ...metric.AddOption) {
+	config := metric.NewAddConfig(opts)
+	c.recorder.record(c.name, incr, config.Attributes(), true)
+}
+
+type recordingHistogram struct {
+	embedded.Float64Histogram
+	name     string
+	recorder *metricRecorder
+}
+
+func (h recordingHistogram) Record(_ context.Context, _ float64, opts ...metric.RecordOption) {
+	config := metric.NewRecordConfig(opts)
+	h.recorder.record(h.name, 0, config.Attributes(), false)
+}
@@ -0,0 +1,43 @@
+package fxfer
+
+import (
+	"io"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// ReaderMiddleware transforms the content of a transfer by wrapping its reader, e.g. to count
+// its lines, redact it or throttle it (see WithReaderMiddleware and WithWriteMiddleware). It is
+// called for each attempt of a transfer with the reader of the attempt.
+type ReaderMiddleware func(reader io.Reader) io.Reader
+
+// applyMiddlewares wraps the reader with the middlewares in their order, the first middleware
+// reads from the reader and the last one is read by the caller. The confirmed bytes reported to
+// the wrapped reader are passed on to the reader if it tracks them (see storage.ConfirmedSizeTracker).
+func applyMiddlewares(reader io.Reader, middlewares []ReaderMiddleware) io.Reader {
+	if len(middlewares) == 0 {
+		return reader
+	}
+	wrapped := reader
+	for _, middleware := range middlewares {
+		wrapped = middleware(wrapped)
+	}
+	if tracker, ok := reader.(storage.ConfirmedSizeTracker); ok {
+		if _, ok = wrapped.(storage.ConfirmedSizeTracker); !ok {
+			wrapped = trackedReader{Reader: wrapped, tracker: tracker}
+		}
+	}
+	return wrapped
+}
+
+// trackedReader reports the confirmed bytes of a reader wrapped by the middlewares to the
+// tracker it wraps.
+type trackedReader struct {
+	io.Reader
+	tracker storage.ConfirmedSizeTracker
+}
+
+// AddConfirmedSize implements the storage.ConfirmedSizeTracker interface.
+func (r trackedReader) AddConfirmedSize(n int64) {
+	r.tracker.AddConfirmedSize(n)
+}
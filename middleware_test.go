@@ -0,0 +1,92 @@
+package fxfer_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with reader middlewares", func() {
+	const content = "the quick brown fox jumps over the lazy dog"
+
+	var (
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should transform the content read from the source", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithReaderMiddleware(upperCase))
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(strings.ToUpper(content)))
+		Expect(result.BytesTransferred).To(Equal(int64(len(content))))
+	}, NodeTimeout(10*time.Second))
+
+	It("should apply the middlewares in the order they are appended", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithReaderMiddleware(upperCase),
+			fxfer.WithWriteMiddleware(replaceByte('O', 'E')),
+			fxfer.WithWriteMiddleware(replaceByte('E', '3')),
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(os.ReadFile(destConfig.FilePath)).
+			To(BeEquivalentTo(strings.NewReplacer("O", "3", "E", "3").Replace(strings.ToUpper(content))))
+	}, NodeTimeout(10*time.Second))
+})
+
+// upperCase is a fxfer.ReaderMiddleware which upper-cases the ASCII letters of the content.
+func upperCase(reader io.Reader) io.Reader {
+	return transformReader{Reader: reader, transform: bytes.ToUpper}
+}
+
+// replaceByte returns a fxfer.ReaderMiddleware which replaces the from bytes of the content with to.
+func replaceByte(from, to byte) fxfer.ReaderMiddleware {
+	return func(reader io.Reader) io.Reader {
+		return transformReader{Reader: reader, transform: func(p []byte) []byte {
+			return bytes.ReplaceAll(p, []byte{from}, []byte{to})
+		}}
+	}
+}
+
+// transformReader transforms the bytes read from the reader, preserving their length.
+type transformReader struct {
+	io.Reader
+	transform func([]byte) []byte
+}
+
+func (r transformReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	copy(p, r.transform(p[:n]))
+	return
+}
@@ -0,0 +1,96 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/internal/xferfile/xferfiletest"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Transfer with a modification time tolerance", func() {
+	var (
+		mockClient      *mock_protoc.MockClient
+		mockSrcStorage  *mock_storage.MockSource
+		mockDestStorage *mock_storage.MockDestination
+		srcInfo         xferfile.Info
+		destInfo        xferfile.Info
+		src             fxfer.SourceConfig
+		dest            fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockClient = mock_protoc.NewMockClient(mockCtrl)
+		mockSrcStorage = mock_storage.NewMockSource(mockCtrl)
+		mockDestStorage = mock_storage.NewMockDestination(mockCtrl)
+		srcInfo = xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size = "src-file.txt", "txt", 10
+			info.ModTime = time.Date(2024, 1, 1, 12, 0, 1, 0, time.UTC)
+		})
+		// the destination file is nearly complete, transferred from a source reported a second
+		// earlier, e.g. by a server of a second granularity
+		destInfo = xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size, info.Offset = "dest-file.txt", "txt", srcInfo.Size, 8
+			info.ModTime, info.FinishTime = srcInfo.ModTime.Add(-time.Second), time.Time{}
+		})
+		src = fxfer.SourceConfig{FilePath: srcInfo.Path, Storage: mockSrcStorage, Client: mockClient}
+		dest = fxfer.DestinationConfig{FilePath: destInfo.Path, Storage: mockDestStorage, Client: mockClient}
+		mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), src.FilePath, mockClient).Return(srcInfo, nil)
+	})
+
+	It("should resume the destination file whose modification time differs within the tolerance", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithModTimeTolerance(2*time.Second))
+		gomock.InOrder(
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).Return(destInfo, nil),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), src.FilePath, int64(8), mockClient).
+				Return(io.NopCloser(strings.NewReader("89")), nil),
+			mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), dest.FilePath, gomock.Any(), int64(8), mockClient).
+				Return(int64(2), nil),
+			mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), dest.FilePath, mockClient).Return(nil),
+		)
+		mockDestStorage.EXPECT().DeleteFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		mockDestStorage.EXPECT().CreateFile(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(0)
+
+		Expect(tfr.Transfer(ctx, src, dest, func(fxfer.Progress) {})).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+
+	It("should skip the finished destination file whose modification time differs within the tolerance", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithModTimeTolerance(2*time.Second))
+		destInfo.ModTime, destInfo.Offset, destInfo.FinishTime = srcInfo.ModTime.Add(time.Second), srcInfo.Size, time.Now()
+		mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).Return(destInfo, nil)
+
+		result, err := tfr.TransferWithResult(ctx, src, dest, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Skipped).To(BeTrue())
+	}, NodeTimeout(10*time.Second))
+
+	It("should re-create the destination file whose modification time differs beyond the tolerance", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithModTimeTolerance(500*time.Millisecond))
+		createdInfo := destInfo
+		createdInfo.ModTime, createdInfo.Offset = srcInfo.ModTime, 0
+		gomock.InOrder(
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).Return(destInfo, nil),
+			mockDestStorage.EXPECT().DeleteFile(gomock.Any(), dest.FilePath, mockClient).Return(nil),
+			mockDestStorage.EXPECT().CreateFile(gomock.Any(), dest.FilePath, srcInfo.Size, srcInfo.ModTime, mockClient).
+				Return(nil),
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).Return(createdInfo, nil),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), src.FilePath, int64(0), mockClient).
+				Return(io.NopCloser(strings.NewReader("0123456789")), nil),
+			mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), dest.FilePath, gomock.Any(), int64(0), mockClient).
+				Return(int64(10), nil),
+			mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), dest.FilePath, mockClient).Return(nil),
+		)
+
+		Expect(tfr.Transfer(ctx, src, dest, func(fxfer.Progress) {})).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+})
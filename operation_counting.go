@@ -0,0 +1,87 @@
+package fxfer
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/protoc/s3"
+)
+
+// countedConnectionSuffix is appended to the connection ID of a counting client, so that the
+// storages cache its S3 API apart from the one of the client it wraps.
+const countedConnectionSuffix = "#counted"
+
+// operationCountsKey is the context key of the operation counts of a transfer.
+type operationCountsKey struct{}
+
+// operationCounts counts the S3 operations issued by a transfer, by operation name.
+type operationCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *operationCounts) add(operation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[operation]++
+}
+
+// snapshot returns a copy of the counts.
+func (c *operationCounts) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.counts)
+}
+
+// countOperations wraps the clients of the source and the destination in counting clients and
+// returns the context carrying the counts of the transfer (see WithOperationCounting), the counts
+// already carried by the context are kept. The counts are nil unless the operations are counted.
+func (t *transfer) countOperations(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+) (context.Context, SourceConfig, DestinationConfig, *operationCounts) {
+	if !t.operationCounting {
+		return ctx, src, dest, nil
+	}
+	counts, ok := ctx.Value(operationCountsKey{}).(*operationCounts)
+	if !ok {
+		counts = &operationCounts{counts: make(map[string]int64)}
+		ctx = context.WithValue(ctx, operationCountsKey{}, counts)
+	}
+	src.Client = withOperationCounting(src.Client)
+	dest.Client = withOperationCounting(dest.Client)
+	return ctx, src, dest, counts
+}
+
+// countOperation counts the S3 operation into the counts carried by its context, if any.
+func countOperation(ctx context.Context, operation string, _ time.Duration, _ error) {
+	if counts, ok := ctx.Value(operationCountsKey{}).(*operationCounts); ok {
+		counts.add(operation)
+	}
+}
+
+// countingClient is a protoc.Client whose S3 API counts the operations into the counts carried
+// by their context, the API being cached by the storages across the transfers.
+type countingClient struct {
+	protoc.Client
+}
+
+// withOperationCounting wraps the client in a countingClient, unless it is already wrapped.
+func withOperationCounting(client protoc.Client) protoc.Client {
+	if _, ok := client.(countingClient); ok || client == nil {
+		return client
+	}
+	return countingClient{Client: client}
+}
+
+func (c countingClient) GetS3API() protoc.S3API {
+	return s3.NewInstrumentedAPI(c.Client.GetS3API(), countOperation)
+}
+
+func (c countingClient) GetConnectionID() string {
+	return c.Client.GetConnectionID() + countedConnectionSuffix
+}
@@ -0,0 +1,86 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	s3protoc "github.com/derektruong/fxfer/protoc/s3"
+	"github.com/derektruong/fxfer/storage/local"
+	"github.com/derektruong/fxfer/storage/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Transfer with the operations counted", func() {
+	content := strings.Repeat("0123456789", 100)
+
+	var (
+		mockS3API  *mock_protoc.MockS3API
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockS3API = mock_protoc.NewMockS3API(mockCtrl)
+		mockClient := mock_protoc.NewMockClient(mockCtrl)
+		mockClient.EXPECT().GetConnectionID().Return("mock").AnyTimes()
+		mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+		mockClient.EXPECT().GetCredential().Return(s3protoc.Client{BucketName: "bucket"}).AnyTimes()
+
+		srcStorage := s3.NewSource(GinkgoLogr)
+		DeferCleanup(srcStorage.Close)
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: "folder/content.txt", Storage: srcStorage, Client: mockClient}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(GinkgoT().TempDir(), "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	// expectObject expects the object to be fetched and read the times
+	expectObject := func(times int) {
+		mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(&awss3.HeadObjectOutput{
+			ContentLength: aws.Int64(int64(len(content))),
+			ETag:          aws.String(`"etag"`),
+			LastModified:  aws.Time(time.Now()),
+		}, nil).Times(times)
+		mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, *awss3.GetObjectInput, ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+				return &awss3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}, nil
+			}).Times(times)
+	}
+
+	It("should report the number of each S3 operation issued by the transfer", func(ctx context.Context) {
+		expectObject(1)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithOperationCounting())
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.S3Operations).To(Equal(map[string]int64{"HeadObject": 1, "GetObject": 1}))
+
+		By("count the operations of each transfer apart")
+		expectObject(1)
+		result, err = tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.S3Operations).To(Equal(map[string]int64{"HeadObject": 1, "GetObject": 1}))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not report the operations unless they are counted", func(ctx context.Context) {
+		expectObject(1)
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.S3Operations).To(BeNil())
+	}, NodeTimeout(10*time.Second))
+})
@@ -3,6 +3,11 @@ package fxfer
 import (
 	"regexp"
 	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -12,6 +17,7 @@ const (
 	defaultMaxRetryAttempts = 5
 	defaultInitialDelay     = 1 * time.Second
 	defaultMaxDelay         = 30 * time.Second
+	defaultRetryMultiplier  = 2
 )
 
 type TransferOption func(*transfer)
@@ -70,6 +76,24 @@ func WithModifiedBefore(modTime time.Time) TransferOption {
 	}
 }
 
+// WithSourceMaxAge sets the maximum age of the modified time required for transfer, relative
+// to the time of the transfer (e.g. skip the files older than 30 days), unlike WithModifiedAfter.
+// Default is zero (no restriction).
+func WithSourceMaxAge(maxAge time.Duration) TransferOption {
+	return func(t *transfer) {
+		t.fileRule.MaxAge = max(maxAge, 0)
+	}
+}
+
+// WithSourceMinAge sets the minimum age of the modified time required for transfer, relative
+// to the time of the transfer (e.g. only transfer the files at least 1 hour old, which are no
+// longer being written), unlike WithModifiedBefore. Default is zero (no restriction).
+func WithSourceMinAge(minAge time.Duration) TransferOption {
+	return func(t *transfer) {
+		t.fileRule.MinAge = max(minAge, 0)
+	}
+}
+
 // WithFileNamePattern sets the regular expression pattern for file names.
 // Default is nil (no restriction).
 func WithFileNamePattern(pattern *regexp.Regexp) TransferOption {
@@ -109,6 +133,8 @@ const (
 // WithChecksumAlgorithm sets the checksum algorithm for the transfer.
 // It is recommended to use the default checksum algorithm (CRC32) unless
 // there is a specific requirement for a different algorithm.
+// The checksum of the content read from the source is reported by
+// Transfer.TransferWithResult (see TransferResult.Checksum).
 func WithChecksumAlgorithm(algorithm ChecksumAlgorithm) TransferOption {
 	// TODO: implement checksum algorithm
 	return func(t *transfer) {
@@ -116,6 +142,91 @@ func WithChecksumAlgorithm(algorithm ChecksumAlgorithm) TransferOption {
 	}
 }
 
+// WithCompression compresses the content on the fly with the codec while transferring
+// it, the destination stores the compressed content (see NewDecompressReader to read it
+// back) and records the codec in its file info. Default is NoneCompressionCodec.
+//
+// The compressed size is not known up front, so the destination storage must support
+// files of unknown size (see SizeUnknown). Progress still reports the uncompressed size
+// of the source. As the offsets of the compressed destination do not match the offsets
+// of the source, an interrupted compressed transfer cannot be resumed and restarts from
+// the beginning.
+func WithCompression(codec CompressionCodec) TransferOption {
+	return func(t *transfer) {
+		t.compressionCodec = codec
+	}
+}
+
+// WithClientEncryption encrypts the content with AES-256 (AES-CTR) before it lands in the
+// destination, without relying on a server-side encryption. The key must be 32 bytes long.
+// A random IV is generated for each destination file and recorded in its file info (see
+// crypt.IVMeta), an interrupted transfer is resumed by continuing the keystream from the
+// offset of the destination. Use crypt.NewSource to read back the decrypted content.
+func WithClientEncryption(key []byte) TransferOption {
+	return func(t *transfer) {
+		t.encryptionKey = key
+	}
+}
+
+// WithServerSideCopy copies the content within the storage service when the destination
+// supports it for the source (see storage.ServerSideCopier), e.g. between S3 buckets served by
+// the same endpoint and region, instead of streaming it through the client. The transfer falls
+// back to streaming otherwise, as well as when the content is compressed or encrypted.
+// Default is false.
+func WithServerSideCopy() TransferOption {
+	return func(t *transfer) {
+		t.serverSideCopy = true
+	}
+}
+
+// WithValidator sets a user-defined validator invoked after the built-in file rules
+// (e.g. WithMaxFileSize), the transfer is aborted with the error returned by the
+// validator (see Validator). Default is nil (no validation).
+func WithValidator(validator Validator) TransferOption {
+	return func(t *transfer) {
+		t.validator = validator
+	}
+}
+
+// WithEnumerationConcurrency sets the maximum number of file infos fetched concurrently
+// by Transfer.TransferDirectory before transferring the files. The error fetching the info
+// of a file is reported as the failure of this file (see WithContinueOnError).
+// Default is 1 (the info of each file is fetched right before its transfer).
+func WithEnumerationConcurrency(n int) TransferOption {
+	if n <= 0 {
+		n = 1
+	}
+	return func(t *transfer) {
+		t.enumerationConcurrency = n
+	}
+}
+
+// WithCancelableProgress sets a callback called along with the ProgressUpdatedCallback on
+// every progress update, returning a non-nil error from it aborts the transfer promptly and
+// the transfer returns this error. The partial destination file is preserved, so that the
+// transfer can be resumed later (see WithDeleteOnAbort). Default is nil.
+func WithCancelableProgress(cb CancelableProgressCallback) TransferOption {
+	return func(t *transfer) {
+		t.cancelableProgress = cb
+	}
+}
+
+// WithDeleteOnAbort deletes the partial destination file when the transfer is aborted
+// by the callback of WithCancelableProgress. Default is false (preserved).
+func WithDeleteOnAbort() TransferOption {
+	return func(t *transfer) {
+		t.deleteOnAbort = true
+	}
+}
+
+// WithSilent discards the logs of the transfer, whatever the logger given to NewTransfer. The
+// logs of the storages go to their own logger. Default is false.
+func WithSilent() TransferOption {
+	return func(t *transfer) {
+		t.logger = logr.Discard()
+	}
+}
+
 // WithDisabledRetry disables the retry mechanism for the transfer.
 // Default is false (enabled). If disabled, the transfer will not
 // retry failed transfers, regardless of setting WithRetryConfig option.
@@ -125,6 +236,293 @@ func WithDisabledRetry() TransferOption {
 	}
 }
 
+// WithSourcePreflight opens the source file (and closes it) before creating the destination file,
+// so that an unreadable source (e.g. missing or denied) fails the transfer before any state is
+// created on the destination (e.g. the multipart upload of an S3 object, left to be cleaned up).
+// It costs an extra open of the source for each created destination file. Default is false.
+func WithSourcePreflight() TransferOption {
+	return func(t *transfer) {
+		t.sourcePreflight = true
+	}
+}
+
+// WithContinueOnError continues a batch transfer (e.g. Transfer.TransferDirectory)
+// past individual file failures, the failures are joined and returned at the end.
+// Default is false (the batch stops at the first failure).
+func WithContinueOnError() TransferOption {
+	return func(t *transfer) {
+		t.continueOnError = true
+	}
+}
+
+// WithDryRun reports what would be transferred without writing to the destination.
+// The transfer only fetches the source and destination file info, checks the file
+// rules and emits a single progress with ProgressStatusDryRun (see DryRunResult).
+// Default is false.
+func WithDryRun() TransferOption {
+	return func(t *transfer) {
+		t.dryRun = true
+	}
+}
+
+// WithModTimeTolerance considers the modification times of the source file and of the destination
+// file equal when they differ by at most d, e.g. for the sources reporting them with a coarse
+// granularity (such as the MDTM of some FTP servers), so that a nearly complete destination file is
+// resumed rather than deleted and re-created. A difference within the tolerance is logged.
+// Default is 0 (the modification times must be equal).
+func WithModTimeTolerance(d time.Duration) TransferOption {
+	return func(t *transfer) {
+		t.modTimeTolerance = max(d, 0)
+	}
+}
+
+// WithPreserveContentType declares the content type of the source file for the destination file
+// rather than letting the destination infer one, e.g. the Content-Type of an S3 object copied to
+// another S3 object. The content type is recorded in the info of the destination file, so that
+// it survives a resumption. It applies to the sources reporting the content type (see
+// storage.ContentTypeMeta) and the destinations supporting it. Default is false.
+func WithPreserveContentType() TransferOption {
+	return func(t *transfer) {
+		t.preserveContentType = true
+	}
+}
+
+// WithExtensionMismatchPolicy sets the behavior of the transfer when the extension of the
+// destination path differs from the extension of the source file (see ExtensionMismatchPolicy).
+// Default is ExtensionMismatchAllow.
+func WithExtensionMismatchPolicy(policy ExtensionMismatchPolicy) TransferOption {
+	return func(t *transfer) {
+		t.extensionMismatchPolicy = policy
+	}
+}
+
+// WithDestinationNewerPolicy sets the behavior of the transfer when the finalized destination
+// file was transferred from a newer version of the source file than the current one, e.g. in
+// a multi-writer sync (see DestinationNewerPolicy). Default is DestinationNewerReject.
+func WithDestinationNewerPolicy(policy DestinationNewerPolicy) TransferOption {
+	return func(t *transfer) {
+		t.destinationNewerPolicy = policy
+	}
+}
+
+// WithCollisionSuffixing transfers the file to the first destination path free of another file,
+// the path suffixed with a number before its extension (e.g. "file (1).txt") when a file which has
+// not been transferred from the same source occupies it, so that both versions are preserved. The
+// unfinished destination file is still resumed and the identical one skipped. The destination must
+// be able to check whether a file exists (see storage.ExistenceChecker), otherwise the transfer fails
+// with ErrCollisionUnsupported. Default is disabled.
+func WithCollisionSuffixing() TransferOption {
+	return func(t *transfer) {
+		t.collisionSuffixing = true
+	}
+}
+
+// WithDestinationKeyFunc derives the path of the destination file from the path of the
+// source file (see DestinationKeyFunc), the derived path replaces DestinationConfig.FilePath
+// (the path of each file for TransferDirectory) for every operation on the destination.
+func WithDestinationKeyFunc(keyFunc DestinationKeyFunc) TransferOption {
+	return func(t *transfer) {
+		t.destinationKeyFunc = keyFunc
+	}
+}
+
+// WithAdaptiveThrottling shares a cooldown between the file transfers (e.g. of Transfer.TransferDirectory
+// or of concurrent calls) when the storage throttles the requests (storage.ErrThrottled): each throttled
+// attempt doubles the cooldown up to RetryConfig.MaxDelay, each other attempt decreases it by
+// RetryConfig.InitialDelay. The new file transfers wait for the cooldown, and a throttled attempt is not
+// retried before it. Default is disabled.
+func WithAdaptiveThrottling() TransferOption {
+	return func(t *transfer) {
+		t.adaptiveThrottling = true
+	}
+}
+
+// WithOperationCounting counts the S3 operations issued by each transfer to its source and its
+// destination (e.g. PutObject, UploadPart, ListParts), which are reported by operation name in
+// TransferResult.S3Operations for the cost and performance analysis. The operations of the other
+// protocols are not counted. Default is false.
+func WithOperationCounting() TransferOption {
+	return func(t *transfer) {
+		t.operationCounting = true
+	}
+}
+
+// WithSidecar transfers along with each file its sidecar, the file at the same path with the
+// suffix (e.g. ".meta" for "x.dat.meta" of "x.dat"), to the destination path of the file with the
+// suffix. The sidecar is transferred first but only finalized right after the file, the file is
+// deleted along with its sidecar if the sidecar cannot be finalized (see ErrSidecarNotFinalized).
+// A file without sidecar is transferred alone, and the sidecars listed along with their file are
+// not transferred on their own (see Transfer.TransferDirectory). Default is disabled.
+func WithSidecar(suffix string) TransferOption {
+	return func(t *transfer) {
+		t.sidecarSuffix = suffix
+	}
+}
+
+// WithPeriodicVerification writes the content to the destination in checkpoints of everyBytes bytes,
+// each checkpoint is read back from the destination and compared with the bytes read from the source,
+// so that a silent corruption fails the transfer early (ErrVerificationMismatch) rather than at its end.
+// It trades throughput for early failure detection, and only applies to destinations which can read
+// back an unfinished file (see storage.RangeReader). Default is 0 (disabled).
+func WithPeriodicVerification(everyBytes int64) TransferOption {
+	return func(t *transfer) {
+		t.verificationInterval = max(everyBytes, 0)
+	}
+}
+
+// WithRetryClassifier sets the classifier deciding which errors of a file transfer attempt are
+// retried (see RetryClassifier), it is consulted for every failed attempt, including the errors
+// never retried by default (e.g. the failure to fetch the destination file info). It can extend
+// the default classification, e.g. func(err error) bool { return isMine(err) || DefaultRetryClassifier(err) }.
+// Default is DefaultRetryClassifier.
+func WithRetryClassifier(classifier RetryClassifier) TransferOption {
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+	return func(t *transfer) {
+		t.retryClassifier = classifier
+	}
+}
+
+// WithWriteBuffer accumulates the reads of the source into reads of size bytes before passing
+// them to the destination, so that a source returning the content in small increments (e.g. a
+// slow network) is written in fewer, larger writes (e.g. fewer temporary file writes for S3).
+// A buffer is only passed on once full, unless the source ends or fails. Default is 0 (disabled).
+func WithWriteBuffer(size int) TransferOption {
+	return func(t *transfer) {
+		t.writeBufferSize = max(size, 0)
+	}
+}
+
+// WithSourceRoot sets the root directory of the source files of the batch transfers (e.g.
+// Transfer.TransferDirectory), each file is transferred under the destination directory at its
+// path relative to the root, so that the structure above the transferred directory is preserved,
+// e.g. "/data/a/b/c.txt" of the directory "/data/a" is transferred to "backup/a/b/c.txt" with the
+// root "/data" and the destination directory "backup". The relative paths always use forward
+// slashes, whatever the separator of the source. A file outside the root fails the transfer with
+// ErrSourceOutsideRoot. Default is the source directory (or the base directory of the pattern
+// for Transfer.TransferGlob).
+func WithSourceRoot(root string) TransferOption {
+	return func(t *transfer) {
+		t.sourceRoot = root
+	}
+}
+
+// WithSourceReadRetry reopens the source file (see storage.Source.GetFileFromOffset) at the exact
+// byte it stopped at when a read of its content fails, up to maxAttempts consecutive times, so that
+// the content streamed to the destination continues without a gap or an overlap rather than the
+// whole transfer being retried from the offset of the destination file. The attempts are given
+// again once the reopened source delivers content. Default is 0 (disabled).
+func WithSourceReadRetry(maxAttempts int) TransferOption {
+	return func(t *transfer) {
+		t.sourceReadRetries = max(maxAttempts, 0)
+	}
+}
+
+// WithStallTimeout aborts a transfer with ErrStalled when its throughput, measured over the sliding
+// window at each progress refresh (see WithProgressRefreshInterval), stays below minBytesPerSec, so
+// that a transfer hanging at a trickle is retried rather than left running. The finalization of the
+// destination file is not measured. Default is disabled.
+func WithStallTimeout(minBytesPerSec int64, window time.Duration) TransferOption {
+	return func(t *transfer) {
+		t.stallMinBytesPerSec = max(minBytesPerSec, 0)
+		t.stallWindow = max(window, 0)
+	}
+}
+
+// WithHeartbeat repeats the last progress of a transfer, with Progress.Heartbeat set and its
+// Duration updated, whenever no progress has been reported for the interval, e.g. while the source
+// read is slow, the destination file is finalizing or a failed attempt waits to be retried. Unlike
+// WithStallTimeout, it never fails the transfer, so that a liveness monitor can tell a slow
+// transfer from a dead one. Default is disabled.
+func WithHeartbeat(interval time.Duration) TransferOption {
+	return func(t *transfer) {
+		t.heartbeatInterval = max(interval, 0)
+	}
+}
+
+// WithReaderMiddleware appends the middleware to the ones wrapping the content read from the
+// source, before it is counted by the progress and passed on to the destination. The middlewares
+// are applied in the order they are appended: the first one reads from the source and the last
+// one is read by the transfer. The middlewares must preserve the length of the content, as the
+// destination file is created with the size of the source file and resumed from the number of
+// bytes it has written.
+// The checksum of the transfer covers the content of the source before the middlewares
+// (see TransferResult.Checksum). A transfer with middlewares is not copied server-side.
+func WithReaderMiddleware(middleware ReaderMiddleware) TransferOption {
+	return func(t *transfer) {
+		if middleware != nil {
+			t.readerMiddlewares = append(t.readerMiddlewares, middleware)
+		}
+	}
+}
+
+// WithWriteMiddleware appends the middleware to the ones wrapping the content written to the
+// destination, after it is counted by the progress, compressed and encrypted. The middlewares
+// are applied in the order they are appended: the first one reads the content and the last one
+// is read by the destination. As for WithReaderMiddleware, the middlewares must preserve the
+// length of the content. A transfer with middlewares is not copied server-side.
+func WithWriteMiddleware(middleware ReaderMiddleware) TransferOption {
+	return func(t *transfer) {
+		if middleware != nil {
+			t.writeMiddlewares = append(t.writeMiddlewares, middleware)
+		}
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry tracer provider of the transfer, a Transfer call
+// is traced by a span with a child span for each phase (fetching the source file info, creating
+// the destination file, streaming the content and finalizing the destination file). The spans are
+// carried by the context passed to the storages, so that their calls (e.g. of the S3 SDK) nest
+// under them. Default is the global tracer provider (see otel.GetTracerProvider).
+func WithTracerProvider(provider trace.TracerProvider) TransferOption {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return func(t *transfer) {
+		t.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithMeterProvider sets the OpenTelemetry meter provider of the transfer, which records the
+// transfer attempts started, succeeded, failed and retried, the bytes transferred and the
+// duration of the attempts, by type of source and destination storage (e.g. "s3", "local").
+// Default is the global meter provider (see otel.GetMeterProvider).
+func WithMeterProvider(provider metric.MeterProvider) TransferOption {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	return func(t *transfer) {
+		t.metrics = newTransferMetrics(provider)
+	}
+}
+
+// WithFanOutBuffer buffers up to size bytes of the source per destination of a fan-out (see
+// Transfer.TransferFanOut), so that the faster destinations are not held back by the slowest one
+// until their buffer is full. Default is 0, the destinations read the source in lockstep.
+func WithFanOutBuffer(size int) TransferOption {
+	return func(t *transfer) {
+		t.fanOutBufferSize = max(size, 0)
+	}
+}
+
+// WithCommitHook calls the hook once the destination file of each transfer is finalized, if the hook
+// fails the file is deleted and the transfer fails with ErrCommitFailed. The files of a fan-out
+// (see Transfer.TransferFanOut) are not committed. Default is nil (no hook).
+func WithCommitHook(hook CommitHook) TransferOption {
+	return func(t *transfer) {
+		t.commitHook = hook
+	}
+}
+
+// WithMaxConcurrentFinalizes bounds the number of destination files finalized at once across the
+// transfers, apart from the number of files transferred at once. Default is 0 (unbounded).
+func WithMaxConcurrentFinalizes(n int) TransferOption {
+	return func(t *transfer) {
+		t.maxConcurrentFinalizes = max(n, 0)
+	}
+}
+
 // RetryConfig defines the retry configuration for the transfer.
 type RetryConfig struct {
 	// MaxRetryAttempts is the maximum number of retry attempts, default = 5.
@@ -133,21 +531,30 @@ type RetryConfig struct {
 	InitialDelay time.Duration
 	// MaxDelay is the maximum delay between retries, default = 30 seconds.
 	MaxDelay time.Duration
+	// Multiplier is the factor the delay grows by after each retry, default = 2.
+	Multiplier float64
+	// DisableJitter disables the randomization of each delay between its half and itself, which
+	// keeps the transfers failing at the same time from retrying at the same time, default = false.
+	// It is an opt-out rather than a Jitter field defaulting to true, since the fields of a partial
+	// configuration left unset take their default and an unset bool cannot be told from false.
+	DisableJitter bool
 }
 
 // WithRetryConfig sets the retry configuration for the transfer.
 // Support partial configuration, default values will be used if not set.
 func WithRetryConfig(config RetryConfig) TransferOption {
-	if config.MaxRetryAttempts <= 0 {
-		config.MaxRetryAttempts = defaultMaxRetryAttempts
-	}
-	if config.InitialDelay <= 0 {
-		config.InitialDelay = defaultInitialDelay
-	}
-	if config.MaxDelay <= 0 {
-		config.MaxDelay = defaultMaxDelay
-	}
+	config = config.withDefaults()
 	return func(t *transfer) {
 		t.retryConfig = config
 	}
 }
+
+// WithFinalizeConsistencyBackoff polls an eventually-consistent destination (see
+// storage.ObjectStatter) after finalizing each file until it is visible, spaced as the retries for
+// MaxRetryAttempts polls, then the transfer fails with ErrFinalizedFileNotVisible. Default is disabled.
+func WithFinalizeConsistencyBackoff(config RetryConfig) TransferOption {
+	config = config.withDefaults()
+	return func(t *transfer) {
+		t.finalizeConsistency = &config
+	}
+}
@@ -1,11 +1,15 @@
 package fxfer
 
 import (
+	"context"
+	"io"
 	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
 )
 
 var _ = Describe("Transfer options", func() {
@@ -47,6 +51,16 @@ var _ = Describe("Transfer options", func() {
 		Expect(tfr.fileRule.ModifiedBefore).To(Equal(testTime))
 	})
 
+	It("should set correct source max age", func() {
+		tfr = newTransfer(GinkgoLogr, WithSourceMaxAge(30*24*time.Hour))
+		Expect(tfr.fileRule.MaxAge).To(Equal(30 * 24 * time.Hour))
+	})
+
+	It("should set correct source min age", func() {
+		tfr = newTransfer(GinkgoLogr, WithSourceMinAge(time.Hour))
+		Expect(tfr.fileRule.MinAge).To(Equal(time.Hour))
+	})
+
 	It("should set correct file name pattern", func() {
 		tfr = newTransfer(GinkgoLogr, WithFileNamePattern(nil))
 		Expect(tfr.fileRule.FileNamePattern).To(BeNil())
@@ -67,6 +81,131 @@ var _ = Describe("Transfer options", func() {
 		Expect(tfr.disabledRetry).To(BeTrue())
 	})
 
+	It("should set continue on error", func() {
+		tfr = newTransfer(GinkgoLogr, WithContinueOnError())
+		Expect(tfr.continueOnError).To(BeTrue())
+	})
+
+	It("should set dry run", func() {
+		tfr = newTransfer(GinkgoLogr, WithDryRun())
+		Expect(tfr.dryRun).To(BeTrue())
+	})
+
+	It("should set compression codec", func() {
+		tfr = newTransfer(GinkgoLogr, WithCompression(CompressionCodecZstd))
+		Expect(tfr.compressionCodec).To(Equal(CompressionCodecZstd))
+	})
+
+	It("should set client encryption key", func() {
+		key := []byte("0123456789abcdef0123456789abcdef")
+		tfr = newTransfer(GinkgoLogr, WithClientEncryption(key))
+		Expect(tfr.encryptionKey).To(Equal(key))
+	})
+
+	It("should set server-side copy", func() {
+		tfr = newTransfer(GinkgoLogr, WithServerSideCopy())
+		Expect(tfr.serverSideCopy).To(BeTrue())
+	})
+
+	It("should set validator", func() {
+		tfr = newTransfer(GinkgoLogr, WithValidator(func(context.Context, FileInfo, bool) error {
+			return nil
+		}))
+		Expect(tfr.validator).ToNot(BeNil())
+	})
+
+	It("should set enumeration concurrency", func() {
+		tfr = newTransfer(GinkgoLogr, WithEnumerationConcurrency(8))
+		Expect(tfr.enumerationConcurrency).To(Equal(8))
+	})
+
+	It("should set cancelable progress callback", func() {
+		tfr = newTransfer(GinkgoLogr, WithCancelableProgress(func(Progress) error {
+			return nil
+		}))
+		Expect(tfr.cancelableProgress).ToNot(BeNil())
+	})
+
+	It("should set delete on abort", func() {
+		tfr = newTransfer(GinkgoLogr, WithDeleteOnAbort())
+		Expect(tfr.deleteOnAbort).To(BeTrue())
+	})
+
+	It("should set extension mismatch policy", func() {
+		tfr = newTransfer(GinkgoLogr, WithExtensionMismatchPolicy(ExtensionMismatchReject))
+		Expect(tfr.extensionMismatchPolicy).To(Equal(ExtensionMismatchReject))
+	})
+
+	It("should set destination newer policy", func() {
+		tfr = newTransfer(GinkgoLogr, WithDestinationNewerPolicy(DestinationNewerSkip))
+		Expect(tfr.destinationNewerPolicy).To(Equal(DestinationNewerSkip))
+	})
+
+	It("should set destination key function", func() {
+		tfr = newTransfer(GinkgoLogr, WithDestinationKeyFunc(func(srcPath string) string {
+			return srcPath
+		}))
+		Expect(tfr.destinationKeyFunc).ToNot(BeNil())
+	})
+
+	It("should set adaptive throttling", func() {
+		tfr = newTransfer(GinkgoLogr, WithAdaptiveThrottling())
+		Expect(tfr.adaptiveThrottling).To(BeTrue())
+	})
+
+	It("should set periodic verification interval", func() {
+		tfr = newTransfer(GinkgoLogr, WithPeriodicVerification(4<<20))
+		Expect(tfr.verificationInterval).To(Equal(int64(4 << 20)))
+	})
+
+	It("should set retry classifier", func() {
+		tfr = newTransfer(GinkgoLogr, WithRetryClassifier(func(error) bool {
+			return false
+		}))
+		Expect(tfr.retryClassifier).ToNot(BeNil())
+	})
+
+	It("should set write buffer size", func() {
+		tfr = newTransfer(GinkgoLogr, WithWriteBuffer(64<<10))
+		Expect(tfr.writeBufferSize).To(Equal(64 << 10))
+	})
+
+	It("should set source root", func() {
+		tfr = newTransfer(GinkgoLogr, WithSourceRoot("/data"))
+		Expect(tfr.sourceRoot).To(Equal("/data"))
+	})
+
+	It("should set source read retry", func() {
+		tfr = newTransfer(GinkgoLogr, WithSourceReadRetry(3))
+		Expect(tfr.sourceReadRetries).To(Equal(3))
+
+		tfr = newTransfer(GinkgoLogr, WithSourceReadRetry(-1))
+		Expect(tfr.sourceReadRetries).To(BeZero())
+	})
+
+	It("should append reader middlewares", func() {
+		identity := func(reader io.Reader) io.Reader { return reader }
+		tfr = newTransfer(GinkgoLogr,
+			WithReaderMiddleware(identity), WithReaderMiddleware(nil), WithReaderMiddleware(identity))
+		Expect(tfr.readerMiddlewares).To(HaveLen(2))
+	})
+
+	It("should append write middlewares", func() {
+		identity := func(reader io.Reader) io.Reader { return reader }
+		tfr = newTransfer(GinkgoLogr, WithWriteMiddleware(identity), WithWriteMiddleware(identity))
+		Expect(tfr.writeMiddlewares).To(HaveLen(2))
+	})
+
+	It("should set tracer provider", func() {
+		tfr = newTransfer(GinkgoLogr, WithTracerProvider(tracenoop.NewTracerProvider()))
+		Expect(tfr.tracer).ToNot(BeNil())
+	})
+
+	It("should set meter provider", func() {
+		tfr = newTransfer(GinkgoLogr, WithMeterProvider(noop.NewMeterProvider()))
+		Expect(tfr.metrics).ToNot(BeNil())
+	})
+
 	It("should set correct retry config", func() {
 		tfr = newTransfer(GinkgoLogr, WithRetryConfig(RetryConfig{
 			MaxRetryAttempts: 10,
@@ -76,6 +215,20 @@ var _ = Describe("Transfer options", func() {
 		Expect(tfr.retryConfig.MaxRetryAttempts).To(Equal(10))
 		Expect(tfr.retryConfig.InitialDelay).To(Equal(1 * time.Second))
 		Expect(tfr.retryConfig.MaxDelay).To(Equal(10 * time.Second))
+		Expect(tfr.retryConfig.Multiplier).To(Equal(float64(2)))
+		Expect(tfr.retryConfig.DisableJitter).To(BeFalse())
+	})
+
+	It("should set correct retry backoff shape", func() {
+		tfr = newTransfer(GinkgoLogr, WithRetryConfig(RetryConfig{Multiplier: 1.5}))
+		Expect(tfr.retryConfig.Multiplier).To(Equal(1.5))
+		Expect(tfr.retryConfig.DisableJitter).To(BeFalse())
+	})
+
+	It("should disable the jitter of the retry backoff explicitly", func() {
+		tfr = newTransfer(GinkgoLogr, WithRetryConfig(RetryConfig{DisableJitter: true}))
+		Expect(tfr.retryConfig.Multiplier).To(Equal(float64(2)))
+		Expect(tfr.retryConfig.DisableJitter).To(BeTrue())
 	})
 })
 
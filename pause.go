@@ -0,0 +1,85 @@
+package fxfer
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// pauseGate quiesces the transfers of a transferer (see Transfer.Pause), the readers of the
+// active transfers and the new transfers wait at the gate while it is paused.
+type pauseGate struct {
+	// mu is used to protect the channel closed on resume, nil unless paused
+	mu      sync.Mutex
+	resumed chan struct{}
+}
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+// isPaused reports whether the gate is paused.
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// wait blocks while the gate is paused, it returns the error of the context if it is done first.
+func (g *pauseGate) wait(ctx context.Context) (err error) {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-resumed:
+	}
+	return
+}
+
+// reader returns the reader of the source which waits at the gate before each read, so that
+// a paused transfer stops between the reads of its chunks and parts.
+func (g *pauseGate) reader(ctx context.Context, reader io.Reader) io.Reader {
+	return &pausableReader{ctx: ctx, reader: reader, gate: g}
+}
+
+// pausableReader is a reader which waits at the pause gate before each read.
+type pausableReader struct {
+	ctx    context.Context
+	reader io.Reader
+	gate   *pauseGate
+}
+
+func (r *pausableReader) Read(p []byte) (n int, err error) {
+	if err = r.gate.wait(r.ctx); err != nil {
+		return
+	}
+	return r.reader.Read(p)
+}
+
+func (t *transfer) Pause() {
+	t.pauseGate.pause()
+	t.logger.Info("transferer is paused")
+}
+
+func (t *transfer) Resume() {
+	t.pauseGate.resume()
+	t.logger.Info("transferer is resumed")
+}
@@ -0,0 +1,132 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer.Pause", func() {
+	var (
+		content     string
+		tempDir     string
+		srcConfig   fxfer.SourceConfig
+		destStorage *local.Destination
+	)
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 100000)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+	})
+
+	It("should hold the active and new transfers until resumed", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		slowDest := &slowDestination{Destination: destStorage, delay: 10 * time.Millisecond}
+		activePath := filepath.Join(tempDir, "active", "content.txt")
+		newPath := filepath.Join(tempDir, "new", "content.txt")
+
+		activeDone := make(chan error, 1)
+		go func() {
+			activeDone <- tfr.Transfer(ctx, srcConfig,
+				fxfer.DestinationConfig{FilePath: activePath, Storage: slowDest, Client: local_protoc.NewIO()},
+				func(fxfer.Progress) {})
+		}()
+		Eventually(slowDest.readSize.Load).Should(BeNumerically(">", 0))
+
+		By("pause the transferer while the transfer is in flight")
+		tfr.Pause()
+		// a read which passed the gate before the pause may still complete
+		time.Sleep(50 * time.Millisecond)
+		pausedSize := slowDest.readSize.Load()
+		Consistently(slowDest.readSize.Load, 300*time.Millisecond).Should(Equal(pausedSize))
+		Expect(pausedSize).To(BeNumerically("<", len(content)))
+
+		By("submit a new transfer while paused")
+		newDone := make(chan error, 1)
+		go func() {
+			newDone <- tfr.Transfer(ctx, srcConfig,
+				fxfer.DestinationConfig{FilePath: newPath, Storage: destStorage, Client: local_protoc.NewIO()},
+				func(fxfer.Progress) {})
+		}()
+		Consistently(newDone, 200*time.Millisecond).ShouldNot(Receive())
+		Expect(newPath).ToNot(BeAnExistingFile())
+		Expect(activeDone).ToNot(Receive())
+
+		By("resume the transferer")
+		tfr.Resume()
+		Eventually(activeDone, 5*time.Second).Should(Receive(BeNil()))
+		Eventually(newDone, 5*time.Second).Should(Receive(BeNil()))
+		for _, path := range []string{activePath, newPath} {
+			data, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(content))
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should stop waiting once the context of a paused transfer is canceled", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		tfr.Pause()
+		DeferCleanup(tfr.Resume)
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := tfr.Transfer(cancelCtx, srcConfig,
+			fxfer.DestinationConfig{
+				FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+				Storage:  destStorage,
+				Client:   local_protoc.NewIO(),
+			},
+			func(fxfer.Progress) {})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	}, NodeTimeout(10*time.Second))
+})
+
+// slowDestination is a local destination which reads the content chunk by chunk, waiting
+// for the delay between the chunks.
+type slowDestination struct {
+	*local.Destination
+	delay    time.Duration
+	readSize atomic.Int64
+}
+
+func (d *slowDestination) TransferFileChunk(
+	ctx context.Context,
+	filePath string,
+	reader io.Reader,
+	offset int64,
+	cli protoc.Client,
+) (n int64, err error) {
+	return d.Destination.TransferFileChunk(ctx, filePath, &slowReader{destination: d, reader: reader}, offset, cli)
+}
+
+type slowReader struct {
+	destination *slowDestination
+	reader      io.Reader
+}
+
+func (r *slowReader) Read(p []byte) (n int, err error) {
+	time.Sleep(r.destination.delay)
+	n, err = r.reader.Read(p[:min(len(p), 20000)])
+	r.destination.readSize.Add(int64(n))
+	return
+}
@@ -0,0 +1,88 @@
+package fxfer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressBarWidth is the number of characters of the bar rendered by NewWriterProgress.
+const progressBarWidth = 30
+
+// NewWriterProgress returns a ProgressUpdatedCallback rendering the progress to w as a
+// single line progress bar with the percentage, the speed and the ETA, the line is updated
+// in place with carriage returns (e.g. for os.Stdout of a CLI tool). A final line is
+// printed once the transfer has finished or failed.
+func NewWriterProgress(w io.Writer) ProgressUpdatedCallback {
+	var (
+		mu      sync.Mutex
+		lastLen int
+	)
+	render := func(line string, final bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		// pad the line to overwrite the remains of a longer previous line
+		padding := strings.Repeat(" ", max(lastLen-len(line), 0))
+		lastLen = len(line)
+		if final {
+			padding += "\n"
+			lastLen = 0
+		}
+		_, _ = fmt.Fprintf(w, "\r%s%s", line, padding)
+	}
+
+	return func(progress Progress) {
+		switch progress.Status {
+		case ProgressStatusInProgress:
+			render(fmt.Sprintf("%s %s/s ETA %s",
+				formatProgressBar(progress), formatBytes(progress.Speed), formatETA(progress)), false)
+		case ProgressStatusFinalizing:
+			render(fmt.Sprintf("%s finalizing", formatProgressBar(progress)), false)
+		case ProgressStatusFinished:
+			render(fmt.Sprintf("%s %s in %s", formatProgressBar(progress),
+				formatBytes(progress.TransferredSize), progress.Duration.Round(time.Millisecond)), true)
+		case ProgressStatusInError:
+			render(fmt.Sprintf("%s error: %v", formatProgressBar(progress), progress.Error), true)
+		}
+	}
+}
+
+// formatProgressBar formats the bar and the percentage of the progress, e.g. "[=====>    ]  50%",
+// or the transferred size if the total size is unknown.
+func formatProgressBar(progress Progress) string {
+	if progress.TotalSize == SizeUnknown {
+		return fmt.Sprintf("[%s] %s", strings.Repeat("?", progressBarWidth), formatBytes(progress.TransferredSize))
+	}
+	percentage := min(max(progress.Percentage, 0), 100)
+	filled := percentage * progressBarWidth / 100
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %3d%%", bar, percentage)
+}
+
+// formatETA formats the estimated time remaining at the current speed, "--" if unknown.
+func formatETA(progress Progress) string {
+	if progress.TotalSize == SizeUnknown || progress.Speed <= 0 {
+		return "--"
+	}
+	remaining := max(progress.TotalSize-progress.TransferredSize, 0)
+	return (time.Duration(remaining/progress.Speed) * time.Second).String()
+}
+
+// formatBytes formats the number of bytes with binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
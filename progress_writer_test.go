@@ -0,0 +1,71 @@
+package fxfer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewWriterProgress", func() {
+	var (
+		buf bytes.Buffer
+		cb  ProgressUpdatedCallback
+	)
+
+	BeforeEach(func() {
+		buf.Reset()
+		cb = NewWriterProgress(&buf)
+	})
+
+	It("should update the line in place and report 100% on the final line", func() {
+		cb(Progress{
+			Status:          ProgressStatusInProgress,
+			TotalSize:       4 << 20,
+			TransferredSize: 1 << 20,
+			Percentage:      25,
+			Speed:           1 << 20,
+		})
+		cb(Progress{
+			Status:          ProgressStatusFinalizing,
+			TotalSize:       4 << 20,
+			TransferredSize: 4 << 20,
+			Percentage:      99,
+		})
+		cb(Progress{
+			Status:          ProgressStatusFinished,
+			TotalSize:       4 << 20,
+			TransferredSize: 4 << 20,
+			Percentage:      100,
+			Duration:        2 * time.Second,
+		})
+
+		output := buf.String()
+		Expect(output).To(HaveSuffix("\n"))
+		Expect(strings.Count(output, "\n")).To(Equal(1))
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\r")
+		Expect(lines).To(HaveLen(4))
+		Expect(lines[1]).To(Equal("[=======>                      ]  25% 1.0 MiB/s ETA 3s"))
+		Expect(lines[2]).To(HavePrefix("[=============================>]  99% finalizing"))
+		Expect(strings.TrimSpace(lines[3])).To(Equal("[==============================] 100% 4.0 MiB in 2s"))
+	})
+
+	It("should report the error on the final line", func() {
+		cb(Progress{Status: ProgressStatusInProgress, TotalSize: 100, TransferredSize: 50, Percentage: 50})
+		cb(Progress{Status: ProgressStatusInError, TotalSize: 100, TransferredSize: 50, Percentage: 50,
+			Error: errors.New("connection reset")})
+
+		lines := strings.Split(buf.String(), "\r")
+		Expect(lines[1]).To(HaveSuffix("0 B/s ETA --"))
+		Expect(lines[2]).To(Equal("[===============>              ]  50% error: connection reset\n"))
+	})
+
+	It("should report the transferred size when the total size is unknown", func() {
+		cb(Progress{Status: ProgressStatusInProgress, TotalSize: SizeUnknown, TransferredSize: 1536, Speed: 512})
+
+		Expect(buf.String()).To(Equal("\r[" + strings.Repeat("?", progressBarWidth) + "] 1.5 KiB 512 B/s ETA --"))
+	})
+})
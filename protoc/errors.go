@@ -4,3 +4,4 @@ import "fmt"
 
 var ErrFTPClientConfigInvalid = fmt.Errorf("client: config invalid, expected FTP")
 var ErrSFTPClientConfigInvalid = fmt.Errorf("client: config invalid, expected SFTP")
+var ErrLocalAddrInvalid = fmt.Errorf("client: local address invalid, expected IP address")
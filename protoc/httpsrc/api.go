@@ -0,0 +1,225 @@
+package httpsrc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the URL does not exist on the server.
+var ErrNotFound = errors.New("httpsrc: file not found")
+
+// ErrRangeIgnored is returned when the server responds to a ranged GET with the whole content,
+// the content can then only be read from the beginning.
+var ErrRangeIgnored = errors.New("httpsrc: range ignored by the server")
+
+// ErrRedirectRefused is returned when a redirect response is refused by the redirect policy of
+// the client (see Client.DisableRedirects, Client.MaxRedirects and Client.SameOriginRedirects).
+var ErrRedirectRefused = errors.New("httpsrc: redirect refused")
+
+// defaultMaxRedirects is the maximum number of redirects followed unless Client.MaxRedirects is
+// set, the one of http.Client.
+const defaultMaxRedirects = 10
+
+// StatusError is returned when the server responds to a request with an unexpected status.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("httpsrc: %s %s: unexpected status %d %s",
+		e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPStatusCode returns the status of the response, so that the authentication and
+// authorization failures are not retried.
+func (e *StatusError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// FileInfo is the info of the file at a URL.
+type FileInfo struct {
+	// Size is the size of the file, -1 if the server does not report it
+	Size int64
+	// ModTime is the modification time of the file, zero if the server does not report it
+	ModTime time.Time
+}
+
+// Stat returns the info of the file at the URL (HEAD). The servers refusing HEAD, e.g. for a
+// URL pre-signed for GET only, are asked for the first byte of the file (ranged GET) instead.
+func (c Client) Stat(ctx context.Context, rawURL string) (info FileInfo, err error) {
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodHead, rawURL, nil); err != nil {
+		return
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return fileInfo(res, res.ContentLength)
+	case http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return c.statWithGet(ctx, rawURL)
+	}
+	err = c.statusError(http.MethodHead, rawURL, res.StatusCode)
+	return
+}
+
+// Get returns the content of the file from the offset (ranged GET), ErrRangeIgnored is returned
+// if the server responds with the whole content or with the content from another offset, e.g.
+// when a redirect target does not honor the range.
+func (c Client) Get(ctx context.Context, rawURL string, offset int64) (reader io.ReadCloser, err error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodGet, rawURL, header); err != nil {
+		return
+	}
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(res); ok && start != offset {
+			res.Body.Close()
+			return nil, fmt.Errorf("%w: %s: content from %d instead of %d", ErrRangeIgnored, rawURL, start, offset)
+		}
+		return res.Body, nil
+	case res.StatusCode == http.StatusOK && offset == 0:
+		return res.Body, nil
+	case res.StatusCode == http.StatusOK:
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrRangeIgnored, rawURL)
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// the offset is the end of the file
+		res.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	res.Body.Close()
+	return nil, c.statusError(http.MethodGet, rawURL, res.StatusCode)
+}
+
+// statWithGet returns the info of the file at the URL from the response to a GET of its first
+// byte, the size is the total of its Content-Range.
+func (c Client) statWithGet(ctx context.Context, rawURL string) (info FileInfo, err error) {
+	header := http.Header{}
+	header.Set("Range", "bytes=0-0")
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodGet, rawURL, header); err != nil {
+		return
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return fileInfo(res, res.ContentLength)
+	case http.StatusPartialContent:
+		size := int64(-1)
+		contentRange := res.Header.Get("Content-Range")
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 && contentRange[i+1:] != "*" {
+			if size, err = strconv.ParseInt(contentRange[i+1:], 10, 64); err != nil {
+				return
+			}
+		}
+		return fileInfo(res, size)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the file is empty
+		return fileInfo(res, 0)
+	}
+	err = c.statusError(http.MethodGet, rawURL, res.StatusCode)
+	return
+}
+
+// do sends the request of the method to the URL.
+func (c Client) do(
+	ctx context.Context,
+	method, rawURL string,
+	header http.Header,
+) (res *http.Response, err error) {
+	if c.Timeout > 0 && method != http.MethodGet {
+		// the content of a GET is read after the response is returned, its timeout is the one of the HTTP client
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, method, rawURL, nil); err != nil {
+		return
+	}
+	// the content is transferred as is, so that its size is the one reported by the server
+	req.Header.Set("Accept-Encoding", "identity")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	httpClient := http.Client{}
+	if c.HTTPClient != nil {
+		httpClient = *c.HTTPClient
+	}
+	// the redirects are checked against the policy of the client before the check of the HTTP client, if any
+	checkRedirect := httpClient.CheckRedirect
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) (err error) {
+		if err = c.checkRedirect(req, via); err != nil || checkRedirect == nil {
+			return
+		}
+		return checkRedirect(req, via)
+	}
+	return httpClient.Do(req)
+}
+
+// checkRedirect returns ErrRedirectRefused if the redirect of the request is refused by the
+// redirect policy of the client, via are the requests made so far, the oldest first.
+func (c Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	maxRedirects := c.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	switch initial := via[0].URL; {
+	case c.DisableRedirects:
+		return fmt.Errorf("%w: redirects are not followed: %s", ErrRedirectRefused, req.URL.Redacted())
+	case len(via) > maxRedirects:
+		return fmt.Errorf("%w: stopped after %d redirects", ErrRedirectRefused, maxRedirects)
+	case c.SameOriginRedirects && (req.URL.Scheme != initial.Scheme || req.URL.Host != initial.Host):
+		return fmt.Errorf("%w: redirect to another origin: %s://%s", ErrRedirectRefused, req.URL.Scheme, req.URL.Host)
+	}
+	return nil
+}
+
+// statusError returns ErrNotFound for a missing file, a StatusError otherwise.
+func (c Client) statusError(method, rawURL string, statusCode int) error {
+	if statusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, rawURL)
+	}
+	return &StatusError{Method: method, URL: rawURL, StatusCode: statusCode}
+}
+
+// contentRangeStart returns the first byte of the content of the partial response, from its
+// Content-Range header (e.g. "bytes 4-9/10").
+func contentRangeStart(res *http.Response) (start int64, ok bool) {
+	contentRange, found := strings.CutPrefix(res.Header.Get("Content-Range"), "bytes ")
+	if !found {
+		return
+	}
+	first, _, found := strings.Cut(contentRange, "-")
+	if !found {
+		return
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil
+}
+
+// fileInfo returns the info of the file of the size from the headers of the response.
+func fileInfo(res *http.Response, size int64) (info FileInfo, err error) {
+	info.Size = max(size, -1)
+	if lastModified := res.Header.Get("Last-Modified"); lastModified != "" {
+		if info.ModTime, err = http.ParseTime(lastModified); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package httpsrc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+var connectionIDNamespace = uuid.MustParse("6b1e3c0a-8f2d-4e57-b9a4-2d7c5e1f0a93")
+
+// Client represents the client of HTTP(S) URL sources, e.g. public or pre-signed URLs,
+// the file paths of the transfers are the URLs themselves.
+type Client struct {
+	// BearerToken is sent in the Authorization header of the requests, none is sent if empty
+	// (e.g. for a pre-signed URL)
+	BearerToken string `json:"bearerToken,omitempty"`
+
+	// Timeout is the timeout of each HTTP request, 0 means no timeout
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// HTTPClient is the HTTP client sending the requests (e.g. with a custom TLS configuration
+	// or proxy), http.DefaultClient is used if nil
+	HTTPClient *http.Client `json:"-"`
+
+	// DisableRedirects refuses the redirect responses (3xx) instead of following them
+	DisableRedirects bool `json:"disableRedirects,omitempty"`
+
+	// MaxRedirects is the maximum number of redirects followed by a request, 0 means 10
+	MaxRedirects int `json:"maxRedirects,omitempty"`
+
+	// SameOriginRedirects refuses the redirects to another origin (scheme, host and port),
+	// e.g. so that a pre-signed URL is not redirected to an untrusted host
+	SameOriginRedirects bool `json:"sameOriginRedirects,omitempty"`
+}
+
+// ClientOption is a function that configures the Client
+type ClientOption func(*Client)
+
+// WithBearerToken sets the bearer token of the requests (see Client.BearerToken).
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.BearerToken = token
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request (see Client.Timeout).
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.Timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client sending the requests (see Client.HTTPClient).
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithFollowRedirects sets whether the redirect responses are followed (see Client.DisableRedirects),
+// they are followed by default.
+func WithFollowRedirects(follow bool) ClientOption {
+	return func(c *Client) {
+		c.DisableRedirects = !follow
+	}
+}
+
+// WithMaxRedirects sets the maximum number of redirects followed by a request (see
+// Client.MaxRedirects), the redirects are refused if n is not positive.
+func WithMaxRedirects(n int) ClientOption {
+	return func(c *Client) {
+		c.MaxRedirects = max(n, 0)
+		c.DisableRedirects = n <= 0
+	}
+}
+
+// WithSameOriginRedirects refuses the redirects to another origin (see Client.SameOriginRedirects).
+func WithSameOriginRedirects() ClientOption {
+	return func(c *Client) {
+		c.SameOriginRedirects = true
+	}
+}
+
+// NewClient creates a new HTTP(S) URL client with the optional ClientOption(s).
+func NewClient(opts ...ClientOption) (c *Client) {
+	c = &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return
+}
+
+func (c Client) GetConnectionPool(logr.Logger) protoc.ConnectionPool {
+	panic(errors.ErrUnsupported)
+}
+
+func (c Client) GetS3API() protoc.S3API {
+	panic(errors.ErrUnsupported)
+}
+
+func (c Client) GetCredential() any {
+	return c
+}
+
+func (c Client) GetConnectionID() string {
+	name := fmt.Sprintf("%s:%s", c.BearerToken, c.Timeout)
+	return uuid.NewSHA1(connectionIDNamespace, []byte(name)).String()
+}
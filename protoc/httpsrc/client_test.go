@@ -0,0 +1,43 @@
+package httpsrc
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client APIs", func() {
+	var cli *Client
+
+	BeforeEach(func() {
+		cli = NewClient(WithBearerToken("secret"))
+	})
+
+	It("should return error for GetConnectionPool and GetS3API", func() {
+		Expect(func() { cli.GetConnectionPool(GinkgoLogr) }).Should(PanicWith(MatchError(errors.ErrUnsupported)))
+		Expect(func() { cli.GetS3API() }).Should(PanicWith(MatchError(errors.ErrUnsupported)))
+	})
+
+	It("should return correct credential", func() {
+		Expect(cli.GetCredential()).To(Equal(*cli))
+	})
+
+	It("should return a connection ID depending on the token and the timeout", func() {
+		id := cli.GetConnectionID()
+		Expect(NewClient(WithBearerToken("secret")).GetConnectionID()).To(Equal(id))
+		Expect(NewClient(WithBearerToken("other")).GetConnectionID()).ToNot(Equal(id))
+		Expect(NewClient(WithBearerToken("secret"), WithTimeout(time.Second)).GetConnectionID()).ToNot(Equal(id))
+	})
+
+	It("should set the redirect policy", func() {
+		Expect(cli.DisableRedirects).To(BeFalse())
+		Expect(NewClient(WithFollowRedirects(false)).DisableRedirects).To(BeTrue())
+		Expect(*NewClient(WithMaxRedirects(3), WithSameOriginRedirects())).To(Equal(Client{
+			MaxRedirects:        3,
+			SameOriginRedirects: true,
+		}))
+		Expect(NewClient(WithMaxRedirects(0)).DisableRedirects).To(BeTrue())
+	})
+})
@@ -4,11 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go/metrics/smithyotelmetrics"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/derektruong/fxfer/protoc"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
@@ -24,12 +32,132 @@ type Client struct {
 	Region     string `json:"region"`
 	AccessKey  string `json:"accessKey"`
 	SecretKey  string `json:"secretKey"`
+
+	// UsePathStyle addresses the bucket in the path of the URL (e.g. http://endpoint/bucket/key)
+	// instead of the virtual-hosted style, as required by MinIO and some on-prem gateways
+	UsePathStyle bool `json:"usePathStyle,omitempty"`
+
+	// Timeout is the timeout of each HTTP request, 0 means no timeout
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// HTTPClient is the HTTP client sending the requests (e.g. with a custom TLS configuration
+	// or proxy), the default HTTP client of the AWS SDK is used if nil
+	HTTPClient *http.Client `json:"-"`
+
+	// LocalAddr is the source IP address the requests are sent from (e.g. to egress via a
+	// specific interface of a multi-homed host), the system picks it if empty
+	LocalAddr string `json:"localAddr,omitempty"`
+
+	// Config is the AWS SDK config the S3 API is built from (see NewClientWithConfig),
+	// its credential provider is used instead of AccessKey and SecretKey
+	Config *aws.Config `json:"-"`
+
+	// Identity identifies the credentials of Config in the connection ID (e.g. the ARN of
+	// the assumed role), since the credentials of a provider cannot be hashed
+	Identity string `json:"identity,omitempty"`
+
+	// RequestHeaders are set on every request sent to S3 (e.g. x-amz-expected-bucket-owner or
+	// the tenant ID required by a multi-tenant gateway), they are signed with the request
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+
+	// ExpectedBucketOwner is the account ID expected to own the bucket, set on every operation
+	// of the storages, so that S3 refuses (HTTP 403) the operations on a bucket owned by another
+	// account, e.g. a deleted bucket whose name has been taken over. None is expected if empty
+	ExpectedBucketOwner string `json:"expectedBucketOwner,omitempty"`
+
+	// RetryMode is the retry mode of the AWS SDK (e.g. aws.RetryModeAdaptive, which also
+	// rate-limits the requests once throttled), the mode of the SDK is used if empty
+	RetryMode aws.RetryMode `json:"retryMode,omitempty"`
+
+	// RetryMaxAttempts is the maximum number of attempts of each request by the AWS SDK, 1 disables
+	// the retries of the SDK, so that a failure is only retried by the transfer instead of being
+	// retried by both. The maximum of the SDK is used if 0
+	RetryMaxAttempts int `json:"retryMaxAttempts,omitempty"`
+
+	// Retryer replaces the retryer of the AWS SDK (see awss3.Options.Retryer), RetryMaxAttempts
+	// still bounds its attempts if set. RetryMode is ignored if set
+	Retryer aws.Retryer `json:"-"`
+}
+
+// ClientOption is a function that configures the Client
+type ClientOption func(*Client)
+
+// WithPathStyle enables the path-style addressing of the bucket (see Client.UsePathStyle).
+func WithPathStyle() ClientOption {
+	return func(c *Client) {
+		c.UsePathStyle = true
+	}
 }
 
-// NewClient creates a new S3 client.
+// WithTimeout sets the timeout of each HTTP request (see Client.Timeout).
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.Timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client sending the requests (see Client.HTTPClient).
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithLocalAddr sets the source IP address the requests are sent from (see Client.LocalAddr).
+func WithLocalAddr(addr string) ClientOption {
+	return func(c *Client) {
+		c.LocalAddr = addr
+	}
+}
+
+// WithIdentity sets the identity of the credentials of the AWS SDK config (see Client.Identity).
+func WithIdentity(identity string) ClientOption {
+	return func(c *Client) {
+		c.Identity = identity
+	}
+}
+
+// WithRequestHeaders sets the headers of every request (see Client.RequestHeaders).
+func WithRequestHeaders(headers map[string]string) ClientOption {
+	return func(c *Client) {
+		c.RequestHeaders = headers
+	}
+}
+
+// WithExpectedBucketOwner sets the account ID expected to own the bucket (see Client.ExpectedBucketOwner).
+func WithExpectedBucketOwner(accountID string) ClientOption {
+	return func(c *Client) {
+		c.ExpectedBucketOwner = accountID
+	}
+}
+
+// WithRetryMode sets the retry mode of the AWS SDK (see Client.RetryMode).
+func WithRetryMode(mode aws.RetryMode) ClientOption {
+	return func(c *Client) {
+		c.RetryMode = mode
+	}
+}
+
+// WithRetryMaxAttempts sets the maximum number of attempts of each request by the AWS SDK, 1
+// disables its retries (see Client.RetryMaxAttempts).
+func WithRetryMaxAttempts(maxAttempts int) ClientOption {
+	return func(c *Client) {
+		c.RetryMaxAttempts = maxAttempts
+	}
+}
+
+// WithRetryer replaces the retryer of the AWS SDK (see Client.Retryer).
+func WithRetryer(retryer aws.Retryer) ClientOption {
+	return func(c *Client) {
+		c.Retryer = retryer
+	}
+}
+
+// NewClient creates a new S3 client with the optional ClientOption(s).
 func NewClient(
 	endpoint, bucketName,
 	Region, AccessKey, SecretKey string,
+	opts ...ClientOption,
 ) (c *Client) {
 	c = &Client{
 		Endpoint:   endpoint,
@@ -38,6 +166,29 @@ func NewClient(
 		AccessKey:  AccessKey,
 		SecretKey:  SecretKey,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return
+}
+
+// NewClientWithConfig creates a new S3 client from an AWS SDK config with the optional
+// ClientOption(s), e.g. to use IAM roles, SSO or web-identity tokens instead of static keys.
+// The endpoint and region are taken from the config.
+func NewClientWithConfig(
+	cfg aws.Config,
+	bucketName string,
+	opts ...ClientOption,
+) (c *Client) {
+	c = &Client{
+		Endpoint:   aws.ToString(cfg.BaseEndpoint),
+		BucketName: bucketName,
+		Region:     cfg.Region,
+		Config:     &cfg,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
 	return
 }
 
@@ -46,6 +197,14 @@ func (c Client) GetConnectionPool(logr.Logger) protoc.ConnectionPool {
 }
 
 func (c Client) GetS3API() protoc.S3API {
+	if c.Config != nil {
+		return awss3.NewFromConfig(*c.Config, c.applyOptions)
+	}
+	return awss3.New(c.s3Options())
+}
+
+// s3Options returns the options of the AWS SDK S3 client built from the static keys.
+func (c Client) s3Options() awss3.Options {
 	s3Options := awss3.Options{
 		Region:       c.Region,
 		BaseEndpoint: aws.String(c.Endpoint),
@@ -57,7 +216,75 @@ func (c Client) GetS3API() protoc.S3API {
 		}),
 		MeterProvider: smithyotelmetrics.Adapt(otel.GetMeterProvider()),
 	}
-	return awss3.New(s3Options)
+	c.applyOptions(&s3Options)
+	return s3Options
+}
+
+// applyOptions applies the addressing style, the request headers, the retry and the HTTP options
+// of the client.
+func (c Client) applyOptions(s3Options *awss3.Options) {
+	s3Options.UsePathStyle = c.UsePathStyle
+	if c.RetryMode != "" {
+		s3Options.RetryMode = c.RetryMode
+	}
+	if c.RetryMaxAttempts > 0 {
+		s3Options.RetryMaxAttempts = c.RetryMaxAttempts
+	}
+	if c.Retryer != nil {
+		s3Options.Retryer = c.Retryer
+	}
+	for _, header := range slices.Sorted(maps.Keys(c.RequestHeaders)) {
+		s3Options.APIOptions = append(s3Options.APIOptions, smithyhttp.SetHeaderValue(header, c.RequestHeaders[header]))
+	}
+	switch {
+	case c.HTTPClient != nil:
+		httpClient := *c.HTTPClient
+		if c.Timeout > 0 {
+			httpClient.Timeout = c.Timeout
+		}
+		if c.LocalAddr != "" {
+			// the transport of the custom client is cloned, so that it is not bound for other users
+			tr, ok := httpClient.Transport.(*http.Transport)
+			if !ok || tr == nil {
+				tr = http.DefaultTransport.(*http.Transport)
+			}
+			tr = tr.Clone()
+			tr.DialContext = c.dialContext(new(net.Dialer))
+			httpClient.Transport = tr
+		}
+		s3Options.HTTPClient = &httpClient
+	case c.Timeout > 0 || c.LocalAddr != "":
+		httpClient := awshttp.NewBuildableClient()
+		if c.Timeout > 0 {
+			httpClient = httpClient.WithTimeout(c.Timeout)
+		}
+		if c.LocalAddr != "" {
+			dialer := httpClient.GetDialer()
+			httpClient = httpClient.WithTransportOptions(func(tr *http.Transport) {
+				tr.DialContext = c.dialContext(dialer)
+			})
+		}
+		s3Options.HTTPClient = httpClient
+	}
+}
+
+// dial dials the address with the dialer, it is replaced in tests to capture the dialer.
+var dial = func(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	return dialer.DialContext(ctx, network, address)
+}
+
+// dialContext returns the DialContext of the transport, binding the connections to LocalAddr.
+func (c Client) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (conn net.Conn, err error) {
+		var addr netip.Addr
+		if addr, err = netip.ParseAddr(c.LocalAddr); err != nil {
+			err = fmt.Errorf("%w: %w", protoc.ErrLocalAddrInvalid, err)
+			return
+		}
+		bound := *dialer
+		bound.LocalAddr = net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, 0))
+		return dial(ctx, &bound, network, address)
+	}
 }
 
 func (c Client) GetCredential() any {
@@ -65,13 +292,34 @@ func (c Client) GetCredential() any {
 }
 
 func (c Client) GetConnectionID() string {
-	return uuid.NewSHA1(
-		connectionIDNamespace,
-		[]byte(fmt.Sprintf(
-			"%s:%s:%s:%s:%s",
-			c.Endpoint, c.BucketName, c.Region, c.AccessKey, c.SecretKey),
-		),
-	).String()
+	name := fmt.Sprintf(
+		"%s:%s:%s:%s:%s",
+		c.Endpoint, c.BucketName, c.Region, c.AccessKey, c.SecretKey)
+	if c.Config != nil {
+		name = fmt.Sprintf("%s:%s:%s:config:%s", c.Endpoint, c.BucketName, c.Region, c.Identity)
+	}
+	// the addressing style, the timeout, the local address, the bucket owner, the retry settings and
+	// the request headers are only part of the ID when they are set, so that the ID of a default
+	// client stays stable
+	if c.UsePathStyle {
+		name += ":pathStyle"
+	}
+	if c.RetryMode != "" || c.RetryMaxAttempts > 0 {
+		name += fmt.Sprintf(":retry=%s/%d", c.RetryMode, c.RetryMaxAttempts)
+	}
+	if c.Timeout > 0 {
+		name += ":" + c.Timeout.String()
+	}
+	if c.LocalAddr != "" {
+		name += ":" + c.LocalAddr
+	}
+	if c.ExpectedBucketOwner != "" {
+		name += ":owner=" + c.ExpectedBucketOwner
+	}
+	for _, header := range slices.Sorted(maps.Keys(c.RequestHeaders)) {
+		name += fmt.Sprintf(":%s=%s", header, c.RequestHeaders[header])
+	}
+	return uuid.NewSHA1(connectionIDNamespace, []byte(name)).String()
 }
 
 func (c Client) GetURI() string {
@@ -1,7 +1,16 @@
 package s3
 
 import (
+	"context"
 	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -45,4 +54,208 @@ var _ = Describe("Client APIs", func() {
 		url := cli.GetURI()
 		Expect(url).To(Equal("local-s3.com/test-bucket"))
 	})
+
+	It("should use the virtual-hosted style by default", func() {
+		opts := cli.s3Options()
+		Expect(opts.UsePathStyle).To(BeFalse())
+		Expect(opts.BaseEndpoint).To(HaveValue(Equal("https://local-s3.com")))
+		Expect(opts.HTTPClient).To(BeNil())
+	})
+
+	It("should use the path style when requested", func() {
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456", WithPathStyle())
+		Expect(cli.s3Options().UsePathStyle).To(BeTrue())
+		Expect(cli.GetConnectionID()).ToNot(Equal(
+			NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456").GetConnectionID(),
+		))
+	})
+
+	It("should use the custom HTTP client with the timeout", func() {
+		httpClient := &http.Client{Transport: &http.Transport{}}
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+			WithHTTPClient(httpClient), WithTimeout(5*time.Second))
+		opts := cli.s3Options()
+		Expect(opts.HTTPClient).To(BeAssignableToTypeOf(&http.Client{}))
+		Expect(opts.HTTPClient.(*http.Client).Transport).To(BeIdenticalTo(httpClient.Transport))
+		Expect(opts.HTTPClient.(*http.Client).Timeout).To(Equal(5 * time.Second))
+		Expect(httpClient.Timeout).To(BeZero())
+	})
+
+	It("should use the default HTTP client with the timeout", func() {
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456", WithTimeout(5*time.Second))
+		opts := cli.s3Options()
+		Expect(opts.HTTPClient).To(BeAssignableToTypeOf(&awshttp.BuildableClient{}))
+		Expect(opts.HTTPClient.(*awshttp.BuildableClient).GetTimeout()).To(Equal(5 * time.Second))
+	})
+
+	It("should set the request headers on every request", func(ctx context.Context) {
+		var captured *http.Request
+		httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			captured = req
+			return nil, errDialCaptured
+		})}
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+			WithHTTPClient(httpClient), WithPathStyle(),
+			WithRequestHeaders(map[string]string{
+				"X-Amz-Expected-Bucket-Owner": "111122223333",
+				"X-Tenant-Id":                 "tenant-a",
+			}))
+		s3API := awss3.New(cli.s3Options(), func(o *awss3.Options) {
+			o.RetryMaxAttempts = 1
+		})
+		_, err := s3API.HeadObject(ctx, &awss3.HeadObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String("file.txt"),
+		})
+		Expect(err).To(MatchError(errDialCaptured))
+		Expect(captured).ToNot(BeNil())
+		Expect(captured.Header.Get("X-Amz-Expected-Bucket-Owner")).To(Equal("111122223333"))
+		Expect(captured.Header.Get("X-Tenant-Id")).To(Equal("tenant-a"))
+		Expect(captured.Header.Get("Authorization")).To(ContainSubstring("x-tenant-id"))
+		Expect(cli.GetConnectionID()).ToNot(Equal(
+			NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+				WithHTTPClient(httpClient), WithPathStyle()).GetConnectionID(),
+		))
+	})
+
+	It("should disable the retries of the AWS SDK", func() {
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456", WithRetryMaxAttempts(1))
+		opts := cli.s3Options()
+		Expect(opts.RetryMaxAttempts).To(Equal(1))
+		Expect(cli.GetS3API().(*awss3.Client).Options().Retryer.MaxAttempts()).To(Equal(1))
+		Expect(cli.GetConnectionID()).ToNot(Equal(
+			NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456").GetConnectionID(),
+		))
+	})
+
+	It("should use the adaptive retry mode with the max attempts", func() {
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+			WithRetryMode(aws.RetryModeAdaptive), WithRetryMaxAttempts(5))
+		opts := cli.s3Options()
+		Expect(opts.RetryMode).To(Equal(aws.RetryModeAdaptive))
+		Expect(opts.RetryMaxAttempts).To(Equal(5))
+		clientOpts := cli.GetS3API().(*awss3.Client).Options()
+		Expect(clientOpts.RetryMode).To(Equal(aws.RetryModeAdaptive))
+		Expect(clientOpts.Retryer.MaxAttempts()).To(Equal(5))
+	})
+
+	It("should use the custom retryer", func() {
+		retryer := aws.NopRetryer{}
+		cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456", WithRetryer(retryer))
+		Expect(cli.s3Options().Retryer).To(Equal(retryer))
+		Expect(cli.GetS3API().(*awss3.Client).Options().Retryer).To(Equal(retryer))
+	})
+
+	Context("with local address", func() {
+		var dialedAddr net.Addr
+
+		BeforeEach(func() {
+			dialedAddr = nil
+			originalDial := dial
+			dial = func(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+				dialedAddr = dialer.LocalAddr
+				return nil, errDialCaptured
+			}
+			DeferCleanup(func() {
+				dial = originalDial
+			})
+		})
+
+		It("should bind the default HTTP client to the local address", func(ctx context.Context) {
+			cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+				WithLocalAddr("10.0.0.5"), WithTimeout(5*time.Second))
+			opts := cli.s3Options()
+			Expect(opts.HTTPClient).To(BeAssignableToTypeOf(&awshttp.BuildableClient{}))
+			httpClient := opts.HTTPClient.(*awshttp.BuildableClient)
+			Expect(httpClient.GetTimeout()).To(Equal(5 * time.Second))
+			_, err := httpClient.GetTransport().DialContext(ctx, "tcp", "minio:9000")
+			Expect(err).To(MatchError(errDialCaptured))
+			Expect(dialedAddr).To(Equal(&net.TCPAddr{IP: net.ParseIP("10.0.0.5").To4()}))
+			Expect(cli.GetConnectionID()).ToNot(Equal(
+				NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+					WithTimeout(5*time.Second)).GetConnectionID(),
+			))
+		})
+
+		It("should bind a clone of the custom HTTP client transport", func(ctx context.Context) {
+			transport := &http.Transport{}
+			cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+				WithHTTPClient(&http.Client{Transport: transport}), WithLocalAddr("::1"))
+			opts := cli.s3Options()
+			Expect(opts.HTTPClient).To(BeAssignableToTypeOf(&http.Client{}))
+			boundTransport := opts.HTTPClient.(*http.Client).Transport.(*http.Transport)
+			Expect(boundTransport).ToNot(BeIdenticalTo(transport))
+			Expect(transport.DialContext).To(BeNil())
+			_, err := boundTransport.DialContext(ctx, "tcp", "minio:9000")
+			Expect(err).To(MatchError(errDialCaptured))
+			Expect(dialedAddr).To(Equal(&net.TCPAddr{IP: net.ParseIP("::1")}))
+		})
+
+		It("should return error if the local address cannot be parsed", func(ctx context.Context) {
+			cli = NewClient("http://minio:9000", "test-bucket", "us-east-1", "123", "456",
+				WithLocalAddr("eth0"))
+			httpClient := cli.s3Options().HTTPClient.(*awshttp.BuildableClient)
+			_, err := httpClient.GetTransport().DialContext(ctx, "tcp", "minio:9000")
+			Expect(err).To(MatchError(protoc.ErrLocalAddrInvalid))
+			Expect(dialedAddr).To(BeNil())
+		})
+	})
+
+	Context("with AWS SDK config", func() {
+		var cfg aws.Config
+
+		BeforeEach(func() {
+			cfg = aws.Config{
+				Region:       "ap-southeast-1",
+				BaseEndpoint: aws.String("https://local-s3.com"),
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "role-key", SecretAccessKey: "role-secret"}, nil
+				}),
+			}
+			cli = NewClientWithConfig(cfg, "test-bucket", WithIdentity("arn:aws:iam::123:role/fxfer"), WithPathStyle())
+		})
+
+		It("should take the endpoint and region from the config", func() {
+			Expect(cli.Endpoint).To(Equal("https://local-s3.com"))
+			Expect(cli.Region).To(Equal("ap-southeast-1"))
+			Expect(cli.GetURI()).To(Equal("local-s3.com/test-bucket"))
+		})
+
+		It("should return S3 API built from the config", func(ctx context.Context) {
+			s3API := cli.GetS3API()
+			Expect(s3API).To(BeAssignableToTypeOf(&awss3.Client{}))
+			opts := s3API.(*awss3.Client).Options()
+			Expect(opts.Region).To(Equal("ap-southeast-1"))
+			Expect(opts.BaseEndpoint).To(HaveValue(Equal("https://local-s3.com")))
+			Expect(opts.UsePathStyle).To(BeTrue())
+			creds, err := opts.Credentials.Retrieve(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds.AccessKeyID).To(Equal("role-key"))
+		})
+
+		It("should override the retry settings of the config", func() {
+			cfg.RetryMaxAttempts = 10
+			cli = NewClientWithConfig(cfg, "test-bucket", WithRetryMaxAttempts(1))
+			Expect(cli.GetS3API().(*awss3.Client).Options().Retryer.MaxAttempts()).To(Equal(1))
+		})
+
+		It("should return stable connection ID derived from the identity", func() {
+			id := cli.GetConnectionID()
+			Expect(id).To(Equal(NewClientWithConfig(cfg, "test-bucket",
+				WithIdentity("arn:aws:iam::123:role/fxfer"), WithPathStyle()).GetConnectionID()))
+			Expect(id).ToNot(Equal(NewClientWithConfig(cfg, "test-bucket",
+				WithIdentity("arn:aws:iam::456:role/fxfer"), WithPathStyle()).GetConnectionID()))
+			Expect(id).ToNot(Equal(NewClientWithConfig(cfg, "other-bucket",
+				WithIdentity("arn:aws:iam::123:role/fxfer"), WithPathStyle()).GetConnectionID()))
+		})
+	})
 })
+
+var errDialCaptured = errors.New("dial captured")
+
+// roundTripperFunc sends the requests of an HTTP client with a function.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,194 @@
+package s3
+
+import (
+	"context"
+	"time"
+
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
+)
+
+// OperationRecorder records an S3 operation issued through an instrumented API (see
+// NewInstrumentedAPI) with the context of the call, the name of the operation (e.g. "UploadPart"),
+// its latency until its response is received and its error, nil if it succeeded. It is called
+// concurrently by the concurrent operations.
+type OperationRecorder func(ctx context.Context, operation string, latency time.Duration, err error)
+
+// instrumentedAPI is a protoc.S3API which records each operation it forwards to the inner API.
+type instrumentedAPI struct {
+	inner    protoc.S3API
+	recorder OperationRecorder
+}
+
+// NewInstrumentedAPI wraps the inner API so that each operation is recorded once by the recorder
+// when it returns, e.g. to count the calls per operation or to measure their latencies. The inner
+// API is returned as is when the recorder is nil.
+func NewInstrumentedAPI(inner protoc.S3API, recorder OperationRecorder) protoc.S3API {
+	if recorder == nil {
+		return inner
+	}
+	return &instrumentedAPI{inner: inner, recorder: recorder}
+}
+
+// record calls the operation and records it.
+func record[T any](
+	ctx context.Context,
+	recorder OperationRecorder,
+	operation string,
+	call func() (T, error),
+) (output T, err error) {
+	start := time.Now()
+	output, err = call()
+	recorder(ctx, operation, time.Since(start), err)
+	return
+}
+
+func (a *instrumentedAPI) PutObject(
+	ctx context.Context,
+	input *awss3.PutObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.PutObjectOutput, error) {
+	return record(ctx, a.recorder, "PutObject", func() (*awss3.PutObjectOutput, error) {
+		return a.inner.PutObject(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) ListParts(
+	ctx context.Context,
+	input *awss3.ListPartsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListPartsOutput, error) {
+	return record(ctx, a.recorder, "ListParts", func() (*awss3.ListPartsOutput, error) {
+		return a.inner.ListParts(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) UploadPart(
+	ctx context.Context,
+	input *awss3.UploadPartInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartOutput, error) {
+	return record(ctx, a.recorder, "UploadPart", func() (*awss3.UploadPartOutput, error) {
+		return a.inner.UploadPart(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) GetObject(
+	ctx context.Context,
+	input *awss3.GetObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.GetObjectOutput, error) {
+	return record(ctx, a.recorder, "GetObject", func() (*awss3.GetObjectOutput, error) {
+		return a.inner.GetObject(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) HeadObject(
+	ctx context.Context,
+	input *awss3.HeadObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.HeadObjectOutput, error) {
+	return record(ctx, a.recorder, "HeadObject", func() (*awss3.HeadObjectOutput, error) {
+		return a.inner.HeadObject(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) CreateMultipartUpload(
+	ctx context.Context,
+	input *awss3.CreateMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CreateMultipartUploadOutput, error) {
+	return record(ctx, a.recorder, "CreateMultipartUpload", func() (*awss3.CreateMultipartUploadOutput, error) {
+		return a.inner.CreateMultipartUpload(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) AbortMultipartUpload(
+	ctx context.Context,
+	input *awss3.AbortMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.AbortMultipartUploadOutput, error) {
+	return record(ctx, a.recorder, "AbortMultipartUpload", func() (*awss3.AbortMultipartUploadOutput, error) {
+		return a.inner.AbortMultipartUpload(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) DeleteObject(
+	ctx context.Context,
+	input *awss3.DeleteObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectOutput, error) {
+	return record(ctx, a.recorder, "DeleteObject", func() (*awss3.DeleteObjectOutput, error) {
+		return a.inner.DeleteObject(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) DeleteObjects(
+	ctx context.Context,
+	input *awss3.DeleteObjectsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectsOutput, error) {
+	return record(ctx, a.recorder, "DeleteObjects", func() (*awss3.DeleteObjectsOutput, error) {
+		return a.inner.DeleteObjects(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) CompleteMultipartUpload(
+	ctx context.Context,
+	input *awss3.CompleteMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CompleteMultipartUploadOutput, error) {
+	return record(ctx, a.recorder, "CompleteMultipartUpload", func() (*awss3.CompleteMultipartUploadOutput, error) {
+		return a.inner.CompleteMultipartUpload(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) UploadPartCopy(
+	ctx context.Context,
+	input *awss3.UploadPartCopyInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartCopyOutput, error) {
+	return record(ctx, a.recorder, "UploadPartCopy", func() (*awss3.UploadPartCopyOutput, error) {
+		return a.inner.UploadPartCopy(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) ListObjectsV2(
+	ctx context.Context,
+	input *awss3.ListObjectsV2Input,
+	opts ...func(*awss3.Options),
+) (*awss3.ListObjectsV2Output, error) {
+	return record(ctx, a.recorder, "ListObjectsV2", func() (*awss3.ListObjectsV2Output, error) {
+		return a.inner.ListObjectsV2(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) GetObjectAttributes(
+	ctx context.Context,
+	input *awss3.GetObjectAttributesInput,
+	opts ...func(*awss3.Options),
+) (*awss3.GetObjectAttributesOutput, error) {
+	return record(ctx, a.recorder, "GetObjectAttributes", func() (*awss3.GetObjectAttributesOutput, error) {
+		return a.inner.GetObjectAttributes(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) RestoreObject(
+	ctx context.Context,
+	input *awss3.RestoreObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.RestoreObjectOutput, error) {
+	return record(ctx, a.recorder, "RestoreObject", func() (*awss3.RestoreObjectOutput, error) {
+		return a.inner.RestoreObject(ctx, input, opts...)
+	})
+}
+
+func (a *instrumentedAPI) ListMultipartUploads(
+	ctx context.Context,
+	input *awss3.ListMultipartUploadsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListMultipartUploadsOutput, error) {
+	return record(ctx, a.recorder, "ListMultipartUploads", func() (*awss3.ListMultipartUploadsOutput, error) {
+		return a.inner.ListMultipartUploads(ctx, input, opts...)
+	})
+}
@@ -0,0 +1,187 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+// measurement is an operation recorded by an instrumented API.
+type measurement struct {
+	operation string
+	latency   time.Duration
+	err       error
+}
+
+var _ = Describe("NewInstrumentedAPI", func() {
+	var (
+		mockS3API    *mock_protoc.MockS3API
+		mu           sync.Mutex
+		measurements []measurement
+		recorder     OperationRecorder
+	)
+
+	BeforeEach(func() {
+		mockS3API = mock_protoc.NewMockS3API(gomock.NewController(GinkgoT()))
+		measurements = nil
+		recorder = func(_ context.Context, operation string, latency time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			measurements = append(measurements, measurement{operation: operation, latency: latency, err: err})
+		}
+	})
+
+	It("should not wrap the API without a recorder", func() {
+		Expect(NewInstrumentedAPI(mockS3API, nil)).To(BeIdenticalTo(mockS3API))
+	})
+
+	DescribeTable("should forward each operation and record it once",
+		func(ctx context.Context, operation string, call func(ctx context.Context, api protoc.S3API, callErr error) error) {
+			api := NewInstrumentedAPI(mockS3API, recorder)
+			Expect(call(ctx, api, nil)).To(Succeed())
+			Expect(measurements).To(HaveLen(1))
+			Expect(measurements[0].operation).To(Equal(operation))
+			Expect(measurements[0].err).ToNot(HaveOccurred())
+
+			By("record the operation failing")
+			failure := errors.New("operation failed")
+			Expect(call(ctx, api, failure)).To(MatchError(failure))
+			Expect(measurements).To(HaveLen(2))
+			Expect(measurements[1].operation).To(Equal(operation))
+			Expect(measurements[1].err).To(MatchError(failure))
+		},
+		Entry("PutObject", "PutObject", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.PutObjectInput{}, &awss3.PutObjectOutput{}
+			mockS3API.EXPECT().PutObject(ctx, input).Return(output, callErr)
+			actual, err := api.PutObject(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("ListParts", "ListParts", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.ListPartsInput{}, &awss3.ListPartsOutput{}
+			mockS3API.EXPECT().ListParts(ctx, input).Return(output, callErr)
+			actual, err := api.ListParts(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("UploadPart", "UploadPart", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.UploadPartInput{}, &awss3.UploadPartOutput{}
+			mockS3API.EXPECT().UploadPart(ctx, input).Return(output, callErr)
+			actual, err := api.UploadPart(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("GetObject", "GetObject", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.GetObjectInput{}, &awss3.GetObjectOutput{}
+			mockS3API.EXPECT().GetObject(ctx, input).Return(output, callErr)
+			actual, err := api.GetObject(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("HeadObject", "HeadObject", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.HeadObjectInput{}, &awss3.HeadObjectOutput{}
+			mockS3API.EXPECT().HeadObject(ctx, input).Return(output, callErr)
+			actual, err := api.HeadObject(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("CreateMultipartUpload", "CreateMultipartUpload", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.CreateMultipartUploadInput{}, &awss3.CreateMultipartUploadOutput{}
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, input).Return(output, callErr)
+			actual, err := api.CreateMultipartUpload(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("AbortMultipartUpload", "AbortMultipartUpload", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.AbortMultipartUploadInput{}, &awss3.AbortMultipartUploadOutput{}
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, input).Return(output, callErr)
+			actual, err := api.AbortMultipartUpload(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("DeleteObject", "DeleteObject", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.DeleteObjectInput{}, &awss3.DeleteObjectOutput{}
+			mockS3API.EXPECT().DeleteObject(ctx, input).Return(output, callErr)
+			actual, err := api.DeleteObject(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("DeleteObjects", "DeleteObjects", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.DeleteObjectsInput{}, &awss3.DeleteObjectsOutput{}
+			mockS3API.EXPECT().DeleteObjects(ctx, input).Return(output, callErr)
+			actual, err := api.DeleteObjects(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("CompleteMultipartUpload", "CompleteMultipartUpload", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.CompleteMultipartUploadInput{}, &awss3.CompleteMultipartUploadOutput{}
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, input).Return(output, callErr)
+			actual, err := api.CompleteMultipartUpload(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("UploadPartCopy", "UploadPartCopy", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.UploadPartCopyInput{}, &awss3.UploadPartCopyOutput{}
+			mockS3API.EXPECT().UploadPartCopy(ctx, input).Return(output, callErr)
+			actual, err := api.UploadPartCopy(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("ListObjectsV2", "ListObjectsV2", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.ListObjectsV2Input{}, &awss3.ListObjectsV2Output{}
+			mockS3API.EXPECT().ListObjectsV2(ctx, input).Return(output, callErr)
+			actual, err := api.ListObjectsV2(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("GetObjectAttributes", "GetObjectAttributes", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.GetObjectAttributesInput{}, &awss3.GetObjectAttributesOutput{}
+			mockS3API.EXPECT().GetObjectAttributes(ctx, input).Return(output, callErr)
+			actual, err := api.GetObjectAttributes(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("RestoreObject", "RestoreObject", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.RestoreObjectInput{}, &awss3.RestoreObjectOutput{}
+			mockS3API.EXPECT().RestoreObject(ctx, input).Return(output, callErr)
+			actual, err := api.RestoreObject(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+		Entry("ListMultipartUploads", "ListMultipartUploads", func(ctx context.Context, api protoc.S3API, callErr error) error {
+			input, output := &awss3.ListMultipartUploadsInput{}, &awss3.ListMultipartUploadsOutput{}
+			mockS3API.EXPECT().ListMultipartUploads(ctx, input).Return(output, callErr)
+			actual, err := api.ListMultipartUploads(ctx, input)
+			Expect(actual).To(BeIdenticalTo(output))
+			return err
+		}),
+	)
+
+	It("should record the latency of the operation with the context of the call", func(ctx context.Context) {
+		type ctxKey struct{}
+		ctx = context.WithValue(ctx, ctxKey{}, "transfer")
+		mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).
+			DoAndReturn(func(context.Context, *awss3.HeadObjectInput, ...func(*awss3.Options)) (*awss3.HeadObjectOutput, error) {
+				time.Sleep(20 * time.Millisecond)
+				return &awss3.HeadObjectOutput{}, nil
+			})
+		var recordedValue any
+		api := NewInstrumentedAPI(mockS3API, func(ctx context.Context, operation string, latency time.Duration, err error) {
+			recordedValue = ctx.Value(ctxKey{})
+			recorder(ctx, operation, latency, err)
+		})
+		_, err := api.HeadObject(ctx, &awss3.HeadObjectInput{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recordedValue).To(Equal("transfer"))
+		Expect(measurements).To(HaveLen(1))
+		Expect(measurements[0].latency).To(BeNumerically(">=", 20*time.Millisecond))
+	}, NodeTimeout(10*time.Second))
+})
@@ -17,4 +17,8 @@ type S3API interface {
 	DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput, opt ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
 	CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput, opt ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
 	UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput, opt ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opt ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput, opt ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error)
+	RestoreObject(ctx context.Context, input *s3.RestoreObjectInput, opt ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+	ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput, opt ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
 }
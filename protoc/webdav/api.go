@@ -0,0 +1,335 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the file or the directory does not exist on the server.
+var ErrNotFound = errors.New("webdav: file not found")
+
+// StatusError is returned when the server responds to a request with an unexpected status.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webdav: %s %s: unexpected status %d %s",
+		e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPStatusCode returns the status of the response, so that the authentication and
+// authorization failures are not retried.
+func (e *StatusError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// FileInfo is the info of a file or a directory on the server.
+type FileInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// propfindBody requests the properties of FileInfo.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:getcontentlength/><d:getlastmodified/><d:resourcetype/></d:prop></d:propfind>`
+
+// multistatus is the response of PROPFIND.
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Prop struct {
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// Stat returns the info of the file or the directory at the path (PROPFIND).
+func (c Client) Stat(ctx context.Context, filePath string) (info FileInfo, err error) {
+	var infos []FileInfo
+	if infos, err = c.propfind(ctx, filePath, "0"); err != nil {
+		return
+	}
+	if len(infos) == 0 {
+		err = ErrNotFound
+		return
+	}
+	return infos[0], nil
+}
+
+// ReadDir returns the info of the files and directories in the directory (PROPFIND),
+// their paths are joined to the path of the directory.
+func (c Client) ReadDir(ctx context.Context, dirPath string) (infos []FileInfo, err error) {
+	var all []FileInfo
+	if all, err = c.propfind(ctx, dirPath, "1"); err != nil {
+		return
+	}
+	for _, info := range all {
+		// the directory itself is listed along with its children
+		if path.Clean("/"+info.Path) != path.Clean("/"+dirPath) {
+			infos = append(infos, info)
+		}
+	}
+	return
+}
+
+// Get returns the content of the file from the offset (ranged GET), the content before the
+// offset is skipped if the server does not support the ranges.
+func (c Client) Get(ctx context.Context, filePath string, offset int64) (reader io.ReadCloser, err error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodGet, filePath, nil, -1, header); err != nil {
+		return
+	}
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		return res.Body, nil
+	case http.StatusOK:
+		if offset > 0 {
+			if _, err = io.CopyN(io.Discard, res.Body, offset); err != nil {
+				res.Body.Close()
+				return
+			}
+		}
+		return res.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the offset is the end of the file
+		res.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	res.Body.Close()
+	return nil, c.statusError(http.MethodGet, filePath, res.StatusCode)
+}
+
+// Put writes the content as the whole file (PUT), the length is -1 if unknown.
+func (c Client) Put(ctx context.Context, filePath string, body io.Reader, length int64) (err error) {
+	return c.put(ctx, filePath, body, length, nil)
+}
+
+// PutRange writes the length bytes of the content at the offset of the file (PUT with
+// Content-Range), as supported by e.g. Apache mod_dav. A server which does not support it may
+// reject it (StatusError) or ignore the range and replace the whole file.
+func (c Client) PutRange(ctx context.Context, filePath string, body io.Reader, offset, length int64) (err error) {
+	header := http.Header{}
+	header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", offset, offset+length-1))
+	return c.put(ctx, filePath, body, length, header)
+}
+
+// MkdirAll creates the directory along with its missing parents (MKCOL).
+func (c Client) MkdirAll(ctx context.Context, dirPath string) (err error) {
+	dirPath = strings.Trim(path.Clean("/"+dirPath), "/")
+	if dirPath == "" {
+		return
+	}
+	if _, err = c.Stat(ctx, dirPath); err == nil {
+		return
+	} else if !errors.Is(err, ErrNotFound) {
+		return
+	}
+	if err = c.MkdirAll(ctx, path.Dir(dirPath)); err != nil {
+		return
+	}
+	var res *http.Response
+	if res, err = c.do(ctx, "MKCOL", dirPath+"/", nil, -1, nil); err != nil {
+		return
+	}
+	res.Body.Close()
+	// the directory may have been created concurrently
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusMethodNotAllowed {
+		err = c.statusError("MKCOL", dirPath, res.StatusCode)
+	}
+	return
+}
+
+// Delete deletes the file or the directory (DELETE), ErrNotFound is returned if it does not exist.
+func (c Client) Delete(ctx context.Context, filePath string) (err error) {
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodDelete, filePath, nil, -1, nil); err != nil {
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err = c.statusError(http.MethodDelete, filePath, res.StatusCode)
+	}
+	return
+}
+
+func (c Client) put(ctx context.Context, filePath string, body io.Reader, length int64, header http.Header) (err error) {
+	var res *http.Response
+	if res, err = c.do(ctx, http.MethodPut, filePath, body, length, header); err != nil {
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err = c.statusError(http.MethodPut, filePath, res.StatusCode)
+	}
+	return
+}
+
+func (c Client) propfind(ctx context.Context, filePath, depth string) (infos []FileInfo, err error) {
+	header := http.Header{}
+	header.Set("Depth", depth)
+	header.Set("Content-Type", "application/xml; charset=utf-8")
+	var res *http.Response
+	if res, err = c.do(ctx, "PROPFIND", filePath, strings.NewReader(propfindBody), int64(len(propfindBody)), header); err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusMultiStatus {
+		err = c.statusError("PROPFIND", filePath, res.StatusCode)
+		return
+	}
+	var ms multistatus
+	if err = xml.NewDecoder(res.Body).Decode(&ms); err != nil {
+		return
+	}
+
+	// the hrefs are relative to the root of the server, the paths to the endpoint
+	var basePath string
+	if basePath, err = c.basePath(); err != nil {
+		return
+	}
+	for _, response := range ms.Responses {
+		var href string
+		if href, err = hrefPath(response.Href); err != nil {
+			return
+		}
+		info := FileInfo{Path: strings.TrimPrefix(strings.TrimPrefix(href, basePath), "/")}
+		if strings.HasPrefix(filePath, "/") {
+			info.Path = "/" + info.Path
+		}
+		for _, propstat := range response.Propstats {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			prop := propstat.Prop
+			info.IsDir = info.IsDir || prop.ResourceType.Collection != nil
+			if prop.ContentLength != "" {
+				if info.Size, err = strconv.ParseInt(prop.ContentLength, 10, 64); err != nil {
+					return
+				}
+			}
+			if prop.LastModified != "" {
+				if info.ModTime, err = http.ParseTime(prop.LastModified); err != nil {
+					return
+				}
+			}
+		}
+		info.Path = strings.TrimSuffix(info.Path, "/")
+		infos = append(infos, info)
+	}
+	return
+}
+
+// do sends the request of the method to the path relative to the endpoint.
+func (c Client) do(
+	ctx context.Context,
+	method, filePath string,
+	body io.Reader,
+	length int64,
+	header http.Header,
+) (res *http.Response, err error) {
+	if c.Timeout > 0 && method != http.MethodGet {
+		// the content of a GET is read after the response is returned, its timeout is the one of the HTTP client
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer func() {
+			if err != nil || res == nil {
+				cancel()
+				return
+			}
+			res.Body = cancelingBody{ReadCloser: res.Body, cancel: cancel}
+		}()
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, method, c.url(filePath), body); err != nil {
+		return
+	}
+	if length >= 0 {
+		req.ContentLength = length
+		if length == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// url returns the URL of the path relative to the endpoint, each segment is escaped.
+func (c Client) url(filePath string) string {
+	segments := strings.Split(strings.TrimPrefix(filePath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimSuffix(c.Endpoint, "/") + "/" + strings.Join(segments, "/")
+}
+
+// basePath returns the path of the endpoint on the server.
+func (c Client) basePath() (basePath string, err error) {
+	var endpoint *url.URL
+	if endpoint, err = url.Parse(c.Endpoint); err != nil {
+		return
+	}
+	return strings.TrimSuffix(endpoint.Path, "/"), nil
+}
+
+// statusError returns ErrNotFound for a missing file, a StatusError otherwise.
+func (c Client) statusError(method, filePath string, statusCode int) error {
+	if statusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, filePath)
+	}
+	return &StatusError{Method: method, Path: filePath, StatusCode: statusCode}
+}
+
+// hrefPath returns the unescaped path of the href, which may be an absolute URL.
+func hrefPath(href string) (hrefPath string, err error) {
+	var hrefURL *url.URL
+	if hrefURL, err = url.Parse(href); err != nil {
+		return
+	}
+	return hrefURL.Path, nil
+}
+
+// cancelingBody cancels the context of the request once the body of its response is closed.
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelingBody) Close() (err error) {
+	err = b.ReadCloser.Close()
+	b.cancel()
+	return
+}
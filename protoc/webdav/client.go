@@ -0,0 +1,81 @@
+package webdav
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+var connectionIDNamespace = uuid.MustParse("0f0d6a3e-5f4b-4c52-9a8e-4a1c2b7d9e61")
+
+// Client represents the WebDAV storage client (e.g. of a Nextcloud server).
+type Client struct {
+	// Endpoint is the URL of the WebDAV root the paths are relative to,
+	// e.g. https://cloud.example.com/remote.php/dav/files/user
+	Endpoint string `json:"endpoint"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Timeout is the timeout of each HTTP request, 0 means no timeout
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// HTTPClient is the HTTP client sending the requests (e.g. with a custom TLS configuration
+	// or proxy), http.DefaultClient is used if nil
+	HTTPClient *http.Client `json:"-"`
+}
+
+// ClientOption is a function that configures the Client
+type ClientOption func(*Client)
+
+// WithTimeout sets the timeout of each HTTP request (see Client.Timeout).
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.Timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client sending the requests (see Client.HTTPClient).
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// NewClient creates a new WebDAV client with the optional ClientOption(s).
+func NewClient(endpoint, username, password string, opts ...ClientOption) (c *Client) {
+	c = &Client{
+		Endpoint: endpoint,
+		Username: username,
+		Password: password,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return
+}
+
+func (c Client) GetConnectionPool(logr.Logger) protoc.ConnectionPool {
+	panic(errors.ErrUnsupported)
+}
+
+func (c Client) GetS3API() protoc.S3API {
+	panic(errors.ErrUnsupported)
+}
+
+func (c Client) GetCredential() any {
+	return c
+}
+
+func (c Client) GetConnectionID() string {
+	name := fmt.Sprintf("%s:%s:%s", c.Endpoint, c.Username, c.Password)
+	// the timeout is only part of the ID when it is set, so that the ID of a default client stays stable
+	if c.Timeout > 0 {
+		name += ":" + c.Timeout.String()
+	}
+	return uuid.NewSHA1(connectionIDNamespace, []byte(name)).String()
+}
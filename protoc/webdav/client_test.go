@@ -0,0 +1,38 @@
+package webdav
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client APIs", func() {
+	var cli *Client
+
+	BeforeEach(func() {
+		cli = NewClient("https://cloud.example.com/remote.php/dav/files/user/", "user", "secret")
+	})
+
+	It("should return error for GetConnectionPool and GetS3API", func() {
+		Expect(func() { cli.GetConnectionPool(GinkgoLogr) }).Should(PanicWith(MatchError(errors.ErrUnsupported)))
+		Expect(func() { cli.GetS3API() }).Should(PanicWith(MatchError(errors.ErrUnsupported)))
+	})
+
+	It("should return correct credential", func() {
+		Expect(cli.GetCredential()).To(Equal(*cli))
+	})
+
+	It("should return a connection ID depending on the timeout only when it is set", func() {
+		id := cli.GetConnectionID()
+		Expect(NewClient(cli.Endpoint, cli.Username, cli.Password).GetConnectionID()).To(Equal(id))
+		Expect(NewClient(cli.Endpoint, cli.Username, cli.Password, WithTimeout(time.Second)).GetConnectionID()).
+			ToNot(Equal(id))
+	})
+
+	It("should escape each segment of the path in the URL", func() {
+		Expect(cli.url("/docs/2024 report/a#b.txt")).
+			To(Equal("https://cloud.example.com/remote.php/dav/files/user/docs/2024%20report/a%23b.txt"))
+	})
+})
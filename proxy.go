@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/derektruong/fxfer/internal/iometer"
+	"github.com/derektruong/fxfer/internal/xferfile"
 	"github.com/samber/lo"
 )
 
@@ -15,6 +16,11 @@ import (
 // progress of a transfer is updated.
 type ProgressUpdatedCallback func(progress Progress)
 
+// CancelableProgressCallback is a function that is called when the progress of
+// a transfer is updated, returning a non-nil error aborts the transfer with this
+// error (see WithCancelableProgress).
+type CancelableProgressCallback func(progress Progress) (err error)
+
 // ProgressStatus is an enum that represents the status of the progress
 type ProgressStatus int
 
@@ -27,6 +33,8 @@ const (
 	ProgressStatusFinished
 	// ProgressStatusInError is the status of the progress when the transfer is in error
 	ProgressStatusInError
+	// ProgressStatusDryRun is the status of the progress reporting a dry-run (see WithDryRun)
+	ProgressStatusDryRun
 )
 
 // Progress is a struct that contains information about the progress
@@ -34,12 +42,21 @@ type Progress struct {
 	// Status is the status of the progress
 	Status ProgressStatus
 
-	// TotalSize is the total number of bytes that need to be transferred
+	// TotalSize is the total number of bytes that need to be transferred,
+	// it is SizeUnknown when streaming a source of unknown size
 	TotalSize int64
 
-	// TransferredSize is the number of bytes that have been transferred
+	// TransferredSize is the number of bytes that have been transferred (read from the source)
 	TransferredSize int64
 
+	// ConfirmedSize is the number of bytes that the destination has confirmed as durably
+	// written, it lags behind TransferredSize while parts are still being uploaded
+	ConfirmedSize int64
+
+	// Offset is the offset of the destination file which the transfer resumed from,
+	// TransferredSize includes it (when Status is ProgressStatusInError)
+	Offset int64
+
 	// Percentage is the percentage of the transfer that has been completed
 	Percentage int
 
@@ -57,6 +74,57 @@ type Progress struct {
 
 	// FinishAt is the time when the transfer finished
 	FinishAt time.Time
+
+	// Skipped reports whether the transfer is skipped since the destination file is
+	// already identical to the source file (when Status is ProgressStatusFinished)
+	Skipped bool
+
+	// Heartbeat reports whether the progress repeats the last one since no progress has been
+	// reported for the interval of WithHeartbeat, only its Duration is updated
+	Heartbeat bool
+
+	// DryRun is the report of the dry-run (when Status is ProgressStatusDryRun)
+	DryRun *DryRunResult
+
+	// BatchProgress is the aggregate progress when the file is transferred
+	// as a part of a batch (e.g. Transfer.TransferDirectory)
+	BatchProgress
+}
+
+// BatchProgress is a struct that contains information about the aggregate
+// progress of a batch transfer
+type BatchProgress struct {
+	// CurrentFile is the path of the source file being transferred
+	CurrentFile string
+
+	// FilesCompleted is the number of files that have been transferred
+	FilesCompleted int
+
+	// TotalFiles is the total number of files that need to be transferred
+	TotalFiles int
+
+	// BytesCompleted is the number of bytes of the transferred files, plus the
+	// bytes transferred of the current file
+	BytesCompleted int64
+
+	// TotalBytes is the total number of bytes of the files that need to be
+	// transferred, the files of unknown size are not counted
+	TotalBytes int64
+}
+
+// WeightedPercentage is the percentage of the batch that has been completed, weighting
+// equally the files completed and the bytes completed, so that neither a single large file
+// nor many small files dominate it.
+func (p BatchProgress) WeightedPercentage() int {
+	if p.TotalFiles == 0 {
+		return 0
+	}
+	filesRatio := float64(p.FilesCompleted) / float64(p.TotalFiles)
+	if p.TotalBytes <= 0 {
+		return int(filesRatio * 100)
+	}
+	bytesRatio := float64(min(p.BytesCompleted, p.TotalBytes)) / float64(p.TotalBytes)
+	return int((filesRatio + bytesRatio) / 2 * 100)
 }
 
 const (
@@ -73,12 +141,19 @@ const (
 type proxyReader struct {
 	transferReader *iometer.TransferReader
 
+	// confirmedSize is the number of bytes confirmed by the destination
+	// (see storage.ConfirmedSizeTracker)
+	confirmedSize int64
+
 	// done and doneCtx are used to signal when the reader is done
 	doneCtx context.Context
 	done    context.CancelFunc
 
 	// closed is a flag that indicates if the proxyReader is closed
 	closed bool
+
+	// stallDetector aborts the transfer when its throughput stays too low (see WithStallTimeout)
+	stallDetector *stallDetector
 }
 
 // newProxyReader creates a new proxyReader with the specified io.Reader
@@ -86,6 +161,7 @@ type proxyReader struct {
 func newProxyReader(r io.Reader, transferredSize int64) (p *proxyReader) {
 	p = &proxyReader{
 		transferReader: iometer.NewTransferReader(r, &transferredSize),
+		confirmedSize:  transferredSize,
 	}
 	p.doneCtx, p.done = context.WithCancel(context.Background())
 	return
@@ -102,6 +178,17 @@ func (p *proxyReader) Read(data []byte) (n int, err error) {
 	}
 }
 
+// AddConfirmedSize adds n bytes to the confirmed size, it implements
+// the storage.ConfirmedSizeTracker interface.
+func (p *proxyReader) AddConfirmedSize(n int64) {
+	atomic.AddInt64(&p.confirmedSize, n)
+}
+
+// ConfirmedSize returns the number of bytes confirmed by the destination.
+func (p *proxyReader) ConfirmedSize() int64 {
+	return atomic.LoadInt64(&p.confirmedSize)
+}
+
 // Close closes the underlying io.Reader if it implements the
 // io.Closer interface.
 func (p *proxyReader) Close() (err error) {
@@ -138,7 +225,7 @@ func (p *proxyReader) trackProgress(
 			progressPercentage = finishedProgress
 			status = ProgressStatusFinished
 			exit = true
-		} else {
+		} else if totalSize != xferfile.SizeUnknown {
 			progressPercentage = int(math.Min(
 				finishedProgress,
 				math.Round(float64(transferredSize)/float64(totalSize)*100),
@@ -153,11 +240,17 @@ func (p *proxyReader) trackProgress(
 			Status:          status,
 			TotalSize:       totalSize,
 			TransferredSize: transferredSize,
+			ConfirmedSize:   p.ConfirmedSize(),
 			Percentage:      progressPercentage,
 			Duration:        time.Since(startTime),
 			Speed:           transferredSize / int64(math.Max(1, time.Since(startTime).Seconds())),
 			StartAt:         startTime,
 		})
+		if status == ProgressStatusInProgress && p.stallDetector != nil &&
+			p.stallDetector.observe(time.Now(), transferredSize) {
+			p.done()
+			exit = true
+		}
 		return
 	}
 
@@ -179,3 +272,23 @@ func (p *proxyReader) trackProgress(
 		}
 	}
 }
+
+// copiedSizeTracker reports the bytes copied by the destination itself (see
+// storage.ServerSideCopier) as both transferred and confirmed, since they are
+// never read through the proxyReader.
+type copiedSizeTracker struct {
+	*proxyReader
+	pauseGate *pauseGate
+}
+
+// AddConfirmedSize implements the storage.ConfirmedSizeTracker interface.
+func (c copiedSizeTracker) AddConfirmedSize(n int64) {
+	c.transferReader.AddTransferredSize(n)
+	c.proxyReader.AddConfirmedSize(n)
+}
+
+// WaitToCopy implements the storage.CopyPauser interface, the copy waits at the pause gate
+// before each part.
+func (c copiedSizeTracker) WaitToCopy(ctx context.Context) error {
+	return c.pauseGate.wait(ctx)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
@@ -133,6 +134,41 @@ var _ = Describe("proxyReader", func() {
 			close(completed)
 		}, NodeTimeout(10*time.Second))
 
+		It("should report the confirmed size separately from the read size", func(ctx context.Context) {
+			var mu sync.Mutex
+			cb := func(progress Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				progressUpdates = append(progressUpdates, progress)
+			}
+			lastProgress := func() Progress {
+				mu.Lock()
+				defer mu.Unlock()
+				if len(progressUpdates) == 0 {
+					return Progress{}
+				}
+				return progressUpdates[len(progressUpdates)-1]
+			}
+
+			go proxy.trackProgress(ctx, startTime, totalSize, 10*time.Millisecond, interrupted, completed, cb)
+
+			_, err := io.Copy(io.Discard, proxy)
+			Expect(err).NotTo(HaveOccurred())
+			proxy.AddConfirmedSize(10)
+
+			Eventually(lastProgress).WithContext(ctx).Should(And(
+				HaveField("TransferredSize", totalSize),
+				HaveField("ConfirmedSize", int64(10)),
+			))
+
+			proxy.AddConfirmedSize(totalSize - 10)
+			Eventually(lastProgress).WithContext(ctx).Should(
+				HaveField("ConfirmedSize", totalSize),
+			)
+
+			close(completed)
+		}, NodeTimeout(10*time.Second))
+
 		It("should handle context cancellation", func(ctx context.Context) {
 			cb := func(progress Progress) {
 				progressUpdates = append(progressUpdates, progress)
@@ -185,3 +221,20 @@ var _ = Describe("proxyReader", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 })
+
+var _ = Describe("BatchProgress", func() {
+	DescribeTable("WeightedPercentage",
+		func(progress BatchProgress, expectedPercentage int) {
+			Expect(progress.WeightedPercentage()).To(Equal(expectedPercentage))
+		},
+		Entry("no file", BatchProgress{}, 0),
+		Entry("small file completed",
+			BatchProgress{FilesCompleted: 1, TotalFiles: 2, BytesCompleted: 10, TotalBytes: 1010}, 25),
+		Entry("large file completed",
+			BatchProgress{FilesCompleted: 1, TotalFiles: 2, BytesCompleted: 1000, TotalBytes: 1010}, 74),
+		Entry("sizes unknown",
+			BatchProgress{FilesCompleted: 1, TotalFiles: 4}, 25),
+		Entry("all completed",
+			BatchProgress{FilesCompleted: 2, TotalFiles: 2, BytesCompleted: 1010, TotalBytes: 1010}, 100),
+	)
+})
@@ -0,0 +1,69 @@
+package fxfer
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"time"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// TransferResult is a struct that contains the outcome of a successful transfer
+// (see Transfer.TransferWithResult)
+type TransferResult struct {
+	// BytesTransferred is the number of bytes read from the source by the last attempt,
+	// it excludes the bytes of the destination file the attempt resumed from
+	BytesTransferred int64
+
+	// TotalSize is the total number of bytes of the source file,
+	// it is SizeUnknown when streaming a source of unknown size
+	TotalSize int64
+
+	// StartedAt is the time when the transfer of the destination file started,
+	// which precedes the last attempt if it has been resumed
+	StartedAt time.Time
+
+	// FinishedAt is the time when the transfer finished
+	FinishedAt time.Time
+
+	// Resumed reports whether the last attempt resumed an incomplete destination file
+	// rather than starting from the beginning
+	Resumed bool
+
+	// Skipped reports whether the transfer is skipped since the destination file is kept as is
+	Skipped bool
+
+	// RetryAttempts is the number of attempts retried after a failure
+	RetryAttempts int
+
+	// Checksum is the checksum of the content of the source file with the checksum algorithm
+	// (see WithChecksumAlgorithm), it is nil unless the last attempt read the whole content
+	// from the beginning (e.g. it is resumed, skipped or copied server-side)
+	Checksum []byte
+
+	// DestinationObject references the finalized destination object (e.g. the ETag and the
+	// version of an S3 object), it is empty unless the destination implements
+	// storage.ObjectFinalizer
+	DestinationObject storage.FinalizedObject
+
+	// S3Operations is the number of S3 operations issued by the transfer to its source and its
+	// destination by operation name (e.g. "UploadPart"), including those of the failed attempts,
+	// it is nil unless WithOperationCounting is set
+	S3Operations map[string]int64
+}
+
+// newHash returns a new hash of the checksum algorithm, nil for NoneChecksumAlgorithm.
+func (a ChecksumAlgorithm) newHash() hash.Hash {
+	switch a {
+	case ChecksumAlgorithmCRC32:
+		return crc32.NewIEEE()
+	case ChecksumAlgorithmMD5:
+		return md5.New()
+	case ChecksumAlgorithmSHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package fxfer_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with result", func() {
+	noopCallback := func(fxfer.Progress) {}
+
+	var (
+		content     string
+		destStorage *corruptingDestination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 1000)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &corruptingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should report the result of a fresh transfer", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmSHA256))
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+
+		checksum := sha256.Sum256([]byte(content))
+		Expect(result.BytesTransferred).To(Equal(int64(len(content))))
+		Expect(result.TotalSize).To(Equal(int64(len(content))))
+		Expect(result.Resumed).To(BeFalse())
+		Expect(result.Skipped).To(BeFalse())
+		Expect(result.RetryAttempts).To(BeZero())
+		Expect(result.Checksum).To(Equal(checksum[:]))
+		Expect(result.StartedAt).ToNot(BeZero())
+		Expect(result.FinishedAt).To(BeTemporally(">=", result.StartedAt))
+	}, NodeTimeout(10*time.Second))
+
+	It("should report the result of a resumed transfer", func(ctx context.Context) {
+		Expect(destStorage.CreateFile(ctx, destConfig.FilePath, int64(len(content)), fileModTime(srcConfig.FilePath),
+			destConfig.Client)).To(Succeed())
+		_, err := destStorage.TransferFileChunk(ctx, destConfig.FilePath, strings.NewReader(content[:4000]), 0,
+			destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmCRC32))
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.BytesTransferred).To(Equal(int64(len(content) - 4000)))
+		Expect(result.Resumed).To(BeTrue())
+		Expect(result.Checksum).To(BeNil())
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should report the retry attempts of the transfer", func(ctx context.Context) {
+		destStorage.corruptChunk = 2
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithPeriodicVerification(1000),
+			fxfer.WithRetryConfig(fxfer.RetryConfig{
+				MaxRetryAttempts: 2,
+				InitialDelay:     time.Millisecond,
+				MaxDelay:         time.Millisecond,
+			}),
+		)
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.RetryAttempts).To(Equal(1))
+		Expect(result.Resumed).To(BeFalse())
+		Expect(result.BytesTransferred).To(Equal(int64(len(content))))
+	}, NodeTimeout(10*time.Second))
+
+	It("should report the skipped transfer of an identical destination", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Skipped).To(BeTrue())
+		Expect(result.BytesTransferred).To(BeZero())
+		Expect(result.TotalSize).To(Equal(int64(len(content))))
+	}, NodeTimeout(10*time.Second))
+
+	It("should report the finalized destination object", func(ctx context.Context) {
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		object := storage.FinalizedObject{ETag: "etag", VersionID: "version-id", Location: destConfig.FilePath}
+		destConfig.Storage = &objectFinalizingDestination{Destination: localDest, object: object}
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, noopCallback)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.DestinationObject).To(Equal(object))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+})
+
+// objectFinalizingDestination is a local destination which references the finalized file
+// as the object (see storage.ObjectFinalizer).
+type objectFinalizingDestination struct {
+	*local.Destination
+	object storage.FinalizedObject
+}
+
+func (d *objectFinalizingDestination) FinalizeTransferWithObject(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (object storage.FinalizedObject, err error) {
+	if err = d.Destination.FinalizeTransfer(ctx, filePath, cli); err != nil {
+		return
+	}
+	return d.object, nil
+}
+
+// fileModTime returns the modification time of the file.
+func fileModTime(filePath string) time.Time {
+	GinkgoHelper()
+	stat, err := os.Stat(filePath)
+	Expect(err).ToNot(HaveOccurred())
+	return stat.ModTime()
+}
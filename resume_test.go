@@ -0,0 +1,282 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/iotest"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	httpsrc_protoc "github.com/derektruong/fxfer/protoc/httpsrc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/httpsrc"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// offsetRecordingSource records the offsets the source file is read from.
+type offsetRecordingSource struct {
+	storage.Source
+
+	mu      sync.Mutex
+	offsets []int64
+}
+
+func (s *offsetRecordingSource) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	client protoc.Client,
+) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.offsets = append(s.offsets, offset)
+	s.mu.Unlock()
+	return s.Source.GetFileFromOffset(ctx, filePath, offset, client)
+}
+
+func (s *offsetRecordingSource) Offsets() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.offsets...)
+}
+
+// versionedSource records the version of the source file in its info and reads it only while it
+// has this version (see storage.ConditionalReader), the file is overwritten with the changed
+// content by the first read.
+type versionedSource struct {
+	*offsetRecordingSource
+	changedContent string
+
+	mu      sync.Mutex
+	version int
+	changed bool
+}
+
+func (s *versionedSource) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	client protoc.Client,
+) (info xferfile.Info, err error) {
+	if info, err = s.offsetRecordingSource.GetFileInfo(ctx, filePath, client); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info.Metadata = map[string]string{storage.SourceVersionMeta: "v" + strconv.Itoa(s.version)}
+	return
+}
+
+func (s *versionedSource) GetFileFromOffsetIfMatch(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	version string,
+	client protoc.Client,
+) (io.ReadCloser, error) {
+	s.mu.Lock()
+	if !s.changed {
+		// the file is overwritten with a new version once its info has been fetched
+		s.changed = true
+		s.version++
+		Expect(os.WriteFile(filePath, []byte(s.changedContent), 0644)).To(Succeed())
+	}
+	current := "v" + strconv.Itoa(s.version)
+	s.mu.Unlock()
+	if version != current {
+		// the precondition failed (HTTP 412)
+		return nil, fmt.Errorf("%w: version %s instead of %s", storage.ErrSourceChanged, current, version)
+	}
+	return s.offsetRecordingSource.GetFileFromOffset(ctx, filePath, offset, client)
+}
+
+var _ = Describe("Transfer after a crash", func() {
+	var (
+		content     string
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		destStorage *local.Destination
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat(gofakeit.Sentence(20), 50)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage.InfoSyncSize = 64
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should resume from the offset persisted before the crash", func(ctx context.Context) {
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("simulate a crash mid-chunk (partial write, no finalize)")
+		Expect(destStorage.CreateFile(
+			ctx, destConfig.FilePath, int64(len(content)), stat.ModTime(), destConfig.Client,
+		)).To(Succeed())
+		crashedOffset := int64(len(content) / 3)
+		_, err = destStorage.TransferFileChunk(
+			ctx,
+			destConfig.FilePath,
+			io.MultiReader(
+				strings.NewReader(content[:crashedOffset]),
+				iotest.ErrReader(errors.New("crash")),
+			),
+			0,
+			destConfig.Client,
+		)
+		Expect(err).To(HaveOccurred())
+
+		crashedState, err := destStorage.ResumeState(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(crashedState.Offset).To(Equal(crashedOffset))
+		Expect(crashedState.Finished).To(BeFalse())
+
+		By("transfer again with a new transfer (process restart)")
+		src := &offsetRecordingSource{Source: srcConfig.Storage}
+		srcConfig.Storage = src
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		By("assert the transfer resumed rather than restarted")
+		Expect(src.Offsets()).To(Equal([]int64{crashedOffset}))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		state, err := destStorage.ResumeState(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.Finished).To(BeTrue())
+		Expect(state.Offset).To(Equal(int64(len(content))))
+		Expect(state.StartTime).To(BeTemporally("==", crashedState.StartTime))
+	}, NodeTimeout(10*time.Second))
+
+	It("should re-create the destination when the source size changed but not its modification time", func(ctx context.Context) {
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("simulate an interrupted transfer")
+		Expect(destStorage.CreateFile(
+			ctx, destConfig.FilePath, int64(len(content)), stat.ModTime(), destConfig.Client,
+		)).To(Succeed())
+		_, err = destStorage.TransferFileChunk(
+			ctx, destConfig.FilePath, strings.NewReader(content[:len(content)/2]), 0, destConfig.Client,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		staleState, err := destStorage.ResumeState(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("change the source content while keeping its modification time")
+		content += gofakeit.Sentence(10)
+		Expect(os.WriteFile(srcConfig.FilePath, []byte(content), 0644)).To(Succeed())
+		Expect(os.Chtimes(srcConfig.FilePath, stat.ModTime(), stat.ModTime())).To(Succeed())
+
+		src := &offsetRecordingSource{Source: srcConfig.Storage}
+		srcConfig.Storage = src
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		By("assert the destination was re-created")
+		Expect(src.Offsets()).To(Equal([]int64{0}))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+		state, err := destStorage.ResumeState(ctx, destConfig.FilePath, destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state.Size).To(Equal(int64(len(content))))
+		Expect(state.StartTime).To(BeTemporally(">", staleState.StartTime))
+	}, NodeTimeout(10*time.Second))
+
+	It("should restart from the beginning when the source cannot be read from the offset", func(ctx context.Context) {
+		modTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the Range header is ignored
+			w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			if r.Method != http.MethodHead {
+				_, _ = io.WriteString(w, content)
+			}
+		}))
+		DeferCleanup(server.Close)
+		src := &offsetRecordingSource{Source: httpsrc.NewSource(GinkgoLogr)}
+		srcConfig = fxfer.SourceConfig{
+			FilePath: server.URL + "/content.txt",
+			Storage:  src,
+			Client:   httpsrc_protoc.NewClient(),
+		}
+
+		By("simulate an interrupted transfer")
+		Expect(destStorage.CreateFile(
+			ctx, destConfig.FilePath, int64(len(content)), modTime, destConfig.Client,
+		)).To(Succeed())
+		crashedOffset := int64(len(content) / 3)
+		_, err := destStorage.TransferFileChunk(
+			ctx, destConfig.FilePath, strings.NewReader(content[:crashedOffset]), 0, destConfig.Client,
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithRetryConfig(fxfer.RetryConfig{
+			MaxRetryAttempts: 2,
+			InitialDelay:     10 * time.Millisecond,
+			MaxDelay:         10 * time.Millisecond,
+			Multiplier:       1,
+		}))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		By("assert the transfer restarted from the beginning")
+		Expect(src.Offsets()).To(Equal([]int64{crashedOffset, 0}))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should restart from the beginning when the source changed since its info was fetched", func(ctx context.Context) {
+		stat, err := os.Stat(srcConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("simulate an interrupted transfer")
+		Expect(destStorage.CreateFile(
+			ctx, destConfig.FilePath, int64(len(content)), stat.ModTime(), destConfig.Client,
+		)).To(Succeed())
+		crashedOffset := int64(len(content) / 3)
+		_, err = destStorage.TransferFileChunk(
+			ctx, destConfig.FilePath, strings.NewReader(content[:crashedOffset]), 0, destConfig.Client,
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		src := &versionedSource{
+			offsetRecordingSource: &offsetRecordingSource{Source: srcConfig.Storage},
+			changedContent:        strings.ToUpper(content),
+		}
+		srcConfig.Storage = src
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithRetryConfig(fxfer.RetryConfig{
+			MaxRetryAttempts: 2,
+			InitialDelay:     10 * time.Millisecond,
+			MaxDelay:         10 * time.Millisecond,
+			Multiplier:       1,
+		}))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		By("assert the transfer restarted from the beginning with the changed content")
+		Expect(src.Offsets()).To(Equal([]int64{0}))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(src.changedContent))
+	}, NodeTimeout(10*time.Second))
+})
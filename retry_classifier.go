@@ -0,0 +1,58 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// RetryClassifier reports whether the error of a failed file transfer attempt is transient,
+// the attempt is retried if so (see WithRetryClassifier).
+type RetryClassifier func(err error) bool
+
+// permanentErrorCodes are the error codes of the storage services (e.g. S3) which fail again
+// when the request is retried as is.
+var permanentErrorCodes = []string{
+	"AccessDenied",
+	"Forbidden",
+	"InvalidAccessKeyId",
+	"SignatureDoesNotMatch",
+	"ExpiredToken",
+	"NoSuchBucket",
+	"EntityTooSmall",
+}
+
+// DefaultRetryClassifier is the RetryClassifier of a transfer unless WithRetryClassifier is set.
+// It retries the failures to transfer the content to the destination or to finalize it, except
+// the cancellation of the context (unless the transfer is stalled, see ErrStalled) and the errors
+// which fail again when retried: authentication and authorization failures (HTTP 401 and 403),
+// a missing bucket and the parts too small to be completed.
+func DefaultRetryClassifier(err error) bool {
+	if !errors.Is(err, errRetryable) {
+		return false
+	}
+	if errors.Is(err, ErrStalled) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, storage.ErrPermissionMissing) || errors.Is(err, storage.ErrDestinationImmutable) {
+		return false
+	}
+	// the errors of the storage services are matched by their behavior, e.g. smithy.APIError
+	// and the HTTP response error of the AWS SDK
+	var codeErr interface{ ErrorCode() string }
+	if errors.As(err, &codeErr) && slices.Contains(permanentErrorCodes, codeErr.ErrorCode()) {
+		return false
+	}
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		switch statusErr.HTTPStatusCode() {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc/httpsrc"
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/samber/lo"
+)
+
+// ErrSidecarNotFinalized is returned when the sidecar of a file cannot be finalized after the
+// file, both are then deleted from the destination (see WithSidecar).
+var ErrSidecarNotFinalized = errors.New("sidecar: cannot finalize the sidecar of the file")
+
+// sidecarKey is the context key of the sidecar transferred along with a file (see WithSidecar).
+type sidecarKey struct{}
+
+// sidecar is the sidecar of a file, which is transferred before the file but only finalized
+// once the file is.
+type sidecar struct {
+	// dest is the destination of the transferred sidecar, nil if it has not been transferred,
+	// e.g. it has been skipped since it is identical
+	dest *DestinationConfig
+	// deferring is true while the sidecar itself is transferred, its finalization is deferred
+	deferring bool
+	finalized bool
+}
+
+// sidecarFromContext returns the sidecar transferred along with the file of the context, nil if none.
+func sidecarFromContext(ctx context.Context) *sidecar {
+	sc, _ := ctx.Value(sidecarKey{}).(*sidecar)
+	return sc
+}
+
+// isSidecarTransfer reports whether the context is the one of the transfer of a sidecar.
+func isSidecarTransfer(ctx context.Context) bool {
+	sc := sidecarFromContext(ctx)
+	return sc != nil && sc.deferring
+}
+
+// transferSidecar transfers the sidecar of the source file, if any, to the destination file path
+// with the same suffix without finalizing it (see WithSidecar). The returned context finalizes the
+// sidecar right after the file (see finalizeTransfer), sc is nil if the file has no sidecar.
+func (t *transfer) transferSidecar(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+) (fileCtx context.Context, sc *sidecar, err error) {
+	fileCtx = ctx
+	if t.sidecarSuffix == "" || sidecarFromContext(ctx) != nil {
+		return
+	}
+	sidecarSrc, sidecarDest := src, dest
+	sidecarSrc.FilePath += t.sidecarSuffix
+	sidecarDest.FilePath += t.sidecarSuffix
+
+	var srcInfo xferfile.Info
+	if srcInfo, err = t.getSourceFileInfo(ctx, sidecarSrc); err != nil {
+		if isSourceNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	sc = &sidecar{deferring: true}
+	if _, err = t.transferFile(context.WithValue(ctx, sidecarKey{}, sc), srcInfo, sidecarSrc, sidecarDest,
+		func(Progress) {}); err != nil {
+		return
+	}
+	sc.deferring = false
+	fileCtx = context.WithValue(ctx, sidecarKey{}, sc)
+	return
+}
+
+// finalizeSidecar finalizes the transferred sidecar unless it has already been finalized.
+func (t *transfer) finalizeSidecar(ctx context.Context, sc *sidecar) (err error) {
+	if sc == nil || sc.dest == nil || sc.finalized {
+		return
+	}
+	if _, err = t.finalizeFile(ctx, *sc.dest); err != nil {
+		// the cause is not wrapped, the sidecar is not finalized again by a retry of the file
+		err = fmt.Errorf("%w %s: %v", ErrSidecarNotFinalized, sc.dest.FilePath, err)
+		return
+	}
+	sc.finalized = true
+	return
+}
+
+// withoutSidecars removes the listed files which are the sidecar of another listed file, they
+// are transferred along with it (see WithSidecar).
+func (t *transfer) withoutSidecars(srcInfos []xferfile.Info) []xferfile.Info {
+	if t.sidecarSuffix == "" {
+		return srcInfos
+	}
+	paths := lo.SliceToMap(srcInfos, func(info xferfile.Info) (string, bool) {
+		return info.Path, true
+	})
+	return lo.Reject(srcInfos, func(info xferfile.Info, _ int) bool {
+		return t.isListedSidecar(info.Path, paths)
+	})
+}
+
+// isListedSidecar reports whether the file is the sidecar of one of the listed files.
+func (t *transfer) isListedSidecar(path string, listedPaths map[string]bool) bool {
+	return strings.HasSuffix(path, t.sidecarSuffix) && listedPaths[strings.TrimSuffix(path, t.sidecarSuffix)]
+}
+
+// isSourceNotExist reports whether the error is the one of a source file which does not exist,
+// whatever the source storage.
+func isSourceNotExist(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, xferfile.ErrFileNotExists) ||
+		errors.Is(err, httpsrc.ErrNotFound) || errors.Is(err, webdav.ErrNotFound) {
+		return true
+	}
+	var statusErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusNotFound
+}
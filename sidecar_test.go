@@ -0,0 +1,119 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with a sidecar", func() {
+	var (
+		srcDir, destDir string
+		destStorage     *finalizeRecordingDestination
+		srcConfig       fxfer.SourceConfig
+		destConfig      fxfer.DestinationConfig
+		tfr             fxfer.Transfer
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcDir, destDir = filepath.Join(tempDir, "src"), filepath.Join(tempDir, "dest")
+		Expect(os.MkdirAll(srcDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(srcDir, "x.dat"), []byte(strings.Repeat("0123456789", 100)), 0644)).
+			To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &finalizeRecordingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{
+			FilePath: filepath.Join(srcDir, "x.dat"),
+			Storage:  srcStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(destDir, "x.dat"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithSidecar(".meta"))
+	})
+
+	writeSidecar := func() {
+		Expect(os.WriteFile(filepath.Join(srcDir, "x.dat.meta"), []byte(`{"owner":"derek"}`), 0644)).To(Succeed())
+	}
+
+	It("should transfer the sidecar and finalize it right after its file", func(ctx context.Context) {
+		writeSidecar()
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(filepath.Join(destDir, "x.dat")).To(BeARegularFile())
+		Expect(os.ReadFile(filepath.Join(destDir, "x.dat.meta"))).To(Equal([]byte(`{"owner":"derek"}`)))
+		Expect(destStorage.finalized).To(Equal([]string{
+			filepath.Join(destDir, "x.dat"),
+			filepath.Join(destDir, "x.dat.meta"),
+		}))
+	}, NodeTimeout(10*time.Second))
+
+	It("should transfer the file alone when it has no sidecar", func(ctx context.Context) {
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(filepath.Join(destDir, "x.dat")).To(BeARegularFile())
+		Expect(filepath.Join(destDir, "x.dat.meta")).ToNot(BeAnExistingFile())
+		Expect(destStorage.finalized).To(Equal([]string{filepath.Join(destDir, "x.dat")}))
+	}, NodeTimeout(10*time.Second))
+
+	It("should keep neither the file nor its sidecar when the sidecar cannot be finalized", func(ctx context.Context) {
+		writeSidecar()
+		destStorage.failSuffix = ".meta"
+
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(fxfer.ErrSidecarNotFinalized))
+		Expect(filepath.Join(destDir, "x.dat")).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(destDir, "x.dat.meta")).ToNot(BeAnExistingFile())
+	}, NodeTimeout(10*time.Second))
+
+	It("should transfer the listed sidecar along with its file only", func(ctx context.Context) {
+		writeSidecar()
+		srcConfig.FilePath, destConfig.FilePath = srcDir, destDir
+
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+		Expect(destStorage.finalized).To(Equal([]string{
+			filepath.Join(destDir, "x.dat"),
+			filepath.Join(destDir, "x.dat.meta"),
+		}))
+	}, NodeTimeout(10*time.Second))
+})
+
+// finalizeRecordingDestination is a local destination which records the finalized files in
+// order, and fails to finalize the files with the failSuffix.
+type finalizeRecordingDestination struct {
+	*local.Destination
+	failSuffix string
+	finalized  []string
+}
+
+func (d *finalizeRecordingDestination) FinalizeTransfer(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (err error) {
+	if d.failSuffix != "" && strings.HasSuffix(filePath, d.failSuffix) {
+		return errors.New("finalize failed")
+	}
+	if err = d.Destination.FinalizeTransfer(ctx, filePath, cli); err == nil {
+		d.finalized = append(d.finalized, filePath)
+	}
+	return
+}
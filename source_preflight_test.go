@@ -0,0 +1,82 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/internal/xferfile/xferfiletest"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Transfer with a source preflight", func() {
+	var (
+		mockClient      *mock_protoc.MockClient
+		mockSrcStorage  *mock_storage.MockSource
+		mockDestStorage *mock_storage.MockDestination
+		srcInfo         xferfile.Info
+		src             fxfer.SourceConfig
+		dest            fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockClient = mock_protoc.NewMockClient(mockCtrl)
+		mockSrcStorage = mock_storage.NewMockSource(mockCtrl)
+		mockDestStorage = mock_storage.NewMockDestination(mockCtrl)
+		srcInfo = xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size = "src-file.txt", "txt", 10
+		})
+		src = fxfer.SourceConfig{FilePath: srcInfo.Path, Storage: mockSrcStorage, Client: mockClient}
+		dest = fxfer.DestinationConfig{FilePath: "dest-file.txt", Storage: mockDestStorage, Client: mockClient}
+	})
+
+	It("should fail before creating the destination file when the source is unreadable", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithSourcePreflight())
+		gomock.InOrder(
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), src.FilePath, mockClient).Return(srcInfo, nil),
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), src.FilePath, int64(0), mockClient).
+				Return(nil, os.ErrPermission),
+		)
+		mockDestStorage.EXPECT().CreateFile(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(0)
+
+		Expect(tfr.Transfer(ctx, src, dest, func(fxfer.Progress) {})).To(MatchError(os.ErrPermission))
+	}, NodeTimeout(10*time.Second))
+
+	It("should open the source again to transfer it once the preflight passes", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithSourcePreflight())
+		destInfo := xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size, info.Offset = dest.FilePath, "txt", srcInfo.Size, 0
+			info.ModTime, info.FinishTime = srcInfo.ModTime, time.Time{}
+		})
+		newReader := func() io.ReadCloser { return io.NopCloser(strings.NewReader("0123456789")) }
+		gomock.InOrder(
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), src.FilePath, mockClient).Return(srcInfo, nil),
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), src.FilePath, int64(0), mockClient).
+				Return(newReader(), nil),
+			mockDestStorage.EXPECT().CreateFile(gomock.Any(), dest.FilePath, srcInfo.Size, gomock.Any(), mockClient).
+				Return(nil),
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), dest.FilePath, mockClient).Return(destInfo, nil),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), src.FilePath, int64(0), mockClient).
+				Return(newReader(), nil),
+			mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), dest.FilePath, gomock.Any(), int64(0), mockClient).
+				Return(srcInfo.Size, nil),
+			mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), dest.FilePath, mockClient).Return(nil),
+		)
+
+		Expect(tfr.Transfer(ctx, src, dest, func(fxfer.Progress) {})).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,79 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/go-logr/logr"
+)
+
+// reopeningReader reads the source file from an offset, it tracks the number of bytes delivered
+// and reopens the source file at the exact byte it stopped at when a read fails (see
+// WithSourceReadRetry), so that its reader sees a continuous stream.
+type reopeningReader struct {
+	ctx        context.Context
+	logger     logr.Logger
+	src        SourceConfig
+	srcInfo    xferfile.Info
+	reader     io.ReadCloser
+	offset     int64
+	maxReopens int
+	reopens    int
+}
+
+func newReopeningReader(
+	ctx context.Context,
+	logger logr.Logger,
+	src SourceConfig,
+	srcInfo xferfile.Info,
+	reader io.ReadCloser,
+	offset int64,
+	maxReopens int,
+) *reopeningReader {
+	return &reopeningReader{
+		ctx:        ctx,
+		logger:     logger,
+		src:        src,
+		srcInfo:    srcInfo,
+		reader:     reader,
+		offset:     offset,
+		maxReopens: maxReopens,
+	}
+}
+
+func (r *reopeningReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.offset += int64(n)
+	if n > 0 {
+		// the attempts are consecutive, a reader making progress is given them again
+		r.reopens = 0
+	}
+	if err == nil || errors.Is(err, io.EOF) || r.ctx.Err() != nil || r.reopens >= r.maxReopens {
+		return
+	}
+	if err = r.reopen(err); err != nil || n > 0 {
+		return
+	}
+	return r.Read(p)
+}
+
+// reopen reopens the source file at the offset after the read error readErr.
+func (r *reopeningReader) reopen(readErr error) (err error) {
+	r.reopens++
+	r.logger.Info("reopening the source file after a read error",
+		"srcPath", r.src.FilePath, "offset", r.offset, "attempt", r.reopens, "error", readErr.Error())
+	_ = r.reader.Close()
+	var reader io.ReadCloser
+	if reader, err = getSourceFromOffset(r.ctx, r.src, r.srcInfo, r.offset); err != nil {
+		// the closed reader is kept, it fails the next reads
+		return errors.Join(readErr, err)
+	}
+	r.reader = reader
+	return
+}
+
+func (r *reopeningReader) Close() error {
+	return r.reader.Close()
+}
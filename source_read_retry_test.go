@@ -0,0 +1,101 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with a source read retry", func() {
+	var (
+		content    string
+		srcStorage *droppingSource
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 100)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		localSrc, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcStorage = &droppingSource{Source: localSrc, dropAfter: 300, err: errors.New("connection reset")}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should continue the stream from the byte the source stopped at", func(ctx context.Context) {
+		srcStorage.drops = 2
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithSourceReadRetry(1))
+		result, err := tfr.TransferWithResult(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(srcStorage.offsets).To(Equal([]int64{0, 300, 600}))
+		Expect(result.BytesTransferred).To(Equal(int64(len(content))))
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail once the consecutive attempts are exhausted", func(ctx context.Context) {
+		srcStorage.dropAfter = 0
+		srcStorage.drops = 3
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithSourceReadRetry(2))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(srcStorage.err))
+		Expect(srcStorage.offsets).To(Equal([]int64{0, 0, 0}))
+	}, NodeTimeout(10*time.Second))
+})
+
+// droppingSource is a local source whose first drops readers fail with err after dropAfter
+// bytes, it records the offsets the file is read from.
+type droppingSource struct {
+	*local.Source
+	dropAfter int64
+	drops     int
+	err       error
+	offsets   []int64
+}
+
+func (s *droppingSource) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	s.offsets = append(s.offsets, offset)
+	if reader, err = s.Source.GetFileFromOffset(ctx, filePath, offset, cli); err != nil || s.drops == 0 {
+		return
+	}
+	s.drops--
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(io.LimitReader(reader, s.dropAfter), errReader{s.err}), reader}, nil
+}
+
+// errReader is a reader which fails with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
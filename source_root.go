@@ -0,0 +1,33 @@
+package fxfer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSourceOutsideRoot is returned when a source file of a batch transfer is not under the
+// source root (see WithSourceRoot).
+var ErrSourceOutsideRoot = errors.New("source root: the source file is outside the root")
+
+// batchDestinationPath returns the path of the destination file of the source file of a batch
+// transfer, i.e. its path relative to the base directory (or the source root if set) under the
+// destination directory. The relative path always uses forward slashes (e.g. for S3 keys),
+// whatever the separator of the source paths.
+func (t *transfer) batchDestinationPath(baseDir, srcPath, destDir string) (destPath string, err error) {
+	if t.sourceRoot != "" {
+		baseDir = t.sourceRoot
+	}
+	var relPath string
+	if relPath, err = filepath.Rel(baseDir, srcPath); err != nil {
+		return
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		err = fmt.Errorf("%w: %s is outside %s", ErrSourceOutsideRoot, srcPath, baseDir)
+		return
+	}
+	return path.Join(destDir, relPath), nil
+}
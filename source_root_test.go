@@ -0,0 +1,72 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer a directory with a source root", func() {
+	var (
+		tempDir    string
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+		for _, relPath := range []string{"data/a/b/c.txt", "data/a/d.txt"} {
+			srcPath := filepath.Join(tempDir, filepath.FromSlash(relPath))
+			Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+			Expect(os.WriteFile(srcPath, []byte("content of "+relPath), 0644)).To(Succeed())
+		}
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{
+			FilePath: filepath.Join(tempDir, "data", "a"),
+			Storage:  srcStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "backup"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should preserve the structure of the files relative to the root", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithSourceRoot(filepath.Join(tempDir, "data")))
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(os.ReadFile(filepath.Join(tempDir, "backup", "a", "b", "c.txt"))).
+			To(BeEquivalentTo("content of data/a/b/c.txt"))
+		Expect(os.ReadFile(filepath.Join(tempDir, "backup", "a", "d.txt"))).
+			To(BeEquivalentTo("content of data/a/d.txt"))
+	}, NodeTimeout(10*time.Second))
+
+	It("should preserve the structure relative to the source directory by default", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(filepath.Join(tempDir, "backup", "b", "c.txt")).To(BeARegularFile())
+		Expect(filepath.Join(tempDir, "backup", "d.txt")).To(BeARegularFile())
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail the files outside the root", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithSourceRoot(filepath.Join(tempDir, "data", "a", "b")))
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(fxfer.ErrSourceOutsideRoot))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,66 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStalled is returned when the throughput of a transfer stays below the minimum over the window
+// of WithStallTimeout, the transfer is retried since a stall is usually transient.
+var ErrStalled = errors.New("stall: the throughput of the transfer stayed below the minimum")
+
+// throughputSample is the size transferred at a time of the transfer.
+type throughputSample struct {
+	at   time.Time
+	size int64
+}
+
+// stallDetector aborts a transfer with ErrStalled once its throughput over the sliding window
+// stays below the minimum (see WithStallTimeout).
+type stallDetector struct {
+	minBytesPerSec int64
+	window         time.Duration
+	samples        []throughputSample
+	abort          context.CancelCauseFunc
+	// pauseGate restarts the window while the transferer is paused (see Transfer.Pause)
+	pauseGate *pauseGate
+}
+
+// newStallDetector creates a stallDetector of the transfer which started at the size.
+func (t *transfer) newStallDetector(
+	startAt time.Time,
+	size int64,
+	abort context.CancelCauseFunc,
+) *stallDetector {
+	return &stallDetector{
+		minBytesPerSec: t.stallMinBytesPerSec,
+		window:         t.stallWindow,
+		samples:        []throughputSample{{at: startAt, size: size}},
+		abort:          abort,
+		pauseGate:      &t.pauseGate,
+	}
+}
+
+// observe records the size transferred at the time, it aborts the transfer and reports whether
+// it is stalled once the throughput over the last window is below the minimum.
+func (d *stallDetector) observe(at time.Time, size int64) (stalled bool) {
+	if d.pauseGate != nil && d.pauseGate.isPaused() {
+		d.samples = []throughputSample{{at: at, size: size}}
+		return
+	}
+	d.samples = append(d.samples, throughputSample{at: at, size: size})
+	// the oldest sample kept is the latest one which is at least a window old
+	for len(d.samples) > 1 && at.Sub(d.samples[1].at) >= d.window {
+		d.samples = d.samples[1:]
+	}
+	oldest := d.samples[0]
+	elapsed := at.Sub(oldest.at)
+	if elapsed < d.window {
+		return
+	}
+	if stalled = float64(size-oldest.size)/elapsed.Seconds() < float64(d.minBytesPerSec); stalled {
+		d.abort(ErrStalled)
+	}
+	return
+}
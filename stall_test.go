@@ -0,0 +1,108 @@
+package fxfer_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithStallTimeout", func() {
+	noopCallback := func(fxfer.Progress) {}
+
+	var (
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+		attempts   atomic.Int32
+		trickle    fxfer.ReaderMiddleware
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 10000)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+
+		attempts.Store(0)
+		trickle = func(reader io.Reader) io.Reader {
+			attempts.Add(1)
+			return &trickleReader{reader: reader}
+		}
+	})
+
+	It("should abort the transfer whose throughput stays below the minimum", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithProgressRefreshInterval(20*time.Millisecond),
+			fxfer.WithStallTimeout(100*1024, 200*time.Millisecond),
+			fxfer.WithReaderMiddleware(trickle),
+		)
+
+		var inError atomic.Bool
+		err := tfr.Transfer(ctx, srcConfig, destConfig, func(progress fxfer.Progress) {
+			if progress.Status == fxfer.ProgressStatusInError {
+				inError.Store(true)
+			}
+		})
+		Expect(err).To(MatchError(fxfer.ErrStalled))
+		Expect(inError.Load()).To(BeTrue())
+	}, NodeTimeout(10*time.Second))
+
+	It("should retry the stalled transfer", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithRetryConfig(fxfer.RetryConfig{
+				MaxRetryAttempts: 2,
+				InitialDelay:     10 * time.Millisecond,
+				MaxDelay:         10 * time.Millisecond,
+				Multiplier:       1,
+			}),
+			fxfer.WithProgressRefreshInterval(20*time.Millisecond),
+			fxfer.WithStallTimeout(100*1024, 200*time.Millisecond),
+			fxfer.WithReaderMiddleware(trickle),
+		)
+
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(MatchError(fxfer.ErrStalled))
+		Expect(attempts.Load()).To(BeNumerically(">", 1))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not abort the transfer whose throughput is above the minimum", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(),
+			fxfer.WithProgressRefreshInterval(20*time.Millisecond),
+			fxfer.WithStallTimeout(1024, 200*time.Millisecond),
+		)
+
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+		Expect(os.ReadFile(destConfig.FilePath)).To(HaveLen(100000))
+	}, NodeTimeout(10*time.Second))
+})
+
+// trickleReader reads 10 bytes every 10 milliseconds, about 1 KB/s.
+type trickleReader struct {
+	reader io.Reader
+}
+
+func (r *trickleReader) Read(p []byte) (n int, err error) {
+	time.Sleep(10 * time.Millisecond)
+	return r.reader.Read(p[:min(len(p), 10)])
+}
@@ -0,0 +1,95 @@
+// Package crypt provides the client-side AES-256 encryption of the transferred
+// content (AES-CTR), and a Source decrypting the content read back from a storage.
+//
+// AES-CTR does not change the size of the content and its keystream can be
+// positioned at any offset, so an encrypted transfer can be resumed from the
+// offset of the destination with the IV recorded in the destination file info.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// KeySize is the size in bytes of an AES-256 key
+const KeySize = 32
+
+// IVMeta is the metadata key of the destination file info recording the hex-encoded IV
+const IVMeta = "encryptionIV"
+
+var (
+	// ErrInvalidKey is returned when the key is not a valid AES-256 key
+	ErrInvalidKey = errors.New("crypt: invalid key, expected 32 bytes for AES-256")
+	// ErrInvalidIV is returned when the IV is not a valid AES-CTR IV
+	ErrInvalidIV = errors.New("crypt: invalid IV, expected 16 bytes")
+)
+
+// NewIV generates a new random IV.
+func NewIV() (iv []byte, err error) {
+	iv = make([]byte, aes.BlockSize)
+	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// EncodeIV encodes the IV to be recorded in the metadata (see IVMeta).
+func EncodeIV(iv []byte) string {
+	return hex.EncodeToString(iv)
+}
+
+// DecodeIV decodes the IV recorded in the metadata (see IVMeta).
+func DecodeIV(metadata map[string]string) (iv []byte, err error) {
+	encoded, ok := metadata[IVMeta]
+	if !ok {
+		err = ErrInvalidIV
+		return
+	}
+	if iv, err = hex.DecodeString(encoded); err != nil || len(iv) != aes.BlockSize {
+		iv, err = nil, ErrInvalidIV
+	}
+	return
+}
+
+// NewReader returns a reader encrypting (or decrypting, AES-CTR is symmetric)
+// the content of r, which starts at the offset of the whole content.
+func NewReader(r io.Reader, key []byte, iv []byte, offset int64) (reader io.Reader, err error) {
+	if len(key) != KeySize {
+		err = ErrInvalidKey
+		return
+	}
+	if len(iv) != aes.BlockSize {
+		err = ErrInvalidIV
+		return
+	}
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+
+	// position the keystream at the block of the offset, then skip the
+	// remaining bytes of the keystream within that block
+	stream := cipher.NewCTR(block, addCounter(iv, uint64(offset/aes.BlockSize)))
+	if skip := offset % aes.BlockSize; skip > 0 {
+		discard := make([]byte, skip)
+		stream.XORKeyStream(discard, discard)
+	}
+	reader = &cipher.StreamReader{S: stream, R: r}
+	return
+}
+
+// addCounter returns the IV incremented by n, as a 128-bit big-endian counter.
+func addCounter(iv []byte, n uint64) []byte {
+	counter := make([]byte, len(iv))
+	copy(counter, iv)
+	for i := len(counter) - 1; i >= 0 && n > 0; i-- {
+		sum := uint64(counter[i]) + n&0xff
+		counter[i] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+	return counter
+}
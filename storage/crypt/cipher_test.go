@@ -0,0 +1,78 @@
+package crypt_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer/storage/crypt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cipher", func() {
+	var (
+		key     []byte
+		iv      []byte
+		content string
+	)
+
+	BeforeEach(func() {
+		var err error
+		key = []byte(gofakeit.LetterN(crypt.KeySize))
+		iv, err = crypt.NewIV()
+		Expect(err).ToNot(HaveOccurred())
+		content = gofakeit.Sentence(100)
+	})
+
+	encrypt := func(r io.Reader, offset int64) []byte {
+		GinkgoHelper()
+		reader, err := crypt.NewReader(r, key, iv, offset)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(reader)
+		Expect(err).ToNot(HaveOccurred())
+		return data
+	}
+
+	It("should round-trip the content", func() {
+		encrypted := encrypt(strings.NewReader(content), 0)
+		Expect(encrypted).To(HaveLen(len(content)))
+		Expect(string(encrypted)).ToNot(Equal(content))
+		Expect(string(encrypt(bytes.NewReader(encrypted), 0))).To(Equal(content))
+	})
+
+	DescribeTable("should continue the keystream from the offset",
+		func(offset int) {
+			encrypted := encrypt(strings.NewReader(content), 0)
+			Expect(encrypt(strings.NewReader(content[offset:]), int64(offset))).
+				To(Equal(encrypted[offset:]))
+		},
+		Entry("offset aligned to a block", 32),
+		Entry("offset within a block", 37),
+		Entry("offset within the first block", 5),
+	)
+
+	It("should carry the counter over the bytes of the IV", func() {
+		iv = bytes.Repeat([]byte{0xff}, 16)
+		iv[0] = 0
+		encrypted := encrypt(strings.NewReader(content), 0)
+		Expect(encrypt(strings.NewReader(content[40:]), 40)).To(Equal(encrypted[40:]))
+	})
+
+	It("should return error if the key is not an AES-256 key", func() {
+		_, err := crypt.NewReader(strings.NewReader(content), key[:16], iv, 0)
+		Expect(err).To(MatchError(crypt.ErrInvalidKey))
+	})
+
+	It("should encode and decode the IV", func() {
+		decoded, err := crypt.DecodeIV(map[string]string{crypt.IVMeta: crypt.EncodeIV(iv)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(iv))
+
+		_, err = crypt.DecodeIV(map[string]string{crypt.IVMeta: "invalid"})
+		Expect(err).To(MatchError(crypt.ErrInvalidIV))
+		_, err = crypt.DecodeIV(nil)
+		Expect(err).To(MatchError(crypt.ErrInvalidIV))
+	})
+})
@@ -0,0 +1,112 @@
+package crypt
+
+import (
+	"context"
+	"io"
+	"iter"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+// Source represents a source decrypting the content of the underlying source,
+// which has been encrypted with the key and the IV (see IVMeta).
+type Source struct {
+	logger logr.Logger
+
+	source storage.Source
+	key    []byte
+	iv     []byte
+}
+
+// NewSource creates a new decrypting source wrapping the underlying source.
+func NewSource(logger logr.Logger, source storage.Source, key []byte, iv []byte) (s *Source, err error) {
+	if len(key) != KeySize {
+		err = ErrInvalidKey
+		return
+	}
+	s = &Source{
+		logger: logger.WithName("crypt.source"),
+		source: source,
+		key:    key,
+		iv:     iv,
+	}
+	return
+}
+
+func (s *Source) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (info xferfile.Info, err error) {
+	return s.source.GetFileInfo(ctx, filePath, cli)
+}
+
+func (s *Source) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	var encryptedReader io.ReadCloser
+	if encryptedReader, err = s.source.GetFileFromOffset(ctx, filePath, offset, cli); err != nil {
+		return
+	}
+	var decryptedReader io.Reader
+	if decryptedReader, err = NewReader(encryptedReader, s.key, s.iv, offset); err != nil {
+		_ = encryptedReader.Close()
+		return
+	}
+	reader = struct {
+		io.Reader
+		io.Closer
+	}{decryptedReader, encryptedReader}
+	return
+}
+
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	return s.source.ListFiles(ctx, dirPath, cli)
+}
+
+// IterFiles iterates over the files of the underlying source (see storage.FileIterator), lazily
+// if it implements storage.FileIterator, otherwise over the files it lists.
+func (s *Source) IterFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) iter.Seq2[xferfile.Info, error] {
+	if iterator, ok := s.source.(storage.FileIterator); ok {
+		return iterator.IterFiles(ctx, dirPath, cli)
+	}
+	return func(yield func(xferfile.Info, error) bool) {
+		infos, err := s.source.ListFiles(ctx, dirPath, cli)
+		if err != nil {
+			yield(xferfile.Info{}, err)
+			return
+		}
+		for _, info := range infos {
+			if !yield(info, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	return s.source.Glob(ctx, pattern, cli)
+}
+
+func (s *Source) Close() {
+	s.source.Close()
+	s.logger.Info("closed crypt source")
+}
@@ -0,0 +1,66 @@
+package crypt_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"github.com/derektruong/fxfer/storage/local"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Source", func() {
+	var key []byte
+
+	BeforeEach(func() {
+		key = []byte(gofakeit.LetterN(crypt.KeySize))
+	})
+
+	Describe("IterFiles", func() {
+		It("should forward the iteration to the underlying file iterator", func(ctx context.Context) {
+			tempDir := GinkgoT().TempDir()
+			for _, name := range []string{"a.txt", filepath.Join("nested", "b.txt")} {
+				Expect(os.MkdirAll(filepath.Dir(filepath.Join(tempDir, name)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644)).To(Succeed())
+			}
+			localSource, err := local.NewSource(GinkgoLogr)
+			Expect(err).ToNot(HaveOccurred())
+			cryptSource, err := crypt.NewSource(GinkgoLogr, localSource, key, nil)
+			Expect(err).ToNot(HaveOccurred())
+			var iterator storage.FileIterator = cryptSource
+
+			var paths []string
+			for info, err := range iterator.IterFiles(ctx, tempDir, local_protoc.NewIO()) {
+				Expect(err).ToNot(HaveOccurred())
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(Equal([]string{
+				filepath.Join(tempDir, "a.txt"),
+				filepath.Join(tempDir, "nested", "b.txt"),
+			}))
+		})
+
+		It("should iterate over the listed files of a source which is not a file iterator", func(ctx context.Context) {
+			mockSource := mock_storage.NewMockSource(gomock.NewController(GinkgoT()))
+			mockSource.EXPECT().ListFiles(ctx, "dir", nil).
+				Return([]xferfile.Info{{Path: "dir/a.txt"}, {Path: "dir/b.txt"}}, nil)
+			cryptSource, err := crypt.NewSource(GinkgoLogr, mockSource, key, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			var paths []string
+			for info, err := range cryptSource.IterFiles(ctx, "dir", nil) {
+				Expect(err).ToNot(HaveOccurred())
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(Equal([]string{"dir/a.txt", "dir/b.txt"}))
+		})
+	})
+})
@@ -75,3 +75,134 @@ type Destination interface {
 	// Close closes the destination
 	Close()
 }
+
+// ConfirmedSizeTracker can be implemented by the reader passed to Destination.TransferFileChunk
+// to be notified of the number of bytes durably written to the destination. Unlike the bytes read
+// from the reader, the confirmed bytes only advance once the destination has persisted them (e.g.
+// once a part of a multipart upload has completed).
+type ConfirmedSizeTracker interface {
+	// AddConfirmedSize adds n bytes to the confirmed size, it may be called concurrently
+	AddConfirmedSize(n int64)
+}
+
+// TransferEstimate is the estimated cost of transferring a file to a destination.
+type TransferEstimate struct {
+	// Requests is the total number of requests issued to the destination
+	Requests int64
+
+	// PartUploads is the number of parts uploaded to the destination
+	PartUploads int64
+
+	// PartSize is the size of each part in bytes (the last part may be smaller)
+	PartSize int64
+
+	// TransferredBytes is the number of bytes transferred to the destination
+	TransferredBytes int64
+
+	// TempDiskHighWaterMark is the maximum number of bytes buffered on the
+	// temporary disk at the same time
+	TempDiskHighWaterMark int64
+}
+
+// Estimator can be implemented by a Destination to estimate the cost
+// of a transfer without issuing any request.
+type Estimator interface {
+	// EstimateTransfer estimates the cost of a fresh transfer of a file of the given size
+	EstimateTransfer(size int64) (estimate TransferEstimate, err error)
+}
+
+// SourceExtensionMeta is the metadata key of the extension of the source file, a destination
+// records it as the extension of the file info (see xferfile.Info.Extension) when the
+// destination path has a different extension.
+const SourceExtensionMeta = "sourceExtension"
+
+// MetadataFileCreator can be implemented by a Destination to attach metadata
+// to the info of a file when creating it.
+type MetadataFileCreator interface {
+	// CreateFileWithMetadata creates a file at the specified path (see Destination.CreateFile),
+	// the metadata is stored in the info of the file (see xferfile.Info.Metadata)
+	CreateFileWithMetadata(
+		ctx context.Context,
+		path string, size int64, modTime time.Time, metadata map[string]string,
+		client protoc.Client,
+	) (err error)
+}
+
+// ServerSideCopier can be implemented by a Destination to copy a file from a source
+// hosted by the same service without streaming its content through the client.
+type ServerSideCopier interface {
+	// CanCopyFrom reports whether the files of the source storage can be copied server-side
+	CanCopyFrom(src Source, srcClient protoc.Client, client protoc.Client) bool
+
+	// CopyFileFrom copies the content of the source file from the offset to its end into the
+	// destination file (see Destination.TransferFileChunk), the copied bytes are reported to
+	// the tracker (may be nil) once the destination has persisted them. The copy waits before
+	// each part if the tracker implements CopyPauser.
+	CopyFileFrom(
+		ctx context.Context,
+		filePath, srcPath string, srcClient protoc.Client,
+		offset int64,
+		client protoc.Client,
+		tracker ConfirmedSizeTracker,
+	) (n int64, err error)
+}
+
+// CopyPauser can be implemented by the tracker passed to ServerSideCopier.CopyFileFrom to hold
+// the copy between its parts, e.g. while the transfer is paused.
+type CopyPauser interface {
+	// WaitToCopy blocks before the next part is copied while the copy is held, it returns the
+	// error of the context if it is done first
+	WaitToCopy(ctx context.Context) error
+}
+
+// RangeReader can be implemented by a Destination to read back the bytes already written to
+// a file before it is finalized, e.g. to verify them against the bytes read from the source.
+type RangeReader interface {
+	// ReadRange returns a reader of the length bytes of the file from the offset,
+	// the reader must be closed by the caller
+	ReadRange(ctx context.Context, filePath string, offset, length int64, client protoc.Client) (reader io.ReadCloser, err error)
+}
+
+// ExistenceChecker can be implemented by a Destination to check whether a file exists at a path,
+// whether or not it has been transferred by the destination (e.g. an object put by another writer).
+type ExistenceChecker interface {
+	// FileExists reports whether a file exists at the specified path
+	FileExists(ctx context.Context, filePath string, client protoc.Client) (exists bool, err error)
+}
+
+// ObjectStatter can be implemented by a Destination to report the file at a path as its readers
+// see it, e.g. the object of an eventually-consistent backend may not be visible yet once finalized.
+type ObjectStatter interface {
+	// StatObject returns the size of the file at the specified path, exists is false if it is
+	// not visible
+	StatObject(ctx context.Context, filePath string, client protoc.Client) (size int64, exists bool, err error)
+}
+
+// FinalizedObject references the object produced by the finalization of a file, so that it can
+// be referenced elsewhere (e.g. a CDN invalidation or a database record) without fetching it.
+type FinalizedObject struct {
+	// ETag is the entity tag of the object
+	ETag string
+
+	// VersionID is the version of the object, it is empty if the bucket is not versioned
+	VersionID string
+
+	// Location is the URI of the object
+	Location string
+}
+
+// ObjectFinalizer can be implemented by a Destination to reference the object produced by the
+// finalization of a file.
+type ObjectFinalizer interface {
+	// FinalizeTransferWithObject finalizes the file at the specified path
+	// (see Destination.FinalizeTransfer) and returns the reference of the finalized object
+	FinalizeTransferWithObject(ctx context.Context, filePath string, client protoc.Client) (object FinalizedObject, err error)
+}
+
+// TransferLocker can be implemented by a Destination to prevent the concurrent transfers of a
+// process to the same file from corrupting each other (e.g. racing multipart uploads).
+type TransferLocker interface {
+	// LockTransfer locks the file at the specified path for a transfer, either waiting for the
+	// transfer holding it or failing with ErrConcurrentTransfer, unlock releases it
+	LockTransfer(ctx context.Context, filePath string, client protoc.Client) (unlock func(), err error)
+}
@@ -6,4 +6,27 @@ var ErrLocalProtocolIOInvalid = errors.New("protocol: local IO invalid, expected
 var ErrFTPProtocolClientInvalid = errors.New("protocol: client invalid, expected FTP")
 var ErrSFTPProtocolClientInvalid = errors.New("protocol: client invalid, expected SFTP")
 var ErrS3ProtocolClientInvalid = errors.New("protocol: client invalid, expected S3")
+var ErrWebDAVProtocolClientInvalid = errors.New("protocol: client invalid, expected WebDAV")
+var ErrHTTPProtocolClientInvalid = errors.New("protocol: client invalid, expected HTTP")
 var ErrFileOrObjectCannotFinalize = errors.New("file or object cannot finalize, please retry")
+var ErrStreamNotRewindable = errors.New("stream: cannot rewind to an already consumed offset")
+var ErrRangeUnsupported = errors.New("source: cannot read from an offset, the file must be transferred from the beginning")
+var ErrSourceChanged = errors.New("source: file changed since its info was fetched, it must be transferred from the beginning")
+var ErrObjectNeedsRestore = errors.New("object: archived in a storage class requiring restore")
+var ErrObjectRestoreInProgress = errors.New("object: restore from the archive storage class is in progress")
+var ErrPartLayoutInvalid = errors.New("part layout: invalid part sizes")
+var ErrFileInfoMismatch = errors.New("file info: recorded path does not match the file path")
+var ErrChunkOffsetOutOfRange = errors.New("chunk: offset is beyond the end of the file")
+var ErrIncompletePartCorrupted = errors.New("part: incomplete part does not match its recorded checksum")
+var ErrPartETagMissing = errors.New("part: uploaded part has no ETag")
+var ErrPartTimeout = errors.New("part: upload timed out, please retry")
+var ErrPartSizeInvalid = errors.New("part size: invalid part size configuration")
+var ErrPartConcurrencyInvalid = errors.New("part concurrency: invalid concurrent part uploads configuration")
+var ErrPartTooSmall = errors.New("part: smaller than the minimum part size of the storage, the part size must be increased")
+var ErrTempDirSpaceInsufficient = errors.New("temporary directory: insufficient space to buffer the parts")
+var ErrThrottled = errors.New("request: throttled by the storage, please slow down")
+var ErrPermissionMissing = errors.New("permission: missing permission required by the transfer")
+var ErrDestinationImmutable = errors.New("object: locked by a retention or a legal hold, cannot be overwritten")
+var ErrDestinationExists = errors.New("object: already exists, cannot be overwritten")
+var ErrConcurrentTransfer = errors.New("transfer: another transfer of the process to the same file is in progress")
+var ErrParentNotDirectory = errors.New("file: parent path exists but is not a directory")
@@ -0,0 +1,113 @@
+package httpsrc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/protoc/httpsrc"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+// Source represents a read-only source of the files at HTTP(S) URLs (e.g. public or
+// pre-signed URLs), the file paths are the URLs.
+type Source struct {
+	logger logr.Logger
+}
+
+func NewSource(logger logr.Logger) (s *Source) {
+	s = &Source{
+		logger: logger.WithName("httpsrc.source"),
+	}
+	return
+}
+
+func (s *Source) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (info xferfile.Info, err error) {
+	var conn httpsrc.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	var fileURL *url.URL
+	if fileURL, err = url.Parse(filePath); err != nil {
+		return
+	}
+	var fileInfo httpsrc.FileInfo
+	if fileInfo, err = conn.Stat(ctx, filePath); err != nil {
+		return
+	}
+	// the name is the last segment of the URL path, without the query of a pre-signed URL
+	fileName, fileExt := fileutils.SplitFileName(fileURL.Path)
+	info = xferfile.Info{
+		Path:      filePath,
+		Name:      fileName,
+		Extension: fileExt,
+		Size:      fileInfo.Size,
+		ModTime:   fileInfo.ModTime,
+	}
+	if fileInfo.Size < 0 {
+		info.Size = xferfile.SizeUnknown
+	}
+	return
+}
+
+// GetFileFromOffset returns the content of the file from the offset, storage.ErrRangeUnsupported
+// is returned if the server cannot serve it from the offset, so that the transfer restarts from
+// the beginning of the file.
+func (s *Source) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	var conn httpsrc.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	if reader, err = conn.Get(ctx, filePath, offset); errors.Is(err, httpsrc.ErrRangeIgnored) {
+		err = fmt.Errorf("%w: %w", storage.ErrRangeUnsupported, err)
+	}
+	return
+}
+
+// ListFiles is not supported, a URL represents a single file.
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	err = errors.ErrUnsupported
+	return
+}
+
+// Glob is not supported, a URL represents a single file.
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	err = errors.ErrUnsupported
+	return
+}
+
+func (s *Source) Close() {
+	s.logger.Info("closed httpsrc source")
+}
+
+// checkClient returns the HTTP client of the protocol client.
+func checkClient(cli protoc.Client) (conn httpsrc.Client, err error) {
+	var ok bool
+	if conn, ok = cli.GetCredential().(httpsrc.Client); !ok {
+		err = storage.ErrHTTPProtocolClientInvalid
+	}
+	return
+}
@@ -0,0 +1,253 @@
+package httpsrc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc/httpsrc"
+	protoc_local "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	const (
+		content = "0123456789"
+		token   = "secret"
+	)
+	modTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	var (
+		srcClient *httpsrc.Client
+		source    *Source
+	)
+
+	// newServer serves the content at /files/report.csv to the requests with the bearer token,
+	// the Range header is honored unless ignoreRange is set.
+	newServer := func(ignoreRange bool) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path != "/files/report.csv" {
+				http.NotFound(w, r)
+				return
+			}
+			if !ignoreRange {
+				http.ServeContent(w, r, "report.csv", modTime, bytes.NewReader([]byte(content)))
+				return
+			}
+			w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			if r.Method != http.MethodHead {
+				_, _ = io.WriteString(w, content)
+			}
+		}))
+		DeferCleanup(server.Close)
+		return server
+	}
+
+	BeforeEach(func() {
+		srcClient = httpsrc.NewClient(httpsrc.WithBearerToken(token))
+		source = NewSource(GinkgoLogr)
+		DeferCleanup(source.Close)
+	})
+
+	Describe("GetFileInfo", func() {
+		It("should return the size and the modification time of the file", func(ctx context.Context) {
+			fileURL := newServer(false).URL + "/files/report.csv?X-Amz-Signature=abc"
+			info, err := source.GetFileInfo(ctx, fileURL, srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Path).To(Equal(fileURL))
+			Expect(info.Name).To(Equal("report"))
+			Expect(info.Extension).To(Equal("csv"))
+			Expect(info.Size).To(Equal(int64(len(content))))
+			Expect(info.ModTime).To(BeTemporally("==", modTime))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return the info from the first byte when HEAD is refused", func(ctx context.Context) {
+			server := newServer(false)
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				http.ServeContent(w, r, "report.csv", modTime, bytes.NewReader([]byte(content)))
+			})
+			info, err := source.GetFileInfo(ctx, server.URL+"/files/report.csv", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(int64(len(content))))
+			Expect(info.ModTime).To(BeTemporally("==", modTime))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the file does not exist", func(ctx context.Context) {
+			_, err := source.GetFileInfo(ctx, newServer(false).URL+"/files/missing.csv", srcClient)
+			Expect(err).To(MatchError(httpsrc.ErrNotFound))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return the status of an unauthorized request", func(ctx context.Context) {
+			_, err := source.GetFileInfo(ctx, newServer(false).URL+"/files/report.csv", httpsrc.NewClient())
+			var statusErr *httpsrc.StatusError
+			Expect(errors.As(err, &statusErr)).To(BeTrue())
+			Expect(statusErr.HTTPStatusCode()).To(Equal(http.StatusUnauthorized))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the client is not an HTTP client", func(ctx context.Context) {
+			_, err := source.GetFileInfo(ctx, "https://example.com/report.csv", protoc_local.NewIO())
+			Expect(err).To(MatchError(storage.ErrHTTPProtocolClientInvalid))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("GetFileFromOffset", func() {
+		Context("with a server supporting the ranges", func() {
+			var fileURL string
+
+			BeforeEach(func() {
+				fileURL = newServer(false).URL + "/files/report.csv"
+			})
+
+			It("should return the content of the file from the offset", func(ctx context.Context) {
+				reader, err := source.GetFileFromOffset(ctx, fileURL, 4, srcClient)
+				Expect(err).ToNot(HaveOccurred())
+				defer reader.Close()
+				Expect(io.ReadAll(reader)).To(BeEquivalentTo("456789"))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return an empty content from the end of the file", func(ctx context.Context) {
+				reader, err := source.GetFileFromOffset(ctx, fileURL, int64(len(content)), srcClient)
+				Expect(err).ToNot(HaveOccurred())
+				defer reader.Close()
+				Expect(io.ReadAll(reader)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+		})
+
+		Context("with a server ignoring the ranges", func() {
+			var fileURL string
+
+			BeforeEach(func() {
+				fileURL = newServer(true).URL + "/files/report.csv"
+			})
+
+			It("should return the whole content from the beginning", func(ctx context.Context) {
+				reader, err := source.GetFileFromOffset(ctx, fileURL, 0, srcClient)
+				Expect(err).ToNot(HaveOccurred())
+				defer reader.Close()
+				Expect(io.ReadAll(reader)).To(BeEquivalentTo(content))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return error so that the transfer restarts from the beginning", func(ctx context.Context) {
+				_, err := source.GetFileFromOffset(ctx, fileURL, 4, srcClient)
+				Expect(err).To(MatchError(storage.ErrRangeUnsupported))
+				Expect(err).To(MatchError(httpsrc.ErrRangeIgnored))
+			}, NodeTimeout(10*time.Second))
+		})
+	})
+
+	Describe("redirects", func() {
+		var (
+			fileServer *httptest.Server
+			redirected []string
+		)
+
+		// newRedirectServer redirects /hops/N to /hops/N-1, then /hops/0 to the URL of the file
+		// on the file server.
+		newRedirectServer := func() *httptest.Server {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				redirected = append(redirected, r.URL.Path)
+				hops, err := strconv.Atoi(path.Base(r.URL.Path))
+				Expect(err).ToNot(HaveOccurred())
+				target := fileServer.URL + "/files/report.csv"
+				if hops > 0 {
+					target = "/hops/" + strconv.Itoa(hops-1)
+				}
+				http.Redirect(w, r, target, http.StatusFound)
+			}))
+			DeferCleanup(server.Close)
+			return server
+		}
+
+		BeforeEach(func() {
+			fileServer = newServer(false)
+			redirected = nil
+		})
+
+		It("should follow the redirects by default with the range of the request", func(ctx context.Context) {
+			reader, err := source.GetFileFromOffset(ctx, newRedirectServer().URL+"/hops/2", 4, srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEquivalentTo("456789"))
+			Expect(redirected).To(Equal([]string{"/hops/2", "/hops/1", "/hops/0"}))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the redirect target ignores the range", func(ctx context.Context) {
+			fileServer = newServer(true)
+			_, err := source.GetFileFromOffset(ctx, newRedirectServer().URL+"/hops/0", 4, srcClient)
+			Expect(err).To(MatchError(storage.ErrRangeUnsupported))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the redirect target serves another range", func(ctx context.Context) {
+			fileServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", "bytes 0-9/10")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = io.WriteString(w, content)
+			})
+			_, err := source.GetFileFromOffset(ctx, newRedirectServer().URL+"/hops/0", 4, srcClient)
+			Expect(err).To(MatchError(storage.ErrRangeUnsupported))
+		}, NodeTimeout(10*time.Second))
+
+		It("should refuse the redirects when they are not followed", func(ctx context.Context) {
+			srcClient = httpsrc.NewClient(httpsrc.WithBearerToken(token), httpsrc.WithFollowRedirects(false))
+			_, err := source.GetFileInfo(ctx, newRedirectServer().URL+"/hops/0", srcClient)
+			Expect(err).To(MatchError(httpsrc.ErrRedirectRefused))
+			Expect(redirected).To(Equal([]string{"/hops/0"}))
+		}, NodeTimeout(10*time.Second))
+
+		It("should refuse the redirects beyond the maximum", func(ctx context.Context) {
+			srcClient = httpsrc.NewClient(httpsrc.WithBearerToken(token), httpsrc.WithMaxRedirects(2))
+			redirectServer := newRedirectServer()
+			_, err := source.GetFileInfo(ctx, redirectServer.URL+"/hops/1", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = source.GetFileInfo(ctx, redirectServer.URL+"/hops/2", srcClient)
+			Expect(err).To(MatchError(httpsrc.ErrRedirectRefused))
+		}, NodeTimeout(10*time.Second))
+
+		It("should refuse the redirects to another origin when only the same origin is allowed", func(ctx context.Context) {
+			srcClient = httpsrc.NewClient(httpsrc.WithBearerToken(token), httpsrc.WithSameOriginRedirects())
+			redirectServer := newRedirectServer()
+			_, err := source.GetFileInfo(ctx, redirectServer.URL+"/hops/0", srcClient)
+			Expect(err).To(MatchError(httpsrc.ErrRedirectRefused))
+
+			By("follow the redirects within the same origin")
+			fileServer = redirectServer
+			redirectServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/hops/0" {
+					http.Redirect(w, r, "/files/report.csv", http.StatusFound)
+					return
+				}
+				http.ServeContent(w, r, "report.csv", modTime, bytes.NewReader([]byte(content)))
+			})
+			info, err := source.GetFileInfo(ctx, redirectServer.URL+"/hops/0", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(int64(len(content))))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ListFiles and Glob", func() {
+		It("should not be supported", func(ctx context.Context) {
+			_, err := source.ListFiles(ctx, "https://example.com/files", srcClient)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+			_, err = source.Glob(ctx, "https://example.com/files/*.csv", srcClient)
+			Expect(err).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+})
@@ -3,8 +3,12 @@ package local
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/derektruong/fxfer/internal/fileutils"
@@ -17,13 +21,36 @@ import (
 
 var defaultFilePerm = os.FileMode(0664)
 
+// partSuffix is appended to the path of a file to form the path of its staging file (.part),
+// the content is written to the staging file and only renamed to the path once finalized.
+const partSuffix = ".part"
+
+// defaultInfoSyncSize is the default number of bytes written between two updates of the
+// info file offset while transferring a chunk.
+const defaultInfoSyncSize = 4 << 20 // 4 MiB
+
 type Destination struct {
 	logger logr.Logger
+
+	// InfoSyncSize is the number of bytes written between two updates of the info file
+	// offset while transferring a chunk, the info file then reflects the bytes on disk
+	// if the process crashes mid-chunk. Default is 4 MiB.
+	InfoSyncSize int64
+
+	// SyncPolicy is the policy of flushing the written bytes to disk (fsync), at the cost of a
+	// slower transfer the more often they are flushed. Default is SyncPolicyOnFinalize.
+	SyncPolicy SyncPolicy
+
+	// PreserveModTime instructs the Destination to set the modification time of the finalized
+	// file to the one of its source (os.Chtimes), rather than the time it was last written.
+	PreserveModTime bool
 }
 
 func NewDestination(logger logr.Logger) (s *Destination, err error) {
 	s = &Destination{
-		logger: logger.WithName("local.destination"),
+		logger:       logger.WithName("local.destination"),
+		InfoSyncSize: defaultInfoSyncSize,
+		SyncPolicy:   SyncPolicyOnFinalize,
 	}
 	return
 }
@@ -42,27 +69,13 @@ func (d *Destination) GetFileInfo(
 		return
 	}
 
-	// extract file info
-	var infoPath string
-	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+	if info, err = d.readInfo(filePath); err != nil {
 		return
 	}
 
-	// read file info
-	var infoData []byte
-	if infoData, err = os.ReadFile(infoPath); err != nil {
-		if os.IsNotExist(err) {
-			err = xferfile.ErrFileNotExists
-		}
-		return
-	}
-	if err = json.Unmarshal(infoData, &info); err != nil {
-		return
-	}
-
-	// open file for reading
+	// the offset is the size of the staging file until the file is finalized
 	var fileStat os.FileInfo
-	if fileStat, err = os.Stat(filePath); err != nil {
+	if fileStat, err = os.Lstat(contentPath(filePath, info)); err != nil {
 		if os.IsNotExist(err) {
 			err = xferfile.ErrFileNotExists
 			return
@@ -70,8 +83,11 @@ func (d *Destination) GetFileInfo(
 		return
 	}
 
-	// set the file info offset
+	// set the file info offset, a recreated link has no content of its own
 	info.Offset = fileStat.Size()
+	if fileStat.Mode()&os.ModeSymlink != 0 {
+		info.Offset = 0
+	}
 	return
 }
 
@@ -79,6 +95,14 @@ func (d *Destination) CreateFile(
 	ctx context.Context,
 	path string, size int64, modTime time.Time,
 	cli protoc.Client,
+) (err error) {
+	return d.CreateFileWithMetadata(ctx, path, size, modTime, nil, cli)
+}
+
+func (d *Destination) CreateFileWithMetadata(
+	ctx context.Context,
+	path string, size int64, modTime time.Time, metadata map[string]string,
+	cli protoc.Client,
 ) (err error) {
 	if _, ok := cli.GetCredential().(local.IO); !ok {
 		err = storage.ErrLocalProtocolIOInvalid
@@ -90,16 +114,25 @@ func (d *Destination) CreateFile(
 		return
 	}
 
-	// create directory if not exists
-	if _, err = os.Stat(dirPath); os.IsNotExist(err) {
+	// create directory if not exists, a file at its path (or at the path of one of its parents)
+	// fails the creation rather than the opening of the staging file
+	var dirInfo os.FileInfo
+	switch dirInfo, err = os.Stat(dirPath); {
+	case os.IsNotExist(err):
 		if err = os.MkdirAll(dirPath, 0755); err != nil {
+			if errors.Is(err, syscall.ENOTDIR) {
+				err = fmt.Errorf("%w: %s", storage.ErrParentNotDirectory, dirPath)
+			}
 			return
 		}
+	case errors.Is(err, syscall.ENOTDIR), err == nil && !dirInfo.IsDir():
+		err = fmt.Errorf("%w: %s", storage.ErrParentNotDirectory, dirPath)
+		return
 	}
 
-	// open file with create flag
+	// the content is written to the staging file, the file is only created at the path once finalized
 	var file *os.File
-	if file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY, defaultFilePerm); err != nil {
+	if file, err = os.OpenFile(stagingPath(path), os.O_CREATE|os.O_WRONLY, defaultFilePerm); err != nil {
 		if os.IsNotExist(err) {
 			err = xferfile.ErrFileNotExists
 		}
@@ -107,6 +140,9 @@ func (d *Destination) CreateFile(
 	}
 	defer file.Close()
 
+	if srcExt, ok := metadata[storage.SourceExtensionMeta]; ok {
+		fileExt = srcExt
+	}
 	return d.writeInfo(path, xferfile.Info{
 		Path:      path,
 		Size:      size,
@@ -114,6 +150,7 @@ func (d *Destination) CreateFile(
 		StartTime: time.Now(),
 		Name:      fileName,
 		Extension: fileExt,
+		Metadata:  metadata,
 	})
 }
 
@@ -129,18 +166,164 @@ func (d *Destination) TransferFileChunk(
 		return
 	}
 
-	// open file with append flag
+	// the chunk is written to the staging file at the offset, any byte beyond it (e.g. of a
+	// retried chunk) is discarded
 	var file *os.File
-	if file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, defaultFilePerm); err != nil {
+	if file, err = os.OpenFile(stagingPath(filePath), os.O_WRONLY, defaultFilePerm); err != nil {
+		if os.IsNotExist(err) {
+			err = xferfile.ErrFileNotExists
+		}
 		return
 	}
 	defer file.Close()
-	if n, err = io.Copy(file, reader); err != nil {
+	var fileStat os.FileInfo
+	if fileStat, err = file.Stat(); err != nil {
+		return
+	}
+	if offset < 0 || offset > fileStat.Size() {
+		err = storage.ErrChunkOffsetOutOfRange
+		return
+	}
+	if err = file.Truncate(offset); err != nil {
+		return
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+
+	// the info file offset is updated while writing, so that it reflects the bytes on disk
+	writer := &infoSyncWriter{dest: d, file: file, filePath: filePath}
+	if writer.info, err = d.readInfo(filePath); err != nil {
+		return
+	}
+	writer.syncedOffset = writer.info.Offset
+	writer.info.Offset, writer.flushedOffset = offset, offset
+	if writer.syncedOffset > offset {
+		// the info file records bytes which have just been truncated
+		if err = writer.sync(false); err != nil {
+			return
+		}
+	}
+
+	n, err = io.Copy(writer, reader)
+	if syncErr := writer.sync(d.SyncPolicy.flushesChunk()); err == nil {
+		err = syncErr
+	}
+	if tracker, ok := reader.(storage.ConfirmedSizeTracker); ok {
+		tracker.AddConfirmedSize(n)
+	}
+	return
+}
+
+// ResumeState returns the resumption state of the file, the offset is the size of the
+// file on disk (see storage.ResumeStateReporter).
+func (d *Destination) ResumeState(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (state storage.ResumeState, err error) {
+	var info xferfile.Info
+	if info, err = d.GetFileInfo(ctx, filePath, cli); err != nil {
 		return
 	}
+	state = storage.ResumeState{
+		Path:      info.Path,
+		Size:      info.Size,
+		Offset:    info.Offset,
+		StartTime: info.StartTime,
+		Finished:  !info.FinishTime.IsZero(),
+	}
 	return
 }
 
+// ReadRange returns a reader of the length bytes of the file on disk from the offset, of its
+// staging file until it is finalized (see storage.RangeReader).
+func (d *Destination) ReadRange(
+	ctx context.Context,
+	filePath string,
+	offset, length int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+	// a file which has not been transferred by the destination is read as is
+	readPath := filePath
+	if info, infoErr := d.readInfo(filePath); infoErr == nil {
+		readPath = contentPath(filePath, info)
+	}
+	var file *os.File
+	if file, err = os.Open(readPath); err != nil {
+		if os.IsNotExist(err) {
+			err = xferfile.ErrFileNotExists
+		}
+		return
+	}
+	reader = struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(file, offset, length), file}
+	return
+}
+
+// FileExists reports whether a file exists on disk at the path (see storage.ExistenceChecker).
+func (d *Destination) FileExists(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (exists bool, err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+	if _, err = os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	return true, nil
+}
+
+// Preflight creates and removes a throwaway file in the directory of the file, or in its
+// closest existing parent since the directory is created with the file (see storage.Preflighter).
+func (d *Destination) Preflight(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+
+	dirPath := filepath.Dir(filePath)
+	for {
+		if _, err = os.Stat(dirPath); !os.IsNotExist(err) {
+			break
+		}
+		parentPath := filepath.Dir(dirPath)
+		if parentPath == dirPath {
+			break
+		}
+		dirPath = parentPath
+	}
+	if err != nil {
+		return
+	}
+
+	var file *os.File
+	if file, err = os.CreateTemp(dirPath, ".fxfer-preflight-*"); err != nil {
+		if os.IsPermission(err) {
+			err = fmt.Errorf("%w: write on %s: %w", storage.ErrPermissionMissing, dirPath, err)
+		}
+		return
+	}
+	_ = file.Close()
+	return os.Remove(file.Name())
+}
+
 func (d *Destination) FinalizeTransfer(
 	ctx context.Context,
 	filePath string,
@@ -155,10 +338,40 @@ func (d *Destination) FinalizeTransfer(
 	if info, err = d.GetFileInfo(ctx, filePath, cli); err != nil {
 		return
 	}
+	// the size of a stream is only known once it has been fully written
+	if info.Size == xferfile.SizeUnknown {
+		info.Size = info.Offset
+	}
 	if info.Offset != info.Size {
 		err = storage.ErrFileOrObjectCannotFinalize
 		return
 	}
+	// the file is already finalized, e.g. the finalization is retried
+	if !info.FinishTime.IsZero() {
+		return
+	}
+	if target, ok := info.Metadata[storage.SymlinkTargetMeta]; ok {
+		// the link replaces the (empty) staging file, it is renamed to the path as the file would be
+		if err = os.Remove(stagingPath(filePath)); err != nil {
+			return
+		}
+		if err = os.Symlink(target, stagingPath(filePath)); err != nil {
+			return
+		}
+	} else {
+		if err = d.flushStagingFile(filePath); err != nil {
+			return
+		}
+		if d.PreserveModTime && !info.ModTime.IsZero() {
+			if err = os.Chtimes(stagingPath(filePath), time.Time{}, info.ModTime); err != nil {
+				return
+			}
+		}
+	}
+	// the complete file appears at the path at once
+	if err = os.Rename(stagingPath(filePath), filePath); err != nil {
+		return
+	}
 	info.Offset = info.Size
 	info.FinishTime = time.Now()
 	return d.writeInfo(filePath, info)
@@ -170,22 +383,98 @@ func (d *Destination) DeleteFile(ctx context.Context, filePath string, protocol
 		return
 	}
 
-	if err = os.Remove(filePath); err != nil {
+	// the file is either staged or finalized, or both if it is transferred again
+	stagingErr, fileErr := os.Remove(stagingPath(filePath)), os.Remove(filePath)
+	if os.IsNotExist(stagingErr) && os.IsNotExist(fileErr) {
+		return xferfile.ErrFileNotExists
+	}
+	for _, removeErr := range []error{stagingErr, fileErr} {
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			return removeErr
+		}
+	}
+	var infoPath string
+	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+		return
+	}
+	if err = os.Remove(infoPath); err != nil {
 		if os.IsNotExist(err) {
 			err = xferfile.ErrFileNotExists
 		}
 		return
 	}
+	return
+}
+
+// stagingPath returns the path of the staging file of the file (see partSuffix).
+func stagingPath(filePath string) string {
+	return filePath + partSuffix
+}
+
+// contentPath returns the path of the content of the file on disk, its staging file until it
+// is finalized.
+func contentPath(filePath string, info xferfile.Info) string {
+	if info.FinishTime.IsZero() {
+		return stagingPath(filePath)
+	}
+	return filePath
+}
+
+func (d *Destination) readInfo(filePath string) (info xferfile.Info, err error) {
 	var infoPath string
 	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
 		return
 	}
-	if err = os.Remove(infoPath); err != nil {
+	var infoData []byte
+	if infoData, err = os.ReadFile(infoPath); err != nil {
+		if os.IsNotExist(err) {
+			return d.migrateLegacyInfo(filePath, infoPath)
+		}
+		return
+	}
+	if err = json.Unmarshal(infoData, &info); err != nil {
+		return
+	}
+	// the info file must belong to this file, not to a file sharing its name
+	if info.Path != filePath {
+		err = storage.ErrFileInfoMismatch
+	}
+	return
+}
+
+// migrateLegacyInfo moves the info file of the file from its legacy path (see
+// xferfile.GenerateLegacyInfoPath) to the info path, along with the content of the unfinished
+// file to its staging file, so that the transfers started before are resumed.
+// xferfile.ErrFileNotExists is returned if the file has no legacy info file.
+func (d *Destination) migrateLegacyInfo(filePath, infoPath string) (info xferfile.Info, err error) {
+	var legacyInfoPath string
+	if legacyInfoPath, err = xferfile.GenerateLegacyInfoPath(filePath); err != nil {
+		return
+	}
+	var infoData []byte
+	if infoData, err = os.ReadFile(legacyInfoPath); err != nil {
 		if os.IsNotExist(err) {
 			err = xferfile.ErrFileNotExists
 		}
 		return
 	}
+	if err = json.Unmarshal(infoData, &info); err != nil {
+		return
+	}
+	// the legacy info file is shared by the files differing only by extension
+	if info.Path != filePath {
+		err = xferfile.ErrFileNotExists
+		return
+	}
+	if info.FinishTime.IsZero() {
+		if err = os.Rename(filePath, stagingPath(filePath)); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+	if err = os.Rename(legacyInfoPath, infoPath); err != nil {
+		return
+	}
+	d.logger.Info("migrated legacy info file", "filePath", filePath, "legacyInfoPath", legacyInfoPath)
 	return
 }
 
@@ -200,3 +489,43 @@ func (d *Destination) writeInfo(filePath string, info xferfile.Info) (err error)
 	}
 	return os.WriteFile(infoPath, infoData, defaultFilePerm)
 }
+
+// infoSyncWriter writes to the file and updates the info file offset every
+// Destination.InfoSyncSize bytes.
+type infoSyncWriter struct {
+	dest          *Destination
+	file          *os.File
+	filePath      string
+	info          xferfile.Info
+	syncedOffset  int64
+	flushedOffset int64
+}
+
+func (w *infoSyncWriter) Write(p []byte) (n int, err error) {
+	n, err = w.file.Write(p)
+	w.info.Offset += int64(n)
+	if err == nil && w.info.Offset-w.syncedOffset >= w.dest.InfoSyncSize {
+		err = w.sync(w.dest.SyncPolicy == SyncPolicyEveryNBytes)
+	}
+	return
+}
+
+// sync records the offset of the file in the info file, after flushing the file to disk if
+// flush is set, so that the info file never claims bytes which are not persisted under
+// SyncPolicyEveryNBytes.
+func (w *infoSyncWriter) sync(flush bool) (err error) {
+	if flush && w.info.Offset != w.flushedOffset {
+		if err = syncFile(w.file); err != nil {
+			return
+		}
+		w.flushedOffset = w.info.Offset
+	}
+	if w.info.Offset == w.syncedOffset {
+		return
+	}
+	if err = w.dest.writeInfo(w.filePath, w.info); err != nil {
+		return
+	}
+	w.syncedOffset = w.info.Offset
+	return
+}
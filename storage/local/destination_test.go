@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"testing/iotest"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
@@ -68,6 +72,55 @@ var _ = Describe("Destination", func() {
 				HaveField("ModTime", Not(BeZero())),
 			))
 		}, NodeTimeout(10*time.Second))
+
+		It("should return error if the file info belongs to another file", func(ctx context.Context) {
+			filePath := tempDir + "/test-abc-mismatch.txt"
+			writeDestFileContent(filePath, xferfile.Info{
+				Path:      tempDir + "/test-abc-mismatch.md",
+				Name:      "test-abc-mismatch",
+				Extension: "md",
+			}, testContent)
+			_, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).To(MatchError(storage.ErrFileInfoMismatch))
+		}, NodeTimeout(10*time.Second))
+
+		It("should resume a file recorded in a legacy info file", func(ctx context.Context) {
+			filePath := tempDir + "/test-abc-legacy.txt"
+			writeLegacyDestFileContent(filePath, xferfile.Info{
+				Path:      filePath,
+				Size:      int64(len(testContent)),
+				Name:      "test-abc-legacy",
+				Extension: "txt",
+				ModTime:   gofakeit.PastDate(),
+			}, testContent[:7])
+
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(7)))
+
+			By("assert the info file is migrated from its legacy path")
+			Expect(tempDir + "/test-abc-legacy.info").ToNot(BeAnExistingFile())
+			Expect(tempDir + "/test-abc-legacy.txt.info").To(BeAnExistingFile())
+
+			By("resume the transfer from the offset")
+			_, err = destStorage.TransferFileChunk(
+				ctx, filePath, strings.NewReader(testContent[info.Offset:]), info.Offset, localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+			Expect(os.ReadFile(filePath)).To(BeEquivalentTo(testContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not take the legacy info file of a file differing by extension", func(ctx context.Context) {
+			writeLegacyDestFileContent(tempDir+"/test-abc-legacy-ext.md", xferfile.Info{
+				Path:      tempDir + "/test-abc-legacy-ext.md",
+				Name:      "test-abc-legacy-ext",
+				Extension: "md",
+			}, testContent)
+			_, err := destStorage.GetFileInfo(ctx, tempDir+"/test-abc-legacy-ext.txt", localProtoc)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+			Expect(tempDir + "/test-abc-legacy-ext.info").To(BeAnExistingFile())
+		}, NodeTimeout(10*time.Second))
 	})
 
 	Describe("CreateFile", func() {
@@ -97,6 +150,20 @@ var _ = Describe("Destination", func() {
 			Expect(infoFS.IsDir()).To(BeTrue())
 		}, NodeTimeout(10*time.Second))
 
+		DescribeTable("should return error if a file occupies the path of the parent directory",
+			func(ctx context.Context, filePath string) {
+				Expect(os.WriteFile(tempDir+"/test-abc-occupied", []byte("content"), 0644)).To(Succeed())
+
+				err := destStorage.CreateFile(ctx, tempDir+filePath, 10000, gofakeit.PastDate(), localProtoc)
+				Expect(err).To(MatchError(storage.ErrParentNotDirectory))
+
+				By("assert the file occupying the path is kept as is")
+				Expect(os.ReadFile(tempDir + "/test-abc-occupied")).To(BeEquivalentTo("content"))
+			},
+			Entry("the parent directory", NodeTimeout(10*time.Second), "/test-abc-occupied/test-abc.txt"),
+			Entry("an ancestor directory", NodeTimeout(10*time.Second), "/test-abc-occupied/nested/test-abc.txt"),
+		)
+
 		It("should create the file successfully", func(ctx context.Context) {
 			filePath := tempDir + "/test-abc-2.txt"
 			modTime := gofakeit.PastDate()
@@ -118,10 +185,11 @@ var _ = Describe("Destination", func() {
 				HaveField("ModTime", BeTemporally("~", modTime, time.Second)),
 			))
 
-			By("assert the file exists")
-			infoFS, err := os.Stat(filePath)
+			By("assert the file is staged")
+			infoFS, err := os.Stat(filePath + ".part")
 			Expect(err).ToNot(HaveOccurred())
-			Expect(infoFS.Name()).To(Equal(info.Name + "." + info.Extension))
+			Expect(infoFS.Name()).To(Equal(info.Name + "." + info.Extension + ".part"))
+			Expect(filePath).ToNot(BeAnExistingFile())
 		}, NodeTimeout(10*time.Second))
 	})
 
@@ -208,7 +276,7 @@ var _ = Describe("Destination", func() {
 
 			By("assert the file content")
 			buf := new(bytes.Buffer)
-			f, err := os.Open(filePath)
+			f, err := os.Open(filePath + ".part")
 			Expect(err).ToNot(HaveOccurred())
 			defer f.Close()
 			_, err = io.Copy(buf, f)
@@ -217,6 +285,270 @@ var _ = Describe("Destination", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 
+	Describe("TransferFileChunk at an offset", func() {
+		var filePath string
+
+		BeforeEach(func() {
+			// the temporary directory is shared by the suite, each spec writes its own file
+			filePath = tempDir + "/test-abc-offset-" + gofakeit.UUID() + ".txt"
+			testContent = "aabbbcccc"
+			Expect(destStorage.CreateFile(
+				context.Background(),
+				filePath, int64(len(testContent)), gofakeit.PastDate(),
+				localProtoc,
+			)).To(Succeed())
+			_, err = destStorage.TransferFileChunk(
+				context.Background(),
+				filePath,
+				strings.NewReader(testContent[:5]),
+				0,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should overwrite a retried chunk at the same offset", func(ctx context.Context) {
+			for range 2 {
+				n, err := destStorage.TransferFileChunk(
+					ctx,
+					filePath,
+					strings.NewReader(testContent[5:]),
+					5,
+					localProtoc,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(len(testContent) - 5)))
+			}
+
+			Expect(os.ReadFile(filePath + ".part")).To(BeEquivalentTo(testContent))
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(len(testContent))))
+		}, NodeTimeout(10*time.Second))
+
+		It("should discard the bytes beyond an offset less than the file size", func(ctx context.Context) {
+			n, err := destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				strings.NewReader(testContent[2:]),
+				2,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(len(testContent) - 2)))
+
+			Expect(os.ReadFile(filePath + ".part")).To(BeEquivalentTo(testContent))
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(len(testContent))))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if the offset is beyond the file size", func(ctx context.Context) {
+			_, err := destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				strings.NewReader(testContent[7:]),
+				7,
+				localProtoc,
+			)
+			Expect(err).To(MatchError(storage.ErrChunkOffsetOutOfRange))
+			Expect(os.ReadFile(filePath + ".part")).To(BeEquivalentTo(testContent[:5]))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ResumeState", func() {
+		var filePath string
+		var errCrash = errors.New("crash")
+
+		BeforeEach(func() {
+			// the temporary directory is shared by the suite, each spec writes its own file
+			filePath = tempDir + "/test-abc-resume-" + gofakeit.UUID() + ".txt"
+			destStorage.InfoSyncSize = 4
+			testContent = strings.Repeat("a", 20)
+		})
+
+		readRecordedOffset := func() int64 {
+			GinkgoHelper()
+			infoPath, err := xferfile.GenerateInfoPath(filePath)
+			Expect(err).ToNot(HaveOccurred())
+			infoData, err := os.ReadFile(infoPath)
+			Expect(err).ToNot(HaveOccurred())
+			var info xferfile.Info
+			Expect(json.Unmarshal(infoData, &info)).To(Succeed())
+			return info.Offset
+		}
+
+		It("should record the offset in the info file while writing", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, int64(len(testContent)), gofakeit.PastDate(),
+				localProtoc,
+			)).To(Succeed())
+
+			By("transfer a chunk interrupted after 10 bytes")
+			var recordedOffsets []int64
+			reader := io.MultiReader(
+				iotest.OneByteReader(strings.NewReader(testContent[:10])),
+				iotest.ErrReader(errCrash),
+			)
+			n, err := destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				readerFunc(func(p []byte) (int, error) {
+					recordedOffsets = append(recordedOffsets, readRecordedOffset())
+					return reader.Read(p)
+				}),
+				0,
+				localProtoc,
+			)
+			Expect(err).To(MatchError(errCrash))
+			Expect(n).To(Equal(int64(10)))
+
+			By("assert the offset is recorded every InfoSyncSize bytes, then once interrupted")
+			Expect(recordedOffsets).To(ContainElements(int64(4), int64(8)))
+			Expect(recordedOffsets).ToNot(ContainElement(int64(9)))
+			Expect(readRecordedOffset()).To(Equal(int64(10)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return the resumption state of an unfinished file", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, int64(len(testContent)), gofakeit.PastDate(),
+				localProtoc,
+			)).To(Succeed())
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				io.MultiReader(strings.NewReader(testContent[:7]), iotest.ErrReader(errCrash)),
+				0,
+				localProtoc,
+			)
+			Expect(err).To(MatchError(errCrash))
+
+			state, err := destStorage.ResumeState(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(And(
+				HaveField("Path", filePath),
+				HaveField("Size", int64(len(testContent))),
+				HaveField("Offset", int64(7)),
+				HaveField("UploadID", BeEmpty()),
+				HaveField("StartTime", Not(BeZero())),
+				HaveField("Finished", BeFalse()),
+			))
+
+			By("assert the state is serializable")
+			stateData, err := json.Marshal(state)
+			Expect(err).ToNot(HaveOccurred())
+			var gotState storage.ResumeState
+			Expect(json.Unmarshal(stateData, &gotState)).To(Succeed())
+			Expect(gotState.Offset).To(Equal(state.Offset))
+		}, NodeTimeout(10*time.Second))
+
+		It("should resume an interrupted transfer from the staging file", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, int64(len(testContent)), gofakeit.PastDate(),
+				localProtoc,
+			)).To(Succeed())
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				io.MultiReader(strings.NewReader(testContent[:7]), iotest.ErrReader(errCrash)),
+				0,
+				localProtoc,
+			)
+			Expect(err).To(MatchError(errCrash))
+
+			By("assert the offset is the size of the staging file")
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(7)))
+			Expect(filePath).ToNot(BeAnExistingFile())
+
+			By("resume the transfer from the offset")
+			n, err := destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				strings.NewReader(testContent[info.Offset:]),
+				info.Offset,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(len(testContent) - 7)))
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+			Expect(os.ReadFile(filePath)).To(BeEquivalentTo(testContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if file does not exist", func(ctx context.Context) {
+			_, err := destStorage.ResumeState(ctx, tempDir+"/test-abc-missing.txt", localProtoc)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ReadRange", func() {
+		It("should read back the range of the written content", func(ctx context.Context) {
+			filePath := tempDir + "/test-abc-range-" + gofakeit.UUID() + ".txt"
+			testContent = "0123456789"
+			Expect(destStorage.CreateFile(ctx, filePath, int64(len(testContent)), gofakeit.PastDate(), localProtoc)).
+				To(Succeed())
+			_, err = destStorage.TransferFileChunk(ctx, filePath, strings.NewReader(testContent), 0, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+
+			reader, err := destStorage.ReadRange(ctx, filePath, 3, 4, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("3456"))
+		})
+
+		It("should return error if file does not exist", func(ctx context.Context) {
+			_, err = destStorage.ReadRange(ctx, tempDir+"/test-abc-range-missing.txt", 0, 4, localProtoc)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		})
+	})
+
+	Describe("FileExists", func() {
+		It("should report whether a file exists, even if it was not transferred", func(ctx context.Context) {
+			filePath := tempDir + "/test-abc-exists-" + gofakeit.UUID() + ".txt"
+			Expect(destStorage.FileExists(ctx, filePath, localProtoc)).To(BeFalse())
+
+			Expect(os.WriteFile(filePath, []byte("content"), 0644)).To(Succeed())
+			Expect(destStorage.FileExists(ctx, filePath, localProtoc)).To(BeTrue())
+		})
+	})
+
+	Describe("Preflight", func() {
+		It("should check a directory which is yet to be created", func(ctx context.Context) {
+			dirPath := tempDir + "/test-abc-preflight-" + gofakeit.UUID()
+			Expect(destStorage.Preflight(ctx, dirPath+"/nested/test-abc.txt", localProtoc)).To(Succeed())
+
+			_, err = os.Stat(dirPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			entries, err := filepath.Glob(tempDir + "/.fxfer-preflight-*")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+
+		It("should return error if the directory is not writable", func(ctx context.Context) {
+			if os.Geteuid() == 0 {
+				Skip("the permission of the directory is ignored for root")
+			}
+			dirPath := tempDir + "/test-abc-preflight-" + gofakeit.UUID()
+			Expect(os.Mkdir(dirPath, 0555)).To(Succeed())
+
+			err = destStorage.Preflight(ctx, dirPath+"/test-abc.txt", localProtoc)
+			Expect(err).To(MatchError(storage.ErrPermissionMissing))
+		})
+
+		It("should return error if the parent is a file", func(ctx context.Context) {
+			parentPath := tempDir + "/test-abc-preflight-" + gofakeit.UUID() + ".txt"
+			Expect(os.WriteFile(parentPath, []byte(testContent), 0644)).To(Succeed())
+
+			Expect(destStorage.Preflight(ctx, parentPath+"/test-abc.txt", localProtoc)).ToNot(Succeed())
+		})
+	})
+
 	Describe("FinalizeTransfer", func() {
 		var filePath string
 
@@ -257,6 +589,108 @@ var _ = Describe("Destination", func() {
 			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(info.FinishTime).ToNot(BeZero())
+			Expect(info.Offset).To(Equal(int64(len(testContent))))
+		}, NodeTimeout(10*time.Second))
+
+		It("should rename the staging file to the path once finalized", func(ctx context.Context) {
+			filePath = tempDir + "/test-abc-5-rename-" + gofakeit.UUID() + ".txt"
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, int64(len(testContent)), gofakeit.PastDate(),
+				localProtoc,
+			)).To(Succeed())
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				bytes.NewReader([]byte(testContent)),
+				0,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filePath).ToNot(BeAnExistingFile())
+
+			By("finalize the transfer twice")
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+
+			By("assert the staging file is renamed to the path")
+			Expect(filePath + ".part").ToNot(BeAnExistingFile())
+			Expect(os.ReadFile(filePath)).To(BeEquivalentTo(testContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should finalize the transfer of unknown size with the written size", func(ctx context.Context) {
+			filePath = tempDir + "/test-abc-5-unknown-size.txt"
+			modTime := gofakeit.PastDate()
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, xferfile.SizeUnknown, modTime,
+				localProtoc,
+			)).To(Succeed())
+
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				bytes.NewReader([]byte(testContent)),
+				0,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("finalize the transfer")
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+
+			By("assert the file info")
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(int64(len(testContent))))
+			Expect(info.FinishTime).ToNot(BeZero())
+		}, NodeTimeout(10*time.Second))
+
+		It("should set the modification time of the source when preserved", func(ctx context.Context) {
+			destStorage.PreserveModTime = true
+			DeferCleanup(func() { destStorage.PreserveModTime = false })
+			modTime := gofakeit.PastDate()
+			Expect(destStorage.CreateFile(
+				ctx,
+				filePath, int64(len(testContent)), modTime,
+				localProtoc,
+			)).To(Succeed())
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				bytes.NewReader([]byte(testContent)),
+				0,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("finalize the transfer")
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+
+			By("assert the modification time of the file")
+			fileStat, err := os.Stat(filePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileStat.ModTime()).To(BeTemporally("~", modTime, time.Millisecond))
+		}, NodeTimeout(10*time.Second))
+
+		It("should recreate the link recorded in the file info", func(ctx context.Context) {
+			filePath = tempDir + "/test-abc-5-link-" + gofakeit.UUID() + ".txt"
+			Expect(destStorage.CreateFileWithMetadata(
+				ctx,
+				filePath, 0, gofakeit.PastDate(),
+				map[string]string{storage.SymlinkTargetMeta: "test-abc-5-target.txt"},
+				localProtoc,
+			)).To(Succeed())
+
+			By("finalize the transfer")
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+
+			By("assert the link is recreated")
+			Expect(os.Readlink(filePath)).To(Equal("test-abc-5-target.txt"))
+			Expect(filePath + ".part").ToNot(BeAnExistingFile())
+			info, err := destStorage.GetFileInfo(ctx, filePath, localProtoc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(info.Size))
 		}, NodeTimeout(10*time.Second))
 
 		It("should return error if file cannot finalize", func(ctx context.Context) {
@@ -303,6 +737,7 @@ var _ = Describe("Destination", func() {
 			By("assert the file does not exist")
 			_, err := os.Stat(filePath)
 			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(filePath + ".part").ToNot(BeAnExistingFile())
 
 			By("assert the file info does not exist")
 			_, err = os.Stat(filePath + ".info")
@@ -311,11 +746,22 @@ var _ = Describe("Destination", func() {
 	})
 })
 
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// writeDestFileContent writes the content of the file, to its staging file unless it is finished.
 func writeDestFileContent(filePath string, fileInfo xferfile.Info, content string) {
 	GinkgoHelper()
+	contentPath := filePath
+	if fileInfo.FinishTime.IsZero() {
+		contentPath += ".part"
+	}
 	ExpectWithOffset(
 		1,
-		os.WriteFile(filePath, []byte(content), 0644),
+		os.WriteFile(contentPath, []byte(content), 0644),
 	).To(Succeed())
 	infoPath, err := xferfile.GenerateInfoPath(filePath)
 	Expect(err).ToNot(HaveOccurred())
@@ -326,3 +772,15 @@ func writeDestFileContent(filePath string, fileInfo xferfile.Info, content strin
 		os.WriteFile(infoPath, infoData, 0644),
 	).To(Succeed())
 }
+
+// writeLegacyDestFileContent writes the unfinished file and its info file as they were written
+// before the info path kept the extension and the content was staged.
+func writeLegacyDestFileContent(filePath string, fileInfo xferfile.Info, content string) {
+	GinkgoHelper()
+	Expect(os.WriteFile(filePath, []byte(content), 0644)).To(Succeed())
+	infoPath, err := xferfile.GenerateLegacyInfoPath(filePath)
+	Expect(err).ToNot(HaveOccurred())
+	infoData, err := json.Marshal(fileInfo)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(os.WriteFile(infoPath, infoData, 0644)).To(Succeed())
+}
@@ -3,7 +3,11 @@ package local
 import (
 	"context"
 	"io"
+	"io/fs"
+	"iter"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/derektruong/fxfer/internal/fileutils"
 	"github.com/derektruong/fxfer/internal/xferfile"
@@ -15,12 +19,33 @@ import (
 
 type Source struct {
 	logger logr.Logger
+
+	// recreateSymlinks instructs the Source to transfer the symbolic links as links
+	// (see WithRecreateSymlinks)
+	recreateSymlinks bool
+}
+
+// SourceOption is a function that configures the Source
+type SourceOption func(*Source)
+
+// WithRecreateSymlinks instructs the Source to transfer the symbolic links as links rather than
+// the content of their target: the info of a link (os.Lstat) records its target
+// (see storage.SymlinkTargetMeta) and its content is empty, the local Destination then recreates
+// the link with the same target (os.Symlink). A relative target is kept as is, so that the links
+// within a mirrored tree point within the mirror. The other destinations write an empty file.
+func WithRecreateSymlinks() SourceOption {
+	return func(s *Source) {
+		s.recreateSymlinks = true
+	}
 }
 
-func NewSource(logger logr.Logger) (s *Source, err error) {
+func NewSource(logger logr.Logger, opts ...SourceOption) (s *Source, err error) {
 	s = &Source{
 		logger: logger.WithName("local.source"),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	return
 }
 
@@ -34,7 +59,7 @@ func (s *Source) GetFileInfo(
 		return
 	}
 	var fileInfo os.FileInfo
-	if fileInfo, err = os.Stat(filePath); err != nil {
+	if fileInfo, err = s.stat(filePath); err != nil {
 		return
 	}
 	var fileName, fileExt string
@@ -48,6 +73,7 @@ func (s *Source) GetFileInfo(
 		Size:      fileInfo.Size(),
 		ModTime:   fileInfo.ModTime(),
 	}
+	err = s.recordSymlinkTarget(&info, fileInfo)
 	return
 }
 
@@ -61,6 +87,15 @@ func (s *Source) GetFileFromOffset(
 		err = storage.ErrLocalProtocolIOInvalid
 		return
 	}
+	// a link transferred as a link has no content of its own
+	var fileInfo os.FileInfo
+	if fileInfo, err = s.stat(filePath); err != nil {
+		return
+	}
+	if isSymlink(fileInfo) {
+		reader = io.NopCloser(strings.NewReader(""))
+		return
+	}
 	var file *os.File
 	if file, err = os.Open(filePath); err != nil {
 		return
@@ -72,6 +107,137 @@ func (s *Source) GetFileFromOffset(
 	return
 }
 
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	for info, iterErr := range s.IterFiles(ctx, dirPath, cli) {
+		if err = iterErr; err != nil {
+			return
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
+// IterFiles iterates over the files of the directory tree as it is walked
+// (see storage.FileIterator).
+func (s *Source) IterFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) iter.Seq2[xferfile.Info, error] {
+	return func(yield func(xferfile.Info, error) bool) {
+		if _, ok := cli.GetCredential().(local.IO); !ok {
+			yield(xferfile.Info{}, storage.ErrLocalProtocolIOInvalid)
+			return
+		}
+		err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, walkErr error) (err error) {
+			if walkErr != nil {
+				return walkErr
+			}
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if d.IsDir() {
+				return
+			}
+			var fileInfo fs.FileInfo
+			if fileInfo, err = d.Info(); err != nil {
+				return
+			}
+			fileName, fileExt := fileutils.SplitFileName(path)
+			info := xferfile.Info{
+				Path:      path,
+				Name:      fileName,
+				Extension: fileExt,
+				Size:      fileInfo.Size(),
+				ModTime:   fileInfo.ModTime(),
+			}
+			if err = s.recordSymlinkTarget(&info, fileInfo); err != nil {
+				return
+			}
+			if !yield(info, nil) {
+				return fs.SkipAll
+			}
+			return
+		})
+		if err != nil {
+			yield(xferfile.Info{}, err)
+		}
+	}
+}
+
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+	var matches []string
+	if matches, err = filepath.Glob(pattern); err != nil {
+		return
+	}
+	for _, match := range matches {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var fileInfo fs.FileInfo
+		if fileInfo, err = s.stat(match); err != nil {
+			return
+		}
+		if fileInfo.IsDir() {
+			continue
+		}
+		fileName, fileExt := fileutils.SplitFileName(match)
+		info := xferfile.Info{
+			Path:      match,
+			Name:      fileName,
+			Extension: fileExt,
+			Size:      fileInfo.Size(),
+			ModTime:   fileInfo.ModTime(),
+		}
+		if err = s.recordSymlinkTarget(&info, fileInfo); err != nil {
+			return
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
 func (s *Source) Close() {
 	s.logger.Info("closed local source")
 }
+
+// stat returns the info of the file, of the link itself rather than of its target if the links
+// are transferred as links (see WithRecreateSymlinks).
+func (s *Source) stat(filePath string) (os.FileInfo, error) {
+	if s.recreateSymlinks {
+		return os.Lstat(filePath)
+	}
+	return os.Stat(filePath)
+}
+
+// recordSymlinkTarget records the target of the link in its info, whose content is empty, if
+// the links are transferred as links (see WithRecreateSymlinks).
+func (s *Source) recordSymlinkTarget(info *xferfile.Info, fileInfo os.FileInfo) (err error) {
+	if !s.recreateSymlinks || !isSymlink(fileInfo) {
+		return
+	}
+	var target string
+	if target, err = os.Readlink(info.Path); err != nil {
+		return
+	}
+	info.Size = 0
+	info.Metadata = map[string]string{storage.SymlinkTargetMeta: target}
+	return
+}
+
+// isSymlink reports whether the file is a symbolic link.
+func isSymlink(fileInfo os.FileInfo) bool {
+	return fileInfo.Mode()&os.ModeSymlink != 0
+}
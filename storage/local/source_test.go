@@ -9,6 +9,7 @@ import (
 
 	"github.com/brianvoe/gofakeit/v7"
 	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
 	"github.com/derektruong/fxfer/storage/local"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -96,6 +97,166 @@ var _ = Describe("Source", func() {
 			Expect(os.IsNotExist(err)).To(BeTrue())
 		})
 	})
+
+	Describe("ListFiles", func() {
+		It("should list all files in nested directories", func(ctx context.Context) {
+			dirPath := filepath.Join(tempDir, "list-files")
+			Expect(os.MkdirAll(filepath.Join(dirPath, "a", "b"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(dirPath, "empty"), 0755)).To(Succeed())
+			writeSourceFileContent(filepath.Join(dirPath, "root.txt"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "a", "a.txt"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "a", "b", "b"), testContent)
+
+			infos, err := srcStorage.ListFiles(ctx, dirPath, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(ConsistOf(
+				And(
+					HaveField("Path", filepath.Join(dirPath, "root.txt")),
+					HaveField("Name", "root"),
+					HaveField("Extension", "txt"),
+					HaveField("Size", int64(len(testContent))),
+				),
+				And(
+					HaveField("Path", filepath.Join(dirPath, "a", "a.txt")),
+					HaveField("Name", "a"),
+					HaveField("Extension", "txt"),
+				),
+				And(
+					HaveField("Path", filepath.Join(dirPath, "a", "b", "b")),
+					HaveField("Name", "b"),
+					HaveField("Extension", ""),
+				),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if directory does not exist", func(ctx context.Context) {
+			_, err := srcStorage.ListFiles(ctx, filepath.Join(tempDir, "not-exists"), local_protoc.NewIO())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("WithRecreateSymlinks", func() {
+		var linkPath string
+
+		BeforeEach(func() {
+			dirPath := filepath.Join(tempDir, "symlinks-"+gofakeit.UUID())
+			Expect(os.MkdirAll(dirPath, 0755)).To(Succeed())
+			writeSourceFileContent(filepath.Join(dirPath, "target.txt"), testContent)
+			linkPath = filepath.Join(dirPath, "link.txt")
+			Expect(os.Symlink("target.txt", linkPath)).To(Succeed())
+		})
+
+		It("should record the target of a link with an empty content", func(ctx context.Context) {
+			srcStorage, err = local.NewSource(GinkgoLogr, local.WithRecreateSymlinks())
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := srcStorage.GetFileInfo(ctx, linkPath, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(And(
+				HaveField("Size", int64(0)),
+				HaveField("Metadata", HaveKeyWithValue(storage.SymlinkTargetMeta, "target.txt")),
+			))
+			reader, err := srcStorage.GetFileFromOffset(ctx, linkPath, 0, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEmpty())
+
+			By("assert the listed link records its target")
+			infos, err := srcStorage.ListFiles(ctx, filepath.Dir(linkPath), local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(ContainElement(And(
+				HaveField("Path", linkPath),
+				HaveField("Metadata", HaveKeyWithValue(storage.SymlinkTargetMeta, "target.txt")),
+			)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should dereference a link by default", func(ctx context.Context) {
+			info, err := srcStorage.GetFileInfo(ctx, linkPath, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(int64(len(testContent))))
+			Expect(info.Metadata).ToNot(HaveKey(storage.SymlinkTargetMeta))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("IterFiles", func() {
+		var dirPath string
+
+		BeforeEach(func() {
+			dirPath = filepath.Join(tempDir, "iter-files-"+gofakeit.UUID())
+			Expect(os.MkdirAll(filepath.Join(dirPath, "a", "b"), 0755)).To(Succeed())
+			writeSourceFileContent(filepath.Join(dirPath, "root.txt"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "a", "a.txt"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "a", "b", "b.txt"), testContent)
+		})
+
+		It("should iterate over the files of nested directories", func(ctx context.Context) {
+			var paths []string
+			for info, err := range srcStorage.IterFiles(ctx, dirPath, local_protoc.NewIO()) {
+				Expect(err).ToNot(HaveOccurred())
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(ConsistOf(
+				filepath.Join(dirPath, "root.txt"),
+				filepath.Join(dirPath, "a", "a.txt"),
+				filepath.Join(dirPath, "a", "b", "b.txt"),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should stop once the context is canceled", func(ctx context.Context) {
+			iterCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			var (
+				count   int
+				lastErr error
+			)
+			for _, err := range srcStorage.IterFiles(iterCtx, dirPath, local_protoc.NewIO()) {
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				count++
+				cancel()
+			}
+			Expect(count).To(Equal(1))
+			Expect(lastErr).To(MatchError(context.Canceled))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("Glob", func() {
+		It("should list the files matching the pattern", func(ctx context.Context) {
+			dirPath := filepath.Join(tempDir, "glob")
+			Expect(os.MkdirAll(filepath.Join(dirPath, "2024-01"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(dirPath, "2024-02", "app-dir.log"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(dirPath, "2023-12"), 0755)).To(Succeed())
+			writeSourceFileContent(filepath.Join(dirPath, "2024-01", "app-1.log"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "2024-01", "db-1.log"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "2024-02", "app-2.log"), testContent)
+			writeSourceFileContent(filepath.Join(dirPath, "2023-12", "app-0.log"), testContent)
+
+			infos, err := srcStorage.Glob(ctx, filepath.Join(dirPath, "2024-*", "app-*.log"), local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(HaveExactElements(
+				And(
+					HaveField("Path", filepath.Join(dirPath, "2024-01", "app-1.log")),
+					HaveField("Name", "app-1"),
+					HaveField("Extension", "log"),
+					HaveField("Size", int64(len(testContent))),
+				),
+				HaveField("Path", filepath.Join(dirPath, "2024-02", "app-2.log")),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return no file if nothing matches the pattern", func(ctx context.Context) {
+			infos, err := srcStorage.Glob(ctx, filepath.Join(tempDir, "not-exists", "*.log"), local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if the pattern is malformed", func(ctx context.Context) {
+			_, err := srcStorage.Glob(ctx, filepath.Join(tempDir, "[a-"), local_protoc.NewIO())
+			Expect(err).To(MatchError(filepath.ErrBadPattern))
+		}, NodeTimeout(10*time.Second))
+	})
 })
 
 func writeSourceFileContent(filePath string, content string) {
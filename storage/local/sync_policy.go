@@ -0,0 +1,44 @@
+package local
+
+import "os"
+
+// SyncPolicy is the policy of the Destination flushing the written bytes to disk (fsync), the
+// bytes which are not flushed yet may be lost on a power loss although their chunk is
+// transferred, and the transfer then resumes from the size of the file actually on disk.
+type SyncPolicy int
+
+const (
+	// SyncPolicyNone never flushes the file, leaving the bytes to the OS buffering.
+	SyncPolicyNone SyncPolicy = iota
+	// SyncPolicyOnFinalize flushes the file once before it is finalized.
+	SyncPolicyOnFinalize
+	// SyncPolicyEveryChunk flushes the file at the end of every chunk and before it is finalized.
+	SyncPolicyEveryChunk
+	// SyncPolicyEveryNBytes flushes the file every Destination.InfoSyncSize bytes, before the
+	// offset is recorded in the info file, at the end of every chunk and before it is finalized.
+	SyncPolicyEveryNBytes
+)
+
+// syncFile flushes the file to disk, it is replaced in tests to count the flushes.
+var syncFile = func(file *os.File) error {
+	return file.Sync()
+}
+
+// flushesChunk reports whether the file is flushed at the end of every chunk.
+func (p SyncPolicy) flushesChunk() bool {
+	return p == SyncPolicyEveryChunk || p == SyncPolicyEveryNBytes
+}
+
+// flushStagingFile flushes the staging file of the file to disk before it is finalized, unless
+// the policy is SyncPolicyNone.
+func (d *Destination) flushStagingFile(filePath string) (err error) {
+	if d.SyncPolicy == SyncPolicyNone {
+		return
+	}
+	var file *os.File
+	if file, err = os.OpenFile(stagingPath(filePath), os.O_WRONLY, defaultFilePerm); err != nil {
+		return
+	}
+	defer file.Close()
+	return syncFile(file)
+}
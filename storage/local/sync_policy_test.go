@@ -0,0 +1,100 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Destination SyncPolicy", func() {
+	var (
+		destStorage *Destination
+		localProtoc *local_protoc.IO
+		filePath    string
+		testContent string
+		// flushedSizes and recordedOffsets are the size of the file and the offset recorded in
+		// its info file at each flush
+		flushedSizes, recordedOffsets []int64
+	)
+
+	BeforeEach(func() {
+		var err error
+		destStorage, err = NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage.InfoSyncSize = 4
+		localProtoc = local_protoc.NewIO()
+		filePath = filepath.Join(GinkgoT().TempDir(), "test-abc-sync.txt")
+		testContent = strings.Repeat("a", 20)
+
+		flushedSizes, recordedOffsets = nil, nil
+		originalSyncFile := syncFile
+		syncFile = func(file *os.File) error {
+			fileStat, err := file.Stat()
+			Expect(err).ToNot(HaveOccurred())
+			info, err := destStorage.readInfo(filePath)
+			Expect(err).ToNot(HaveOccurred())
+			flushedSizes = append(flushedSizes, fileStat.Size())
+			recordedOffsets = append(recordedOffsets, info.Offset)
+			return originalSyncFile(file)
+		}
+		DeferCleanup(func() { syncFile = originalSyncFile })
+	})
+
+	// transferInTwoChunks transfers the content in two chunks of 10 bytes written one byte at a
+	// time, then finalizes it.
+	transferInTwoChunks := func(ctx context.Context) {
+		GinkgoHelper()
+		Expect(destStorage.CreateFile(ctx, filePath, int64(len(testContent)), time.Now(), localProtoc)).
+			To(Succeed())
+		for offset := int64(0); offset < int64(len(testContent)); offset += 10 {
+			_, err := destStorage.TransferFileChunk(
+				ctx,
+				filePath,
+				iotest.OneByteReader(strings.NewReader(testContent[offset:offset+10])),
+				offset,
+				localProtoc,
+			)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(destStorage.FinalizeTransfer(ctx, filePath, localProtoc)).To(Succeed())
+		Expect(os.ReadFile(filePath)).To(BeEquivalentTo(testContent))
+	}
+
+	It("should flush the file only before it is finalized by default", func(ctx context.Context) {
+		Expect(destStorage.SyncPolicy).To(Equal(SyncPolicyOnFinalize))
+		transferInTwoChunks(ctx)
+		Expect(flushedSizes).To(HaveExactElements(int64(20)))
+	}, NodeTimeout(10*time.Second))
+
+	It("should never flush the file with SyncPolicyNone", func(ctx context.Context) {
+		destStorage.SyncPolicy = SyncPolicyNone
+		transferInTwoChunks(ctx)
+		Expect(flushedSizes).To(BeEmpty())
+	}, NodeTimeout(10*time.Second))
+
+	It("should flush the file at the end of every chunk with SyncPolicyEveryChunk", func(ctx context.Context) {
+		destStorage.SyncPolicy = SyncPolicyEveryChunk
+		transferInTwoChunks(ctx)
+		Expect(flushedSizes).To(HaveExactElements(int64(10), int64(20), int64(20)))
+	}, NodeTimeout(10*time.Second))
+
+	It("should flush the file before recording each offset with SyncPolicyEveryNBytes", func(ctx context.Context) {
+		destStorage.SyncPolicy = SyncPolicyEveryNBytes
+		transferInTwoChunks(ctx)
+		Expect(flushedSizes).To(HaveExactElements(
+			int64(4), int64(8), int64(10), int64(14), int64(18), int64(20), int64(20),
+		))
+
+		By("assert the info file only records the flushed offsets")
+		Expect(recordedOffsets).To(HaveExactElements(
+			int64(0), int64(4), int64(8), int64(10), int64(14), int64(18), int64(20),
+		))
+	}, NodeTimeout(10*time.Second))
+})
@@ -1,3 +1,3 @@
-//go:generate go run go.uber.org/mock/mockgen -destination=./mock_storage.go -package=mock_storage github.com/derektruong/fxfer/storage Source,Destination
+//go:generate go run go.uber.org/mock/mockgen -destination=./mock_storage.go -package=mock_storage github.com/derektruong/fxfer/storage Source,Destination,ServerSideCopier
 
 package mock_storage
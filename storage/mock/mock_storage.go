@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/derektruong/fxfer/storage (interfaces: Source,Destination)
+// Source: github.com/derektruong/fxfer/storage (interfaces: Source,Destination,ServerSideCopier)
 //
 // Generated by this command:
 //
-//	mockgen -destination=./mock_storage.go -package=mock_storage github.com/derektruong/fxfer/storage Source,Destination
+//	mockgen -destination=./mock_storage.go -package=mock_storage github.com/derektruong/fxfer/storage Source,Destination,ServerSideCopier
 //
 
 // Package mock_storage is a generated GoMock package.
@@ -15,9 +15,10 @@ import (
 	reflect "reflect"
 	time "time"
 
-	gomock "go.uber.org/mock/gomock"
 	xferfile "github.com/derektruong/fxfer/internal/xferfile"
 	protoc "github.com/derektruong/fxfer/protoc"
+	storage "github.com/derektruong/fxfer/storage"
+	gomock "go.uber.org/mock/gomock"
 )
 
 // MockSource is a mock of Source interface.
@@ -57,33 +58,63 @@ func (mr *MockSourceMockRecorder) Close() *gomock.Call {
 }
 
 // GetFileFromOffset mocks base method.
-func (m *MockSource) GetFileFromOffset(ctx context.Context, filePath string, offset int64, credential protoc.Client) (io.ReadCloser, error) {
+func (m *MockSource) GetFileFromOffset(ctx context.Context, filePath string, offset int64, client protoc.Client) (io.ReadCloser, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFileFromOffset", ctx, filePath, offset, credential)
+	ret := m.ctrl.Call(m, "GetFileFromOffset", ctx, filePath, offset, client)
 	ret0, _ := ret[0].(io.ReadCloser)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFileFromOffset indicates an expected call of GetFileFromOffset.
-func (mr *MockSourceMockRecorder) GetFileFromOffset(ctx, filePath, offset, credential any) *gomock.Call {
+func (mr *MockSourceMockRecorder) GetFileFromOffset(ctx, filePath, offset, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileFromOffset", reflect.TypeOf((*MockSource)(nil).GetFileFromOffset), ctx, filePath, offset, credential)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileFromOffset", reflect.TypeOf((*MockSource)(nil).GetFileFromOffset), ctx, filePath, offset, client)
 }
 
 // GetFileInfo mocks base method.
-func (m *MockSource) GetFileInfo(ctx context.Context, filePath string, protocol protoc.Client) (xferfile.Info, error) {
+func (m *MockSource) GetFileInfo(ctx context.Context, filePath string, client protoc.Client) (xferfile.Info, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFileInfo", ctx, filePath, protocol)
+	ret := m.ctrl.Call(m, "GetFileInfo", ctx, filePath, client)
 	ret0, _ := ret[0].(xferfile.Info)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFileInfo indicates an expected call of GetFileInfo.
-func (mr *MockSourceMockRecorder) GetFileInfo(ctx, filePath, protocol any) *gomock.Call {
+func (mr *MockSourceMockRecorder) GetFileInfo(ctx, filePath, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileInfo", reflect.TypeOf((*MockSource)(nil).GetFileInfo), ctx, filePath, client)
+}
+
+// Glob mocks base method.
+func (m *MockSource) Glob(ctx context.Context, pattern string, client protoc.Client) ([]xferfile.Info, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Glob", ctx, pattern, client)
+	ret0, _ := ret[0].([]xferfile.Info)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Glob indicates an expected call of Glob.
+func (mr *MockSourceMockRecorder) Glob(ctx, pattern, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Glob", reflect.TypeOf((*MockSource)(nil).Glob), ctx, pattern, client)
+}
+
+// ListFiles mocks base method.
+func (m *MockSource) ListFiles(ctx context.Context, dirPath string, client protoc.Client) ([]xferfile.Info, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFiles", ctx, dirPath, client)
+	ret0, _ := ret[0].([]xferfile.Info)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFiles indicates an expected call of ListFiles.
+func (mr *MockSourceMockRecorder) ListFiles(ctx, dirPath, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileInfo", reflect.TypeOf((*MockSource)(nil).GetFileInfo), ctx, filePath, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFiles", reflect.TypeOf((*MockSource)(nil).ListFiles), ctx, dirPath, client)
 }
 
 // MockDestination is a mock of Destination interface.
@@ -123,73 +154,126 @@ func (mr *MockDestinationMockRecorder) Close() *gomock.Call {
 }
 
 // CreateFile mocks base method.
-func (m *MockDestination) CreateFile(ctx context.Context, path string, size int64, modTime time.Time, protocol protoc.Client) error {
+func (m *MockDestination) CreateFile(ctx context.Context, path string, size int64, modTime time.Time, client protoc.Client) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateFile", ctx, path, size, modTime, protocol)
+	ret := m.ctrl.Call(m, "CreateFile", ctx, path, size, modTime, client)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateFile indicates an expected call of CreateFile.
-func (mr *MockDestinationMockRecorder) CreateFile(ctx, path, size, modTime, protocol any) *gomock.Call {
+func (mr *MockDestinationMockRecorder) CreateFile(ctx, path, size, modTime, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFile", reflect.TypeOf((*MockDestination)(nil).CreateFile), ctx, path, size, modTime, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFile", reflect.TypeOf((*MockDestination)(nil).CreateFile), ctx, path, size, modTime, client)
 }
 
 // DeleteFile mocks base method.
-func (m *MockDestination) DeleteFile(ctx context.Context, filePath string, protocol protoc.Client) error {
+func (m *MockDestination) DeleteFile(ctx context.Context, filePath string, client protoc.Client) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteFile", ctx, filePath, protocol)
+	ret := m.ctrl.Call(m, "DeleteFile", ctx, filePath, client)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteFile indicates an expected call of DeleteFile.
-func (mr *MockDestinationMockRecorder) DeleteFile(ctx, filePath, protocol any) *gomock.Call {
+func (mr *MockDestinationMockRecorder) DeleteFile(ctx, filePath, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDestination)(nil).DeleteFile), ctx, filePath, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDestination)(nil).DeleteFile), ctx, filePath, client)
 }
 
 // FinalizeTransfer mocks base method.
-func (m *MockDestination) FinalizeTransfer(ctx context.Context, filePath string, protocol protoc.Client) error {
+func (m *MockDestination) FinalizeTransfer(ctx context.Context, filePath string, client protoc.Client) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FinalizeTransfer", ctx, filePath, protocol)
+	ret := m.ctrl.Call(m, "FinalizeTransfer", ctx, filePath, client)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // FinalizeTransfer indicates an expected call of FinalizeTransfer.
-func (mr *MockDestinationMockRecorder) FinalizeTransfer(ctx, filePath, protocol any) *gomock.Call {
+func (mr *MockDestinationMockRecorder) FinalizeTransfer(ctx, filePath, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalizeTransfer", reflect.TypeOf((*MockDestination)(nil).FinalizeTransfer), ctx, filePath, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinalizeTransfer", reflect.TypeOf((*MockDestination)(nil).FinalizeTransfer), ctx, filePath, client)
 }
 
 // GetFileInfo mocks base method.
-func (m *MockDestination) GetFileInfo(ctx context.Context, filePath string, protocol protoc.Client) (xferfile.Info, error) {
+func (m *MockDestination) GetFileInfo(ctx context.Context, filePath string, client protoc.Client) (xferfile.Info, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFileInfo", ctx, filePath, protocol)
+	ret := m.ctrl.Call(m, "GetFileInfo", ctx, filePath, client)
 	ret0, _ := ret[0].(xferfile.Info)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetFileInfo indicates an expected call of GetFileInfo.
-func (mr *MockDestinationMockRecorder) GetFileInfo(ctx, filePath, protocol any) *gomock.Call {
+func (mr *MockDestinationMockRecorder) GetFileInfo(ctx, filePath, client any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileInfo", reflect.TypeOf((*MockDestination)(nil).GetFileInfo), ctx, filePath, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFileInfo", reflect.TypeOf((*MockDestination)(nil).GetFileInfo), ctx, filePath, client)
 }
 
 // TransferFileChunk mocks base method.
-func (m *MockDestination) TransferFileChunk(ctx context.Context, filePath string, reader io.Reader, offset int64, protocol protoc.Client) (int64, error) {
+func (m *MockDestination) TransferFileChunk(ctx context.Context, filePath string, reader io.Reader, offset int64, client protoc.Client) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TransferFileChunk", ctx, filePath, reader, offset, protocol)
+	ret := m.ctrl.Call(m, "TransferFileChunk", ctx, filePath, reader, offset, client)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // TransferFileChunk indicates an expected call of TransferFileChunk.
-func (mr *MockDestinationMockRecorder) TransferFileChunk(ctx, filePath, reader, offset, protocol any) *gomock.Call {
+func (mr *MockDestinationMockRecorder) TransferFileChunk(ctx, filePath, reader, offset, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferFileChunk", reflect.TypeOf((*MockDestination)(nil).TransferFileChunk), ctx, filePath, reader, offset, client)
+}
+
+// MockServerSideCopier is a mock of ServerSideCopier interface.
+type MockServerSideCopier struct {
+	ctrl     *gomock.Controller
+	recorder *MockServerSideCopierMockRecorder
+	isgomock struct{}
+}
+
+// MockServerSideCopierMockRecorder is the mock recorder for MockServerSideCopier.
+type MockServerSideCopierMockRecorder struct {
+	mock *MockServerSideCopier
+}
+
+// NewMockServerSideCopier creates a new mock instance.
+func NewMockServerSideCopier(ctrl *gomock.Controller) *MockServerSideCopier {
+	mock := &MockServerSideCopier{ctrl: ctrl}
+	mock.recorder = &MockServerSideCopierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServerSideCopier) EXPECT() *MockServerSideCopierMockRecorder {
+	return m.recorder
+}
+
+// CanCopyFrom mocks base method.
+func (m *MockServerSideCopier) CanCopyFrom(src storage.Source, srcClient, client protoc.Client) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanCopyFrom", src, srcClient, client)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CanCopyFrom indicates an expected call of CanCopyFrom.
+func (mr *MockServerSideCopierMockRecorder) CanCopyFrom(src, srcClient, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanCopyFrom", reflect.TypeOf((*MockServerSideCopier)(nil).CanCopyFrom), src, srcClient, client)
+}
+
+// CopyFileFrom mocks base method.
+func (m *MockServerSideCopier) CopyFileFrom(ctx context.Context, filePath, srcPath string, srcClient protoc.Client, offset int64, client protoc.Client, tracker storage.ConfirmedSizeTracker) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CopyFileFrom", ctx, filePath, srcPath, srcClient, offset, client, tracker)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CopyFileFrom indicates an expected call of CopyFileFrom.
+func (mr *MockServerSideCopierMockRecorder) CopyFileFrom(ctx, filePath, srcPath, srcClient, offset, client, tracker any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferFileChunk", reflect.TypeOf((*MockDestination)(nil).TransferFileChunk), ctx, filePath, reader, offset, protocol)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyFileFrom", reflect.TypeOf((*MockServerSideCopier)(nil).CopyFileFrom), ctx, filePath, srcPath, srcClient, offset, client, tracker)
 }
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PartLayoutMeta is the metadata key of the part layout of a file (see EncodePartLayout),
+// a destination uploading the file in parts follows this layout when it is recorded in the
+// info of the destination file, e.g. to reproduce the composite ETag of an S3 object.
+const PartLayoutMeta = "partLayout"
+
+// EncodePartLayout encodes the sizes of the parts of a file into a metadata value.
+func EncodePartLayout(partSizes []int64) string {
+	values := make([]string, len(partSizes))
+	for i, size := range partSizes {
+		values[i] = strconv.FormatInt(size, 10)
+	}
+	return strings.Join(values, ",")
+}
+
+// DecodePartLayout decodes the sizes of the parts of a file from the metadata,
+// it returns nil if no part layout is recorded (see PartLayoutMeta).
+func DecodePartLayout(metadata map[string]string) (partSizes []int64, err error) {
+	value, ok := metadata[PartLayoutMeta]
+	if !ok || value == "" {
+		return
+	}
+	values := strings.Split(value, ",")
+	partSizes = make([]int64, len(values))
+	for i, v := range values {
+		if partSizes[i], err = strconv.ParseInt(v, 10, 64); err != nil || partSizes[i] <= 0 {
+			return nil, ErrPartLayoutInvalid
+		}
+	}
+	return
+}
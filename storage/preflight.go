@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/derektruong/fxfer/protoc"
+)
+
+// Preflighter can be implemented by a Destination to verify up front that a file can be
+// written at the path with the client (e.g. that the credentials are granted the permissions
+// required by a transfer), rather than discovering a missing permission mid-transfer.
+type Preflighter interface {
+	// Preflight performs a minimal write next to the file at the specified path and undoes it,
+	// ErrPermissionMissing is returned (wrapped, naming the permission) if it is denied
+	Preflight(ctx context.Context, filePath string, client protoc.Client) (err error)
+}
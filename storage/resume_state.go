@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc"
+)
+
+// ResumeState describes the progress of an unfinished destination file, it is serializable
+// so that a process can persist it and resume the transfer after a restart.
+type ResumeState struct {
+	// Path is the path of the destination file
+	Path string `json:"path"`
+
+	// Size is the size of the file in bytes (xferfile.SizeUnknown for a stream)
+	Size int64 `json:"size"`
+
+	// Offset is the number of bytes persisted by the destination, the transfer resumes from it
+	Offset int64 `json:"offset"`
+
+	// UploadID identifies the upload in the destination service (e.g. the S3 multipart
+	// upload ID), it is empty when the destination has no such concept
+	UploadID string `json:"uploadId,omitempty"`
+
+	// PartCount is the number of parts already uploaded (zero if the upload is not in parts)
+	PartCount int `json:"partCount,omitempty"`
+
+	// StartTime is the time the destination file was initiated, it distinguishes a resumed
+	// upload from a re-created one
+	StartTime time.Time `json:"startTime"`
+
+	// Finished reports whether the destination file has already been finalized
+	Finished bool `json:"finished"`
+}
+
+// ResumeStateReporter can be implemented by a Destination to describe the resumption
+// state of a file (see ResumeState).
+type ResumeStateReporter interface {
+	// ResumeState returns the resumption state of the file at the specified path,
+	// xferfile.ErrFileNotExists is returned if the file has not been created
+	ResumeState(ctx context.Context, filePath string, client protoc.Client) (state ResumeState, err error)
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// adaptivePartSize is the part size of an upload, which is halved once its parts have timed out
+// repeatedly (see WithAdaptivePartSize).
+type adaptivePartSize struct {
+	mu       sync.Mutex
+	size     int64
+	minSize  int64
+	timeouts int
+}
+
+// get returns the current part size.
+func (a *adaptivePartSize) get() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.size
+}
+
+// observeTimeout records the timeout of a part of the size, the part size is halved (down to the
+// minimum) every maxTimeouts timeouts. The parts larger than the current part size, e.g. produced
+// before it was reduced, are not counted.
+func (a *adaptivePartSize) observeTimeout(partSize int64, maxTimeouts int) (size int64, reduced bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if partSize > a.size || a.size <= a.minSize {
+		return a.size, false
+	}
+	if a.timeouts++; a.timeouts < maxTimeouts {
+		return a.size, false
+	}
+	a.timeouts = 0
+	a.size = max(a.size/2, a.minSize)
+	return a.size, true
+}
+
+// WithAdaptivePartSize bounds each part upload by partTimeout, a timed-out part fails the transfer
+// with storage.ErrPartTimeout, which is retried. Once maxTimeouts parts of an upload have timed
+// out (0 never), the size of its following parts is halved, down to MinPartSize (or the size
+// keeping the upload within MaxMultipartParts parts), so that a transfer over a flaky link makes
+// progress in smaller parts rather than sending the same large part again. The reduced part size
+// is kept by the destination until the upload is finalized or deleted. The uploads following a
+// part layout (see storage.PartLayoutMeta) keep their part sizes. Default is 0 (no timeout).
+// Note: the timeout does not apply when DisableContentHashes is set.
+func WithAdaptivePartSize(partTimeout time.Duration, maxTimeouts int) DestinationOption {
+	return func(d *Destination) {
+		d.partTimeout = max(partTimeout, 0)
+		d.partTimeoutsBeforeReduction = max(maxTimeouts, 0)
+	}
+}
+
+// adaptivePartSizeOf returns the adaptive part size of the upload, starting from the optimal
+// part size, nil if the part size is not reduced on timeouts (see WithAdaptivePartSize).
+func (d *Destination) adaptivePartSizeOf(u *s3Upload, optimalPartSize int64) *adaptivePartSize {
+	if d.partTimeout <= 0 || d.partTimeoutsBeforeReduction <= 0 {
+		return nil
+	}
+	d.partSizesMu.Lock()
+	defer d.partSizesMu.Unlock()
+	key := smallFileKey(u.bucket, u.objectKey)
+	if partSize, ok := d.partSizes[key]; ok {
+		return partSize
+	}
+
+	// all the parts being at least the minimum size, the upload fits in MaxMultipartParts parts
+	size := u.info.Size
+	if size < 0 {
+		size = d.MaxObjectSize
+	}
+	minSize := max(d.MinPartSize, (size+d.MaxMultipartParts-1)/d.MaxMultipartParts)
+	partSize := &adaptivePartSize{size: optimalPartSize, minSize: min(minSize, optimalPartSize)}
+	d.partSizes[key] = partSize
+	return partSize
+}
+
+// forgetAdaptivePartSize removes the adaptive part size of the upload once it is finalized or deleted.
+func (d *Destination) forgetAdaptivePartSize(bucket, objectKey string) {
+	d.partSizesMu.Lock()
+	defer d.partSizesMu.Unlock()
+	delete(d.partSizes, smallFileKey(bucket, objectKey))
+}
+
+// putPartWithTimeout uploads the part bounded by the part timeout (see WithAdaptivePartSize), the
+// timeout is reported to the adaptive part size of the upload, if any.
+func (u *s3Upload) putPartWithTimeout(
+	ctx context.Context,
+	uploadPartInput *awss3.UploadPartInput, file io.ReadSeeker, size int64,
+	partSize *adaptivePartSize,
+) (etag string, err error) {
+	store := u.store
+	if store.partTimeout <= 0 {
+		return u.putPartForUpload(ctx, uploadPartInput, file, size)
+	}
+	partCtx, cancel := context.WithTimeoutCause(ctx, store.partTimeout, storage.ErrPartTimeout)
+	defer cancel()
+	if etag, err = u.putPartForUpload(partCtx, uploadPartInput, file, size); err == nil ||
+		ctx.Err() != nil || !errors.Is(context.Cause(partCtx), storage.ErrPartTimeout) {
+		return
+	}
+
+	// the context error is not wrapped, so that the timed-out part is retried
+	err = fmt.Errorf("%w: part %d after %s", storage.ErrPartTimeout, *uploadPartInput.PartNumber, store.partTimeout)
+	if partSize != nil {
+		if reducedSize, reduced := partSize.observeTimeout(size, store.partTimeoutsBeforeReduction); reduced {
+			store.logger.Info("parts timed out repeatedly, reducing the part size",
+				"objectKey", u.objectKey, "partSize", reducedSize)
+		}
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
+)
+
+// bucketOwnerS3API is a protoc.S3API which sets the expected owner of the bucket on the input of
+// each operation (see s3.Client.ExpectedBucketOwner), the input of the caller is left untouched.
+type bucketOwnerS3API struct {
+	protoc.S3API
+	owner *string
+}
+
+// withExpectedBucketOwner wraps the client in a bucketOwnerS3API, unless the owner is empty.
+func withExpectedBucketOwner(client protoc.S3API, owner string) protoc.S3API {
+	if owner == "" {
+		return client
+	}
+	return &bucketOwnerS3API{S3API: client, owner: aws.String(owner)}
+}
+
+func (c *bucketOwnerS3API) PutObject(
+	ctx context.Context,
+	input *awss3.PutObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.PutObjectOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.PutObject(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) ListParts(
+	ctx context.Context,
+	input *awss3.ListPartsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListPartsOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.ListParts(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) UploadPart(
+	ctx context.Context,
+	input *awss3.UploadPartInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.UploadPart(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) GetObject(
+	ctx context.Context,
+	input *awss3.GetObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.GetObjectOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.GetObject(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) HeadObject(
+	ctx context.Context,
+	input *awss3.HeadObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.HeadObjectOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.HeadObject(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) CreateMultipartUpload(
+	ctx context.Context,
+	input *awss3.CreateMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CreateMultipartUploadOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.CreateMultipartUpload(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) AbortMultipartUpload(
+	ctx context.Context,
+	input *awss3.AbortMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.AbortMultipartUploadOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.AbortMultipartUpload(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) DeleteObject(
+	ctx context.Context,
+	input *awss3.DeleteObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.DeleteObject(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) DeleteObjects(
+	ctx context.Context,
+	input *awss3.DeleteObjectsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectsOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.DeleteObjects(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) CompleteMultipartUpload(
+	ctx context.Context,
+	input *awss3.CompleteMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CompleteMultipartUploadOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.CompleteMultipartUpload(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) UploadPartCopy(
+	ctx context.Context,
+	input *awss3.UploadPartCopyInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartCopyOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.UploadPartCopy(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) ListObjectsV2(
+	ctx context.Context,
+	input *awss3.ListObjectsV2Input,
+	opts ...func(*awss3.Options),
+) (*awss3.ListObjectsV2Output, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.ListObjectsV2(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) GetObjectAttributes(
+	ctx context.Context,
+	input *awss3.GetObjectAttributesInput,
+	opts ...func(*awss3.Options),
+) (*awss3.GetObjectAttributesOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.GetObjectAttributes(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) RestoreObject(
+	ctx context.Context,
+	input *awss3.RestoreObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.RestoreObjectOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.RestoreObject(ctx, &ownedInput, opts...)
+}
+
+func (c *bucketOwnerS3API) ListMultipartUploads(
+	ctx context.Context,
+	input *awss3.ListMultipartUploadsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListMultipartUploadsOutput, error) {
+	ownedInput := *input
+	ownedInput.ExpectedBucketOwner = c.owner
+	return c.S3API.ListMultipartUploads(ctx, &ownedInput, opts...)
+}
@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	s3_protoc "github.com/derektruong/fxfer/protoc/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("expected bucket owner", func() {
+	var (
+		bucket    = aws.String(bucketName)
+		owner     = aws.String("111122223333")
+		mockS3API *mock_protoc.MockS3API
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		DeferCleanup(mockCtrl.Finish)
+		mockS3API = mock_protoc.NewMockS3API(mockCtrl)
+	})
+
+	It("should not wrap the client without an expected owner", func() {
+		Expect(withExpectedBucketOwner(mockS3API, "")).To(BeIdenticalTo(mockS3API))
+	})
+
+	DescribeTable("should set the expected owner on the input of each operation",
+		func(ctx context.Context, call func(ctx context.Context, api protoc.S3API)) {
+			call(ctx, withExpectedBucketOwner(mockS3API, *owner))
+		},
+		Entry("PutObject", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().PutObject(ctx, &awss3.PutObjectInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.PutObjectOutput{}, nil)
+			input := &awss3.PutObjectInput{Bucket: bucket}
+			_, err := api.PutObject(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("ListParts", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().ListParts(ctx, &awss3.ListPartsInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.ListPartsOutput{}, nil)
+			input := &awss3.ListPartsInput{Bucket: bucket}
+			_, err := api.ListParts(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("UploadPart", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().UploadPart(ctx, &awss3.UploadPartInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.UploadPartOutput{}, nil)
+			input := &awss3.UploadPartInput{Bucket: bucket}
+			_, err := api.UploadPart(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("GetObject", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().GetObject(ctx, &awss3.GetObjectInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.GetObjectOutput{}, nil)
+			input := &awss3.GetObjectInput{Bucket: bucket}
+			_, err := api.GetObject(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("HeadObject", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.HeadObjectOutput{}, nil)
+			input := &awss3.HeadObjectInput{Bucket: bucket}
+			_, err := api.HeadObject(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("CreateMultipartUpload", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.CreateMultipartUploadOutput{}, nil)
+			input := &awss3.CreateMultipartUploadInput{Bucket: bucket}
+			_, err := api.CreateMultipartUpload(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("AbortMultipartUpload", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.AbortMultipartUploadOutput{}, nil)
+			input := &awss3.AbortMultipartUploadInput{Bucket: bucket}
+			_, err := api.AbortMultipartUpload(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("DeleteObject", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().DeleteObject(ctx, &awss3.DeleteObjectInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.DeleteObjectOutput{}, nil)
+			input := &awss3.DeleteObjectInput{Bucket: bucket}
+			_, err := api.DeleteObject(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("DeleteObjects", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().DeleteObjects(ctx, &awss3.DeleteObjectsInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.DeleteObjectsOutput{}, nil)
+			input := &awss3.DeleteObjectsInput{Bucket: bucket}
+			_, err := api.DeleteObjects(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("CompleteMultipartUpload", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, &awss3.CompleteMultipartUploadInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.CompleteMultipartUploadOutput{}, nil)
+			input := &awss3.CompleteMultipartUploadInput{Bucket: bucket}
+			_, err := api.CompleteMultipartUpload(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("UploadPartCopy", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().UploadPartCopy(ctx, &awss3.UploadPartCopyInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.UploadPartCopyOutput{}, nil)
+			input := &awss3.UploadPartCopyInput{Bucket: bucket}
+			_, err := api.UploadPartCopy(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("ListObjectsV2", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.ListObjectsV2Output{}, nil)
+			input := &awss3.ListObjectsV2Input{Bucket: bucket}
+			_, err := api.ListObjectsV2(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("GetObjectAttributes", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().GetObjectAttributes(ctx, &awss3.GetObjectAttributesInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.GetObjectAttributesOutput{}, nil)
+			input := &awss3.GetObjectAttributesInput{Bucket: bucket}
+			_, err := api.GetObjectAttributes(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("RestoreObject", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().RestoreObject(ctx, &awss3.RestoreObjectInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.RestoreObjectOutput{}, nil)
+			input := &awss3.RestoreObjectInput{Bucket: bucket}
+			_, err := api.RestoreObject(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+		Entry("ListMultipartUploads", func(ctx context.Context, api protoc.S3API) {
+			mockS3API.EXPECT().ListMultipartUploads(ctx, &awss3.ListMultipartUploadsInput{Bucket: bucket, ExpectedBucketOwner: owner}).
+				Return(&awss3.ListMultipartUploadsOutput{}, nil)
+			input := &awss3.ListMultipartUploadsInput{Bucket: bucket}
+			_, err := api.ListMultipartUploads(ctx, input)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.ExpectedBucketOwner).To(BeNil())
+		}),
+	)
+
+	It("should set the expected owner of the client on the operations of the storages", func(ctx context.Context) {
+		mockClient := mock_protoc.NewMockClient(gomock.NewController(GinkgoT()))
+		s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey,
+			s3_protoc.WithExpectedBucketOwner(*owner))
+		mockClient.EXPECT().GetConnectionID().Return(s3ProtocClient.GetConnectionID())
+		mockClient.EXPECT().GetS3API().Return(mockS3API)
+		mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		mockS3API.EXPECT().HeadObject(gomock.Any(), &awss3.HeadObjectInput{
+			Bucket:              bucket,
+			Key:                 aws.String("file.txt"),
+			ExpectedBucketOwner: owner,
+		}).Return(&awss3.HeadObjectOutput{ContentLength: aws.Int64(10)}, nil)
+
+		srcStorage := NewSource(GinkgoLogr)
+		DeferCleanup(srcStorage.Close)
+		info, err := srcStorage.GetFileInfo(ctx, "file.txt", mockClient)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Size).To(Equal(int64(10)))
+	}, NodeTimeout(10*time.Second))
+})
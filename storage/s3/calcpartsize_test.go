@@ -1,7 +1,11 @@
 package s3
 
 import (
+	"context"
 	"fmt"
+	"time"
+
+	"github.com/derektruong/fxfer/storage"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
@@ -9,6 +13,102 @@ import (
 
 const enableTestDebugOutput = false
 
+var _ = Describe("Validate part sizes", func() {
+	It("should accept the default part sizes", func() {
+		d, err := NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.Validate()).To(Succeed())
+	})
+
+	DescribeTable("should reject the violated invariant on construction",
+		func(configure func(d *Destination), message string) {
+			_, err := NewDestination(GinkgoLogr, configure)
+			Expect(err).To(MatchError(storage.ErrPartSizeInvalid))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("negative minimum part size",
+			func(d *Destination) { d.MinPartSize = -1 },
+			"MinPartSize (-1) is negative"),
+		Entry("minimum part size above the preferred part size",
+			func(d *Destination) { d.MinPartSize = d.PreferredPartSize + 1 },
+			"MinPartSize (52428801) exceeds PreferredPartSize (52428800)"),
+		Entry("preferred part size above the maximum part size",
+			func(d *Destination) { d.PreferredPartSize = d.MaxPartSize + 1 },
+			"PreferredPartSize (5368709121) exceeds MaxPartSize (5368709120)"),
+		Entry("preferred part size not positive",
+			func(d *Destination) { d.MinPartSize, d.PreferredPartSize = 0, 0 },
+			"PreferredPartSize (0) is not positive"),
+		Entry("no part",
+			func(d *Destination) { d.MaxMultipartParts = 0 },
+			"MaxMultipartParts (0) is not positive"),
+		Entry("maximum object size above the total of the parts",
+			func(d *Destination) { d.MaxMultipartParts = 100 },
+			"MaxObjectSize (5497558138880) exceeds MaxPartSize * MaxMultipartParts (536870912000)"),
+	)
+
+	It("should reject a negative part upload concurrency on construction", func() {
+		_, err := NewDestination(GinkgoLogr, func(d *Destination) { d.MaxConcurrentPartUploads = -1 })
+		Expect(err).To(MatchError(storage.ErrPartConcurrencyInvalid))
+		Expect(err).To(MatchError(ContainSubstring("MaxConcurrentPartUploads (-1) is negative")))
+
+		d, err := NewDestination(GinkgoLogr, func(d *Destination) { d.MaxConcurrentPartUploads = 0 })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.maxConcurrentPartUploads()).To(Equal(int64(defaultMaxConcurrentPartUploads)))
+	})
+
+	It("should fail the creation of a file the part sizes cannot upload", func(ctx context.Context) {
+		d := destStorageFactory(nil)
+		d.MinPartSize = d.PreferredPartSize + 1
+		Expect(d.CreateFile(ctx, "file.txt", 10, time.Now(), nil)).To(MatchError(storage.ErrPartSizeInvalid))
+
+		d = destStorageFactory(func(d *Destination) {
+			d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 4, 4, 8
+		})
+		Expect(d.CreateFile(ctx, "file.txt", 8*d.MaxMultipartParts+1, time.Now(), nil)).
+			To(MatchError(storage.ErrPartSizeInvalid))
+	})
+})
+
+var _ = Describe("PartSizeStrategy", func() {
+	It("should ramp the part size up every step up to the maximum", func() {
+		strategy := RampingPartSize{InitialSize: 2, MaxSize: 10, PartsPerStep: 3}
+		var sizes []int64
+		for i := int64(0); i < 12; i++ {
+			size, err := strategy.PartSize(100, i)
+			Expect(err).ToNot(HaveOccurred())
+			sizes = append(sizes, size)
+		}
+		Expect(sizes).To(Equal([]int64{2, 2, 2, 4, 4, 4, 8, 8, 8, 10, 10, 10}))
+
+		_, err := RampingPartSize{InitialSize: 2}.PartSize(100, 0)
+		Expect(err).To(MatchError(storage.ErrPartSizeInvalid))
+	})
+
+	It("should return error when the part size is out of the part size limits", func(ctx context.Context) {
+		d := destStorageFactory(func(d *Destination) {
+			d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 4, 4, 8
+			d.PartSizeStrategy = FixedPartSize(2)
+		})
+		Expect(d.CreateFile(ctx, "file.txt", 10, time.Now(), nil)).
+			To(MatchError(ContainSubstring("size (2) of part 1 is out of MinPartSize (4) and MaxPartSize (8)")))
+
+		d.PartSizeStrategy = FixedPartSize(16)
+		_, err := d.EstimateTransfer(20)
+		Expect(err).To(MatchError(storage.ErrPartSizeInvalid))
+	})
+
+	It("should estimate the parts of the strategy", func() {
+		d := destStorageFactory(func(d *Destination) {
+			d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 2, 4, 8
+			d.PartSizeStrategy = RampingPartSize{InitialSize: 2, MaxSize: 8, PartsPerStep: 2}
+		})
+		estimate, err := d.EstimateTransfer(20)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(estimate.PartUploads).To(Equal(int64(5)))
+		Expect(estimate.PartSize).To(Equal(int64(2)))
+	})
+})
+
 var _ = Describe("Calculate Part Size", func() {
 	var (
 		mockCtrl *gomock.Controller
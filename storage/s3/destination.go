@@ -10,6 +10,9 @@
 //	s3:ListMultipartUploadParts
 //	s3:PutObject
 //
+// Destination.Preflight (or fxfer.DestinationConfig.Preflight) verifies these
+// permissions up front and names the first one missing.
+//
 // While this package uses the official AWS SDK for Go, Destination is able
 // to work with any S3-compatible service such as MinIO. In order to change
 // the HTTP endpoint used for sending requests to, adjust the `BaseEndpoint`
@@ -68,18 +71,25 @@ package s3
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"maps"
 	"math"
 	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/smithy-go"
@@ -97,16 +107,33 @@ import (
 	"golang.org/x/exp/slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+const (
+	// infoReadRequests is the number of requests issued to read the info of an upload
+	// (GetObject for the info object, ListParts and HeadObject for the incomplete part)
+	infoReadRequests = 3
+	// maxPartsPerListing is the maximum number of parts returned by a single ListParts request
+	maxPartsPerListing = 1000
+	// defaultMaxConcurrentPartUploads is the default of Destination.MaxConcurrentPartUploads
+	defaultMaxConcurrentPartUploads = 10
+)
+
 const (
 	bucketMeta       = "bucket"
 	objectKeyMeta    = "objectKey"
 	multipartKeyMeta = "multipartKey"
 	multipartIDMeta  = "multipartID"
 	isSinglePartMeta = "isSinglePart"
+	// incompletePartChecksumMeta is the CRC32 (IEEE, hex) of the incomplete part object
+	// (see WithIncompletePartChecksum)
+	incompletePartChecksumMeta = "incompletePartChecksum"
+	// partChecksumMetaPrefix prefixes the number of a part whose MD5 (hex) is recorded
+	// (see Destination.VerifyPartsOnResume)
+	partChecksumMetaPrefix = "partMD5."
 )
 
 type s3Upload struct {
@@ -124,6 +151,10 @@ type s3Upload struct {
 	// multipartKey is the object key under which we save the multipart upload
 	multipartKey string
 
+	// metadataBucket is the S3 bucket of the info and incomplete part objects
+	// (see Destination.MetadataBucket)
+	metadataBucket string
+
 	// multipartID is the ID given by S3 to us for the multipart upload
 	multipartID string
 
@@ -132,6 +163,10 @@ type s3Upload struct {
 	// the GetInfo and writeInfo functions.
 	info *xferfile.Info
 
+	// infoMu is used to protect the metadata of the info recorded while the parts are
+	// uploaded concurrently
+	infoMu sync.Mutex
+
 	// uploadSemaphore limits the number of concurrent multipart part uploads to S3.
 	uploadSemaphore *semaphore.Weighted
 
@@ -143,6 +178,21 @@ type s3Upload struct {
 
 	// temporaryDirectory is the path where Destination will create temporary files
 	temporaryDirectory string
+
+	// scratchDirectory is the directory of the temporary files of the upload under the
+	// temporaryDirectory, empty if it has none (see Destination.ScratchDirectoryPerTransfer)
+	scratchDirectory string
+
+	// partFiles records the temporary part files of the upload until they are removed
+	partFiles tempFileSet
+
+	// smallFile reports whether the upload is a small file put without info object nor
+	// multipart upload (see WithoutInfoForSmallFiles)
+	smallFile bool
+
+	// legacyKeys reports whether the info and incomplete part objects of the upload are under
+	// their legacy keys, the upload is then resumed with them (see legacyInfoKey)
+	legacyKeys bool
 }
 
 // s3Part represents a single part of a S3 multipart upload.
@@ -153,13 +203,23 @@ type s3Part struct {
 }
 
 type Destination struct {
-	// MetadataObjectPrefix is prepended to the name of each .info and .part S3
-	// object that is created. If it is not set, then ObjectPrefix is used.
+	// MetadataObjectPrefix is prepended to the key of each .info and .part S3
+	// object that is created (e.g. "fxfer/" stores the info object of "data/a.txt"
+	// as "fxfer/data/a.txt.info"). If it is not set, they are stored next to the object.
 	//
 	// Note: With customization in the Upload server, we should not use this field, as it will be overwritten by
 	// the storage.SetFilePrefix function.
 	MetadataObjectPrefix string
 
+	// MetadataBucket is the bucket of the .info and .part S3 objects, e.g. when the bucket
+	// policy of the objects does not allow sidecar objects. If it is not set, they are stored
+	// in the bucket of the object. The credentials must be allowed to write to both buckets.
+	MetadataBucket string
+
+	// InfoSuffix is appended to the key of the object to form the key of its info object.
+	// If it is not set, ".info" is used.
+	InfoSuffix string
+
 	// MaxObjectSize is the maximum size an S3 Object can have according to S3
 	// API specifications. See link above.
 	MaxObjectSize int64
@@ -189,6 +249,13 @@ type Destination struct {
 	// range of MinPartSize to MaxPartSize.
 	PreferredPartSize int64
 
+	// PartSizeStrategy decides the size of each part, e.g. FixedPartSize or RampingPartSize. If it
+	// is not set, the parts are of PreferredPartSize, or of the smallest size fitting the upload
+	// in MaxMultipartParts parts if larger. The part layout of the source (see
+	// storage.PartLayoutMeta) and the adaptive part size (see WithAdaptivePartSize) take
+	// precedence over it.
+	PartSizeStrategy PartSizeStrategy
+
 	// MaxMultipartParts is the maximum number of parts an S3 multipart upload is
 	// allowed to have according to AWS S3 API specifications.
 	// See: http://docs.aws.amazon.com/AmazonS3/latest/dev/qfacts.html
@@ -200,11 +267,43 @@ type Destination struct {
 	// communicating with the S3 API, which can have unpredictable latency.
 	MaxBufferedParts int64
 
+	// MaxBufferedBytes bounds the bytes of the parts of a single transfer buffered in memory
+	// or on disk, from the time a part is received until it is uploaded, e.g. when the
+	// PreferredPartSize is large. Once the next part would exceed it, the Destination stops
+	// receiving from the client until enough parts are uploaded. A part larger than it is
+	// received once all the others are uploaded. Default is 0 (only MaxBufferedParts applies).
+	MaxBufferedBytes int64
+
+	// MaxConcurrentPartUploads is the maximum number of parts of a single
+	// transfer that are uploaded to S3 concurrently. Default is 10, also used if it is 0.
+	MaxConcurrentPartUploads int64
+
 	// TemporaryDirectory is the path where Destination will create temporary files
 	// on disk during the upload. An empty string ("", the default value) will
 	// cause Destination to use the operating system's default temporary directory.
+	// Before uploading the parts, the directory must have at least PreferredPartSize *
+	// MaxBufferedParts bytes available, or MaxBufferedBytes if less
+	// (storage.ErrTempDirSpaceInsufficient otherwise).
 	TemporaryDirectory string
 
+	// PartStore stores the parts of the uploads until they are uploaded, e.g. on a tmpfs or in an
+	// encrypted scratch space. If it is not set, the parts are stored in the temporary files of
+	// TemporaryDirectory, or in memory if it is TempDirUseMemory or if the
+	// FILE_TRANSFERER_S3_TEMP_MEMORY environment variable is "1". The incomplete parts of resumed
+	// uploads are downloaded to the same store, whose space is not checked if it is custom.
+	//
+	// The store of the parts, as well as TemporaryDirectory, is local to the process and never
+	// affects the resumption: the state of an upload lives in S3, so that an upload started with
+	// the parts on disk can be resumed with the parts in memory, and conversely.
+	PartStore PartStore
+
+	// ScratchDirectoryPerTransfer instructs the Destination to create the temporary files of each
+	// transfer in a scratch directory of its own under TemporaryDirectory, created along with the
+	// file and removed once it is finalized or deleted (even if it fails). The temporary files of
+	// concurrent transfers are then grouped, and those left behind by a failed transfer are removed
+	// along with its directory. The files preallocated by WithTempFilePrealloc stay in TemporaryDirectory.
+	ScratchDirectoryPerTransfer bool
+
 	// DisableContentHashes instructs the Destination to not calculate the MD5 and SHA256
 	// hashes when uploading data to S3. These hashes are used for file integrity checks
 	// and for authentication. However, these hashes also consume a significant amount of
@@ -212,31 +311,268 @@ type Destination struct {
 	// Note that this property is experimental and might be removed in the future!
 	DisableContentHashes bool
 
+	// VerifyPartsOnResume instructs the Destination to record the MD5 checksum of each part in
+	// the info as it is uploaded, and to check the ETags of the uploaded parts against them when
+	// the upload is resumed, which protects against parts corrupted by a partial write or listed
+	// inconsistently. The upload resumes from the first mismatched part, which is uploaded again
+	// along with the following ones. It requires the ETags of the parts to be the MD5 of their
+	// content (e.g. not with SSE-KMS nor SSE-C) and is ignored when DisableContentHashes is set.
+	// Note that the info is written again after each part.
+	VerifyPartsOnResume bool
+
+	// DisableObjectExistenceCheck instructs the Destination to consider an upload whose
+	// multipart upload no longer exists as completed without checking that the object
+	// exists (HeadObject). By default, an info object left behind by an object deleted
+	// out-of-band is reported as xferfile.ErrFileNotExists, so that the file is re-created.
+	DisableObjectExistenceCheck bool
+
+	// CheckObjectLock instructs the Destination to check that the object is neither under an
+	// object lock retention nor a legal hold (HeadObject) before deleting it, e.g. to re-create
+	// it from a modified source. A locked object is reported as storage.ErrDestinationImmutable
+	// rather than hidden behind a delete marker or failing the deletion with an AWS error.
+	// It requires the s3:GetObjectRetention and s3:GetObjectLegalHold permissions.
+	CheckObjectLock bool
+
+	// DisableBatchDelete instructs the Destination to delete the objects of a file one by one
+	// (DeleteObject) rather than in a batch (DeleteObjects), for the S3-compatible backends not
+	// implementing it. The objects are also deleted one by one once DeleteObjects has failed
+	// with NotImplemented or MethodNotAllowed.
+	DisableBatchDelete bool
+
+	// OperationTimeout bounds each S3 operation (e.g. UploadPart, CompleteMultipartUpload),
+	// so that a hung request fails with context.DeadlineExceeded rather than blocking the
+	// transfer as long as its context lives. The context of the caller is not canceled.
+	// It must be set before the first operation of a connection. Default is 0 (no timeout).
+	OperationTimeout time.Duration
+
+	// OperationRecorder records each S3 operation (e.g. UploadPart, CompleteMultipartUpload) with its latency and its
+	// error, e.g. to count the calls or to export their latencies (see s3.NewInstrumentedAPI).
+	// It must be set before the first operation of a connection. Default is nil (not recorded).
+	OperationRecorder s3.OperationRecorder
+
 	// logger: An instance of logr.Logger for logging purposes.
 	logger logr.Logger
 
 	// connsMu and conns are used to protect the connection pool for s3 connections
 	connsMu sync.Mutex
 	conns   map[string]*s3Client
+
+	// tempFilePrealloc is the number of temporary files pre-created in tempFiles
+	tempFilePrealloc int
+	tempFiles        *tempFilePool
+
+	// disableChunkedSigning instructs the Destination to upload the parts with a fixed Content-Length
+	// (see WithDisableChunkedSigning)
+	disableChunkedSigning bool
+
+	// preserveModTime instructs the Destination to record the modification time of the source
+	// in the user metadata of the objects (see WithPreserveModTime)
+	preserveModTime bool
+
+	// createIfNotExists instructs the Destination to complete the uploads only if the object
+	// does not exist (see WithCreateIfNotExists)
+	createIfNotExists bool
+
+	// incompletePartSemaphore limits the number of concurrent operations on the incomplete
+	// part objects across the transfers, it is nil if they are unlimited
+	incompletePartSemaphore *semaphore.Weighted
+
+	// incompletePartChecksum instructs the Destination to verify the incomplete part objects
+	// against their recorded checksum when resuming (see WithIncompletePartChecksum)
+	incompletePartChecksum bool
+
+	// smallFileThreshold is the size below which the files are put without info object
+	// (see WithoutInfoForSmallFiles), 0 if they all have one
+	smallFileThreshold int64
+
+	// smallFilesMu and smallFiles are used to protect the small files until they are finalized
+	smallFilesMu sync.Mutex
+	smallFiles   map[string]*smallFile
+
+	// partTimeout bounds each part upload and partTimeoutsBeforeReduction is the number of timed-out
+	// parts after which the part size of an upload is halved (see WithAdaptivePartSize)
+	partTimeout                 time.Duration
+	partTimeoutsBeforeReduction int
+
+	// partSizesMu and partSizes are used to protect the adaptive part sizes of the uploads
+	partSizesMu sync.Mutex
+	partSizes   map[string]*adaptivePartSize
+
+	// transferLocksMu and transferLocks are used to protect the files locked by a transfer, each
+	// channel is closed once its file is unlocked, it is nil if the transfers are not locked
+	// (see WithTransferLock)
+	transferLocksMu      sync.Mutex
+	transferLocks        map[string]chan struct{}
+	transferLockFailFast bool
+
+	// batchDeleteUnsupported is set once DeleteObjects is not implemented by the backend
+	// (see DisableBatchDelete)
+	batchDeleteUnsupported atomic.Bool
+}
+
+// debugLevel is the verbosity of the logs of the per-part events of the uploads.
+const debugLevel = 1
+
+// DestinationOption is a function that configures the Destination
+type DestinationOption func(*Destination)
+
+// WithTempFilePrealloc pre-creates a pool of n temporary files in the TemporaryDirectory,
+// the parts of the uploads are buffered in files drawn from the pool and returned to it
+// (truncated) once uploaded, which avoids creating and removing a file per part during a
+// burst of uploads. The pool grows no further than n files, an exhausted pool falls back
+// to creating files. The files are removed when the destination is closed.
+// Note: the pool is created by NewDestination, a TemporaryDirectory set afterwards only
+// applies to the files created once the pool is exhausted.
+func WithTempFilePrealloc(n int) DestinationOption {
+	return func(d *Destination) {
+		d.tempFilePrealloc = n
+	}
+}
+
+// WithDisableChunkedSigning instructs the Destination to upload the parts with their payload hash
+// computed up front and a fixed Content-Length, rather than letting the SDK stream them with the
+// aws-chunked encoding and a trailing checksum, which some S3-compatible backends do not support.
+// The checksums are then only calculated when S3 requires them.
+func WithDisableChunkedSigning() DestinationOption {
+	return func(d *Destination) {
+		d.disableChunkedSigning = true
+	}
+}
+
+// WithIncompletePartConcurrency limits the number of concurrent downloads and deletions of
+// the incomplete part objects (.part) across all the transfers of the destination, so that many
+// transfers resuming at once do not flood the S3 endpoint. The operations beyond n wait for a
+// slot, or fail with the error of their context. Default is 0 (unlimited).
+func WithIncompletePartConcurrency(n int64) DestinationOption {
+	return func(d *Destination) {
+		d.incompletePartSemaphore = nil
+		if n > 0 {
+			d.incompletePartSemaphore = semaphore.NewWeighted(n)
+		}
+	}
+}
+
+// WithIncompletePartChecksum instructs the Destination to record the checksum of the incomplete
+// part object (.part) in the info when it is written, and to verify the downloaded part against
+// it when resuming. A corrupted part is deleted and storage.ErrIncompletePartCorrupted is returned,
+// the transfer is then retried from the offset before the part, so that its tail is read again from
+// the source rather than uploaded corrupted. A part written without the option is not verified.
+func WithIncompletePartChecksum() DestinationOption {
+	return func(d *Destination) {
+		d.incompletePartChecksum = true
+	}
 }
 
-// NewDestination constructs a new storage using the supplied bucket and service object.
-func NewDestination(logger logr.Logger) (d *Destination) {
+// WithCreateIfNotExists instructs the Destination to complete the multipart uploads with the
+// If-None-Match: * precondition, so that S3 atomically rejects the completion if another writer
+// created the object in the meantime, unlike a HeadObject check which leaves a race between the
+// check and the write. The finalization then fails with storage.ErrDestinationExists and the
+// existing object is kept. The backend must support conditional writes.
+func WithCreateIfNotExists() DestinationOption {
+	return func(d *Destination) {
+		d.createIfNotExists = true
+	}
+}
+
+// WithoutInfoForSmallFiles instructs the Destination to put the files smaller than threshold
+// bytes with a single PutObject, without the info object (.info) nor the multipart upload and
+// its incomplete part (.part), which halves the number of objects of small-file-heavy workloads.
+// The info of such a file is recorded in the metadata of its object and read with HeadObject.
+// A small file cannot be resumed, an interrupted transfer starts again from the beginning.
+// Default is 0 (all the files have an info object).
+func WithoutInfoForSmallFiles(threshold int64) DestinationOption {
+	return func(d *Destination) {
+		d.smallFileThreshold = max(threshold, 0)
+	}
+}
+
+// NewDestination constructs a new storage using the supplied bucket and service object. It returns
+// the error of Validate if the options leave part sizes which cannot upload the objects.
+func NewDestination(logger logr.Logger, opts ...DestinationOption) (d *Destination, err error) {
 	d = &Destination{
-		MaxObjectSize:      5 * 1024 * 1024 * 1024 * 1024, // 5TB
-		MinPartSize:        5 * 1024 * 1024,               // 5MB
-		MaxPartSize:        5 * 1024 * 1024 * 1024,        // 5GB
-		PreferredPartSize:  50 * 1024 * 1024,              // 50MB
-		MaxMultipartParts:  10000,
-		MaxBufferedParts:   20,
-		TemporaryDirectory: "",
-		logger:             logger.WithName("s3.destination"),
-		conns:              make(map[string]*s3Client),
+		MaxObjectSize:            5 * 1024 * 1024 * 1024 * 1024, // 5TB
+		MinPartSize:              5 * 1024 * 1024,               // 5MB
+		MaxPartSize:              5 * 1024 * 1024 * 1024,        // 5GB
+		PreferredPartSize:        50 * 1024 * 1024,              // 50MB
+		MaxMultipartParts:        10000,
+		MaxBufferedParts:         20,
+		MaxConcurrentPartUploads: defaultMaxConcurrentPartUploads,
+		TemporaryDirectory:       "",
+		logger:                   logger.WithName("s3.destination"),
+		conns:                    make(map[string]*s3Client),
+		smallFiles:               make(map[string]*smallFile),
+		partSizes:                make(map[string]*adaptivePartSize),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	// the part sizes may still be set once created, they are validated again on create
+	if err = d.Validate(); err != nil {
+		return
+	}
+	if d.tempFilePrealloc > 0 {
+		var poolErr error
+		if d.tempFiles, poolErr = newTempFilePool(d.TemporaryDirectory, d.tempFilePrealloc); poolErr != nil {
+			// the parts are then buffered in files created on demand
+			d.logger.Error(poolErr, "failed to preallocate temporary files", "count", d.tempFilePrealloc)
+		}
+	}
+	return
+}
+
+// Validate returns storage.ErrPartSizeInvalid describing the violated invariant if the part sizes
+// cannot upload the objects: MinPartSize <= PreferredPartSize <= MaxPartSize, at least one part
+// and MaxObjectSize <= MaxPartSize * MaxMultipartParts. It also returns
+// storage.ErrPartConcurrencyInvalid if MaxConcurrentPartUploads is negative.
+func (d *Destination) Validate() (err error) {
+	if err = d.validatePartSizes(); err != nil {
+		return
+	}
+	if maxUploadSize := d.MaxPartSize * d.MaxMultipartParts; d.MaxObjectSize > maxUploadSize {
+		err = fmt.Errorf("%w: MaxObjectSize (%d) exceeds MaxPartSize * MaxMultipartParts (%d)",
+			storage.ErrPartSizeInvalid, d.MaxObjectSize, maxUploadSize)
+		return
+	}
+	if d.MaxConcurrentPartUploads < 0 {
+		err = fmt.Errorf("%w: MaxConcurrentPartUploads (%d) is negative",
+			storage.ErrPartConcurrencyInvalid, d.MaxConcurrentPartUploads)
+	}
+	return
+}
+
+// maxConcurrentPartUploads returns MaxConcurrentPartUploads, or its default if it is not positive
+// since a semaphore of no weight would never be acquired.
+func (d *Destination) maxConcurrentPartUploads() int64 {
+	if d.MaxConcurrentPartUploads <= 0 {
+		return defaultMaxConcurrentPartUploads
+	}
+	return d.MaxConcurrentPartUploads
+}
+
+// validatePartSizes returns storage.ErrPartSizeInvalid if the part sizes are not ordered or the
+// number of parts is not positive.
+func (d *Destination) validatePartSizes() (err error) {
+	switch {
+	case d.MinPartSize < 0:
+		err = fmt.Errorf("%w: MinPartSize (%d) is negative", storage.ErrPartSizeInvalid, d.MinPartSize)
+	case d.MinPartSize > d.PreferredPartSize:
+		err = fmt.Errorf("%w: MinPartSize (%d) exceeds PreferredPartSize (%d)",
+			storage.ErrPartSizeInvalid, d.MinPartSize, d.PreferredPartSize)
+	case d.PreferredPartSize > d.MaxPartSize:
+		err = fmt.Errorf("%w: PreferredPartSize (%d) exceeds MaxPartSize (%d)",
+			storage.ErrPartSizeInvalid, d.PreferredPartSize, d.MaxPartSize)
+	case d.PreferredPartSize <= 0:
+		err = fmt.Errorf("%w: PreferredPartSize (%d) is not positive", storage.ErrPartSizeInvalid, d.PreferredPartSize)
+	case d.MaxMultipartParts <= 0:
+		err = fmt.Errorf("%w: MaxMultipartParts (%d) is not positive", storage.ErrPartSizeInvalid, d.MaxMultipartParts)
 	}
 	return
 }
 
 func (d *Destination) Close() {
+	if d.tempFiles != nil {
+		d.tempFiles.close()
+	}
 	d.logger.Info("closed s3 destination")
 }
 
@@ -260,17 +596,209 @@ func (d *Destination) GetFileInfo(
 	return
 }
 
+// FileExists reports whether the object exists at the path, whether or not it has been
+// transferred by the destination (see storage.ExistenceChecker).
+func (d *Destination) FileExists(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (exists bool, err error) {
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
+		return
+	}
+	if _, err = s3Cli.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s3Cli.bucket),
+		Key:    aws.String(filePath),
+	}); err != nil {
+		if isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) ||
+			isAwsErrorCode(err, "NotFound") {
+			err = nil
+		}
+		return
+	}
+	return true, nil
+}
+
+// StatObject returns the size of the object as S3 reports it (HeadObject), exists is false if the
+// object is not found (see storage.ObjectStatter).
+func (d *Destination) StatObject(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (size int64, exists bool, err error) {
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
+		return
+	}
+	var res *awss3.HeadObjectOutput
+	if res, err = s3Cli.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s3Cli.bucket),
+		Key:    aws.String(filePath),
+	}); err != nil {
+		if isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) ||
+			isAwsErrorCode(err, "NotFound") {
+			err = nil
+		}
+		return
+	}
+	return lo.FromPtr(res.ContentLength), true, nil
+}
+
+// ResumeState returns the resumption state of the object, the upload identity is the ID
+// of its multipart upload (see storage.ResumeStateReporter).
+func (d *Destination) ResumeState(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (state storage.ResumeState, err error) {
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
+		return
+	}
+
+	upload := d.getUpload(filePath, s3Cli.bucket, s3Cli.client)
+	if err = upload.setInternalInfo(ctx); err != nil {
+		return
+	}
+	state = storage.ResumeState{
+		Path:      upload.info.Path,
+		Size:      upload.info.Size,
+		Offset:    upload.info.Offset,
+		UploadID:  upload.info.Metadata[multipartIDMeta],
+		PartCount: len(upload.parts),
+		StartTime: upload.info.StartTime,
+		Finished:  !upload.info.FinishTime.IsZero(),
+	}
+	return
+}
+
+// Preflight writes, reads and deletes a throwaway object next to the object, then creates,
+// lists and aborts a multipart upload of it, each denied request is reported with the
+// permission it requires (see storage.Preflighter).
+func (d *Destination) Preflight(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (err error) {
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
+		return
+	}
+	client := s3Cli.client
+	bucket := aws.String(s3Cli.bucket)
+	key := path.Join(path.Dir(filePath), fmt.Sprintf(".fxfer-preflight-%016x", rand.Uint64()))
+
+	// the info objects are written, read and deleted (see MetadataBucket and MetadataObjectPrefix)
+	metadataBucket := aws.String(cmp.Or(d.MetadataBucket, s3Cli.bucket))
+	metadataKey := d.MetadataObjectPrefix + key
+	if _, err = client.PutObject(ctx, &awss3.PutObjectInput{
+		Bucket: metadataBucket,
+		Key:    aws.String(metadataKey),
+		Body:   bytes.NewReader(nil),
+	}); err != nil {
+		return preflightError("s3:PutObject", metadataKey, err)
+	}
+	obj, getErr := client.GetObject(ctx, &awss3.GetObjectInput{Bucket: metadataBucket, Key: aws.String(metadataKey)})
+	if getErr == nil {
+		_ = obj.Body.Close()
+	}
+	_, deleteErr := client.DeleteObject(ctx, &awss3.DeleteObjectInput{Bucket: metadataBucket, Key: aws.String(metadataKey)})
+	if err = errors.Join(
+		preflightError("s3:GetObject", metadataKey, getErr),
+		preflightError("s3:DeleteObject", metadataKey, deleteErr),
+	); err != nil {
+		return
+	}
+
+	// the content is uploaded in a multipart upload, listed when resuming and aborted when deleted
+	var res *awss3.CreateMultipartUploadOutput
+	if res, err = client.CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
+		Bucket: bucket,
+		Key:    aws.String(key),
+	}); err != nil {
+		return preflightError("s3:PutObject", key, err)
+	}
+	_, listErr := client.ListParts(ctx, &awss3.ListPartsInput{
+		Bucket:   bucket,
+		Key:      aws.String(key),
+		UploadId: res.UploadId,
+	})
+	_, abortErr := client.AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{
+		Bucket:   bucket,
+		Key:      aws.String(key),
+		UploadId: res.UploadId,
+	})
+	return errors.Join(
+		preflightError("s3:ListMultipartUploadParts", key, listErr),
+		preflightError("s3:AbortMultipartUpload", key, abortErr),
+	)
+}
+
+// checkObjectLock returns storage.ErrDestinationImmutable if the object is under an active
+// retention or a legal hold (see Destination.CheckObjectLock), a missing object is not locked.
+func checkObjectLock(ctx context.Context, s3Cli *s3Client, key string) (err error) {
+	var res *awss3.HeadObjectOutput
+	if res, err = s3Cli.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s3Cli.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		if isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) || isAwsErrorCode(err, "NotFound") {
+			err = nil
+		}
+		return
+	}
+	if res.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn {
+		return fmt.Errorf("%w: %s is under a legal hold", storage.ErrDestinationImmutable, key)
+	}
+	if res.ObjectLockRetainUntilDate != nil && res.ObjectLockRetainUntilDate.After(time.Now()) {
+		return fmt.Errorf("%w: %s is retained in %s mode until %s", storage.ErrDestinationImmutable,
+			key, res.ObjectLockMode, res.ObjectLockRetainUntilDate.Format(time.RFC3339))
+	}
+	return
+}
+
+// preflightError returns the error of a preflight request requiring the permission,
+// marked with storage.ErrPermissionMissing if the request is denied.
+func preflightError(permission, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if isAwsErrorCode(err, "AccessDenied") || isAwsErrorCode(err, "Forbidden") {
+		return fmt.Errorf("%w: %s on %s: %w", storage.ErrPermissionMissing, permission, key, err)
+	}
+	return fmt.Errorf("%s on %s: %w", permission, key, err)
+}
+
 func (d *Destination) CreateFile(
 	ctx context.Context,
 	path string, size int64, modTime time.Time,
 	cli protoc.Client,
+) (err error) {
+	return d.CreateFileWithMetadata(ctx, path, size, modTime, nil, cli)
+}
+
+func (d *Destination) CreateFileWithMetadata(
+	ctx context.Context,
+	path string, size int64, modTime time.Time, metadata map[string]string,
+	cli protoc.Client,
 ) (err error) {
 	if size > d.MaxObjectSize {
 		return fmt.Errorf("file size exceeds maximum object size (%d > %d)", size, d.MaxObjectSize)
 	}
+	// the part sizes are validated against the size of the file rather than MaxObjectSize,
+	// so that a misconfiguration fails the creation rather than the upload of the parts
+	if err = d.validatePartSizes(); err != nil {
+		return
+	}
+	if size != xferfile.SizeUnknown {
+		if _, err = d.partSize(size, 0); err != nil {
+			return
+		}
+	}
 
-	var prefix, fileName, fileExt string
-	if prefix, fileName, fileExt, err = fileutils.ExtractFileParts(path); err != nil {
+	var fileName, fileExt string
+	if _, fileName, fileExt, err = fileutils.ExtractFileParts(path); err != nil {
 		return
 	}
 
@@ -279,6 +807,10 @@ func (d *Destination) CreateFile(
 		return
 	}
 
+	if srcExt, ok := metadata[storage.SourceExtensionMeta]; ok {
+		fileExt = srcExt
+	}
+
 	// prepare transfer file info
 	info := xferfile.Info{
 		Path:      path,
@@ -289,40 +821,54 @@ func (d *Destination) CreateFile(
 		Extension: fileExt,
 	}
 
+	// the small file is put whole by TransferFileChunk, its info is kept until then
+	if d.isSmallFile(size) {
+		info.Metadata = maps.Clone(metadata)
+		if d.fitsSmallFileInfo(info) {
+			d.setSmallFile(s3Cli.bucket, path, &smallFile{info: info})
+			return
+		}
+	}
+
 	res, err := s3Cli.client.CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
-		Bucket: aws.String(s3Cli.bucket),
-		Key:    &path,
+		Bucket:      aws.String(s3Cli.bucket),
+		Key:         &path,
+		Metadata:    d.modTimeMetadata(modTime),
+		ContentType: lo.EmptyableToPtr(metadata[storage.ContentTypeMeta]),
 	})
 	if err != nil {
 		return fmt.Errorf("unable to create multipart upload: %w", err)
 	}
 
-	// store the multipart upload ID in the metadata
+	// store the multipart upload ID in the metadata, along with the caller metadata
 	info.Metadata = map[string]string{
 		bucketMeta:       s3Cli.bucket,
 		objectKeyMeta:    path,
-		multipartKeyMeta: filepath.Join(prefix, fileName+".part"),
+		multipartKeyMeta: d.generateMultipartKey(path),
 		multipartIDMeta:  *res.UploadId,
 	}
+	for key, value := range metadata {
+		if _, reserved := info.Metadata[key]; !reserved {
+			info.Metadata[key] = value
+		}
+	}
 
-	// if size < MinPartSize, we can upload the file in a single part
-	if size <= d.MinPartSize {
+	// if size < MinPartSize, we can upload the file in a single part, a file of an unknown size
+	// is streamed in parts until its end (see xferfile.SizeUnknown)
+	if size >= 0 && size <= d.MinPartSize {
 		info.Metadata[isSinglePartMeta] = "true"
 	}
 
 	// create the info file
-	upload := &s3Upload{
-		bucket:             s3Cli.bucket,
-		objectKey:          path,
-		multipartID:        *res.UploadId,
-		client:             s3Cli.client,
-		info:               &info,
-		parts:              make([]*s3Part, 0),
-		temporaryDirectory: d.TemporaryDirectory,
-	}
+	upload := d.getUpload(path, s3Cli.bucket, s3Cli.client)
+	upload.multipartID = *res.UploadId
+	upload.info = &info
 	if err = upload.writeInfo(ctx, info); err != nil {
 		return fmt.Errorf("unable to create info file: %w", err)
 	}
+	if err = upload.createScratchDirectory(); err != nil {
+		return fmt.Errorf("unable to create scratch directory: %w", err)
+	}
 	return
 }
 
@@ -333,6 +879,8 @@ func (d *Destination) TransferFileChunk(
 	offset int64,
 	cli protoc.Client,
 ) (n int64, err error) {
+	// the throttling errors are marked, so that the transfer can cool down
+	defer func() { err = wrapThrottleError(err) }()
 	var s3Cli *s3Client
 	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
 		return
@@ -340,12 +888,26 @@ func (d *Destination) TransferFileChunk(
 
 	// get the upload object
 	upload := d.getUpload(filePath, s3Cli.bucket, s3Cli.client)
+	// the scratch directory is created again when resuming after it has been removed
+	if err = upload.createScratchDirectory(); err != nil {
+		return 0, fmt.Errorf("unable to create scratch directory: %w", err)
+	}
+	// remove any temporary file left behind, even if the transfer panics
+	defer func() {
+		if cleanUpErr := upload.partFiles.removeAll(); cleanUpErr != nil {
+			d.logger.Error(cleanUpErr, "failed to remove temporary files", "path", filePath)
+		}
+	}()
 
 	// set the info upload if it is not set yet
 	if err = upload.setInternalInfo(ctx); err != nil {
 		return
 	}
 	incompletePartSize := upload.incompletePartSize
+	tracker, _ := src.(storage.ConfirmedSizeTracker)
+	if upload.smallFile {
+		return upload.putSmallFile(ctx, src, tracker)
+	}
 
 	// create a transfer reader with rate limiting
 	transferReader := iometer.NewTransferReader(src, &offset)
@@ -355,49 +917,143 @@ func (d *Destination) TransferFileChunk(
 	// get the total size of the current upload, number of parts to generate next number and whether
 	// an incomplete part exists
 	if incompletePartSize > 0 {
-		var incompletePartFile *os.File
-		if incompletePartFile, err = upload.downloadIncompletePartForUpload(ctx); err != nil {
+		var (
+			incompletePart        io.ReadSeeker
+			releaseIncompletePart func() error
+		)
+		if incompletePart, releaseIncompletePart, err = upload.downloadIncompletePartForUpload(ctx); err != nil {
 			return 0, err
 		}
-		if incompletePartFile == nil {
+		if incompletePart == nil {
 			return 0, fmt.Errorf("expected an incomplete part file but did not get any")
 		}
-		defer cleanUpTempFile(incompletePartFile)
+		defer func() { _ = releaseIncompletePart() }()
 
 		if err = upload.deleteIncompletePartForUpload(ctx); err != nil {
 			return 0, err
 		}
 
 		// prepend an incomplete part, if necessary and adapt the offset
-		src = io.MultiReader(incompletePartFile, src)
+		src = io.MultiReader(incompletePart, src)
 		offset = offset - incompletePartSize
 	}
 
-	bytesUploaded, err := upload.uploadParts(ctx, offset, src)
+	bytesUploaded, err := upload.uploadParts(ctx, offset, src, tracker)
 
 	// the size of the incomplete part should not be counted, because the
 	// process of the incomplete part should be fully transparent to the user.
 	bytesUploaded = max(bytesUploaded - incompletePartSize, 0)
 
 	upload.info.Offset += bytesUploaded
-	if upload.info.Size == 0 {
-		upload.info.Size = bytesUploaded
-	}
 	return bytesUploaded, err
 }
 
+// CanCopyFrom reports whether the objects of the source storage can be copied server-side,
+// i.e. the source is an S3 Source served by the same endpoint and region as the destination.
+// The credentials of the destination must be allowed to read the source objects.
+func (d *Destination) CanCopyFrom(src storage.Source, srcClient protoc.Client, cli protoc.Client) bool {
+	if _, ok := src.(*Source); !ok {
+		return false
+	}
+	srcCred, ok := srcClient.GetCredential().(s3.Client)
+	if !ok {
+		return false
+	}
+	cred, ok := cli.GetCredential().(s3.Client)
+	if !ok {
+		return false
+	}
+	return srcCred.Endpoint == cred.Endpoint && srcCred.Region == cred.Region
+}
+
+// CopyFileFrom copies the source object from the offset to its end into the multipart upload
+// with UploadPartCopy, so the content is never downloaded. An incomplete part left by a previous
+// streaming transfer is discarded and its range is copied again.
+func (d *Destination) CopyFileFrom(
+	ctx context.Context,
+	filePath, srcPath string, srcClient protoc.Client,
+	offset int64,
+	cli protoc.Client,
+	tracker storage.ConfirmedSizeTracker,
+) (n int64, err error) {
+	// the throttling errors are marked, so that the transfer can cool down
+	defer func() { err = wrapThrottleError(err) }()
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(cli); err != nil {
+		return
+	}
+	srcCred, ok := srcClient.GetCredential().(s3.Client)
+	if !ok {
+		err = storage.ErrS3ProtocolClientInvalid
+		return
+	}
+
+	// get the upload object
+	upload := d.getUpload(filePath, s3Cli.bucket, s3Cli.client)
+
+	// set the info upload if it is not set yet
+	if err = upload.setInternalInfo(ctx); err != nil {
+		return
+	}
+	if upload.smallFile {
+		return upload.copySmallFile(ctx, srcCred.BucketName, srcPath, tracker)
+	}
+	incompletePartSize := upload.incompletePartSize
+	if incompletePartSize > 0 {
+		if err = upload.deleteIncompletePartForUpload(ctx); err != nil {
+			return
+		}
+		offset = offset - incompletePartSize
+	}
+
+	bytesCopied, err := upload.copyParts(ctx, srcCred.BucketName, srcPath, offset, tracker)
+
+	// the size of the incomplete part should not be counted, because the
+	// process of the incomplete part should be fully transparent to the user.
+	bytesCopied = max(bytesCopied-incompletePartSize, 0)
+
+	upload.info.Offset += bytesCopied
+	return bytesCopied, err
+}
+
 func (d *Destination) FinalizeTransfer(ctx context.Context, filePath string, protocol protoc.Client) (err error) {
+	_, err = d.FinalizeTransferWithObject(ctx, filePath, protocol)
+	return
+}
+
+// FinalizeTransferWithObject completes the multipart upload of the file and returns the ETag,
+// the version and the location of the completed object (see storage.ObjectFinalizer).
+func (d *Destination) FinalizeTransferWithObject(
+	ctx context.Context,
+	filePath string,
+	protocol protoc.Client,
+) (object storage.FinalizedObject, err error) {
+	// the throttling errors are marked, so that the transfer can cool down
+	defer func() { err = wrapThrottleError(err) }()
 	var s3Cli *s3Client
 	if s3Cli, err = d.checkAndSetClient(protocol); err != nil {
 		return
 	}
 
 	upload := d.getUpload(filePath, s3Cli.bucket, s3Cli.client)
+	// the scratch directory is removed even if the finalization fails, a retry creates it again
+	defer upload.removeScratchDirectory()
 
 	// set the info upload if it is not set yet
 	if err = upload.setInternalInfo(ctx); err != nil {
 		return
 	}
+	if upload.smallFile {
+		return upload.finalizeSmallFile()
+	}
+	// the size of a file of an unknown size is the total uploaded, including its last chunk
+	// kept as the incomplete part
+	unknownSize := upload.info.Size == xferfile.SizeUnknown
+	if unknownSize && upload.incompletePartSize > 0 {
+		if err = upload.uploadIncompletePartAsLast(ctx); err != nil {
+			return
+		}
+	}
 	parts := upload.parts
 
 	if len(parts) == 0 {
@@ -411,18 +1067,24 @@ func (d *Destination) FinalizeTransfer(ctx context.Context, filePath string, pro
 			UploadId:   aws.String(upload.multipartID),
 			PartNumber: aws.Int32(1),
 			Body:       bytes.NewReader([]byte{}),
-		}); err != nil {
+		}, d.uploadPartOptions()...); err != nil {
 			return
 		}
 		parts = []*s3Part{
 			{
-				etag:   *res.ETag,
+				etag:   aws.ToString(res.ETag),
 				number: 1,
 				size:   0,
 			},
 		}
 	}
 
+	// a part without ETag cannot be completed, S3 would reject the whole upload
+	if part, found := lo.Find(parts, func(p *s3Part) bool { return p.etag == "" }); found {
+		err = fmt.Errorf("%w: part %d", storage.ErrPartETagMissing, part.number)
+		return
+	}
+
 	totalPartSize := int64(0)
 	completedParts := lo.Map(parts, func(p *s3Part, _ int) types.CompletedPart {
 		totalPartSize += p.size
@@ -432,24 +1094,63 @@ func (d *Destination) FinalizeTransfer(ctx context.Context, filePath string, pro
 		}
 	})
 
+	if unknownSize {
+		upload.info.Size = totalPartSize
+	}
 	if totalPartSize != upload.info.Size {
-		return storage.ErrFileOrObjectCannotFinalize
+		err = storage.ErrFileOrObjectCannotFinalize
+		return
 	}
 
-	if _, err = upload.client.CompleteMultipartUpload(ctx, &awss3.CompleteMultipartUploadInput{
+	completeInput := &awss3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(upload.bucket),
 		Key:      aws.String(upload.objectKey),
 		UploadId: aws.String(upload.multipartID),
 		MultipartUpload: &types.CompletedMultipartUpload{
 			Parts: completedParts,
 		},
-	}); err != nil {
+	}
+	if d.createIfNotExists {
+		completeInput.IfNoneMatch = aws.String("*")
+	}
+	var output *awss3.CompleteMultipartUploadOutput
+	if output, err = upload.client.CompleteMultipartUpload(ctx, completeInput); err != nil {
+		switch {
+		case d.createIfNotExists && isPreconditionFailed(err):
+			err = fmt.Errorf("%w: %s", storage.ErrDestinationExists, upload.objectKey)
+		case isAwsErrorCode(err, "EntityTooSmall"):
+			err = d.partTooSmallError(parts, err)
+		}
 		return
 	}
 
 	upload.info.Offset = upload.info.Size
 	upload.info.FinishTime = time.Now()
-	return upload.writeInfo(ctx, *upload.info)
+	if err = upload.writeInfo(ctx, *upload.info); err != nil {
+		return
+	}
+	d.forgetAdaptivePartSize(upload.bucket, upload.objectKey)
+	if output != nil {
+		object = storage.FinalizedObject{
+			ETag:      aws.ToString(output.ETag),
+			VersionID: aws.ToString(output.VersionId),
+			Location:  aws.ToString(output.Location),
+		}
+	}
+	return
+}
+
+// partTooSmallError explains the EntityTooSmall error of the completion of the parts: a part
+// other than the last is smaller than the minimum part size of the storage, the smallest one is
+// reported with the part sizes of the destination to increase.
+func (d *Destination) partTooSmallError(parts []*s3Part, err error) error {
+	if len(parts) < 2 {
+		return fmt.Errorf("%w: %w", storage.ErrPartTooSmall, err)
+	}
+	part := lo.MinBy(parts[:len(parts)-1], func(a, b *s3Part) bool { return a.size < b.size })
+	return fmt.Errorf(
+		"%w: part %d is %d bytes, increase MinPartSize (%d) and PreferredPartSize (%d) to at least the minimum part size of the storage: %w",
+		storage.ErrPartTooSmall, part.number, part.size, d.MinPartSize, d.PreferredPartSize, err)
 }
 
 func (d *Destination) DeleteFile(ctx context.Context, filePath string, protocol protoc.Client) (err error) {
@@ -458,75 +1159,112 @@ func (d *Destination) DeleteFile(ctx context.Context, filePath string, protocol
 		return
 	}
 
+	if d.CheckObjectLock {
+		if err = checkObjectLock(ctx, s3Cli, filePath); err != nil {
+			return
+		}
+	}
+
 	upload := d.getUpload(filePath, s3Cli.bucket, s3Cli.client)
+	defer upload.removeScratchDirectory()
+	defer d.forgetAdaptivePartSize(s3Cli.bucket, filePath)
 
 	// set the info upload if it is not set yet
 	if err = upload.setInternalInfo(ctx); err != nil {
 		return
 	}
+	if upload.smallFile {
+		return upload.deleteSmallFile(ctx)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
-	errs := make([]error, 0, 3)
+	// each goroutine has its own errors, they are joined once both are done
+	var abortErr error
+	var deleteErrs []error
 
 	go func() {
 		defer wg.Done()
 
 		// abort the multipart upload
-		if _, err = upload.client.AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{
+		if _, err := upload.client.AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{
 			Bucket:   aws.String(s3Cli.bucket),
 			Key:      &filePath,
 			UploadId: aws.String(upload.multipartID),
 		}); err != nil && !isAwsError[*types.NoSuchUpload](err) {
-			errs = append(errs, err)
+			abortErr = err
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
 
-		var infoPath string
-		if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+		infoKey, err := upload.infoKey()
+		if err != nil {
+			deleteErrs = append(deleteErrs, err)
 			return
 		}
 
-		// delete the info and content files
-		var res *awss3.DeleteObjectsOutput
-		if res, err = upload.client.DeleteObjects(ctx, &awss3.DeleteObjectsInput{
-			Bucket: aws.String(s3Cli.bucket),
-			Delete: &types.Delete{
-				Objects: []types.ObjectIdentifier{
-					{
-						Key: &filePath,
-					},
-					{
-						Key: lo.ToPtr(upload.info.Metadata[multipartKeyMeta]),
-					},
-					{
-						Key: &infoPath,
-					},
-				},
-				Quiet: aws.Bool(true),
-			},
-		}); err != nil {
-			errs = append(errs, err)
-			return
+		// delete the content file, and the info and incomplete part files which may be
+		// in another bucket (see MetadataBucket)
+		objectsByBucket := map[string][]types.ObjectIdentifier{
+			s3Cli.bucket: {{Key: &filePath}},
 		}
-
-		for _, s3Err := range res.Errors {
-			if *s3Err.Code != "NoSuchKey" {
-				errs = append(errs, fmt.Errorf("AWS S3 Error (%s) for object %s: %s", *s3Err.Code, *s3Err.Key, *s3Err.Message))
-			}
+		objectsByBucket[upload.metadataBucket] = append(objectsByBucket[upload.metadataBucket],
+			types.ObjectIdentifier{Key: lo.ToPtr(upload.info.Metadata[multipartKeyMeta])},
+			types.ObjectIdentifier{Key: &infoKey},
+		)
+		for bucket, objects := range objectsByBucket {
+			deleteErrs = append(deleteErrs, d.deleteObjects(ctx, upload.client, bucket, objects)...)
 		}
 	}()
 
 	wg.Wait()
 
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	return errors.Join(append(deleteErrs, abortErr)...)
+}
+
+// deleteObjects deletes the objects of the bucket in a batch (DeleteObjects), or one by one if
+// the batch deletion is disabled or not implemented by the backend (see DisableBatchDelete).
+func (d *Destination) deleteObjects(
+	ctx context.Context,
+	client protoc.S3API,
+	bucket string,
+	objects []types.ObjectIdentifier,
+) (errs []error) {
+	if !d.DisableBatchDelete && !d.batchDeleteUnsupported.Load() {
+		res, err := client.DeleteObjects(ctx, &awss3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err == nil {
+			for _, s3Err := range res.Errors {
+				if *s3Err.Code != "NoSuchKey" {
+					errs = append(errs, fmt.Errorf("AWS S3 Error (%s) for object %s: %s", *s3Err.Code, *s3Err.Key, *s3Err.Message))
+				}
+			}
+			return
+		}
+		if !isAwsErrorCode(err, "NotImplemented") && !isAwsErrorCode(err, "MethodNotAllowed") {
+			return []error{err}
+		}
+		d.batchDeleteUnsupported.Store(true)
+		d.logger.Info("DeleteObjects is not implemented by the backend, deleting the objects one by one",
+			"bucket", bucket, "error", err.Error())
 	}
 
-	return nil
+	for _, object := range objects {
+		if _, err := client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    object.Key,
+		}); err != nil && !isAwsError[*types.NoSuchKey](err) {
+			errs = append(errs, err)
+		}
+	}
+	return
 }
 
 func (d *Destination) getUpload(
@@ -534,15 +1272,77 @@ func (d *Destination) getUpload(
 	bucket string,
 	client protoc.S3API,
 ) (upload *s3Upload) {
+	scratchDirectory := d.scratchDirectory(bucket, filePath)
 	upload = &s3Upload{
 		store:              d,
 		bucket:             bucket,
 		client:             client,
 		objectKey:          filePath,
-		multipartKey:       fmt.Sprintf("%s.part", strings.TrimSuffix(filePath, filepath.Ext(filePath))),
+		multipartKey:       d.generateMultipartKey(filePath),
+		metadataBucket:     cmp.Or(d.MetadataBucket, bucket),
 		parts:              make([]*s3Part, 0),
-		temporaryDirectory: d.TemporaryDirectory,
-		uploadSemaphore:    semaphore.NewWeighted(10),
+		temporaryDirectory: cmp.Or(scratchDirectory, d.TemporaryDirectory),
+		scratchDirectory:   scratchDirectory,
+		uploadSemaphore:    semaphore.NewWeighted(d.maxConcurrentPartUploads()),
+	}
+	return
+}
+
+// EstimateTransfer estimates the cost of a fresh transfer of a file of the given size,
+// driven by fxfer.Transfer, with the current part size and concurrency configuration.
+func (d *Destination) EstimateTransfer(size int64) (estimate storage.TransferEstimate, err error) {
+	if size > d.MaxObjectSize {
+		err = fmt.Errorf("file size exceeds maximum object size (%d > %d)", size, d.MaxObjectSize)
+		return
+	}
+	// the small file is put whole, once its info has been read (GetObject for the info object,
+	// HeadObject for the object) before creating it
+	if d.isSmallFile(size) {
+		estimate = storage.TransferEstimate{Requests: 3, TransferredBytes: size}
+		return
+	}
+	var partSize int64
+	if partSize, err = d.partSize(size, 0); err != nil {
+		return
+	}
+
+	// AWS expects at least one part, so an empty file is completed with an empty part
+	partUploads := int64(1)
+	for uploaded := partSize; uploaded < size; partUploads++ {
+		var nextPartSize int64
+		if nextPartSize, err = d.partSize(size, partUploads); err != nil {
+			return
+		}
+		uploaded += nextPartSize
+	}
+	// ListParts returns at most maxPartsPerListing parts per page
+	listPartsPages := max((partUploads+maxPartsPerListing-1)/maxPartsPerListing, 1)
+
+	estimate = storage.TransferEstimate{
+		// the info of the upload is read by GetFileInfo (before and after CreateFile) and
+		// TransferFileChunk, each one issuing GetObject (info), ListParts and HeadObject (incomplete part)
+		Requests: 3*infoReadRequests +
+			// CreateFile: CreateMultipartUpload and PutObject (info)
+			2 +
+			// TransferFileChunk: UploadPart for each part
+			partUploads +
+			// FinalizeTransfer: GetObject (info), ListParts pages, HeadObject (incomplete part),
+			// CompleteMultipartUpload and PutObject (info)
+			2 + listPartsPages + 2,
+		PartUploads:      partUploads,
+		PartSize:         partSize,
+		TransferredBytes: size,
+	}
+
+	// the parts are buffered in memory instead of on disk
+	if d.TemporaryDirectory == TempDirUseMemory {
+		return
+	}
+	// the parts being uploaded, the parts waiting in the buffer and the part being produced
+	bufferedParts := d.maxConcurrentPartUploads() + d.MaxBufferedParts + 1
+	estimate.TempDiskHighWaterMark = min(size, bufferedParts*partSize)
+	if d.MaxBufferedBytes > 0 {
+		estimate.TempDiskHighWaterMark = min(estimate.TempDiskHighWaterMark, max(d.MaxBufferedBytes, partSize))
 	}
 	return
 }
@@ -606,7 +1406,10 @@ func (d *Destination) checkAndSetClient(protocol protoc.Client) (conn *s3Client,
 		}
 		conn = &s3Client{
 			bucket: cred.BucketName,
-			client: client,
+			client: s3.NewInstrumentedAPI(
+				withExpectedBucketOwner(withOperationTimeout(client, d.OperationTimeout), cred.ExpectedBucketOwner),
+				d.OperationRecorder,
+			),
 		}
 		d.conns[connID] = conn
 	}
@@ -619,43 +1422,81 @@ func (u *s3Upload) writeInfo(ctx context.Context, info xferfile.Info) (err error
 		return
 	}
 	// create object on S3 containing information about the file
-	var infoPath string
-	if infoPath, err = xferfile.GenerateInfoPath(info.Path); err != nil {
+	var infoKey string
+	if infoKey, err = u.infoKey(); err != nil {
 		return
 	}
 	_, err = u.client.PutObject(ctx, &awss3.PutObjectInput{
-		Bucket:        aws.String(u.bucket),
-		Key:           &infoPath,
+		Bucket:        aws.String(u.metadataBucket),
+		Key:           &infoKey,
 		Body:          bytes.NewReader(jsonInfo),
 		ContentLength: aws.Int64(int64(len(jsonInfo))),
 	})
 	return
 }
 
-func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader) (int64, error) {
+func (u *s3Upload) uploadParts(
+	ctx context.Context,
+	offset int64,
+	src io.Reader,
+	tracker storage.ConfirmedSizeTracker,
+) (int64, error) {
 	store := u.store
 	parts := u.parts
 
+	// the first chunk starts with the prepended incomplete part (if any), whose
+	// bytes have already been confirmed by a previous transfer
+	prependedSize := u.incompletePartSize
+	confirm := func(n int64) {
+		if tracker != nil && n > 0 {
+			tracker.AddConfirmedSize(n)
+		}
+	}
+
 	size := u.info.Size
 	bytesUploaded := int64(0)
-	optimalPartSize, err := store.calcOptimalPartSize(size)
+	numParts := len(parts)
+	nextPartNum := int32(numParts + 1)
+	partSize := func(i int) (int64, error) {
+		return store.partSize(size, int64(numParts+i))
+	}
+	firstPartSize, err := partSize(0)
 	if err != nil {
 		return 0, err
 	}
 
-	numParts := len(parts)
-	nextPartNum := int32(numParts + 1)
+	partLayout, err := u.remainingPartLayout(offset)
+	if err != nil {
+		return 0, err
+	}
 
-	partProducer, fileChan := newS3PartProducer(src, store.MaxBufferedParts, store.TemporaryDirectory)
+	partProducer, fileChan := newS3PartProducer(src, store.MaxBufferedParts, u.temporaryDirectory)
+	if err = store.checkTempDirSpace(partProducer.tmpDir); err != nil {
+		return 0, err
+	}
+	partProducer.store = store.PartStore
+	partProducer.tempFiles = store.tempFiles
+	partProducer.partFiles = &u.partFiles
+	partProducer.limitBufferedBytes(store.MaxBufferedBytes)
 
 	producerCtx, cancelProducer := context.WithCancel(ctx)
 	defer func() {
 		cancelProducer()
 		partProducer.closeUnreadFiles()
 	}()
-	go partProducer.produce(producerCtx, optimalPartSize)
+	// the size of the parts produced follows the adaptive part size, if any
+	var adaptiveSize *adaptivePartSize
+	if len(partLayout) > 0 {
+		go partProducer.produceLayout(producerCtx, partLayout)
+	} else if adaptiveSize = store.adaptivePartSizeOf(u, firstPartSize); adaptiveSize != nil {
+		go partProducer.produceParts(producerCtx, func(int) (int64, error) { return adaptiveSize.get(), nil })
+	} else {
+		go partProducer.produceParts(producerCtx, partSize)
+	}
 
 	var eg errgroup.Group
+	// no part is uploaded once one has failed, so that the uploaded parts have no gap
+	var failed atomic.Bool
 
 	for {
 		// we acquire the semaphore before starting the goroutine to avoid
@@ -666,7 +1507,10 @@ func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader)
 			return 0, err
 		}
 		chunk, more := <-fileChan
-		if !more {
+		if !more || failed.Load() {
+			if more {
+				_ = chunk.closeReader()
+			}
 			u.releaseUploadSemaphore()
 			break
 		}
@@ -674,10 +1518,24 @@ func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader)
 		partFile := chunk.reader
 		partSize := chunk.size
 		closePart := chunk.closeReader
-		isSinglePart := u.info.Metadata[isSinglePartMeta] == "true"
 		isFinalChunk := size == offset+bytesUploaded+partSize
+		// the single part of a file is only uploaded whole (as its final chunk), the chunk of an
+		// interrupted transfer is kept as the incomplete part which the resumed transfer prepends,
+		// rather than uploaded as a part too small to be followed by another one. The end of a file
+		// of an unknown size is only known once it is finalized, so its last chunk is kept as the
+		// incomplete part too, the finalization uploads it as the last part.
+		confirmedSize := partSize
+		if bytesUploaded == 0 {
+			confirmedSize -= prependedSize
+		}
+		isCompletePart := partSize >= store.MinPartSize
+		if partLayout != nil {
+			// a part shorter than its layout part is kept as the incomplete part
+			layoutIdx := int(nextPartNum) - numParts - 1
+			isCompletePart = layoutIdx < len(partLayout) && partSize == partLayout[layoutIdx]
+		}
 
-		if partSize >= store.MinPartSize || isFinalChunk || isSinglePart {
+		if isCompletePart || isFinalChunk {
 			part := &s3Part{
 				etag:   "",
 				size:   partSize,
@@ -694,13 +1552,30 @@ func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader)
 					UploadId:   aws.String(u.multipartID),
 					PartNumber: aws.Int32(part.number),
 				}
-				etag, err := u.putPartForUpload(ctx, uploadPartInput, partFile, part.size)
+				var checksum string
+				if store.verifiesParts() {
+					checksum, err = md5Checksum(partFile)
+				}
+				var etag string
+				if err == nil {
+					etag, err = u.putPartWithTimeout(ctx, uploadPartInput, partFile, part.size, adaptiveSize)
+				}
+				if err == nil && etag == "" {
+					err = fmt.Errorf("%w: part %d", storage.ErrPartETagMissing, part.number)
+				}
+				if err == nil && checksum != "" {
+					err = u.recordPartChecksum(ctx, part.number, checksum)
+				}
 				if err == nil {
 					part.etag = etag
+					confirm(confirmedSize)
+					store.logger.V(debugLevel).Info("part is uploaded",
+						"objectKey", u.objectKey, "partNumber", part.number, "size", part.size)
 				}
 
 				closeErr := closePart()
 				if err != nil {
+					failed.Store(true)
 					return err
 				}
 				if closeErr != nil {
@@ -714,6 +1589,9 @@ func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader)
 
 				if err = u.putIncompletePartForUpload(ctx, partFile); err == nil {
 					u.incompletePartSize = partSize
+					confirm(confirmedSize)
+					store.logger.V(debugLevel).Info("incomplete part is stored",
+						"objectKey", u.objectKey, "size", partSize)
 				}
 
 				closeErr := closePart()
@@ -738,6 +1616,120 @@ func (u *s3Upload) uploadParts(ctx context.Context, offset int64, src io.Reader)
 	return bytesUploaded, partProducer.err
 }
 
+// copyParts copies the range of the source object from the offset to the end of the upload
+// into new parts with UploadPartCopy.
+func (u *s3Upload) copyParts(
+	ctx context.Context,
+	srcBucket, srcKey string,
+	offset int64,
+	tracker storage.ConfirmedSizeTracker,
+) (int64, error) {
+	store := u.store
+
+	// the first part starts with the range of the discarded incomplete part (if any),
+	// whose bytes have already been confirmed by a previous transfer
+	prependedSize := u.incompletePartSize
+	confirm := func(n int64) {
+		if tracker != nil && n > 0 {
+			tracker.AddConfirmedSize(n)
+		}
+	}
+	// the tracker may hold the copy between the parts (e.g. while the transfer is paused)
+	pauser, _ := tracker.(storage.CopyPauser)
+
+	size := u.info.Size
+	partLayout, err := u.remainingPartLayout(offset)
+	if err != nil {
+		return 0, err
+	}
+	numParts := len(u.parts)
+	partSize := func(i int) (int64, error) {
+		if i < len(partLayout) {
+			return partLayout[i], nil
+		}
+		return store.partSize(size, int64(numParts+i))
+	}
+	copySource := url.PathEscape(srcBucket + "/" + srcKey)
+	nextPartNum := int32(numParts + 1)
+
+	var eg errgroup.Group
+	bytesCopied := int64(0)
+	var length int64
+	for i, start := 0, offset; start < size; i, start = i+1, start+length {
+		if length, err = partSize(i); err != nil {
+			break
+		}
+		if pauser != nil {
+			if err = pauser.WaitToCopy(ctx); err != nil {
+				break
+			}
+		}
+		if err = u.acquireUploadSemaphore(ctx); err != nil {
+			break
+		}
+		end := min(start+length, size) - 1
+		partSize := end - start + 1
+		confirmedSize := partSize
+		if start == offset {
+			confirmedSize -= prependedSize
+		}
+		partNum := nextPartNum
+
+		eg.Go(func() error {
+			defer u.releaseUploadSemaphore()
+
+			if _, err := u.client.UploadPartCopy(ctx, &awss3.UploadPartCopyInput{
+				Bucket:          aws.String(u.bucket),
+				Key:             aws.String(u.objectKey),
+				UploadId:        aws.String(u.multipartID),
+				PartNumber:      aws.Int32(partNum),
+				CopySource:      aws.String(copySource),
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			}); err != nil {
+				return err
+			}
+			confirm(confirmedSize)
+			store.logger.V(debugLevel).Info("part is copied",
+				"objectKey", u.objectKey, "partNumber", partNum, "size", partSize)
+			return nil
+		})
+
+		bytesCopied += partSize
+		nextPartNum++
+	}
+
+	if copyErr := eg.Wait(); copyErr != nil {
+		return 0, copyErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return bytesCopied, nil
+}
+
+// remainingPartLayout returns the sizes of the parts following the uploaded parts (which end at
+// the offset) when a part layout is recorded in the info (see storage.PartLayoutMeta), nil otherwise.
+func (u *s3Upload) remainingPartLayout(offset int64) (partSizes []int64, err error) {
+	var partLayout []int64
+	if partLayout, err = storage.DecodePartLayout(u.info.Metadata); err != nil || partLayout == nil {
+		return
+	}
+	if int64(len(partLayout)) > u.store.MaxMultipartParts || lo.Sum(partLayout) != u.info.Size ||
+		len(u.parts) > len(partLayout) {
+		return nil, storage.ErrPartLayoutInvalid
+	}
+	// the uploaded parts must follow the layout as well
+	for i, part := range u.parts {
+		if part.size != partLayout[i] {
+			return nil, storage.ErrPartLayoutInvalid
+		}
+	}
+	if lo.Sum(partLayout[:len(u.parts)]) != offset {
+		return nil, storage.ErrPartLayoutInvalid
+	}
+	return partLayout[len(u.parts):], nil
+}
+
 func (u *s3Upload) calcOptimalSpeed() float64 {
 	const (
 		minSpeed = 1 * 1024 * 1024   // 1 MB/s
@@ -764,6 +1756,16 @@ func (u *s3Upload) calcOptimalSpeed() float64 {
 	return finalSpeed
 }
 
+// uploadPartOptions returns the options of the UploadPart requests (see WithDisableChunkedSigning).
+func (d *Destination) uploadPartOptions() (optFns []func(*awss3.Options)) {
+	if d.disableChunkedSigning {
+		optFns = append(optFns, func(o *awss3.Options) {
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		})
+	}
+	return
+}
+
 func (u *s3Upload) acquireUploadSemaphore(ctx context.Context) error {
 	return u.uploadSemaphore.Acquire(ctx, 1)
 }
@@ -785,11 +1787,14 @@ func (u *s3Upload) putPartForUpload(
 	if !store.DisableContentHashes {
 		// By default, use the traditional approach to upload data
 		uploadPartInput.Body = file
-		res, err := u.client.UploadPart(ctx, uploadPartInput)
+		if store.disableChunkedSigning {
+			uploadPartInput.ContentLength = aws.Int64(size)
+		}
+		res, err := u.client.UploadPart(ctx, uploadPartInput, store.uploadPartOptions()...)
 		if err != nil {
 			return "", err
 		}
-		return *res.ETag, nil
+		return aws.ToString(res.ETag), nil
 	} else {
 		// experimental feature to prevent the AWS SDK from calculating the SHA256 hash
 		// for the parts we u to S3.
@@ -835,13 +1840,17 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 	if u.info != nil {
 		return
 	}
+	// the small file being transferred has no info object yet
+	if _, ok := u.store.getSmallFile(u.bucket, u.objectKey); ok {
+		return u.setSmallFileInfo(ctx)
+	}
 
 	var info xferfile.Info
 	var parts []*s3Part
 	var incompletePartSize int64
 
-	var infoPath string
-	if infoPath, err = xferfile.GenerateInfoPath(u.objectKey); err != nil {
+	var infoKey string
+	if infoKey, err = u.infoKey(); err != nil {
 		return
 	}
 
@@ -866,8 +1875,8 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 			// get file info stored in separate object
 			var res *awss3.GetObjectOutput
 			res, infoErr = u.client.GetObject(ctx, &awss3.GetObjectInput{
-				Bucket: aws.String(u.bucket),
-				Key:    &infoPath,
+				Bucket: aws.String(u.metadataBucket),
+				Key:    &infoKey,
 			})
 			if infoErr == nil {
 				infoErr = json.NewDecoder(res.Body).Decode(&info)
@@ -878,14 +1887,26 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 		// the following goroutines are started get file info stored in separate object
 		var res *awss3.GetObjectOutput
 		res, infoErr = u.client.GetObject(ctx, &awss3.GetObjectInput{
-			Bucket: aws.String(u.bucket),
-			Key:    &infoPath,
+			Bucket: aws.String(u.metadataBucket),
+			Key:    &infoKey,
 		})
 		if infoErr == nil {
 			infoErr = json.NewDecoder(res.Body).Decode(&info)
 		}
 	}
 	wg.Wait()
+	// the upload started before the keys kept the extension is resumed under its legacy keys
+	if isAwsError[*types.NoSuchKey](infoErr) {
+		if legacyInfo, legacyErr := u.getLegacyInfo(ctx); legacyErr == nil {
+			info, infoErr = legacyInfo, nil
+		} else if !isAwsError[*types.NoSuchKey](legacyErr) && !errors.Is(legacyErr, xferfile.ErrFileNotExists) {
+			infoErr = legacyErr
+		}
+	}
+	// the small file has no info object, its info is in the metadata of its object
+	if u.store.smallFileThreshold > 0 && isAwsError[*types.NoSuchKey](infoErr) {
+		return u.setSmallFileInfo(ctx)
+	}
 
 	wg.Add(2)
 	go func() {
@@ -912,6 +1933,11 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 		}
 		return
 	}
+	// the info object must belong to this object, not to an object sharing its name
+	if info.Path != u.objectKey {
+		err = storage.ErrFileInfoMismatch
+		return
+	}
 
 	if partsErr != nil {
 		err = partsErr
@@ -929,9 +1955,17 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 		// types.NoSuchKey to not be returned as well.
 		if isAwsError[*types.NoSuchUpload](err) || isAwsErrorCode(err, "NoSuchUpload") ||
 			isAwsError[*types.NoSuchKey](err) || isAwsErrorCode(err, "NoSuchKey") {
+			// the info object may be an orphan of an object deleted out-of-band
+			var exists bool
+			if exists, err = u.objectExists(ctx); err != nil {
+				return
+			}
+			if !exists {
+				err = xferfile.ErrFileNotExists
+				return
+			}
 			info.Offset = info.Size
 			uploadInfoSetFn()
-			err = nil
 		}
 		return
 	}
@@ -941,6 +1975,23 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 		return
 	}
 
+	// the parts uploaded after a gap (e.g. left by a part which failed while the following ones
+	// were uploaded) are uploaded again, as the offset only covers the parts up to the gap
+	for i, part := range parts {
+		if part.number != int32(i+1) {
+			parts = parts[:i]
+			incompletePartSize = 0
+			break
+		}
+	}
+
+	// the parts are checked against the checksums recorded when they were uploaded
+	if u.store.verifiesParts() {
+		if parts, incompletePartSize, err = u.verifyParts(ctx, info, parts, incompletePartSize); err != nil {
+			return
+		}
+	}
+
 	// the offset is the sum of all part sizes and the size of the incomplete part file.
 	offset := incompletePartSize
 	for _, part := range parts {
@@ -954,6 +2005,36 @@ func (u *s3Upload) setInternalInfo(ctx context.Context) (err error) {
 	return nil
 }
 
+// getLegacyInfo gets the info object of the upload under its legacy key, the upload then uses
+// the legacy keys of its info and incomplete part objects. xferfile.ErrFileNotExists is
+// returned if the legacy info object belongs to another object.
+func (u *s3Upload) getLegacyInfo(ctx context.Context) (info xferfile.Info, err error) {
+	var infoKey string
+	if infoKey, err = legacyInfoKey(u.objectKey); err != nil {
+		return
+	}
+	var res *awss3.GetObjectOutput
+	if res, err = u.client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    &infoKey,
+	}); err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if err = json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return
+	}
+	// the legacy info object is shared by the objects differing only by extension
+	if info.Path != u.objectKey {
+		err = xferfile.ErrFileNotExists
+		return
+	}
+	u.legacyKeys = true
+	u.metadataBucket = u.bucket
+	u.multipartKey = legacyMultipartKey(u.objectKey)
+	return
+}
+
 func (u *s3Upload) listAllParts(ctx context.Context) (parts []*s3Part, err error) {
 	var partMarker *string
 	for {
@@ -972,7 +2053,7 @@ func (u *s3Upload) listAllParts(ctx context.Context) (parts []*s3Part, err error
 			parts = append(parts, &s3Part{
 				number: *part.PartNumber,
 				size:   *part.Size,
-				etag:   *part.ETag,
+				etag:   aws.ToString(part.ETag),
 			})
 		}
 		if listPart.IsTruncated != nil && *listPart.IsTruncated {
@@ -984,41 +2065,62 @@ func (u *s3Upload) listAllParts(ctx context.Context) (parts []*s3Part, err error
 	return parts, nil
 }
 
-func (u *s3Upload) downloadIncompletePartForUpload(ctx context.Context) (*os.File, error) {
+// downloadIncompletePartForUpload downloads the incomplete part of the upload to the store of the
+// parts (see newPartStore), releasePart frees it once it is uploaded. The part is nil if the
+// upload has no incomplete part.
+func (u *s3Upload) downloadIncompletePartForUpload(
+	ctx context.Context,
+) (part io.ReadSeeker, releasePart func() error, err error) {
+	release, err := u.store.acquireIncompletePart(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
 	incompleteUploadObject, err := u.getIncompletePartForUpload(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if incompleteUploadObject == nil {
 		// We did not find an incomplete upload
-		return nil, nil
+		return nil, nil, nil
 	}
 	defer incompleteUploadObject.Body.Close()
 
-	partFile, err := os.CreateTemp(u.temporaryDirectory, "file-transfer-s3-tmp-")
+	store := newPartStore(u.store.PartStore, partTempDirectory(u.temporaryDirectory), u.store.tempFiles, &u.partFiles)
+	partFile, releaseFile, err := store.Create()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer func() {
+		if err != nil {
+			_ = releaseFile()
+		}
+	}()
 
-	n, err := io.Copy(partFile, incompleteUploadObject.Body)
+	checksum := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(partFile, checksum), incompleteUploadObject.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if n < *incompleteUploadObject.ContentLength {
-		return nil, errors.New("short read of incomplete upload")
+		return nil, nil, errors.New("short read of incomplete upload")
+	}
+	if err = u.verifyIncompletePart(ctx, checksum.Sum32()); err != nil {
+		return nil, nil, err
 	}
 
 	_, err = partFile.Seek(0, 0)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return partFile, nil
+	return partFile, releaseFile, nil
 }
 
 func (u *s3Upload) getIncompletePartForUpload(ctx context.Context) (*awss3.GetObjectOutput, error) {
 	obj, err := u.client.GetObject(ctx, &awss3.GetObjectInput{
-		Bucket: aws.String(u.bucket),
+		Bucket: aws.String(u.metadataBucket),
 		Key:    lo.ToPtr(u.multipartKey),
 	})
 	if err != nil && (isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) || isAwsErrorCode(err, "AccessDenied")) || isAwsErrorCode(err, "Forbidden") {
@@ -1029,7 +2131,7 @@ func (u *s3Upload) getIncompletePartForUpload(ctx context.Context) (*awss3.GetOb
 
 func (u *s3Upload) headIncompletePartForUpload(ctx context.Context) (int64, error) {
 	obj, err := u.client.HeadObject(ctx, &awss3.HeadObjectInput{
-		Bucket: aws.String(u.bucket),
+		Bucket: aws.String(u.metadataBucket),
 		Key:    lo.ToPtr(u.multipartKey),
 	})
 
@@ -1043,23 +2145,263 @@ func (u *s3Upload) headIncompletePartForUpload(ctx context.Context) (int64, erro
 	return *obj.ContentLength, nil
 }
 
+// objectExists reports whether the object of a completed upload exists,
+// it is assumed to exist if the check is disabled.
+func (u *s3Upload) objectExists(ctx context.Context) (exists bool, err error) {
+	if u.store.DisableObjectExistenceCheck {
+		return true, nil
+	}
+	if _, err = u.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    lo.ToPtr(u.objectKey),
+	}); err != nil {
+		if isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) ||
+			isAwsErrorCode(err, "NotFound") {
+			err = nil
+		}
+		return
+	}
+	return true, nil
+}
+
 func (u *s3Upload) putIncompletePartForUpload(ctx context.Context, file io.ReadSeeker) error {
+	var checksum string
+	if u.store.incompletePartChecksum {
+		hash := crc32.NewIEEE()
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		checksum = fmt.Sprintf("%08x", hash.Sum32())
+	}
+
 	_, err := u.client.PutObject(ctx, &awss3.PutObjectInput{
-		Bucket: aws.String(u.bucket),
+		Bucket: aws.String(u.metadataBucket),
 		Key:    lo.ToPtr(u.multipartKey),
 		Body:   file,
 	})
-	return err
+	if err != nil || checksum == "" {
+		return err
+	}
+	u.infoMu.Lock()
+	defer u.infoMu.Unlock()
+	if u.info.Metadata == nil {
+		u.info.Metadata = make(map[string]string)
+	}
+	u.info.Metadata[incompletePartChecksumMeta] = checksum
+	return u.writeInfo(ctx, *u.info)
+}
+
+// recordPartChecksum records the MD5 checksum of the uploaded part in the info
+// (see Destination.VerifyPartsOnResume).
+func (u *s3Upload) recordPartChecksum(ctx context.Context, number int32, checksum string) error {
+	u.infoMu.Lock()
+	defer u.infoMu.Unlock()
+	if u.info.Metadata == nil {
+		u.info.Metadata = make(map[string]string)
+	}
+	u.info.Metadata[partChecksumMeta(number)] = checksum
+	return u.writeInfo(ctx, *u.info)
+}
+
+// verifyParts checks the ETags of the uploaded parts against the checksums recorded in the info
+// (see Destination.VerifyPartsOnResume), the parts from the first mismatched one are dropped along
+// with the incomplete part, so that the upload resumes from it. The parts without a recorded
+// checksum (e.g. copied) are not checked.
+func (u *s3Upload) verifyParts(
+	ctx context.Context,
+	info xferfile.Info,
+	parts []*s3Part,
+	incompletePartSize int64,
+) (verified []*s3Part, verifiedIncompletePartSize int64, err error) {
+	for i, part := range parts {
+		checksum, ok := info.Metadata[partChecksumMeta(part.number)]
+		if !ok || strings.EqualFold(strings.Trim(part.etag, `"`), checksum) {
+			continue
+		}
+		u.store.logger.Info("uploaded part does not match its checksum, uploading it again",
+			"objectKey", u.objectKey, "partNumber", part.number, "etag", part.etag, "checksum", checksum)
+		if incompletePartSize > 0 {
+			if err = u.deleteIncompletePartForUpload(ctx); err != nil {
+				return
+			}
+		}
+		return parts[:i], 0, nil
+	}
+	return parts, incompletePartSize, nil
+}
+
+// verifyIncompletePart compares the checksum of the downloaded incomplete part with the one
+// recorded in the info (see WithIncompletePartChecksum). On a mismatch, the part and its checksum
+// are deleted, so that the offset of the upload no longer includes the part.
+func (u *s3Upload) verifyIncompletePart(ctx context.Context, checksum uint32) (err error) {
+	if !u.store.incompletePartChecksum || u.info == nil {
+		return
+	}
+	recorded := u.info.Metadata[incompletePartChecksumMeta]
+	if recorded == "" {
+		return
+	}
+	if actual := fmt.Sprintf("%08x", checksum); actual == recorded {
+		return
+	}
+
+	u.store.logger.Info("incomplete part is corrupted, restarting its tail",
+		"objectKey", u.objectKey, "multipartKey", u.multipartKey)
+	if err = u.deleteIncompletePartForUpload(ctx); err != nil {
+		return
+	}
+	delete(u.info.Metadata, incompletePartChecksumMeta)
+	if err = u.writeInfo(ctx, *u.info); err != nil {
+		return
+	}
+	u.info.Offset -= u.incompletePartSize
+	u.incompletePartSize = 0
+	return fmt.Errorf("%w: %s", storage.ErrIncompletePartCorrupted, u.multipartKey)
+}
+
+// uploadIncompletePartAsLast uploads the incomplete part as the last part of the upload, once the
+// end of a file of an unknown size is known.
+func (u *s3Upload) uploadIncompletePartAsLast(ctx context.Context) (err error) {
+	var (
+		partFile    io.ReadSeeker
+		releasePart func() error
+	)
+	if partFile, releasePart, err = u.downloadIncompletePartForUpload(ctx); err != nil {
+		return
+	}
+	if partFile == nil {
+		return fmt.Errorf("expected an incomplete part file but did not get any")
+	}
+	defer func() { _ = releasePart() }()
+
+	part := &s3Part{
+		number: int32(len(u.parts) + 1),
+		size:   u.incompletePartSize,
+	}
+	if part.etag, err = u.putPartForUpload(ctx, &awss3.UploadPartInput{
+		Bucket:     aws.String(u.bucket),
+		Key:        aws.String(u.objectKey),
+		UploadId:   aws.String(u.multipartID),
+		PartNumber: aws.Int32(part.number),
+	}, partFile, part.size); err != nil {
+		return
+	}
+	if err = u.deleteIncompletePartForUpload(ctx); err != nil {
+		return
+	}
+	u.parts = append(u.parts, part)
+	u.incompletePartSize = 0
+	return
 }
 
 func (u *s3Upload) deleteIncompletePartForUpload(ctx context.Context) (err error) {
+	var release func()
+	if release, err = u.store.acquireIncompletePart(ctx); err != nil {
+		return
+	}
+	defer release()
+
 	_, err = u.client.DeleteObject(ctx, &awss3.DeleteObjectInput{
-		Bucket: aws.String(u.bucket),
+		Bucket: aws.String(u.metadataBucket),
 		Key:    lo.ToPtr(u.multipartKey),
 	})
 	return
 }
 
+// verifiesParts reports whether the parts are checked against their recorded checksums
+// (see Destination.VerifyPartsOnResume).
+func (d *Destination) verifiesParts() bool {
+	return d.VerifyPartsOnResume && !d.DisableContentHashes
+}
+
+// acquireIncompletePart waits for a slot of the operations on the incomplete part objects
+// (see WithIncompletePartConcurrency), release frees it.
+func (d *Destination) acquireIncompletePart(ctx context.Context) (release func(), err error) {
+	if d.incompletePartSemaphore == nil {
+		return func() {}, nil
+	}
+	if err = d.incompletePartSemaphore.Acquire(ctx, 1); err != nil {
+		return
+	}
+	return func() { d.incompletePartSemaphore.Release(1) }, nil
+}
+
+// partChecksumMeta returns the key of the recorded checksum of the part (see Destination.VerifyPartsOnResume).
+func partChecksumMeta(number int32) string {
+	return fmt.Sprintf("%s%d", partChecksumMetaPrefix, number)
+}
+
+// md5Checksum returns the MD5 (hex) of the content of the file, which is rewound.
+func md5Checksum(file io.ReadSeeker) (checksum string, err error) {
+	hash := md5.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// generateMultipartKey generates the key of the incomplete part object based on the object
+// key, the extension is kept so that "file.txt" and "file.md" do not share a part object.
+func (d *Destination) generateMultipartKey(objectKey string) string {
+	return d.MetadataObjectPrefix + objectKey + ".part"
+}
+
+// generateInfoKey generates the key of the info object based on the object key
+// (see MetadataObjectPrefix and InfoSuffix).
+func (d *Destination) generateInfoKey(objectKey string) (infoKey string, err error) {
+	if infoKey, err = xferfile.GenerateInfoPath(objectKey); err != nil {
+		return
+	}
+	if d.InfoSuffix != "" {
+		infoKey = objectKey + d.InfoSuffix
+	}
+	return d.MetadataObjectPrefix + infoKey, nil
+}
+
+// infoKey returns the key of the info object of the upload, its legacy key if it was found
+// under it (see legacyInfoKey).
+func (u *s3Upload) infoKey() (string, error) {
+	if u.legacyKeys {
+		return legacyInfoKey(u.objectKey)
+	}
+	return u.store.generateInfoKey(u.objectKey)
+}
+
+// legacyInfoKey and legacyMultipartKey generate the keys of the info and incomplete part objects
+// in their former format, which dropped the extension of the object key and was neither
+// prefixed nor in the metadata bucket.
+func legacyInfoKey(objectKey string) (string, error) {
+	return xferfile.GenerateLegacyInfoPath(objectKey)
+}
+
+func legacyMultipartKey(objectKey string) string {
+	return strings.TrimSuffix(objectKey, path.Ext(objectKey)) + ".part"
+}
+
+// wrapThrottleError marks the throttling errors of S3 (e.g. SlowDown) with storage.ErrThrottled.
+func wrapThrottleError(err error) error {
+	if err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		return fmt.Errorf("%w: %w", storage.ErrThrottled, err)
+	}
+	return err
+}
+
+// isPreconditionFailed reports whether the error is the PreconditionFailed (412) error which
+// S3 returns when the condition of a conditional request (write or If-Match read) is not met.
+func isPreconditionFailed(err error) bool {
+	if isAwsErrorCode(err, "PreconditionFailed") {
+		return true
+	}
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
+
 // isAwsError tests whether an error object is an instance of the AWS error
 // specified by its code.
 func isAwsError[T error](err error) bool {
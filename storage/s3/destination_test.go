@@ -2,13 +2,24 @@ package s3
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -153,7 +164,7 @@ var _ = Describe("Destination", func() {
 			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
 				Return(nil, &types.NoSuchKey{
 					Message: aws.String("info file not found"),
-				})
+				}).Times(2)
 			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
 			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
 				ContentLength: &fileInfo.Size,
@@ -163,6 +174,83 @@ var _ = Describe("Destination", func() {
 			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
 		}, NodeTimeout(10*time.Second))
 
+		It("should return error when info file belongs to another object", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			otherInfo := fileInfo
+			otherInfo.Path = fileInfo.Path + ".other"
+			infoBytes, err := json.Marshal(otherInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+
+			_, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrFileInfoMismatch))
+		}, NodeTimeout(10*time.Second))
+
+		It("should resume an upload recorded in a legacy info object", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			legacyInfoPath, err := xferfile.GenerateLegacyInfoPath(fileInfo.Path)
+			Expect(err).ToNot(HaveOccurred())
+			legacyPartKey := strings.TrimSuffix(fileInfo.Path, filepath.Ext(fileInfo.Path)) + ".part"
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[multipartKeyMeta] = legacyPartKey
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+
+			gomock.InOrder(
+				mockS3API.EXPECT().GetObject(ctx, &awss3.GetObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(infoPath),
+				}).Return(nil, &types.NoSuchKey{}),
+				mockS3API.EXPECT().GetObject(ctx, &awss3.GetObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(legacyInfoPath),
+				}).Return(&awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil),
+			)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{{PartNumber: aws.Int32(1), Size: aws.Int64(100), ETag: aws.String("etag-1")}},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(legacyPartKey),
+			}).Return(&awss3.HeadObjectOutput{ContentLength: aws.Int64(50)}, nil)
+
+			info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(And(
+				HaveField("Path", fileInfo.Path),
+				HaveField("Offset", BeNumerically("==", 150)),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not take the legacy info object of an object differing by extension", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			otherInfo := fileInfo
+			otherInfo.Path = strings.TrimSuffix(fileInfo.Path, filepath.Ext(fileInfo.Path)) + ".other"
+			infoBytes, err := json.Marshal(otherInfo)
+			Expect(err).ToNot(HaveOccurred())
+			gomock.InOrder(
+				mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{}),
+				mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader(infoBytes)),
+				}, nil),
+			)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+
+			_, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+
 		It("should return the correct file info when uploading with incomplete part", func(ctx context.Context) {
 			connID := uuid.NewString()
 			mockClient.EXPECT().GetConnectionID().
@@ -241,6 +329,10 @@ var _ = Describe("Destination", func() {
 				Bucket: aws.String(bucketName),
 				Key:    aws.String(fileInfo.Metadata[multipartKeyMeta]),
 			}).Return(nil, &types.NoSuchKey{})
+			mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Path),
+			}).Return(&awss3.HeadObjectOutput{ContentLength: aws.Int64(fileInfo.Size)}, nil)
 
 			info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
 			Expect(err).ToNot(HaveOccurred())
@@ -248,6 +340,55 @@ var _ = Describe("Destination", func() {
 			Expect(info.Offset).To(Equal(fileInfo.Size))
 		}, NodeTimeout(10*time.Second))
 
+		Context("when the info file is left behind by a deleted object", func() {
+			BeforeEach(func(ctx context.Context) {
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err := json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader(infoBytes)),
+				}, nil)
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(nil, &types.NoSuchUpload{})
+				mockS3API.EXPECT().HeadObject(gomock.Any(), &awss3.HeadObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(fileInfo.Metadata[multipartKeyMeta]),
+				}).Return(nil, &types.NoSuchKey{})
+			})
+
+			It("should return error so that the file is re-created", func(ctx context.Context) {
+				mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(fileInfo.Path),
+				}).Return(nil, &types.NotFound{})
+
+				_, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return the completed file info when the check is disabled", func(ctx context.Context) {
+				destStorage.DisableObjectExistenceCheck = true
+
+				info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(fileInfo.Size))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return error when the object cannot be checked", func(ctx context.Context) {
+				mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(fileInfo.Path),
+				}).Return(nil, errors.New("head object failed"))
+
+				_, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).To(MatchError(ContainSubstring("head object failed")))
+			}, NodeTimeout(10*time.Second))
+		})
+
 		It("should return error when checking and setting client failed", func(ctx context.Context) {
 			mockClient.EXPECT().GetConnectionID().Return("")
 			mockClient.EXPECT().GetS3API().Return(mockS3API)
@@ -260,6 +401,195 @@ var _ = Describe("Destination", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 
+	Describe("FileExists", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should report an object which has not been transferred", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(gomock.Any(), &awss3.HeadObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Path),
+			}).Return(&awss3.HeadObjectOutput{}, nil)
+
+			Expect(destStorage.FileExists(ctx, fileInfo.Path, mockClient)).To(BeTrue())
+		}, NodeTimeout(10*time.Second))
+
+		It("should report a missing object", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NotFound{})
+
+			Expect(destStorage.FileExists(ctx, fileInfo.Path, mockClient)).To(BeFalse())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("StatObject", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should report the size of a visible object", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(gomock.Any(), &awss3.HeadObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Path),
+			}).Return(&awss3.HeadObjectOutput{ContentLength: aws.Int64(42)}, nil)
+
+			size, exists, err := destStorage.StatObject(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+			Expect(size).To(Equal(int64(42)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should report an object which is not visible yet", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NotFound{})
+
+			_, exists, err := destStorage.StatObject(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ResumeState", func() {
+		It("should return the resumption state of an unfinished upload", func(ctx context.Context) {
+			fileInfo.FinishTime = time.Time{}
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.GetObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.GetObjectOutput, error) {
+					Expect(*input.Key).To(Equal(infoPath))
+					fileInfo.Metadata[bucketMeta] = bucketName
+					fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+					fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+					infoBytes, err := json.Marshal(fileInfo)
+					Expect(err).ToNot(HaveOccurred())
+					return &awss3.GetObjectOutput{
+						Body: io.NopCloser(bytes.NewReader(infoBytes)),
+					}, nil
+				})
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{Size: aws.Int64(200), ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+				},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(50),
+			}, nil)
+
+			state, err := destStorage.ResumeState(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(And(
+				HaveField("Path", fileInfo.Path),
+				HaveField("Size", fileInfo.Size),
+				HaveField("Offset", int64(350)),
+				HaveField("UploadID", "test-multipart-id"),
+				HaveField("PartCount", 2),
+				HaveField("StartTime", BeTemporally("~", fileInfo.StartTime, 2*time.Second)),
+				HaveField("Finished", BeFalse()),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when info file not found", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{}).Times(2)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+
+			_, err := destStorage.ResumeState(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("Preflight", func() {
+		accessDeniedErr := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied."}
+
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should write and undo a throwaway object and multipart upload", func(ctx context.Context) {
+			var preflightKey string
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.PutObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					preflightKey = *input.Key
+					Expect(path.Dir(preflightKey)).To(Equal(path.Dir(fileInfo.Path)))
+					Expect(preflightKey).ToNot(Equal(fileInfo.Path))
+					return &awss3.PutObjectOutput{}, nil
+				})
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(nil)),
+			}, nil)
+			mockS3API.EXPECT().DeleteObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.DeleteObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.DeleteObjectOutput, error) {
+					Expect(*input.Key).To(Equal(preflightKey))
+					return &awss3.DeleteObjectOutput{}, nil
+				})
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).Return(&awss3.CreateMultipartUploadOutput{
+				UploadId: aws.String("test-multipart-id"),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.AbortMultipartUploadInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.AbortMultipartUploadOutput, error) {
+					Expect(*input.Key).To(Equal(preflightKey))
+					Expect(*input.UploadId).To(Equal("test-multipart-id"))
+					return &awss3.AbortMultipartUploadOutput{}, nil
+				})
+
+			Expect(destStorage.Preflight(ctx, fileInfo.Path, mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should name the permission when the object cannot be written", func(ctx context.Context) {
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).Return(nil, accessDeniedErr)
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).Times(0)
+
+			err = destStorage.Preflight(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrPermissionMissing))
+			Expect(err).To(MatchError(ContainSubstring("s3:PutObject")))
+		}, NodeTimeout(10*time.Second))
+
+		It("should name the permission and abort when the parts cannot be listed", func(ctx context.Context) {
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).Return(&awss3.PutObjectOutput{}, nil)
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(nil)),
+			}, nil)
+			mockS3API.EXPECT().DeleteObject(ctx, gomock.Any()).Return(&awss3.DeleteObjectOutput{}, nil)
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).Return(&awss3.CreateMultipartUploadOutput{
+				UploadId: aws.String("test-multipart-id"),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(nil, accessDeniedErr)
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).Return(&awss3.AbortMultipartUploadOutput{}, nil)
+
+			err = destStorage.Preflight(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrPermissionMissing))
+			Expect(err).To(MatchError(ContainSubstring("s3:ListMultipartUploadParts")))
+			Expect(err).ToNot(MatchError(ContainSubstring("s3:AbortMultipartUpload")))
+		}, NodeTimeout(10*time.Second))
+	})
+
 	Describe("CreateFile", func() {
 		It("should create new file successfully", func(ctx context.Context) {
 			gomock.InOrder(
@@ -299,7 +629,7 @@ var _ = Describe("Destination", func() {
 							HaveKeyWithValue("multipartID", "test-multipart-id"),
 							HaveKeyWithValue(
 								"multipartKey",
-								strings.Replace(fileInfo.Path, filepath.Ext(fileInfo.Path), ".part", -1),
+								fileInfo.Path+".part",
 							),
 							HaveKeyWithValue("objectKey", fileInfo.Path),
 						))
@@ -314,6 +644,68 @@ var _ = Describe("Destination", func() {
 			)).To(Succeed())
 		}, NodeTimeout(10*time.Second))
 
+		It("should record the source extension of a mismatched file", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).
+				Return(&awss3.CreateMultipartUploadOutput{UploadId: aws.String("test-multipart-id")}, nil)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.PutObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					var gotInfo xferfile.Info
+					Expect(json.NewDecoder(input.Body).Decode(&gotInfo)).To(Succeed())
+					Expect(gotInfo.Name).To(Equal(fileInfo.Name))
+					Expect(gotInfo.Extension).To(Equal("mismatched"))
+					Expect(gotInfo.Metadata).To(HaveKeyWithValue("multipartKey", fileInfo.Path+".part"))
+					return nil, nil
+				})
+
+			Expect(destStorage.CreateFileWithMetadata(
+				ctx,
+				fileInfo.Path, fileInfo.Size, fileInfo.ModTime,
+				map[string]string{storage.SourceExtensionMeta: "mismatched"},
+				mockClient,
+			)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should declare the content type of the source for the multipart upload", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.CreateMultipartUploadInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.CreateMultipartUploadOutput, error) {
+					Expect(input.ContentType).To(HaveValue(Equal("application/vnd.fxfer+xml")))
+					return &awss3.CreateMultipartUploadOutput{UploadId: aws.String("test-multipart-id")}, nil
+				})
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.PutObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					By("recording the content type in the info, so that it survives a resumption")
+					var gotInfo xferfile.Info
+					Expect(json.NewDecoder(input.Body).Decode(&gotInfo)).To(Succeed())
+					Expect(gotInfo.Metadata).To(HaveKeyWithValue(storage.ContentTypeMeta, "application/vnd.fxfer+xml"))
+					return nil, nil
+				})
+
+			Expect(destStorage.CreateFileWithMetadata(
+				ctx,
+				fileInfo.Path, fileInfo.Size, fileInfo.ModTime,
+				map[string]string{storage.ContentTypeMeta: "application/vnd.fxfer+xml"},
+				mockClient,
+			)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
 		// This test ensures that a newly created upload without any chunks can be
 		// directly finished. There are no calls to ListPart or HeadObject because
 		// the upload is not fetched from S3 first.
@@ -356,7 +748,7 @@ var _ = Describe("Destination", func() {
 						HaveKeyWithValue("multipartID", "test-multipart-id"),
 						HaveKeyWithValue(
 							"multipartKey",
-							strings.Replace(fileInfo.Path, filepath.Ext(fileInfo.Path), ".part", -1),
+							fileInfo.Path+".part",
 						),
 						HaveKeyWithValue("objectKey", fileInfo.Path),
 					))
@@ -434,7 +826,7 @@ var _ = Describe("Destination", func() {
 						HaveKeyWithValue("multipartID", "test-multipart-id"),
 						HaveKeyWithValue(
 							"multipartKey",
-							strings.Replace(fileInfo.Path, filepath.Ext(fileInfo.Path), ".part", -1),
+							fileInfo.Path+".part",
 						),
 						HaveKeyWithValue("objectKey", fileInfo.Path),
 					))
@@ -521,16 +913,433 @@ var _ = Describe("Destination", func() {
 			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
 				Return(nil, occurError)
 
-			Expect(destStorage.CreateFile(
+			Expect(destStorage.CreateFile(
+				ctx,
+				fileInfo.Path, fileInfo.Size, fileInfo.ModTime,
+				mockClient,
+			)).To(MatchError(occurError))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferFileChunk", func() {
+		It("should return error when an uploaded part has no ETag", func(ctx context.Context) {
+			fileInfo.Size = 12
+			destStorage = destStorageFactory(func(s *Destination) {
+				s.MaxPartSize = 8
+				s.MinPartSize = 4
+				s.PreferredPartSize = 4
+			})
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+			mockS3API.EXPECT().UploadPart(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.UploadPartInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.UploadPartOutput, error) {
+					if *input.PartNumber == 2 {
+						return &awss3.UploadPartOutput{}, nil
+					}
+					return &awss3.UploadPartOutput{
+						ETag: aws.String(fmt.Sprintf("etag-%d", *input.PartNumber)),
+					}, nil
+				}).Times(3)
+
+			recorder := &confirmedSizeRecorder{Reader: strings.NewReader("123456789012")}
+			_, err = destStorage.TransferFileChunk(
+				ctx,
+				fileInfo.Path,
+				recorder,
+				0,
+				mockClient,
+			)
+			Expect(err).To(MatchError(storage.ErrPartETagMissing))
+			Expect(err).To(MatchError(ContainSubstring("part 2")))
+			Expect(recorder.Steps()).To(ConsistOf(int64(4), int64(4)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should mark the throttling error of a part upload", func(ctx context.Context) {
+			fileInfo.Size = 12
+			destStorage = destStorageFactory(func(s *Destination) {
+				s.MaxPartSize = 8
+				s.MinPartSize = 4
+				s.PreferredPartSize = 4
+			})
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+			slowDownErr := &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+			mockS3API.EXPECT().UploadPart(ctx, gomock.Any()).Return(nil, slowDownErr)
+
+			_, err = destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("1234"), 0, mockClient)
+			Expect(err).To(MatchError(storage.ErrThrottled))
+			Expect(err).To(MatchError(slowDownErr))
+		}, NodeTimeout(10*time.Second))
+
+		Context("with the temporary directory", func() {
+			var tempDir string
+
+			BeforeEach(func(ctx context.Context) {
+				tempDir = GinkgoT().TempDir()
+				fileInfo.Size = 12
+				destStorage = destStorageFactory(func(s *Destination) {
+					s.MaxPartSize = 8
+					s.MinPartSize = 4
+					s.PreferredPartSize = 4
+					s.MaxBufferedParts = 2
+					s.TemporaryDirectory = tempDir
+				})
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err := json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader(infoBytes)),
+				}, nil)
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NoSuchKey{})
+			})
+
+			It("should return error when the available space is insufficient", func(ctx context.Context) {
+				originalAvailableDiskSpace := availableDiskSpace
+				availableDiskSpace = func(dir string) (int64, error) {
+					Expect(dir).To(Equal(tempDir))
+					return 7, nil
+				}
+				DeferCleanup(func() {
+					availableDiskSpace = originalAvailableDiskSpace
+				})
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678"), 0, mockClient)
+				Expect(err).To(MatchError(storage.ErrTempDirSpaceInsufficient))
+				Expect(err).To(MatchError(ContainSubstring("%s has 7 bytes available, 8 bytes required", tempDir)))
+				Expect(os.ReadDir(tempDir)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+
+			It("should not check the available space when the parts are buffered in memory", func(ctx context.Context) {
+				originalAvailableDiskSpace := availableDiskSpace
+				availableDiskSpace = func(string) (int64, error) {
+					return 0, nil
+				}
+				DeferCleanup(func() {
+					availableDiskSpace = originalAvailableDiskSpace
+				})
+				destStorage.TemporaryDirectory = TempDirUseMemory
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					Return(&awss3.UploadPartOutput{ETag: aws.String("etag")}, nil).Times(2)
+
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(8)))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return error when the temporary directory is not a directory", func(ctx context.Context) {
+				notDirPath := filepath.Join(tempDir, "not-a-directory")
+				Expect(os.WriteFile(notDirPath, nil, 0644)).To(Succeed())
+				destStorage.TemporaryDirectory = notDirPath
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678"), 0, mockClient)
+				Expect(err).To(MatchError(ContainSubstring("unable to create temporary part file")))
+				Expect(os.ReadDir(tempDir)).To(HaveLen(1))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return error when the temporary directory is read-only", func(ctx context.Context) {
+				if os.Geteuid() == 0 {
+					Skip("the permissions of the directory do not apply to root")
+				}
+				Expect(os.Chmod(tempDir, 0555)).To(Succeed())
+				DeferCleanup(os.Chmod, tempDir, os.FileMode(0755))
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Times(0)
+
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678"), 0, mockClient)
+				Expect(err).To(MatchError(os.ErrPermission))
+				Expect(err).To(MatchError(ContainSubstring("unable to create temporary part file")))
+				Expect(os.ReadDir(tempDir)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+
+			It("should not leak the part files when the source fails", func(ctx context.Context) {
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					Return(&awss3.UploadPartOutput{ETag: aws.String("etag")}, nil).MaxTimes(1)
+
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path,
+					io.MultiReader(strings.NewReader("123456"), ErrorReader{}), 0, mockClient)
+				Expect(err).To(MatchError("error from ErrorReader"))
+				Expect(os.ReadDir(tempDir)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+
+			It("should create the part files in the scratch directory of the transfer", func(ctx context.Context) {
+				destStorage.ScratchDirectoryPerTransfer = true
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						ctx context.Context,
+						input *awss3.UploadPartInput,
+						opts ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						entries, err := os.ReadDir(tempDir)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(entries).To(HaveExactElements(And(
+							WithTransform(os.DirEntry.IsDir, BeTrue()),
+							WithTransform(os.DirEntry.Name, HavePrefix("fxfer-scratch-")),
+						)))
+						Expect(os.ReadDir(filepath.Join(tempDir, entries[0].Name()))).ToNot(BeEmpty())
+						return &awss3.UploadPartOutput{ETag: aws.String("etag")}, nil
+					}).Times(2)
+
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(8)))
+
+				By("assert the scratch directory is kept empty for the next chunk")
+				scratchDir := destStorage.scratchDirectory(bucketName, fileInfo.Path)
+				Expect(filepath.Dir(scratchDir)).To(Equal(tempDir))
+				Expect(os.ReadDir(scratchDir)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+
+			It("should remove the scratch directory when the transfer is deleted, even if it fails", func(ctx context.Context) {
+				destStorage.ScratchDirectoryPerTransfer = true
+				scratchDir := destStorage.scratchDirectory(bucketName, fileInfo.Path)
+				Expect(os.MkdirAll(scratchDir, 0700)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(scratchDir, "leftover.part"), []byte("1234"), 0600)).To(Succeed())
+				abortErr := errors.New("abort failed")
+				mockS3API.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(nil, abortErr)
+				mockS3API.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).Return(&awss3.DeleteObjectsOutput{}, nil)
+
+				Expect(destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)).To(MatchError(abortErr))
+				Expect(os.ReadDir(tempDir)).To(BeEmpty())
+			}, NodeTimeout(10*time.Second))
+		})
+
+		It("should write chunk successfully", func(ctx context.Context) {
+			fileInfo.Size = 500
+			fileInfo.Size = 0
+			destStorage = destStorageFactory(func(s *Destination) {
+				s.MaxPartSize = 8
+				s.MinPartSize = 4
+				s.PreferredPartSize = 4
+				s.MaxMultipartParts = 10000
+				s.MaxObjectSize = 5 * 1024 * 1024 * 1024 * 1024
+			})
+
+			connID := uuid.NewString()
+			mockClient.EXPECT().GetConnectionID().Return(connID).Times(1)
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().
+				Return(*s3ProtocClient)
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.GetObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.GetObjectOutput, error) {
+					Expect(*input.Bucket).To(Equal(bucketName))
+					Expect(*input.Key).To(Equal(infoPath))
+
+					fileInfo.Metadata[bucketMeta] = bucketName
+					fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+					fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+					infoBytes, err := json.Marshal(fileInfo)
+					Expect(err).ToNot(HaveOccurred())
+					return &awss3.GetObjectOutput{
+						Body: io.NopCloser(bytes.NewReader(infoBytes)),
+					}, nil
+				})
+			mockS3API.EXPECT().ListParts(ctx, &awss3.ListPartsInput{
+				Bucket:           aws.String(bucketName),
+				Key:              &fileInfo.Path,
+				UploadId:         aws.String("test-multipart-id"),
+				PartNumberMarker: nil,
+			}).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{
+						Size:       aws.Int64(100),
+						ETag:       aws.String("etag-1"),
+						PartNumber: aws.Int32(1),
+					},
+					{
+						Size:       aws.Int64(200),
+						ETag:       aws.String("etag-2"),
+						PartNumber: aws.Int32(2),
+					},
+				},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Metadata[multipartKeyMeta]),
+			}).Return(nil, &types.NoSuchKey{})
+
+			// transfer chunks
+			mockS3API.EXPECT().UploadPart(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.UploadPartInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.UploadPartOutput, error) {
+					Expect(*input.Bucket).To(Equal(bucketName))
+					Expect(*input.Key).To(Equal(fileInfo.Path))
+					Expect(*input.UploadId).To(Equal("test-multipart-id"))
+
+					switch *input.PartNumber {
+					case int32(3):
+						buf := make([]byte, 4)
+						_, err := input.Body.Read(buf)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(buf).To(Equal([]byte("1234")))
+						return &awss3.UploadPartOutput{
+							ETag: aws.String("etag-3"),
+						}, nil
+					case int32(4):
+						buf := make([]byte, 4)
+						_, err := input.Body.Read(buf)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(buf).To(Equal([]byte("5678")))
+						return &awss3.UploadPartOutput{
+							ETag: aws.String("etag-4"),
+						}, nil
+					case int32(5):
+						buf := make([]byte, 4)
+						_, err := input.Body.Read(buf)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(buf).To(Equal([]byte("90AB")))
+						return &awss3.UploadPartOutput{
+							ETag: aws.String("etag-5"),
+						}, nil
+					default:
+						Fail("unexpected part number")
+						return nil, nil
+					}
+				}).Times(3)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.PutObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					Expect(*input.Bucket).To(Equal(bucketName))
+					Expect(*input.Key).To(Equal(fileInfo.Metadata[multipartKeyMeta]))
+
+					buf := make([]byte, 2)
+					_, err := input.Body.Read(buf)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bytes.Equal(buf, []byte("CD"))).To(BeTrue())
+					return nil, nil
+				})
+
+			bytesRead, err := destStorage.TransferFileChunk(
 				ctx,
-				fileInfo.Path, fileInfo.Size, fileInfo.ModTime,
-				mockClient,
-			)).To(MatchError(occurError))
+				fileInfo.Path, bytes.NewReader([]byte("1234567890ABCD")), 300, mockClient,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bytesRead).To(Equal(int64(14)))
 		}, NodeTimeout(10*time.Second))
-	})
 
-	Describe("TransferFileChunk", func() {
-		It("should write chunk successfully", func(ctx context.Context) {
+		DescribeTable("should upload the parts of the part layout of the source",
+			func(ctx context.Context, content string, expectedParts map[int32]string, incompletePart string) {
+				fileInfo.Size = 314
+				destStorage = destStorageFactory(func(s *Destination) {
+					s.MaxPartSize = 8
+					s.MinPartSize = 4
+					s.PreferredPartSize = 4
+					s.MaxMultipartParts = 10000
+				})
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+				mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+					DoAndReturn(func(
+						ctx context.Context,
+						input *awss3.GetObjectInput,
+						opts ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						fileInfo.Metadata[bucketMeta] = bucketName
+						fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+						fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+						fileInfo.Metadata[storage.PartLayoutMeta] = "100,200,6,5,3"
+						infoBytes, err := json.Marshal(fileInfo)
+						Expect(err).ToNot(HaveOccurred())
+						return &awss3.GetObjectOutput{
+							Body: io.NopCloser(bytes.NewReader(infoBytes)),
+						}, nil
+					})
+				mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+					Parts: []types.Part{
+						{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+						{Size: aws.Int64(200), ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+					},
+				}, nil)
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+
+				mockS3API.EXPECT().UploadPart(ctx, gomock.Any()).
+					DoAndReturn(func(
+						ctx context.Context,
+						input *awss3.UploadPartInput,
+						opts ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						body, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(expectedParts).To(HaveKeyWithValue(*input.PartNumber, string(body)))
+						return &awss3.UploadPartOutput{ETag: aws.String("etag")}, nil
+					}).Times(len(expectedParts))
+				if incompletePart != "" {
+					mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+						DoAndReturn(func(
+							ctx context.Context,
+							input *awss3.PutObjectInput,
+							opts ...func(*awss3.Options),
+						) (*awss3.PutObjectOutput, error) {
+							Expect(*input.Key).To(Equal(fileInfo.Metadata[multipartKeyMeta]))
+							body, err := io.ReadAll(input.Body)
+							Expect(err).ToNot(HaveOccurred())
+							Expect(string(body)).To(Equal(incompletePart))
+							return &awss3.PutObjectOutput{}, nil
+						})
+				}
+
+				bytesRead, err := destStorage.TransferFileChunk(
+					ctx,
+					fileInfo.Path, strings.NewReader(content), 300, mockClient,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bytesRead).To(Equal(int64(len(content))))
+			},
+			Entry("full content", "1234567890ABCD", map[int32]string{
+				3: "123456",
+				4: "7890A",
+				5: "BCD",
+			}, ""),
+			Entry("partial content", "12345678", map[int32]string{
+				3: "123456",
+			}, "78"),
+		)
+
+		It("should confirm the size of each uploaded part", func(ctx context.Context) {
 			fileInfo.Size = 500
 			fileInfo.Size = 0
 			destStorage = destStorageFactory(func(s *Destination) {
@@ -645,12 +1454,13 @@ var _ = Describe("Destination", func() {
 					return nil, nil
 				})
 
-			bytesRead, err := destStorage.TransferFileChunk(
-				ctx,
-				fileInfo.Path, bytes.NewReader([]byte("1234567890ABCD")), 300, mockClient,
-			)
+			recorder := &confirmedSizeRecorder{Reader: bytes.NewReader([]byte("1234567890ABCD"))}
+			bytesRead, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, recorder, 300, mockClient)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(bytesRead).To(Equal(int64(14)))
+
+			By("assert the confirmed size advances in part-sized steps")
+			Expect(recorder.Steps()).To(ConsistOf(int64(4), int64(4), int64(4), int64(2)))
 		}, NodeTimeout(10*time.Second))
 
 		It("write chunk should write incomplete part because too small", func(ctx context.Context) {
@@ -816,12 +1626,13 @@ var _ = Describe("Destination", func() {
 					}
 				}).Times(2)
 
-			bytesRead, err := destStorage.TransferFileChunk(
-				ctx,
-				fileInfo.Path, bytes.NewReader([]byte("45")), 3, mockClient,
-			)
+			recorder := &confirmedSizeRecorder{Reader: bytes.NewReader([]byte("45"))}
+			bytesRead, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, recorder, 3, mockClient)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(bytesRead).To(Equal(int64(2)))
+
+			By("assert the bytes of the prepended incomplete part are not confirmed twice")
+			Expect(recorder.Steps()).To(ConsistOf(int64(1), int64(1)))
 		}, NodeTimeout(10*time.Second))
 
 		It("write chunk should prepends incomplete part and write a new incomplete part", func(ctx context.Context) {
@@ -1110,9 +1921,953 @@ var _ = Describe("Destination", func() {
 			)
 			Expect(err).To(MatchError("file extension is required"))
 		}, NodeTimeout(10*time.Second))
-	})
 
-	Describe("FinalizeTransfer", func() {
+		Context("with the incomplete part checksum", func() {
+			var (
+				infoBytes      []byte
+				incompletePart []byte
+				uploadedParts  []types.Part
+				partContents   map[int32]string
+				partsMu        sync.Mutex
+			)
+
+			newDestStorage := func() *Destination {
+				return destStorageFactory(func(d *Destination) {
+					d.MinPartSize = 5
+					d.PreferredPartSize = 5
+					WithIncompletePartChecksum()(d)
+				})
+			}
+
+			BeforeEach(func() {
+				destStorage = newDestStorage()
+				incompletePart = nil
+				uploadedParts = nil
+				partContents = map[int32]string{}
+				fileInfo.Size = 12
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err = json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+				mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+				multipartKey := fileInfo.Metadata[multipartKeyMeta]
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.GetObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						if *input.Key == multipartKey {
+							return &awss3.GetObjectOutput{
+								ContentLength: aws.Int64(int64(len(incompletePart))),
+								Body:          io.NopCloser(bytes.NewReader(incompletePart)),
+							}, nil
+						}
+						return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.ListPartsInput,
+						...func(*awss3.Options),
+					) (*awss3.ListPartsOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						return &awss3.ListPartsOutput{Parts: slices.Clone(uploadedParts)}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.HeadObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.HeadObjectOutput, error) {
+						if incompletePart == nil {
+							return nil, &types.NoSuchKey{}
+						}
+						return &awss3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(incompletePart)))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.PutObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.PutObjectOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						if *input.Key == multipartKey {
+							incompletePart = content
+						} else {
+							Expect(*input.Key).To(Equal(infoPath))
+							infoBytes = content
+						}
+						return &awss3.PutObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.DeleteObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.DeleteObjectOutput, error) {
+						incompletePart = nil
+						return &awss3.DeleteObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						etag := fmt.Sprintf("etag-%d", *input.PartNumber)
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						partContents[*input.PartNumber] = string(content)
+						uploadedParts = append(uploadedParts, types.Part{
+							Size:       aws.Int64(int64(len(content))),
+							ETag:       aws.String(etag),
+							PartNumber: input.PartNumber,
+						})
+						return &awss3.UploadPartOutput{ETag: aws.String(etag)}, nil
+					}).AnyTimes()
+			})
+
+			It("should record the checksum of the incomplete part", func(ctx context.Context) {
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("1234567"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(7)))
+				Expect(incompletePart).To(BeEquivalentTo("67"))
+
+				var info xferfile.Info
+				Expect(json.Unmarshal(infoBytes, &info)).To(Succeed())
+				Expect(info.Metadata).To(HaveKeyWithValue(incompletePartChecksumMeta,
+					fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte("67")))))
+			}, NodeTimeout(10*time.Second))
+
+			It("should restart the tail of a corrupted incomplete part", func(ctx context.Context) {
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("1234567"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				By("corrupt the incomplete part before resuming")
+				incompletePart = []byte("6x")
+				destStorage = newDestStorage()
+				info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(int64(7)))
+				_, err = destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("89012"), 7, mockClient)
+				Expect(err).To(MatchError(storage.ErrIncompletePartCorrupted))
+				Expect(incompletePart).To(BeNil())
+				Expect(partContents).To(Equal(map[int32]string{1: "12345"}))
+
+				By("retry from the offset before the incomplete part")
+				destStorage = newDestStorage()
+				info, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(int64(5)))
+				Expect(info.Metadata).ToNot(HaveKey(incompletePartChecksumMeta))
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("6789012"), 5, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(7)))
+				Expect(partContents).To(Equal(map[int32]string{1: "12345", 2: "67890", 3: "12"}))
+			}, NodeTimeout(10*time.Second))
+		})
+
+		Context("with the parts verified on resume", func() {
+			var (
+				infoBytes      []byte
+				incompletePart []byte
+				uploadedParts  []types.Part
+				partContents   map[int32]string
+				partsMu        sync.Mutex
+			)
+
+			newDestStorage := func() *Destination {
+				return destStorageFactory(func(d *Destination) {
+					d.MinPartSize = 5
+					d.PreferredPartSize = 5
+					d.VerifyPartsOnResume = true
+				})
+			}
+			md5Hex := func(content string) string {
+				sum := md5.Sum([]byte(content))
+				return hex.EncodeToString(sum[:])
+			}
+			recordedInfo := func() (info xferfile.Info) {
+				partsMu.Lock()
+				defer partsMu.Unlock()
+				Expect(json.Unmarshal(infoBytes, &info)).To(Succeed())
+				return
+			}
+
+			BeforeEach(func() {
+				destStorage = newDestStorage()
+				incompletePart = nil
+				uploadedParts = nil
+				partContents = map[int32]string{}
+				fileInfo.Size = 12
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err = json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+				mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+				multipartKey := fileInfo.Metadata[multipartKeyMeta]
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.GetObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						if *input.Key == multipartKey {
+							return &awss3.GetObjectOutput{
+								ContentLength: aws.Int64(int64(len(incompletePart))),
+								Body:          io.NopCloser(bytes.NewReader(incompletePart)),
+							}, nil
+						}
+						return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.ListPartsInput,
+						...func(*awss3.Options),
+					) (*awss3.ListPartsOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						return &awss3.ListPartsOutput{Parts: slices.Clone(uploadedParts)}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.HeadObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.HeadObjectOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						if incompletePart == nil {
+							return nil, &types.NoSuchKey{}
+						}
+						return &awss3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(incompletePart)))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.PutObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.PutObjectOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						if *input.Key == multipartKey {
+							incompletePart = content
+						} else {
+							Expect(*input.Key).To(Equal(infoPath))
+							infoBytes = content
+						}
+						return &awss3.PutObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.DeleteObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.DeleteObjectOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						incompletePart = nil
+						return &awss3.DeleteObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						etag := fmt.Sprintf("%q", md5Hex(string(content)))
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						partContents[*input.PartNumber] = string(content)
+						// an uploaded part replaces the part of the same number
+						uploadedParts = slices.DeleteFunc(uploadedParts, func(part types.Part) bool {
+							return *part.PartNumber == *input.PartNumber
+						})
+						uploadedParts = append(uploadedParts, types.Part{
+							Size:       aws.Int64(int64(len(content))),
+							ETag:       aws.String(etag),
+							PartNumber: input.PartNumber,
+						})
+						slices.SortFunc(uploadedParts, func(a, b types.Part) int {
+							return int(*a.PartNumber - *b.PartNumber)
+						})
+						return &awss3.UploadPartOutput{ETag: aws.String(etag)}, nil
+					}).AnyTimes()
+			})
+
+			It("should record the checksum of each uploaded part", func(ctx context.Context) {
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678901"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(11)))
+
+				info := recordedInfo()
+				Expect(info.Metadata).To(HaveKeyWithValue(partChecksumMetaPrefix+"1", md5Hex("12345")))
+				Expect(info.Metadata).To(HaveKeyWithValue(partChecksumMetaPrefix+"2", md5Hex("67890")))
+
+				By("resume with the parts matching their checksums")
+				destStorage = newDestStorage()
+				resumedInfo, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resumedInfo.Offset).To(Equal(int64(11)))
+				Expect(incompletePart).To(BeEquivalentTo("1"))
+			}, NodeTimeout(10*time.Second))
+
+			It("should upload the mismatched part again", func(ctx context.Context) {
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678901"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				By("corrupt the ETag of the second part before resuming")
+				partsMu.Lock()
+				uploadedParts[1].ETag = aws.String(fmt.Sprintf("%q", md5Hex("6789x")))
+				partsMu.Unlock()
+				destStorage = newDestStorage()
+				info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(int64(5)))
+				Expect(incompletePart).To(BeNil())
+
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("6789012"), 5, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(7)))
+				Expect(partContents).To(Equal(map[int32]string{1: "12345", 2: "67890", 3: "12"}))
+
+				By("resume with the part uploaded again")
+				destStorage = newDestStorage()
+				info, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(int64(12)))
+			}, NodeTimeout(10*time.Second))
+		})
+
+		Context("with a part size strategy", func() {
+			var (
+				partSizes []int64
+				partsMu   sync.Mutex
+			)
+
+			BeforeEach(func() {
+				partSizes = nil
+				fileInfo.Size = 20
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err := json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader(infoBytes)),
+				}, nil)
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NoSuchKey{})
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						for len(partSizes) < int(*input.PartNumber) {
+							partSizes = append(partSizes, 0)
+						}
+						partSizes[*input.PartNumber-1] = int64(len(content))
+						return &awss3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+					}).AnyTimes()
+			})
+
+			It("should upload the parts of a fixed size", func(ctx context.Context) {
+				destStorage = destStorageFactory(func(d *Destination) {
+					d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 2, 4, 8
+					d.PartSizeStrategy = FixedPartSize(6)
+				})
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678901234567890"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partSizes).To(Equal([]int64{6, 6, 6, 2}))
+			}, NodeTimeout(10*time.Second))
+
+			It("should upload the parts of a size ramping up over the transfer", func(ctx context.Context) {
+				destStorage = destStorageFactory(func(d *Destination) {
+					d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 2, 4, 8
+					d.PartSizeStrategy = RampingPartSize{InitialSize: 2, MaxSize: 8, PartsPerStep: 2}
+				})
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678901234567890"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partSizes).To(Equal([]int64{2, 2, 4, 4, 8}))
+			}, NodeTimeout(10*time.Second))
+
+			It("should upload the parts with the default concurrency if it is not set", func(ctx context.Context) {
+				destStorage = destStorageFactory(func(d *Destination) {
+					d.MinPartSize, d.PreferredPartSize, d.MaxPartSize = 2, 4, 8
+					d.MaxConcurrentPartUploads = 0
+				})
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345678901234567890"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partSizes).To(Equal([]int64{4, 4, 4, 4, 4}))
+			}, NodeTimeout(10*time.Second))
+		})
+
+		Context("with the part size adapted to the part timeouts", func() {
+			var (
+				infoBytes     []byte
+				uploadedParts []types.Part
+				attemptSizes  []int
+				partsMu       sync.Mutex
+			)
+
+			BeforeEach(func() {
+				destStorage = destStorageFactory(func(d *Destination) {
+					d.MinPartSize = 2
+					d.PreferredPartSize = 8
+					d.MaxConcurrentPartUploads = 1
+					d.MaxBufferedParts = 1
+					WithAdaptivePartSize(50*time.Millisecond, 1)(d)
+				})
+				uploadedParts = nil
+				attemptSizes = nil
+				fileInfo.Size = 20
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err = json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+				mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.GetObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.ListPartsInput,
+						...func(*awss3.Options),
+					) (*awss3.ListPartsOutput, error) {
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						return &awss3.ListPartsOutput{Parts: slices.Clone(uploadedParts)}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+					Return(nil, &types.NoSuchKey{}).AnyTimes()
+				mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.PutObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.PutObjectOutput, error) {
+						Expect(*input.Key).To(Equal(infoPath))
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						infoBytes = content
+						return &awss3.PutObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						ctx context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						partsMu.Lock()
+						attemptSizes = append(attemptSizes, len(content))
+						partsMu.Unlock()
+						// the link is too slow for the parts larger than 4 bytes
+						if len(content) > 4 {
+							<-ctx.Done()
+							return nil, ctx.Err()
+						}
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						uploadedParts = slices.DeleteFunc(uploadedParts, func(part types.Part) bool {
+							return *part.PartNumber == *input.PartNumber
+						})
+						uploadedParts = append(uploadedParts, types.Part{
+							Size:       aws.Int64(int64(len(content))),
+							ETag:       aws.String(fmt.Sprintf("etag-%d", *input.PartNumber)),
+							PartNumber: input.PartNumber,
+						})
+						slices.SortFunc(uploadedParts, func(a, b types.Part) int {
+							return int(*a.PartNumber - *b.PartNumber)
+						})
+						return &awss3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag-%d", *input.PartNumber))}, nil
+					}).AnyTimes()
+			})
+
+			It("should reduce the part size until the transfer completes", func(ctx context.Context) {
+				content := "12345678901234567890"
+				_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader(content), 0, mockClient)
+				Expect(err).To(MatchError(storage.ErrPartTimeout))
+				Expect(err).ToNot(MatchError(context.DeadlineExceeded))
+
+				By("resume the transfer from the uploaded offset until it completes")
+				var info xferfile.Info
+				for attempt := 0; ; attempt++ {
+					Expect(attempt).To(BeNumerically("<", 5))
+					info, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+					Expect(err).ToNot(HaveOccurred())
+					if _, err = destStorage.TransferFileChunk(
+						ctx, fileInfo.Path, strings.NewReader(content[info.Offset:]), info.Offset, mockClient,
+					); err == nil {
+						break
+					}
+					Expect(err).To(MatchError(storage.ErrPartTimeout))
+				}
+				info, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(Equal(int64(20)))
+
+				partsMu.Lock()
+				defer partsMu.Unlock()
+				Expect(attemptSizes[0]).To(Equal(8))
+				Expect(attemptSizes[1:]).To(HaveEach(BeNumerically("<=", 4)))
+				Expect(uploadedParts).To(HaveLen(5))
+				for _, part := range uploadedParts {
+					Expect(*part.Size).To(BeNumerically("<=", 4))
+				}
+			}, NodeTimeout(10*time.Second))
+
+			It("should halve the part size down to the minimum", func() {
+				partSize := &adaptivePartSize{size: 8, minSize: 3}
+				observe := func(size int64) (reduced bool) {
+					_, reduced = partSize.observeTimeout(size, 2)
+					return
+				}
+				Expect(observe(8)).To(BeFalse())
+				Expect(observe(8)).To(BeTrue())
+				Expect(partSize.get()).To(Equal(int64(4)))
+
+				By("ignore the timeouts of the parts produced before the reduction")
+				Expect(observe(8)).To(BeFalse())
+				Expect(observe(4)).To(BeFalse())
+				Expect(observe(4)).To(BeTrue())
+				Expect(observe(3)).To(BeFalse())
+				Expect(observe(3)).To(BeFalse())
+				Expect(partSize.get()).To(Equal(int64(3)))
+			})
+		})
+
+		Context("with a single-part file", func() {
+			var incompletePart []byte
+
+			BeforeEach(func() {
+				incompletePart = nil
+				fileInfo.Size = 10
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				fileInfo.Metadata[isSinglePartMeta] = "true"
+				infoBytes, err := json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+				mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+				multipartKey := fileInfo.Metadata[multipartKeyMeta]
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.GetObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						if *input.Key == multipartKey {
+							return &awss3.GetObjectOutput{
+								ContentLength: aws.Int64(int64(len(incompletePart))),
+								Body:          io.NopCloser(bytes.NewReader(incompletePart)),
+							}, nil
+						}
+						return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).
+					Return(&awss3.ListPartsOutput{}, nil).AnyTimes()
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.HeadObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.HeadObjectOutput, error) {
+						if incompletePart == nil {
+							return nil, &types.NoSuchKey{}
+						}
+						return &awss3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(incompletePart)))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.PutObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.PutObjectOutput, error) {
+						Expect(*input.Key).To(Equal(multipartKey))
+						incompletePart, err = io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						return &awss3.PutObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.DeleteObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.DeleteObjectOutput, error) {
+						incompletePart = nil
+						return &awss3.DeleteObjectOutput{}, nil
+					}).AnyTimes()
+			})
+
+			It("should keep the chunk of an interrupted transfer as the incomplete part", func(ctx context.Context) {
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Times(0)
+
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(5)))
+				Expect(incompletePart).To(BeEquivalentTo("12345"))
+			}, NodeTimeout(10*time.Second))
+
+			It("should upload the whole part when the interrupted transfer resumes", func(ctx context.Context) {
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						Expect(*input.PartNumber).To(Equal(int32(1)))
+						Expect(io.ReadAll(input.Body)).To(BeEquivalentTo("1234567890"))
+						return &awss3.UploadPartOutput{ETag: aws.String("etag-1")}, nil
+					})
+
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(5)))
+
+				By("resume the transfer with a new destination, as after a restart")
+				destStorage = destStorageFactory(nil)
+				n, err = destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("67890"), 5, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(5)))
+				Expect(incompletePart).To(BeNil())
+			}, NodeTimeout(10*time.Second))
+
+			DescribeTable("should resume the transfer regardless of the temporary backend of the new destination",
+				func(ctx context.Context, startTempDir, resumeTempDir string) {
+					mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+						DoAndReturn(func(
+							_ context.Context,
+							input *awss3.UploadPartInput,
+							_ ...func(*awss3.Options),
+						) (*awss3.UploadPartOutput, error) {
+							Expect(io.ReadAll(input.Body)).To(BeEquivalentTo("1234567890"))
+							return &awss3.UploadPartOutput{ETag: aws.String("etag-1")}, nil
+						})
+					tempDir := GinkgoT().TempDir()
+					tempDirectory := func(dir string) string { return cmp.Or(dir, tempDir) }
+
+					destStorage = destStorageFactory(func(s *Destination) {
+						s.TemporaryDirectory = tempDirectory(startTempDir)
+					})
+					_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("12345"), 0, mockClient)
+					Expect(err).ToNot(HaveOccurred())
+
+					By("resume the transfer with a new destination, as after a restart")
+					destStorage = destStorageFactory(func(s *Destination) {
+						s.TemporaryDirectory = tempDirectory(resumeTempDir)
+					})
+					n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("67890"), 5, mockClient)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(int64(5)))
+					Expect(incompletePart).To(BeNil())
+					Expect(os.ReadDir(tempDir)).To(BeEmpty())
+				},
+				Entry("started on disk, resumed in memory", NodeTimeout(10*time.Second), "", TempDirUseMemory),
+				Entry("started in memory, resumed on disk", NodeTimeout(10*time.Second), TempDirUseMemory, ""),
+			)
+		})
+	})
+
+	Describe("CanCopyFrom", func() {
+		DescribeTable("should report whether the source can be copied server-side",
+			func(srcStorage storage.Source, srcClientEditorFn func(c *s3_protoc.Client), expected bool) {
+				srcClient := s3_protoc.NewClient(endpoint, "source-bucket", region, accessKey, secretKey)
+				if srcClientEditorFn != nil {
+					srcClientEditorFn(srcClient)
+				}
+				Expect(destStorage.CanCopyFrom(srcStorage, *srcClient, *s3ProtocClient)).To(Equal(expected))
+			},
+			Entry("same endpoint and region", NewSource(GinkgoLogr), nil, true),
+			Entry("different endpoint", NewSource(GinkgoLogr), func(c *s3_protoc.Client) {
+				c.Endpoint = "http://other-endpoint:9000"
+			}, false),
+			Entry("different region", NewSource(GinkgoLogr), func(c *s3_protoc.Client) {
+				c.Region = "ap-southeast-1"
+			}, false),
+			Entry("non-S3 source", nil, nil, false),
+		)
+
+		It("should not copy from a non-S3 client", func() {
+			Expect(destStorage.CanCopyFrom(NewSource(GinkgoLogr), localio_protoc.NewIO(), *s3ProtocClient)).To(BeFalse())
+		})
+	})
+
+	Describe("CopyFileFrom", func() {
+		var srcClient *s3_protoc.Client
+
+		BeforeEach(func() {
+			fileInfo.Size = 314
+			destStorage = destStorageFactory(func(s *Destination) {
+				s.MaxPartSize = 8
+				s.MinPartSize = 4
+				s.PreferredPartSize = 4
+				s.MaxMultipartParts = 10000
+			})
+			srcClient = s3_protoc.NewClient(endpoint, "source-bucket", region, accessKey, secretKey)
+
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		// expectUploadInfo expects the info of an upload with two parts (300 bytes)
+		// and an incomplete part of the given size
+		expectUploadInfo := func(ctx context.Context, incompletePartSize int64) {
+			GinkgoHelper()
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.GetObjectInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.GetObjectOutput, error) {
+					fileInfo.Metadata[bucketMeta] = bucketName
+					fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+					fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+					infoBytes, err := json.Marshal(fileInfo)
+					Expect(err).ToNot(HaveOccurred())
+					return &awss3.GetObjectOutput{
+						Body: io.NopCloser(bytes.NewReader(infoBytes)),
+					}, nil
+				})
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{Size: aws.Int64(200), ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+				},
+			}, nil)
+			if incompletePartSize == 0 {
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{})
+				return
+			}
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(incompletePartSize),
+			}, nil)
+		}
+
+		// expectPartCopies expects the copies of the source ranges (part number -> range)
+		expectPartCopies := func(ctx context.Context, ranges map[int32]string) {
+			GinkgoHelper()
+			mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).Times(0)
+			mockS3API.EXPECT().UploadPartCopy(ctx, gomock.Any()).
+				DoAndReturn(func(
+					ctx context.Context,
+					input *awss3.UploadPartCopyInput,
+					opts ...func(*awss3.Options),
+				) (*awss3.UploadPartCopyOutput, error) {
+					Expect(*input.Bucket).To(Equal(bucketName))
+					Expect(*input.Key).To(Equal(fileInfo.Path))
+					Expect(*input.UploadId).To(Equal("test-multipart-id"))
+					Expect(*input.CopySource).To(Equal("source-bucket%2Fsource.txt"))
+					Expect(ranges).To(HaveKeyWithValue(*input.PartNumber, *input.CopySourceRange))
+					return &awss3.UploadPartCopyOutput{}, nil
+				}).Times(len(ranges))
+		}
+
+		It("should copy the remaining ranges with UploadPartCopy", func(ctx context.Context) {
+			expectUploadInfo(ctx, 0)
+			expectPartCopies(ctx, map[int32]string{
+				3: "bytes=300-303",
+				4: "bytes=304-307",
+				5: "bytes=308-311",
+				6: "bytes=312-313",
+			})
+
+			recorder := &confirmedSizeRecorder{}
+			n, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 300, mockClient, recorder)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(14)))
+			Expect(recorder.Steps()).To(ConsistOf(int64(4), int64(4), int64(4), int64(2)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should copy the range of the incomplete part again", func(ctx context.Context) {
+			expectUploadInfo(ctx, 2)
+			mockS3API.EXPECT().DeleteObject(ctx, &awss3.DeleteObjectInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Metadata[multipartKeyMeta]),
+			}).Return(&awss3.DeleteObjectOutput{}, nil)
+			expectPartCopies(ctx, map[int32]string{
+				3: "bytes=300-303",
+				4: "bytes=304-307",
+				5: "bytes=308-311",
+				6: "bytes=312-313",
+			})
+
+			recorder := &confirmedSizeRecorder{}
+			n, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 302, mockClient, recorder)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(12)))
+			Expect(recorder.Steps()).To(ConsistOf(int64(2), int64(4), int64(4), int64(2)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should copy the ranges of the part layout of the source", func(ctx context.Context) {
+			fileInfo.Metadata[storage.PartLayoutMeta] = "100,200,6,5,3"
+			expectUploadInfo(ctx, 0)
+			expectPartCopies(ctx, map[int32]string{
+				3: "bytes=300-305",
+				4: "bytes=306-310",
+				5: "bytes=311-313",
+			})
+
+			n, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 300, mockClient, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(14)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the uploaded parts do not follow the part layout", func(ctx context.Context) {
+			fileInfo.Metadata[storage.PartLayoutMeta] = "150,150,6,5,3"
+			expectUploadInfo(ctx, 0)
+
+			_, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 300, mockClient, nil)
+			Expect(err).To(MatchError(storage.ErrPartLayoutInvalid))
+		}, NodeTimeout(10*time.Second))
+
+		It("should wait for the tracker before copying each part", func(ctx context.Context) {
+			expectUploadInfo(ctx, 0)
+			expectPartCopies(ctx, map[int32]string{
+				3: "bytes=300-303",
+				4: "bytes=304-307",
+			})
+
+			var waits int
+			recorder := &pausingRecorder{wait: func(ctx context.Context) error {
+				// the transfer is canceled while held before the third part
+				if waits++; waits == 3 {
+					return context.Canceled
+				}
+				return nil
+			}}
+			_, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 300, mockClient, recorder)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(waits).To(Equal(3))
+			Expect(recorder.Steps()).To(ConsistOf(int64(4), int64(4)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when copying a part failed", func(ctx context.Context) {
+			expectUploadInfo(ctx, 0)
+			mockS3API.EXPECT().UploadPartCopy(ctx, gomock.Any()).
+				Return(nil, errors.New("copy failed")).MinTimes(1)
+
+			n, err := destStorage.CopyFileFrom(ctx, fileInfo.Path, "source.txt", *srcClient, 300, mockClient, nil)
+			Expect(err).To(MatchError("copy failed"))
+			Expect(n).To(BeZero())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("FinalizeTransfer", func() {
+		It("should not complete the upload when a part has no ETag", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Size = 300
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{Size: aws.Int64(200), PartNumber: aws.Int32(2)},
+				},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NotFound{})
+			mockS3API.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).Times(0)
+
+			err = destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrPartETagMissing))
+			Expect(err).To(MatchError(ContainSubstring("part 2")))
+		}, NodeTimeout(10*time.Second))
+
+		It("should explain the part too small to complete the upload", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Size = 350
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{Size: aws.Int64(200), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{Size: aws.Int64(100), ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+					{Size: aws.Int64(50), ETag: aws.String("etag-3"), PartNumber: aws.Int32(3)},
+				},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NotFound{})
+			entityTooSmall := &smithy.GenericAPIError{
+				Code:    "EntityTooSmall",
+				Message: "Your proposed upload is smaller than the minimum allowed object size.",
+			}
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, gomock.Any()).Return(nil, entityTooSmall)
+
+			err = destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrPartTooSmall))
+			Expect(err).To(MatchError(entityTooSmall))
+			Expect(err).To(MatchError(ContainSubstring("part 2 is 100 bytes")))
+			Expect(err).To(MatchError(ContainSubstring("increase MinPartSize")))
+		}, NodeTimeout(10*time.Second))
+
 		It("should finish the upload successfully", func(ctx context.Context) {
 			connID := uuid.NewString()
 			mockClient.EXPECT().GetConnectionID().
@@ -1219,6 +2974,43 @@ var _ = Describe("Destination", func() {
 			)).To(Succeed())
 		}, NodeTimeout(10*time.Second))
 
+		It("should return the completed object", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Size = 300
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{
+					{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{Size: aws.Int64(200), ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+				},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NotFound{})
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, gomock.Any()).
+				Return(&awss3.CompleteMultipartUploadOutput{
+					ETag:      aws.String(`"object-etag-2"`),
+					VersionId: aws.String("version-id"),
+					Location:  aws.String("https://bucket.s3.amazonaws.com/" + fileInfo.Path),
+				}, nil)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).Return(nil, nil)
+
+			object, err := destStorage.FinalizeTransferWithObject(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object).To(Equal(storage.FinalizedObject{
+				ETag:      `"object-etag-2"`,
+				VersionID: "version-id",
+				Location:  "https://bucket.s3.amazonaws.com/" + fileInfo.Path,
+			}))
+		}, NodeTimeout(10*time.Second))
+
 		It("should return error not finalize if total part size not equal source size", func(ctx context.Context) {
 			connID := uuid.NewString()
 			mockClient.EXPECT().GetConnectionID().
@@ -1373,6 +3165,168 @@ var _ = Describe("Destination", func() {
 				mockClient,
 			)).To(MatchError(occurError))
 		}, NodeTimeout(10*time.Second))
+
+		Context("with a file of an unknown size", func() {
+			var (
+				infoBytes      []byte
+				incompletePart []byte
+				uploadedParts  []types.Part
+				partContents   map[int32]string
+				completedParts []types.CompletedPart
+				partsMu        sync.Mutex
+			)
+
+			BeforeEach(func() {
+				destStorage = destStorageFactory(func(d *Destination) {
+					d.MinPartSize = 5
+					d.PreferredPartSize = 5
+				})
+				incompletePart = nil
+				uploadedParts = nil
+				partContents = map[int32]string{}
+				completedParts = nil
+				fileInfo.Size = xferfile.SizeUnknown
+				fileInfo.Offset = 0
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err = json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+				mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+				multipartKey := fileInfo.Metadata[multipartKeyMeta]
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.GetObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						if *input.Key == multipartKey {
+							return &awss3.GetObjectOutput{
+								ContentLength: aws.Int64(int64(len(incompletePart))),
+								Body:          io.NopCloser(bytes.NewReader(incompletePart)),
+							}, nil
+						}
+						return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.ListPartsInput,
+						...func(*awss3.Options),
+					) (*awss3.ListPartsOutput, error) {
+						return &awss3.ListPartsOutput{Parts: uploadedParts}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.HeadObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.HeadObjectOutput, error) {
+						if incompletePart == nil {
+							return nil, &types.NoSuchKey{}
+						}
+						return &awss3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(incompletePart)))}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.PutObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.PutObjectOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						if *input.Key == multipartKey {
+							incompletePart = content
+						} else {
+							Expect(*input.Key).To(Equal(infoPath))
+							infoBytes = content
+						}
+						return &awss3.PutObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						context.Context,
+						*awss3.DeleteObjectInput,
+						...func(*awss3.Options),
+					) (*awss3.DeleteObjectOutput, error) {
+						incompletePart = nil
+						return &awss3.DeleteObjectOutput{}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().UploadPart(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.UploadPartInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.UploadPartOutput, error) {
+						content, err := io.ReadAll(input.Body)
+						Expect(err).ToNot(HaveOccurred())
+						etag := fmt.Sprintf("etag-%d", *input.PartNumber)
+						partsMu.Lock()
+						defer partsMu.Unlock()
+						partContents[*input.PartNumber] = string(content)
+						uploadedParts = append(uploadedParts, types.Part{
+							Size:       aws.Int64(int64(len(content))),
+							ETag:       aws.String(etag),
+							PartNumber: input.PartNumber,
+						})
+						return &awss3.UploadPartOutput{ETag: aws.String(etag)}, nil
+					}).AnyTimes()
+				mockS3API.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.CompleteMultipartUploadInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.CompleteMultipartUploadOutput, error) {
+						completedParts = input.MultipartUpload.Parts
+						return &awss3.CompleteMultipartUploadOutput{}, nil
+					}).AnyTimes()
+			})
+
+			It("should stream the chunks until the end and finish with the uploaded size", func(ctx context.Context) {
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("1234567"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(7)))
+				Expect(incompletePart).To(BeEquivalentTo("67"))
+
+				n, err = destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("89012"), 7, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(5)))
+				Expect(incompletePart).To(BeEquivalentTo("12"))
+
+				info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Size).To(Equal(xferfile.SizeUnknown))
+				Expect(info.Offset).To(Equal(int64(12)))
+
+				Expect(destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)).To(Succeed())
+				Expect(incompletePart).To(BeNil())
+				Expect(partContents).To(Equal(map[int32]string{1: "12345", 2: "67890", 3: "12"}))
+				Expect(completedParts).To(HaveLen(3))
+
+				var finishedInfo xferfile.Info
+				Expect(json.Unmarshal(infoBytes, &finishedInfo)).To(Succeed())
+				Expect(finishedInfo.Size).To(Equal(int64(12)))
+				Expect(finishedInfo.Offset).To(Equal(int64(12)))
+				Expect(finishedInfo.FinishTime).ToNot(BeZero())
+			}, NodeTimeout(10*time.Second))
+
+			It("should finish a stream shorter than a part with a single part", func(ctx context.Context) {
+				n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader("123"), 0, mockClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(3)))
+				Expect(uploadedParts).To(BeEmpty())
+
+				Expect(destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)).To(Succeed())
+				Expect(partContents).To(Equal(map[int32]string{1: "123"}))
+
+				var finishedInfo xferfile.Info
+				Expect(json.Unmarshal(infoBytes, &finishedInfo)).To(Succeed())
+				Expect(finishedInfo.Size).To(Equal(int64(3)))
+			}, NodeTimeout(10*time.Second))
+		})
 	})
 
 	Describe("DeleteFile", func() {
@@ -1442,6 +3396,72 @@ var _ = Describe("Destination", func() {
 			)).To(Succeed())
 		}, NodeTimeout(10*time.Second))
 
+		Context("with the object lock check", func() {
+			BeforeEach(func() {
+				destStorage.CheckObjectLock = true
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			})
+
+			DescribeTable("should refuse to delete a locked object",
+				func(ctx context.Context, res *awss3.HeadObjectOutput, expectedMsg string) {
+					mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+						Bucket: aws.String(bucketName),
+						Key:    aws.String(fileInfo.Path),
+					}).Return(res, nil)
+					mockS3API.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Times(0)
+					mockS3API.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).Times(0)
+
+					err := destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)
+					Expect(err).To(MatchError(storage.ErrDestinationImmutable))
+					Expect(err).To(MatchError(ContainSubstring(expectedMsg)))
+				},
+				Entry("under a legal hold", &awss3.HeadObjectOutput{
+					ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+				}, "legal hold", NodeTimeout(10*time.Second)),
+				Entry("under a retention", &awss3.HeadObjectOutput{
+					ObjectLockMode:            types.ObjectLockModeCompliance,
+					ObjectLockRetainUntilDate: aws.Time(time.Now().Add(24 * time.Hour)),
+				}, "retained in COMPLIANCE mode", NodeTimeout(10*time.Second)),
+			)
+
+			It("should delete an object whose retention has expired", func(ctx context.Context) {
+				mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(fileInfo.Path),
+				}).Return(&awss3.HeadObjectOutput{
+					ObjectLockMode:            types.ObjectLockModeGovernance,
+					ObjectLockRetainUntilDate: aws.Time(time.Now().Add(-time.Hour)),
+					ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOff,
+				}, nil)
+				mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+					DoAndReturn(func(
+						ctx context.Context,
+						input *awss3.GetObjectInput,
+						opts ...func(*awss3.Options),
+					) (*awss3.GetObjectOutput, error) {
+						fileInfo.Metadata[bucketMeta] = bucketName
+						fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+						fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+						infoBytes, err := json.Marshal(fileInfo)
+						Expect(err).ToNot(HaveOccurred())
+						return &awss3.GetObjectOutput{
+							Body: io.NopCloser(bytes.NewReader(infoBytes)),
+						}, nil
+					})
+				mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+				mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    aws.String(fileInfo.Metadata[multipartKeyMeta]),
+				}).Return(nil, &types.NotFound{})
+				mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).Return(nil, nil)
+				mockS3API.EXPECT().DeleteObjects(ctx, gomock.Any()).Return(&awss3.DeleteObjectsOutput{}, nil)
+
+				Expect(destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)).To(Succeed())
+			}, NodeTimeout(10*time.Second))
+		})
+
 		It("should return error when checking and setting client failed", func(ctx context.Context) {
 			mockClient.EXPECT().GetConnectionID().Return("")
 			mockClient.EXPECT().GetS3API().Return(mockS3API)
@@ -1560,6 +3580,491 @@ var _ = Describe("Destination", func() {
 				mockClient,
 			)).To(MatchError(occurError))
 		}, NodeTimeout(10*time.Second))
+
+		Context("with a backend not implementing DeleteObjects", func() {
+			var deletedKeys []string
+
+			BeforeEach(func() {
+				deletedKeys = nil
+				mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+				mockClient.EXPECT().GetS3API().Return(mockS3API)
+				mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+				fileInfo.Metadata[bucketMeta] = bucketName
+				fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+				fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+				infoBytes, err := json.Marshal(fileInfo)
+				Expect(err).ToNot(HaveOccurred())
+				mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+					Body: io.NopCloser(bytes.NewReader(infoBytes)),
+				}, nil)
+				mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+				mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NotFound{})
+				mockS3API.EXPECT().AbortMultipartUpload(gomock.Any(), gomock.Any()).Return(nil, nil)
+				mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(
+						_ context.Context,
+						input *awss3.DeleteObjectInput,
+						_ ...func(*awss3.Options),
+					) (*awss3.DeleteObjectOutput, error) {
+						Expect(*input.Bucket).To(Equal(bucketName))
+						deletedKeys = append(deletedKeys, *input.Key)
+						// the incomplete part does not exist
+						if *input.Key == fileInfo.Metadata[multipartKeyMeta] {
+							return nil, &types.NoSuchKey{}
+						}
+						return &awss3.DeleteObjectOutput{}, nil
+					}).Times(3)
+			})
+
+			It("should delete the objects one by one once DeleteObjects is not implemented", func(ctx context.Context) {
+				mockS3API.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).
+					Return(nil, &smithy.GenericAPIError{Code: "NotImplemented"})
+
+				Expect(destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)).To(Succeed())
+				Expect(deletedKeys).To(Equal([]string{fileInfo.Path, fileInfo.Metadata[multipartKeyMeta], infoPath}))
+				Expect(destStorage.batchDeleteUnsupported.Load()).To(BeTrue())
+			}, NodeTimeout(10*time.Second))
+
+			It("should not delete the objects in a batch when disabled", func(ctx context.Context) {
+				destStorage.DisableBatchDelete = true
+				mockS3API.EXPECT().DeleteObjects(gomock.Any(), gomock.Any()).Times(0)
+
+				Expect(destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)).To(Succeed())
+				Expect(deletedKeys).To(HaveLen(3))
+			}, NodeTimeout(10*time.Second))
+		})
+	})
+
+	Describe("WithDisableChunkedSigning", func() {
+		var s3Client *awss3.Client
+
+		BeforeEach(func() {
+			// the backend rejects the parts streamed with the aws-chunked encoding
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") ||
+					len(r.TransferEncoding) > 0 || r.ContentLength < 0 {
+					w.WriteHeader(http.StatusNotImplemented)
+					return
+				}
+				w.Header().Set("ETag", `"etag"`)
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(server.Close)
+			s3Client = awss3.New(awss3.Options{
+				Region:       region,
+				BaseEndpoint: aws.String(server.URL),
+				UsePathStyle: true,
+				HTTPClient:   server.Client(),
+				// the default of the clients created from an aws.Config
+				RequestChecksumCalculation: aws.RequestChecksumCalculationWhenSupported,
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}, nil
+				}),
+			})
+		})
+
+		uploadPart := func(ctx context.Context) (string, error) {
+			upload := destStorage.getUpload(fileInfo.Path, bucketName, s3Client)
+			return upload.putPartForUpload(ctx, &awss3.UploadPartInput{
+				Bucket:     aws.String(bucketName),
+				Key:        aws.String(fileInfo.Path),
+				UploadId:   aws.String("upload-id"),
+				PartNumber: aws.Int32(1),
+			}, strings.NewReader("12345678"), 8)
+		}
+
+		It("should be rejected by the backend with the chunked signing", func(ctx context.Context) {
+			_, err := uploadPart(ctx)
+			Expect(err).To(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+
+		It("should upload the part with a fixed content length", func(ctx context.Context) {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithDisableChunkedSigning())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uploadPart(ctx)).To(Equal(`"etag"`))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("WithIncompletePartConcurrency", func() {
+		It("should bound the concurrent operations on the incomplete parts across the transfers", func(ctx context.Context) {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithIncompletePartConcurrency(2))
+			Expect(err).ToNot(HaveOccurred())
+			var inFlight, maxInFlight atomic.Int64
+			roundTrip := func() {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for m := maxInFlight.Load(); n > m; m = maxInFlight.Load() {
+					if maxInFlight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, *awss3.GetObjectInput, ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+					roundTrip()
+					return &awss3.GetObjectOutput{
+						Body:          io.NopCloser(strings.NewReader("1234")),
+						ContentLength: aws.Int64(4),
+					}, nil
+				}).Times(4)
+			mockS3API.EXPECT().DeleteObject(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(context.Context, *awss3.DeleteObjectInput, ...func(*awss3.Options)) (*awss3.DeleteObjectOutput, error) {
+					roundTrip()
+					return &awss3.DeleteObjectOutput{}, nil
+				}).Times(4)
+
+			var wg sync.WaitGroup
+			for i := range 4 {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					upload := destStorage.getUpload(fmt.Sprintf("resumed-%d.txt", i), bucketName, mockS3API)
+					_, releasePart, err := upload.downloadIncompletePartForUpload(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					defer releasePart()
+					Expect(upload.deleteIncompletePartForUpload(ctx)).To(Succeed())
+				}()
+			}
+			wg.Wait()
+			Expect(maxInFlight.Load()).To(Equal(int64(2)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should fail the waiting operation with the error of its context", func(ctx context.Context) {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithIncompletePartConcurrency(1))
+			Expect(err).ToNot(HaveOccurred())
+			release, err := destStorage.acquireIncompletePart(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			defer release()
+
+			canceledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			upload := destStorage.getUpload(fileInfo.Path, bucketName, mockS3API)
+			Expect(upload.deleteIncompletePartForUpload(canceledCtx)).To(MatchError(context.Canceled))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("WithCreateIfNotExists", func() {
+		BeforeEach(func() {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithCreateIfNotExists())
+			Expect(err).ToNot(HaveOccurred())
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			fileInfo.Size = 100
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)}},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NotFound{})
+		})
+
+		It("should complete the upload only if the object does not exist", func(ctx context.Context) {
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.CompleteMultipartUploadInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.CompleteMultipartUploadOutput, error) {
+					Expect(input.IfNoneMatch).To(HaveValue(Equal("*")))
+					return &awss3.CompleteMultipartUploadOutput{}, nil
+				})
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).Return(nil, nil)
+
+			Expect(destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should fail when the object is created concurrently", func(ctx context.Context) {
+			mockS3API.EXPECT().CompleteMultipartUpload(ctx, gomock.Any()).
+				Return(nil, &smithy.GenericAPIError{Code: "PreconditionFailed"})
+			mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).Times(0)
+
+			err = destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(storage.ErrDestinationExists))
+			Expect(err).To(MatchError(ContainSubstring(fileInfo.Path)))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("WithPreserveModTime", func() {
+		BeforeEach(func() {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithPreserveModTime())
+			Expect(err).ToNot(HaveOccurred())
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should record the modification time of the source in the object metadata", func(ctx context.Context) {
+			modTime := time.Date(2024, 5, 6, 7, 8, 9, 10, time.FixedZone("UTC+7", 7*60*60))
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
+				Bucket:   aws.String(bucketName),
+				Key:      aws.String(fileInfo.Path),
+				Metadata: map[string]string{"src-modtime": "2024-05-06T00:08:09.00000001Z"},
+			}).Return(&awss3.CreateMultipartUploadOutput{UploadId: aws.String("test-multipart-id")}, nil)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).Return(&awss3.PutObjectOutput{}, nil)
+
+			Expect(destStorage.CreateFile(ctx, fileInfo.Path, 100, modTime, mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("OperationTimeout", func() {
+		BeforeEach(func() {
+			destStorage.OperationTimeout = 50 * time.Millisecond
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should fail a hung operation without canceling the context of the caller", func(ctx context.Context) {
+			fileInfo.Size = 100
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).Return(&awss3.GetObjectOutput{
+				Body: io.NopCloser(bytes.NewReader(infoBytes)),
+			}, nil)
+			mockS3API.EXPECT().ListParts(gomock.Any(), gomock.Any()).Return(&awss3.ListPartsOutput{
+				Parts: []types.Part{{Size: aws.Int64(100), ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)}},
+			}, nil)
+			mockS3API.EXPECT().HeadObject(gomock.Any(), gomock.Any()).Return(nil, &types.NotFound{})
+			mockS3API.EXPECT().CompleteMultipartUpload(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(
+					opCtx context.Context,
+					_ *awss3.CompleteMultipartUploadInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.CompleteMultipartUploadOutput, error) {
+					<-opCtx.Done()
+					return nil, opCtx.Err()
+				})
+
+			startAt := time.Now()
+			err = destStorage.FinalizeTransfer(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(time.Since(startAt)).To(BeNumerically(">=", destStorage.OperationTimeout))
+			Expect(ctx.Err()).ToNot(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+
+		It("should not bound the read of the object content", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(
+					opCtx context.Context,
+					_ *awss3.GetObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.GetObjectOutput, error) {
+					return &awss3.GetObjectOutput{Body: &contextReader{ctx: opCtx, content: "1234"}}, nil
+				})
+
+			var conn *s3Client
+			conn, err = destStorage.checkAndSetClient(mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			output, err := conn.client.GetObject(ctx, &awss3.GetObjectInput{Key: aws.String(fileInfo.Path)})
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(2 * destStorage.OperationTimeout)
+			Expect(io.ReadAll(output.Body)).To(BeEquivalentTo("1234"))
+			Expect(output.Body.Close()).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("WithoutInfoForSmallFiles", func() {
+		BeforeEach(func() {
+			var err error
+			destStorage, err = NewDestination(GinkgoLogr, WithoutInfoForSmallFiles(100))
+			Expect(err).ToNot(HaveOccurred())
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString()).AnyTimes()
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should put a file below the threshold without info object", func(ctx context.Context) {
+			const content = "small file content"
+			By("creating the file without any request")
+			Expect(destStorage.CreateFile(ctx, fileInfo.Path, int64(len(content)), fileInfo.ModTime,
+				mockClient)).To(Succeed())
+			info, err := destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(int64(len(content))))
+			Expect(info.Offset).To(BeZero())
+
+			By("putting the whole content with the info in the object metadata")
+			var encodedInfo string
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.PutObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					Expect(*input.Key).To(Equal(fileInfo.Path))
+					Expect(io.ReadAll(input.Body)).To(BeEquivalentTo(content))
+					Expect(input.Metadata).To(HaveKey(smallFileInfoMeta))
+					encodedInfo = input.Metadata[smallFileInfoMeta]
+					return &awss3.PutObjectOutput{ETag: aws.String("etag")}, nil
+				})
+			n, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader(content), 0, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(len(content))))
+
+			object, err := destStorage.FinalizeTransferWithObject(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(object.ETag).To(Equal("etag"))
+
+			By("deriving the state of the finalized file from HeadObject")
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{}).Times(2)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				Metadata: map[string]string{smallFileInfoMeta: encodedInfo},
+			}, nil)
+			info, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Path).To(Equal(fileInfo.Path))
+			Expect(info.Offset).To(Equal(int64(len(content))))
+			Expect(info.ModTime).To(BeTemporally("==", fileInfo.ModTime))
+			Expect(info.FinishTime).ToNot(BeZero())
+		}, NodeTimeout(10*time.Second))
+
+		It("should declare the content type of the source for the small file", func(ctx context.Context) {
+			const content = "small file content"
+			Expect(destStorage.CreateFileWithMetadata(ctx, fileInfo.Path, int64(len(content)), fileInfo.ModTime,
+				map[string]string{storage.ContentTypeMeta: "application/vnd.fxfer+xml"}, mockClient)).To(Succeed())
+
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.PutObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					Expect(input.ContentType).To(HaveValue(Equal("application/vnd.fxfer+xml")))
+					return &awss3.PutObjectOutput{}, nil
+				})
+			_, err := destStorage.TransferFileChunk(ctx, fileInfo.Path, strings.NewReader(content), 0, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+
+		It("should report a small file whose object does not exist as not existing", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{}).Times(2)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, &types.NotFound{})
+
+			_, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not take over an object put by another writer", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).Return(nil, &types.NoSuchKey{}).Times(2)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{}, nil)
+
+			_, err = destStorage.GetFileInfo(ctx, fileInfo.Path, mockClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+
+		It("should create the info object of a file at the threshold", func(ctx context.Context) {
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, gomock.Any()).
+				Return(&awss3.CreateMultipartUploadOutput{UploadId: aws.String("test-multipart-id")}, nil)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.PutObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					Expect(*input.Key).To(Equal(infoPath))
+					return nil, nil
+				})
+
+			Expect(destStorage.CreateFile(ctx, fileInfo.Path, 100, fileInfo.ModTime, mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("metadata objects location", func() {
+		const metadataBucket = "metadata-bucket"
+
+		var (
+			metadataInfoKey string
+			metadataPartKey string
+		)
+
+		BeforeEach(func() {
+			destStorage.MetadataBucket = metadataBucket
+			destStorage.MetadataObjectPrefix = "fxfer/"
+			destStorage.InfoSuffix = ".state"
+			metadataInfoKey = "fxfer/" + fileInfo.Path + ".state"
+			metadataPartKey = "fxfer/" + fileInfo.Path + ".part"
+			mockClient.EXPECT().GetConnectionID().Return(uuid.NewString())
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should create the info object in the metadata bucket and prefix", func(ctx context.Context) {
+			mockS3API.EXPECT().CreateMultipartUpload(ctx, &awss3.CreateMultipartUploadInput{
+				Bucket: aws.String(bucketName),
+				Key:    aws.String(fileInfo.Path),
+			}).Return(&awss3.CreateMultipartUploadOutput{UploadId: aws.String("test-multipart-id")}, nil)
+			mockS3API.EXPECT().PutObject(ctx, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					input *awss3.PutObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.PutObjectOutput, error) {
+					Expect(*input.Bucket).To(Equal(metadataBucket))
+					Expect(*input.Key).To(Equal(metadataInfoKey))
+					var gotInfo xferfile.Info
+					Expect(json.NewDecoder(input.Body).Decode(&gotInfo)).To(Succeed())
+					Expect(gotInfo.Metadata).To(HaveKeyWithValue(multipartKeyMeta, metadataPartKey))
+					return nil, nil
+				})
+
+			Expect(destStorage.CreateFile(ctx, fileInfo.Path, fileInfo.Size, fileInfo.ModTime,
+				mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should read and delete the metadata objects from the metadata bucket", func(ctx context.Context) {
+			fileInfo.Metadata[bucketMeta] = bucketName
+			fileInfo.Metadata[multipartIDMeta] = "test-multipart-id"
+			fileInfo.Metadata[objectKeyMeta] = fileInfo.Path
+			fileInfo.Metadata[multipartKeyMeta] = metadataPartKey
+			infoBytes, err := json.Marshal(fileInfo)
+			Expect(err).ToNot(HaveOccurred())
+			mockS3API.EXPECT().GetObject(ctx, &awss3.GetObjectInput{
+				Bucket: aws.String(metadataBucket),
+				Key:    aws.String(metadataInfoKey),
+			}).Return(&awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(infoBytes))}, nil)
+			mockS3API.EXPECT().ListParts(ctx, gomock.Any()).Return(&awss3.ListPartsOutput{}, nil)
+			mockS3API.EXPECT().HeadObject(ctx, &awss3.HeadObjectInput{
+				Bucket: aws.String(metadataBucket),
+				Key:    aws.String(metadataPartKey),
+			}).Return(nil, &types.NotFound{})
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).Return(nil, nil)
+			mockS3API.EXPECT().DeleteObjects(ctx, &awss3.DeleteObjectsInput{
+				Bucket: aws.String(bucketName),
+				Delete: &types.Delete{
+					Objects: []types.ObjectIdentifier{{Key: aws.String(fileInfo.Path)}},
+					Quiet:   aws.Bool(true),
+				},
+			}).Return(&awss3.DeleteObjectsOutput{}, nil)
+			mockS3API.EXPECT().DeleteObjects(ctx, &awss3.DeleteObjectsInput{
+				Bucket: aws.String(metadataBucket),
+				Delete: &types.Delete{
+					Objects: []types.ObjectIdentifier{
+						{Key: aws.String(metadataPartKey)},
+						{Key: aws.String(metadataInfoKey)},
+					},
+					Quiet: aws.Bool(true),
+				},
+			}).Return(&awss3.DeleteObjectsOutput{}, nil)
+
+			Expect(destStorage.DeleteFile(ctx, fileInfo.Path, mockClient)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
 	})
 
 	Describe("Close", func() {
@@ -1594,3 +4099,24 @@ func (s s3APIWithTempFileAssertion) UploadPart(
 
 	return nil, fmt.Errorf("not now")
 }
+
+// contextReader reads the content unless the context of its request is done.
+type contextReader struct {
+	ctx     context.Context
+	content string
+	offset  int
+}
+
+func (r *contextReader) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return
+	}
+	if r.offset == len(r.content) {
+		return 0, io.EOF
+	}
+	n = copy(p, r.content[r.offset:])
+	r.offset += n
+	return
+}
+
+func (r *contextReader) Close() error { return nil }
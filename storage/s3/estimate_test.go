@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"github.com/derektruong/fxfer/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EstimateTransfer", func() {
+	var store *Destination
+
+	BeforeEach(func() {
+		store = destStorageFactory(func(d *Destination) {
+			d.MinPartSize = 4
+			d.PreferredPartSize = 4
+			d.MaxPartSize = 8
+			d.MaxMultipartParts = 10000
+			d.MaxObjectSize = 1024 * 1024
+			d.MaxBufferedParts = 2
+			d.MaxConcurrentPartUploads = 3
+		})
+	})
+
+	// a fresh transfer issues 15 requests besides the part uploads and the ListParts pages
+	// when finalizing: 3 info reads (GetObject, ListParts, HeadObject) for each GetFileInfo
+	// (before and after CreateFile) and TransferFileChunk, CreateMultipartUpload and PutObject
+	// for CreateFile, then GetObject, HeadObject, CompleteMultipartUpload and PutObject for
+	// FinalizeTransfer.
+	DescribeTable("should estimate the requests, bytes and temporary disk usage",
+		func(editFn func(d *Destination), size int64, expected storage.TransferEstimate) {
+			if editFn != nil {
+				editFn(store)
+			}
+			Expect(store.EstimateTransfer(size)).To(Equal(expected))
+		},
+		Entry("empty file is completed with an empty part", nil, int64(0), storage.TransferEstimate{
+			Requests:    17,
+			PartUploads: 1,
+			PartSize:    4,
+		}),
+		Entry("file smaller than a part", nil, int64(3), storage.TransferEstimate{
+			Requests:              17,
+			PartUploads:           1,
+			PartSize:              4,
+			TransferredBytes:      3,
+			TempDiskHighWaterMark: 3,
+		}),
+		Entry("file with a smaller last part", nil, int64(14), storage.TransferEstimate{
+			Requests:              20,
+			PartUploads:           4,
+			PartSize:              4,
+			TransferredBytes:      14,
+			TempDiskHighWaterMark: 14,
+		}),
+		Entry("file larger than the buffered parts", nil, int64(100), storage.TransferEstimate{
+			Requests:              41,
+			PartUploads:           25,
+			PartSize:              4,
+			TransferredBytes:      100,
+			TempDiskHighWaterMark: 24,
+		}),
+		Entry("file listing the parts in multiple pages", nil, int64(4001), storage.TransferEstimate{
+			Requests:              1018,
+			PartUploads:           1001,
+			PartSize:              4,
+			TransferredBytes:      4001,
+			TempDiskHighWaterMark: 24,
+		}),
+		Entry("file exceeding the preferred part size layout", func(d *Destination) {
+			d.MaxMultipartParts = 10
+			d.MaxPartSize = 16
+		}, int64(100), storage.TransferEstimate{
+			Requests:              26,
+			PartUploads:           10,
+			PartSize:              10,
+			TransferredBytes:      100,
+			TempDiskHighWaterMark: 60,
+		}),
+		Entry("file larger than the buffered bytes", func(d *Destination) {
+			d.MaxBufferedBytes = 10
+		}, int64(100), storage.TransferEstimate{
+			Requests:              41,
+			PartUploads:           25,
+			PartSize:              4,
+			TransferredBytes:      100,
+			TempDiskHighWaterMark: 10,
+		}),
+		Entry("parts buffered in memory", func(d *Destination) {
+			d.TemporaryDirectory = TempDirUseMemory
+		}, int64(100), storage.TransferEstimate{
+			Requests:         41,
+			PartUploads:      25,
+			PartSize:         4,
+			TransferredBytes: 100,
+		}),
+		Entry("small file put without info object", WithoutInfoForSmallFiles(8), int64(3), storage.TransferEstimate{
+			Requests:         3,
+			TransferredBytes: 3,
+		}),
+	)
+
+	It("should return error if the file exceeds the maximum object size", func() {
+		_, err := store.EstimateTransfer(store.MaxObjectSize + 1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if the file does not fit in the maximum number of parts", func() {
+		store.MaxMultipartParts = 10
+		_, err := store.EstimateTransfer(100)
+		Expect(err).To(HaveOccurred())
+	})
+})
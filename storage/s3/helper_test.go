@@ -1,15 +1,52 @@
 package s3
 
 import (
+	"context"
+	"io"
+	"sync"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 // destStorageFactory creates a new Destination store with the given storeEditorFn applied.
 func destStorageFactory(storageEditorFn func(d *Destination)) *Destination {
 	GinkgoHelper()
-	store := NewDestination(GinkgoLogr)
+	store, err := NewDestination(GinkgoLogr)
+	Expect(err).ToNot(HaveOccurred())
 	if storageEditorFn != nil {
 		storageEditorFn(store)
 	}
 	return store
 }
+
+// confirmedSizeRecorder is a reader recording the confirmed sizes reported by the destination.
+type confirmedSizeRecorder struct {
+	io.Reader
+
+	mu    sync.Mutex
+	steps []int64
+}
+
+func (r *confirmedSizeRecorder) AddConfirmedSize(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, n)
+}
+
+func (r *confirmedSizeRecorder) Steps() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.steps...)
+}
+
+// pausingRecorder is a confirmedSizeRecorder which holds the copy before each part with the
+// wait function (see storage.CopyPauser).
+type pausingRecorder struct {
+	confirmedSizeRecorder
+	wait func(ctx context.Context) error
+}
+
+func (r *pausingRecorder) WaitToCopy(ctx context.Context) error {
+	return r.wait(ctx)
+}
@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// srcModTimeMeta is the user metadata of an object which records the modification time of its
+// source (x-amz-meta-src-modtime, see WithPreserveModTime), in RFC 3339 format.
+const srcModTimeMeta = "src-modtime"
+
+// WithPreserveModTime instructs the Destination to record the modification time of the source in
+// the user metadata of the objects (x-amz-meta-src-modtime), since their LastModified is the time
+// they are completed. The Source reports it as the modification time of such an object, so that
+// the tools comparing the modification times of the synced files see no drift.
+func WithPreserveModTime() DestinationOption {
+	return func(d *Destination) {
+		d.preserveModTime = true
+	}
+}
+
+// modTimeMetadata returns the user metadata recording the modification time of the source, nil
+// if it is not preserved (see WithPreserveModTime) or unknown.
+func (d *Destination) modTimeMetadata(modTime time.Time) map[string]string {
+	if !d.preserveModTime || modTime.IsZero() {
+		return nil
+	}
+	return map[string]string{srcModTimeMeta: modTime.UTC().Format(time.RFC3339Nano)}
+}
+
+// objectModTime returns the modification time of the source recorded in the user metadata of
+// the object (see WithPreserveModTime), its LastModified if none or if it cannot be parsed.
+func objectModTime(metadata map[string]string, lastModified *time.Time) time.Time {
+	if modTime, err := time.Parse(time.RFC3339Nano, metadata[srcModTimeMeta]); err == nil {
+		return modTime
+	}
+	return lo.FromPtr(lastModified)
+}
@@ -0,0 +1,191 @@
+package s3
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/derektruong/fxfer/protoc"
+)
+
+// timeoutS3API is a protoc.S3API which bounds each operation by a timeout derived from the
+// context of the call (see Destination.OperationTimeout and Source.OperationTimeout), the
+// context of the caller is left untouched.
+type timeoutS3API struct {
+	protoc.S3API
+	timeout time.Duration
+}
+
+// withOperationTimeout wraps the client in a timeoutS3API, unless the timeout is not positive.
+func withOperationTimeout(client protoc.S3API, timeout time.Duration) protoc.S3API {
+	if timeout <= 0 {
+		return client
+	}
+	return &timeoutS3API{S3API: client, timeout: timeout}
+}
+
+// callWithTimeout calls the operation with a context bounded by the timeout.
+func callWithTimeout[I, O any](
+	ctx context.Context,
+	timeout time.Duration,
+	operation func(context.Context, I, ...func(*awss3.Options)) (O, error),
+	input I,
+	opts []func(*awss3.Options),
+) (O, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return operation(ctx, input, opts...)
+}
+
+func (c *timeoutS3API) PutObject(
+	ctx context.Context,
+	input *awss3.PutObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.PutObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.PutObject, input, opts)
+}
+
+func (c *timeoutS3API) ListParts(
+	ctx context.Context,
+	input *awss3.ListPartsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListPartsOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.ListParts, input, opts)
+}
+
+func (c *timeoutS3API) UploadPart(
+	ctx context.Context,
+	input *awss3.UploadPartInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.UploadPart, input, opts)
+}
+
+// GetObject bounds the request by the timeout until the response is received, the read of the
+// body is not bounded as it lasts as long as the object is large. The context of the request
+// is released once the body is closed.
+func (c *timeoutS3API) GetObject(
+	ctx context.Context,
+	input *awss3.GetObjectInput,
+	opts ...func(*awss3.Options),
+) (output *awss3.GetObjectOutput, err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(c.timeout, func() { cancel(context.DeadlineExceeded) })
+	output, err = c.S3API.GetObject(ctx, input, opts...)
+	if !timer.Stop() {
+		// the body of a response received as the timeout elapsed cannot be read anymore
+		if err == nil && output != nil && output.Body != nil {
+			_ = output.Body.Close()
+		}
+		output, err = nil, fmt.Errorf("%w: %w", context.DeadlineExceeded, cmp.Or(err, context.Canceled))
+	}
+	if err != nil || output == nil || output.Body == nil {
+		cancel(nil)
+		return
+	}
+	output.Body = &cancelOnCloseReader{ReadCloser: output.Body, cancel: func() { cancel(nil) }}
+	return
+}
+
+func (c *timeoutS3API) HeadObject(
+	ctx context.Context,
+	input *awss3.HeadObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.HeadObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.HeadObject, input, opts)
+}
+
+func (c *timeoutS3API) CreateMultipartUpload(
+	ctx context.Context,
+	input *awss3.CreateMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CreateMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.CreateMultipartUpload, input, opts)
+}
+
+func (c *timeoutS3API) AbortMultipartUpload(
+	ctx context.Context,
+	input *awss3.AbortMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.AbortMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.AbortMultipartUpload, input, opts)
+}
+
+func (c *timeoutS3API) DeleteObject(
+	ctx context.Context,
+	input *awss3.DeleteObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.DeleteObject, input, opts)
+}
+
+func (c *timeoutS3API) DeleteObjects(
+	ctx context.Context,
+	input *awss3.DeleteObjectsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.DeleteObjectsOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.DeleteObjects, input, opts)
+}
+
+func (c *timeoutS3API) CompleteMultipartUpload(
+	ctx context.Context,
+	input *awss3.CompleteMultipartUploadInput,
+	opts ...func(*awss3.Options),
+) (*awss3.CompleteMultipartUploadOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.CompleteMultipartUpload, input, opts)
+}
+
+func (c *timeoutS3API) UploadPartCopy(
+	ctx context.Context,
+	input *awss3.UploadPartCopyInput,
+	opts ...func(*awss3.Options),
+) (*awss3.UploadPartCopyOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.UploadPartCopy, input, opts)
+}
+
+func (c *timeoutS3API) ListObjectsV2(
+	ctx context.Context,
+	input *awss3.ListObjectsV2Input,
+	opts ...func(*awss3.Options),
+) (*awss3.ListObjectsV2Output, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.ListObjectsV2, input, opts)
+}
+
+func (c *timeoutS3API) GetObjectAttributes(
+	ctx context.Context,
+	input *awss3.GetObjectAttributesInput,
+	opts ...func(*awss3.Options),
+) (*awss3.GetObjectAttributesOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.GetObjectAttributes, input, opts)
+}
+
+func (c *timeoutS3API) RestoreObject(
+	ctx context.Context,
+	input *awss3.RestoreObjectInput,
+	opts ...func(*awss3.Options),
+) (*awss3.RestoreObjectOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.RestoreObject, input, opts)
+}
+
+func (c *timeoutS3API) ListMultipartUploads(
+	ctx context.Context,
+	input *awss3.ListMultipartUploadsInput,
+	opts ...func(*awss3.Options),
+) (*awss3.ListMultipartUploadsOutput, error) {
+	return callWithTimeout(ctx, c.timeout, c.S3API.ListMultipartUploads, input, opts)
+}
+
+// cancelOnCloseReader is a reader which cancels the context of its request once closed.
+type cancelOnCloseReader struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnCloseReader) Close() (err error) {
+	err = r.ReadCloser.Close()
+	r.cancel()
+	return
+}
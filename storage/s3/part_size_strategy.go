@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// PartSizeStrategy decides the size of each part of the multipart uploads of a destination
+// (see Destination.PartSizeStrategy).
+type PartSizeStrategy interface {
+	// PartSize returns the size of the part at the index (from 0, the first part of the upload)
+	// of an upload of totalSize bytes, xferfile.SizeUnknown for a stream. The size must be
+	// within MinPartSize and MaxPartSize of the destination, only the last part of the upload
+	// is cut to the remaining bytes, and the upload must fit in MaxMultipartParts parts.
+	PartSize(totalSize, partIndex int64) (int64, error)
+}
+
+// optimalPartSize is the default PartSizeStrategy, uploading the parts of PreferredPartSize or
+// of the smallest size fitting the upload in MaxMultipartParts parts if larger.
+type optimalPartSize struct {
+	d *Destination
+}
+
+func (s optimalPartSize) PartSize(totalSize, _ int64) (int64, error) {
+	return s.d.calcOptimalPartSize(totalSize)
+}
+
+// FixedPartSize is a PartSizeStrategy uploading the parts of the size whatever the size of the
+// upload, the uploads beyond MaxMultipartParts parts of the size fail.
+type FixedPartSize int64
+
+func (s FixedPartSize) PartSize(_, _ int64) (int64, error) {
+	return int64(s), nil
+}
+
+// RampingPartSize is a PartSizeStrategy starting with the parts of InitialSize bytes, whose
+// size is doubled every PartsPerStep parts up to MaxSize, so that the very large uploads issue
+// fewer requests while the small uploads keep small parts. With the default part size limits,
+// RampingPartSize{InitialSize: 8 MiB, MaxSize: 5 GiB, PartsPerStep: 1000} uploads up to 5 TiB.
+type RampingPartSize struct {
+	InitialSize  int64
+	MaxSize      int64
+	PartsPerStep int64
+}
+
+func (s RampingPartSize) PartSize(_, partIndex int64) (size int64, err error) {
+	if s.InitialSize <= 0 || s.PartsPerStep <= 0 {
+		err = fmt.Errorf("%w: RampingPartSize needs a positive InitialSize (%d) and PartsPerStep (%d)",
+			storage.ErrPartSizeInvalid, s.InitialSize, s.PartsPerStep)
+		return
+	}
+	size = s.InitialSize
+	for steps := partIndex / s.PartsPerStep; steps > 0 && size < s.MaxSize; steps-- {
+		size *= 2
+	}
+	return max(min(size, s.MaxSize), s.InitialSize), nil
+}
+
+// partSize returns the size of the part at the index of an upload of totalSize bytes following
+// the PartSizeStrategy, storage.ErrPartSizeInvalid if it cannot be uploaded as a part.
+func (d *Destination) partSize(totalSize, partIndex int64) (size int64, err error) {
+	var strategy PartSizeStrategy = optimalPartSize{d: d}
+	if d.PartSizeStrategy != nil {
+		strategy = d.PartSizeStrategy
+	}
+	if size, err = strategy.PartSize(totalSize, partIndex); err != nil {
+		if !errors.Is(err, storage.ErrPartSizeInvalid) {
+			err = fmt.Errorf("%w: %w", storage.ErrPartSizeInvalid, err)
+		}
+		return
+	}
+	if size < max(d.MinPartSize, 1) || size > d.MaxPartSize {
+		err = fmt.Errorf("%w: size (%d) of part %d is out of MinPartSize (%d) and MaxPartSize (%d)",
+			storage.ErrPartSizeInvalid, size, partIndex+1, d.MinPartSize, d.MaxPartSize)
+	}
+	return
+}
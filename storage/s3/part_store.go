@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// PartStore stores the parts of the uploads from the time they are received until they are
+// uploaded (see Destination.PartStore), e.g. on a tmpfs, in an encrypted scratch space or in a
+// ramfs. A part is written once, then read from its beginning, possibly several times (e.g. when
+// its upload is retried).
+type PartStore interface {
+	// Create creates the storage of a part, release frees it once the part is uploaded
+	Create() (part io.ReadWriteSeeker, release func() error, err error)
+}
+
+// partStore returns the store of the parts of the producer (see newPartStore).
+func (spp *s3PartProducer) partStore() PartStore {
+	return newPartStore(spp.store, spp.tmpDir, spp.tempFiles, spp.partFiles)
+}
+
+// newPartStore returns the store of the parts: the custom store if set, otherwise the memory
+// (see TempDirUseMemory), the pool of temporary files (see WithTempFilePrealloc) or the temporary
+// files of tmpDir. The store only keeps the parts of the current process, the state of the
+// uploads lives in S3, so that an upload can be resumed with a store of another kind.
+func newPartStore(store PartStore, tmpDir string, tempFiles *tempFilePool, partFiles *tempFileSet) PartStore {
+	switch {
+	case store != nil:
+		return store
+	case tmpDir == TempDirUseMemory:
+		return memoryPartStore{}
+	case tempFiles != nil:
+		return pooledPartStore{pool: tempFiles}
+	default:
+		return diskPartStore{dir: tmpDir, partFiles: partFiles}
+	}
+}
+
+// diskPartStore stores the parts in temporary files of the directory, recorded in partFiles
+// until they are removed.
+type diskPartStore struct {
+	dir       string
+	partFiles *tempFileSet
+}
+
+func (s diskPartStore) Create() (part io.ReadWriteSeeker, release func() error, err error) {
+	var file *os.File
+	if file, err = os.CreateTemp(s.dir, tempFilePattern); err != nil {
+		err = fmt.Errorf("unable to create temporary part file: %w", err)
+		return
+	}
+	s.partFiles.add(file.Name())
+	release = func() error {
+		// a possible error from duplicate close operations is ignored on purpose
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
+		if err := os.Remove(file.Name()); err != nil {
+			return err
+		}
+		s.partFiles.remove(file.Name())
+		return nil
+	}
+	return file, release, nil
+}
+
+// pooledPartStore stores the parts in temporary files drawn from the pool, a file is returned
+// to the pool once its part is released.
+type pooledPartStore struct {
+	pool *tempFilePool
+}
+
+func (s pooledPartStore) Create() (part io.ReadWriteSeeker, release func() error, err error) {
+	var file *os.File
+	if file, err = s.pool.get(); err != nil {
+		return
+	}
+	return file, func() error { return s.pool.put(file) }, nil
+}
+
+// memoryPartStore stores the parts in memory.
+type memoryPartStore struct{}
+
+func (memoryPartStore) Create() (part io.ReadWriteSeeker, release func() error, err error) {
+	return &memoryPart{}, func() error { return nil }, nil
+}
+
+// memoryPart is a part stored in a byte slice.
+type memoryPart struct {
+	data   []byte
+	offset int64
+}
+
+func (p *memoryPart) Write(b []byte) (n int, err error) {
+	if end := p.offset + int64(len(b)); end > int64(len(p.data)) {
+		p.data = append(p.data, make([]byte, end-int64(len(p.data)))...)
+	}
+	n = copy(p.data[p.offset:], b)
+	p.offset += int64(n)
+	return
+}
+
+func (p *memoryPart) Read(b []byte) (n int, err error) {
+	if p.offset >= int64(len(p.data)) {
+		return 0, io.EOF
+	}
+	n = copy(b, p.data[p.offset:])
+	p.offset += int64(n)
+	return
+}
+
+func (p *memoryPart) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += p.offset
+	case io.SeekEnd:
+		offset += int64(len(p.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("memoryPart.Seek: negative position")
+	}
+	p.offset = offset
+	return offset, nil
+}
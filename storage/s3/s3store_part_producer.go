@@ -1,11 +1,11 @@
 package s3
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"io"
 	"os"
+
+	"golang.org/x/sync/semaphore"
 )
 
 const TempDirUseMemory = "_memory"
@@ -16,6 +16,21 @@ type s3PartProducer struct {
 	files  chan fileChunk
 	err    error
 	r      io.Reader
+
+	// store is the store of the parts (see Destination.PartStore), if it is nil the parts are
+	// stored in memory or in the temporary files of tmpDir (see partStore)
+	store PartStore
+
+	// tempFiles is the pool the temporary files are drawn from (nil if not preallocated)
+	tempFiles *tempFilePool
+
+	// partFiles records the temporary files created for the parts until they are removed
+	partFiles *tempFileSet
+
+	// bufferedBytes limits the bytes of the parts produced until they are closed to
+	// maxBufferedBytes, it is nil if they are unlimited (see Destination.MaxBufferedBytes)
+	bufferedBytes    *semaphore.Weighted
+	maxBufferedBytes int64
 }
 
 type fileChunk struct {
@@ -26,12 +41,8 @@ type fileChunk struct {
 
 func newS3PartProducer(src io.Reader, backlog int64, tmpDir string) (s3PartProducer, <-chan fileChunk) {
 	fileChan := make(chan fileChunk, backlog)
-	if os.Getenv("FILE_TRANSFERER_S3_TEMP_MEMORY") == "1" {
-		tmpDir = TempDirUseMemory
-	}
-
 	partProducer := s3PartProducer{
-		tmpDir: tmpDir,
+		tmpDir: partTempDirectory(tmpDir),
 		files:  fileChan,
 		r:      src,
 	}
@@ -39,6 +50,26 @@ func newS3PartProducer(src io.Reader, backlog int64, tmpDir string) (s3PartProdu
 	return partProducer, fileChan
 }
 
+// partTempDirectory returns the temporary directory of the parts, TempDirUseMemory if the
+// FILE_TRANSFERER_S3_TEMP_MEMORY environment variable is "1".
+func partTempDirectory(tmpDir string) string {
+	if os.Getenv("FILE_TRANSFERER_S3_TEMP_MEMORY") == "1" {
+		return TempDirUseMemory
+	}
+	return tmpDir
+}
+
+// limitBufferedBytes limits the bytes of the parts produced until they are closed, the producer
+// waits for the consumer to close enough parts before producing the next one. A limit of 0 or
+// less leaves them unlimited.
+func (spp *s3PartProducer) limitBufferedBytes(maxBytes int64) {
+	if maxBytes <= 0 {
+		return
+	}
+	spp.bufferedBytes = semaphore.NewWeighted(maxBytes)
+	spp.maxBufferedBytes = maxBytes
+}
+
 // closeUnreadFiles should always be called by the consumer to ensure that the channels
 // are properly closed and emptied.
 func (spp *s3PartProducer) closeUnreadFiles() {
@@ -50,9 +81,39 @@ func (spp *s3PartProducer) closeUnreadFiles() {
 }
 
 func (spp *s3PartProducer) produce(ctx context.Context, partSize int64) {
+	spp.produceParts(ctx, func(int) (int64, error) { return partSize, nil })
+}
+
+// produceLayout produces parts with the given sizes, the last size is
+// used for any part beyond the layout.
+func (spp *s3PartProducer) produceLayout(ctx context.Context, partSizes []int64) {
+	spp.produceParts(ctx, func(i int) (int64, error) {
+		return partSizes[min(i, len(partSizes)-1)], nil
+	})
+}
+
+// produceParts produces parts with the size of each part (from 0), an error of the size stops
+// producing like an error of the source.
+func (spp *s3PartProducer) produceParts(ctx context.Context, partSize func(i int) (int64, error)) {
 outerLoop:
-	for {
-		file, ok, err := spp.nextPart(partSize)
+	for i := 0; ; i++ {
+		size, err := partSize(i)
+		if err != nil {
+			spp.err = err
+			break
+		}
+		// a part larger than the limit waits for all the others to be closed
+		reserved := min(size, spp.maxBufferedBytes)
+		if spp.bufferedBytes != nil {
+			if err = spp.bufferedBytes.Acquire(ctx, reserved); err != nil {
+				// we are told to stop producing. Stop producing.
+				break
+			}
+		}
+		file, ok, err := spp.nextPart(size)
+		if err != nil || !ok {
+			spp.releaseBufferedBytes(reserved)
+		}
 		if err != nil {
 			// an error occurred. Stop producing.
 			spp.err = err
@@ -62,6 +123,7 @@ outerLoop:
 			// the source was fully read. Stop producing.
 			break
 		}
+		spp.holdBufferedBytes(&file, reserved)
 		select {
 		case spp.files <- file:
 		case <-ctx.Done():
@@ -73,71 +135,58 @@ outerLoop:
 	close(spp.files)
 }
 
-func (spp *s3PartProducer) nextPart(size int64) (fileChunk, bool, error) {
-	if spp.tmpDir != TempDirUseMemory {
-		// create a temporary file to store the part
-		file, err := os.CreateTemp(spp.tmpDir, "file-transfer-s3-tmp-")
-		if err != nil {
-			return fileChunk{}, false, err
-		}
-
-		limitedReader := io.LimitReader(spp.r, size)
-
-		n, err := io.Copy(file, limitedReader)
-		if err != nil {
-			cleanUpTempFile(file)
-			return fileChunk{}, false, err
-		}
+// holdBufferedBytes releases the bytes reserved beyond the size of the part, the part holds
+// the others until it is closed.
+func (spp *s3PartProducer) holdBufferedBytes(file *fileChunk, reserved int64) {
+	if spp.bufferedBytes == nil {
+		return
+	}
+	held := min(file.size, reserved)
+	spp.releaseBufferedBytes(reserved - held)
+	closeReader := file.closeReader
+	file.closeReader = func() error {
+		defer spp.releaseBufferedBytes(held)
+		return closeReader()
+	}
+}
 
-		// If the entire request body is read and no more data is available,
-		// io.Copy returns 0 since it is unable to read any bytes. In that
-		// case, we can close the s3PartProducer.
-		if n == 0 {
-			cleanUpTempFile(file)
-			return fileChunk{}, false, nil
-		}
+func (spp *s3PartProducer) releaseBufferedBytes(n int64) {
+	if spp.bufferedBytes != nil && n > 0 {
+		spp.bufferedBytes.Release(n)
+	}
+}
 
-		// Seek to the beginning of the file
-		_, _ = file.Seek(0, io.SeekStart)
-
-		return fileChunk{
-			reader: file,
-			closeReader: func() error {
-				// The HTTP client already takes care of closing the request body
-				// (see https://pkg.go.dev/net/http#Request and https://pkg.go.dev/net/http#Client.Do).
-				// Since the file opened here are used for request bodies, it is not
-				// necessary to close them on our own, but we still do it just to be sure.
-				// However, a possible error from duplicate close operations is ignored on purpose.
-				if err = file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
-					return err
-				}
-				return os.Remove(file.Name())
-			},
-			size: n,
-		}, true, nil
-	} else {
-		// Create a temporary buffer to store the part
-		buf := new(bytes.Buffer)
-
-		limitedReader := io.LimitReader(spp.r, size)
-
-		n, err := io.Copy(buf, limitedReader)
-		if err != nil {
-			return fileChunk{}, false, err
-		}
+// nextPart stores the next part of up to size bytes read from the source in its part store (see
+// PartStore), ok is false once the source is fully read.
+func (spp *s3PartProducer) nextPart(size int64) (chunk fileChunk, ok bool, err error) {
+	part, release, err := spp.partStore().Create()
+	if err != nil {
+		return
+	}
 
-		// if the entire request body is read and no more data is available,
-		// io.Copy returns 0 since it is unable to read any bytes. In that
-		// case, we can close the s3PartProducer.
-		if n == 0 {
-			return fileChunk{}, false, nil
-		}
+	var n int64
+	if n, err = io.Copy(part, io.LimitReader(spp.r, size)); err != nil {
+		_ = release()
+		return
+	}
+	// If the entire request body is read and no more data is available,
+	// io.Copy returns 0 since it is unable to read any bytes. In that
+	// case, we can close the s3PartProducer.
+	if n == 0 {
+		err = release()
+		return
+	}
+	if _, err = part.Seek(0, io.SeekStart); err != nil {
+		_ = release()
+		return
+	}
 
-		return fileChunk{
-			// buf does not get written to anymore, so we can turn it into a reader
-			reader:      bytes.NewReader(buf.Bytes()),
-			closeReader: func() error { return nil },
-			size:        n,
-		}, true, nil
+	chunk = fileChunk{
+		// the part is hidden behind a plain ReadSeeker, so that the HTTP client does not
+		// close it when it is used as a request body, it is closed once released
+		reader:      struct{ io.ReadSeeker }{part},
+		closeReader: release,
+		size:        n,
 	}
+	return chunk, true, nil
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -25,6 +28,20 @@ func (ErrorReader) Read(b []byte) (int, error) {
 	return 0, errors.New("error from ErrorReader")
 }
 
+// recordingPartStore is a PartStore keeping the parts in memory, recording the parts created
+// and released.
+type recordingPartStore struct {
+	created, released atomic.Int32
+}
+
+func (s *recordingPartStore) Create() (io.ReadWriteSeeker, func() error, error) {
+	s.created.Add(1)
+	return &memoryPart{}, func() error {
+		s.released.Add(1)
+		return nil
+	}, nil
+}
+
 var _ = Describe("S3storePartProducer", func() {
 	It("should use memory when FILE_TRANSFERER_S3_TEMP_MEMORY is set", func() {
 		Expect(os.Setenv("FILE_TRANSFERER_S3_TEMP_MEMORY", "1")).To(Succeed())
@@ -71,6 +88,165 @@ var _ = Describe("S3storePartProducer", func() {
 		}
 	})
 
+	It("part producer should draw the files from the pool and recycle them", func() {
+		pool, err := newTempFilePool(GinkgoT().TempDir(), 2)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(pool.close)
+		pooledNames := make([]string, 0, len(pool.files))
+		for _, file := range pool.files {
+			pooledNames = append(pooledNames, file.Name())
+		}
+
+		pp, fileChan := newS3PartProducer(strings.NewReader("testtest"), 0, pool.dir)
+		pp.tempFiles = pool
+		go pp.produce(context.Background(), 3)
+
+		actualStr := ""
+		for chunk := range fileChan {
+			data, err := io.ReadAll(chunk.reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(chunk.reader).ToNot(BeAssignableToTypeOf(&os.File{}))
+			actualStr += string(data)
+			Expect(chunk.closeReader()).To(Succeed())
+		}
+		Expect(pp.err).ToNot(HaveOccurred())
+		Expect(actualStr).To(Equal("testtest"))
+
+		By("assert no file was created or removed")
+		Expect(pool.available()).To(Equal(2))
+		entries, err := os.ReadDir(pool.dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		for _, entry := range entries {
+			Expect(pooledNames).To(ContainElement(filepath.Join(pool.dir, entry.Name())))
+		}
+	})
+
+	It("part producer should record the part files until they are removed", func() {
+		tmpDir := GinkgoT().TempDir()
+		partFiles := &tempFileSet{}
+		pp, fileChan := newS3PartProducer(strings.NewReader("12345678"), 10, tmpDir)
+		pp.partFiles = partFiles
+		go pp.produce(context.Background(), 2)
+
+		chunks := make([]fileChunk, 0, 4)
+		for chunk := range fileChan {
+			chunks = append(chunks, chunk)
+		}
+		Expect(pp.err).ToNot(HaveOccurred())
+		Expect(chunks).To(HaveLen(4))
+		Expect(chunks[0].closeReader()).To(Succeed())
+		Expect(os.ReadDir(tmpDir)).To(HaveLen(3))
+
+		// the other parts are abandoned, e.g. by an interrupted upload
+		Expect(partFiles.removeAll()).To(Succeed())
+		Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+		Expect(partFiles.names).To(BeEmpty())
+	})
+
+	It("part producer should wait for the parts to be closed beyond the buffered bytes", func() {
+		pp, fileChan := newS3PartProducer(InfiniteZeroReader{}, 10, GinkgoT().TempDir())
+		pp.limitBufferedBytes(25)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer func() {
+			cancel()
+			pp.closeUnreadFiles()
+		}()
+		go pp.produce(ctx, 10)
+
+		By("produce the parts fitting in the buffered bytes")
+		var chunks []fileChunk
+		for range 2 {
+			var chunk fileChunk
+			Eventually(fileChan).Should(Receive(&chunk))
+			chunks = append(chunks, chunk)
+		}
+		Consistently(fileChan, 100*time.Millisecond).ShouldNot(Receive())
+
+		By("produce the next part once a part is closed")
+		Expect(chunks[0].closeReader()).To(Succeed())
+		var chunk fileChunk
+		Eventually(fileChan).Should(Receive(&chunk))
+		Expect(chunk.size).To(Equal(int64(10)))
+		Consistently(fileChan, 100*time.Millisecond).ShouldNot(Receive())
+		for _, chunk := range append(chunks[1:], chunk) {
+			Expect(chunk.closeReader()).To(Succeed())
+		}
+	})
+
+	It("part producer should produce a part larger than the buffered bytes alone", func() {
+		pp, fileChan := newS3PartProducer(InfiniteZeroReader{}, 10, TempDirUseMemory)
+		pp.limitBufferedBytes(5)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer func() {
+			cancel()
+			pp.closeUnreadFiles()
+		}()
+		go pp.produce(ctx, 10)
+
+		var chunk fileChunk
+		Eventually(fileChan).Should(Receive(&chunk))
+		Expect(chunk.size).To(Equal(int64(10)))
+		Consistently(fileChan, 100*time.Millisecond).ShouldNot(Receive())
+
+		Expect(chunk.closeReader()).To(Succeed())
+		Eventually(fileChan).Should(Receive(&chunk))
+		Expect(chunk.closeReader()).To(Succeed())
+	})
+
+	It("part producer should store the parts in the custom part store", func() {
+		store := &recordingPartStore{}
+		pp, fileChan := newS3PartProducer(strings.NewReader("0123456789"), 0, GinkgoT().TempDir())
+		pp.store = store
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go pp.produce(ctx, 4)
+
+		var parts []string
+		for chunk := range fileChan {
+			By("read the part twice, as a retried upload does")
+			for range 2 {
+				_, err := chunk.reader.Seek(0, io.SeekStart)
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(chunk.reader)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(HaveLen(int(chunk.size)))
+			}
+			_, err := chunk.reader.Seek(0, io.SeekStart)
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(chunk.reader)
+			Expect(err).ToNot(HaveOccurred())
+			parts = append(parts, string(data))
+			Expect(chunk.closeReader()).To(Succeed())
+		}
+		Expect(pp.err).ToNot(HaveOccurred())
+		Expect(parts).To(Equal([]string{"0123", "4567", "89"}))
+
+		By("assert every part is released, including the empty one ending the source")
+		Eventually(store.released.Load).Should(Equal(int32(4)))
+		Expect(store.created.Load()).To(Equal(int32(4)))
+	})
+
+	It("part producer should round-trip the parts stored in memory", func() {
+		pp, fileChan := newS3PartProducer(strings.NewReader("0123456789"), 0, TempDirUseMemory)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go pp.produce(ctx, 6)
+
+		var content string
+		for chunk := range fileChan {
+			data, err := io.ReadAll(chunk.reader)
+			Expect(err).ToNot(HaveOccurred())
+			content += string(data)
+			Expect(chunk.closeReader()).To(Succeed())
+		}
+		Expect(content).To(Equal("0123456789"))
+	})
+
 	It("part producer should exist when context is cancelled", func() {
 		pp, fileChan := newS3PartProducer(InfiniteZeroReader{}, 0, "")
 
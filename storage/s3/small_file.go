@@ -0,0 +1,243 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/samber/lo"
+)
+
+const (
+	// smallFileInfoMeta is the user metadata of a small file object which records its info
+	// (see WithoutInfoForSmallFiles), base64 encoded as S3 only accepts ASCII metadata
+	smallFileInfoMeta = "fxfer-info"
+	// maxSmallFileInfoSize is the maximum size of the encoded info of a small file, S3 limits
+	// the user metadata of an object to 2 KB
+	maxSmallFileInfoSize = 2048 - len(smallFileInfoMeta)
+)
+
+// smallFile is a file below the threshold of WithoutInfoForSmallFiles, it is put whole with
+// its info in the metadata of the object, rather than in an info object along with a multipart
+// upload. Its info is kept in memory until it is finalized.
+type smallFile struct {
+	info   xferfile.Info
+	object storage.FinalizedObject
+}
+
+// isSmallFile reports whether a file of the size is put without an info object
+// (see WithoutInfoForSmallFiles).
+func (d *Destination) isSmallFile(size int64) bool {
+	return d.smallFileThreshold > 0 && size >= 0 && size < d.smallFileThreshold
+}
+
+// smallFileKey returns the key of the small file of the object in the bucket.
+func smallFileKey(bucket, objectKey string) string {
+	return bucket + "/" + objectKey
+}
+
+// setSmallFile records the small file until it is finalized or deleted, a nil file removes it.
+func (d *Destination) setSmallFile(bucket, objectKey string, file *smallFile) {
+	d.smallFilesMu.Lock()
+	defer d.smallFilesMu.Unlock()
+	if file == nil {
+		delete(d.smallFiles, smallFileKey(bucket, objectKey))
+		return
+	}
+	d.smallFiles[smallFileKey(bucket, objectKey)] = file
+}
+
+// getSmallFile returns the small file of the object, if it is recorded.
+func (d *Destination) getSmallFile(bucket, objectKey string) (file smallFile, ok bool) {
+	d.smallFilesMu.Lock()
+	defer d.smallFilesMu.Unlock()
+	var recorded *smallFile
+	if recorded, ok = d.smallFiles[smallFileKey(bucket, objectKey)]; ok {
+		file = *recorded
+	}
+	return
+}
+
+// fitsSmallFileInfo reports whether the info of the small file, once finished, fits in the
+// metadata of its object along with its other metadata.
+func (d *Destination) fitsSmallFileInfo(info xferfile.Info) bool {
+	info.Offset = info.Size
+	info.FinishTime = info.StartTime
+	encoded, err := encodeSmallFileInfo(info)
+	metadataSize := 0
+	for key, value := range d.modTimeMetadata(info.ModTime) {
+		metadataSize += len(key) + len(value)
+	}
+	return err == nil && len(encoded)+metadataSize <= maxSmallFileInfoSize
+}
+
+// encodeSmallFileInfo encodes the info of a small file into the metadata of its object.
+func encodeSmallFileInfo(info xferfile.Info) (encoded string, err error) {
+	var jsonInfo []byte
+	if jsonInfo, err = json.Marshal(info); err != nil {
+		return
+	}
+	return base64.StdEncoding.EncodeToString(jsonInfo), nil
+}
+
+// setSmallFileInfo sets the info of the upload from the small file recorded in memory, or from
+// the metadata of its object (HeadObject) once it has been put. An object without the metadata
+// has not been put by the destination, it is reported as xferfile.ErrFileNotExists.
+func (u *s3Upload) setSmallFileInfo(ctx context.Context) (err error) {
+	if file, ok := u.store.getSmallFile(u.bucket, u.objectKey); ok {
+		u.info = &file.info
+		u.smallFile = true
+		return
+	}
+
+	var res *awss3.HeadObjectOutput
+	if res, err = u.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    lo.ToPtr(u.objectKey),
+	}); err != nil {
+		if isAwsError[*types.NoSuchKey](err) || isAwsError[*types.NotFound](err) ||
+			isAwsErrorCode(err, "NotFound") {
+			err = xferfile.ErrFileNotExists
+		}
+		return
+	}
+	encoded, ok := res.Metadata[smallFileInfoMeta]
+	if !ok {
+		err = xferfile.ErrFileNotExists
+		return
+	}
+	var jsonInfo []byte
+	if jsonInfo, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return
+	}
+	var info xferfile.Info
+	if err = json.Unmarshal(jsonInfo, &info); err != nil {
+		return
+	}
+	if info.Path != u.objectKey {
+		err = storage.ErrFileInfoMismatch
+		return
+	}
+	u.info = &info
+	u.smallFile = true
+	return
+}
+
+// putSmallFile reads the whole content of the small file and puts it with its info in the
+// metadata of the object, an interrupted read puts nothing so the file is transferred again
+// from the beginning.
+func (u *s3Upload) putSmallFile(
+	ctx context.Context,
+	src io.Reader,
+	tracker storage.ConfirmedSizeTracker,
+) (n int64, err error) {
+	if u.info.Offset == u.info.Size && !u.info.FinishTime.IsZero() {
+		return
+	}
+	var content []byte
+	if content, err = io.ReadAll(io.LimitReader(src, u.info.Size+1)); err != nil {
+		return
+	}
+	if int64(len(content)) != u.info.Size {
+		err = fmt.Errorf("%w: read %d bytes of %d", storage.ErrFileOrObjectCannotFinalize, len(content), u.info.Size)
+		return
+	}
+
+	info := *u.info
+	info.Offset = info.Size
+	info.FinishTime = time.Now()
+	var encodedInfo string
+	if encodedInfo, err = encodeSmallFileInfo(info); err != nil {
+		return
+	}
+	input := &awss3.PutObjectInput{
+		Bucket:        aws.String(u.bucket),
+		Key:           lo.ToPtr(u.objectKey),
+		Body:          bytes.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+		ContentType:   lo.EmptyableToPtr(info.Metadata[storage.ContentTypeMeta]),
+		Metadata:      map[string]string{smallFileInfoMeta: encodedInfo},
+	}
+	for key, value := range u.store.modTimeMetadata(info.ModTime) {
+		input.Metadata[key] = value
+	}
+	if u.store.createIfNotExists {
+		input.IfNoneMatch = aws.String("*")
+	}
+	var output *awss3.PutObjectOutput
+	if output, err = u.client.PutObject(ctx, input); err != nil {
+		if u.store.createIfNotExists && isPreconditionFailed(err) {
+			err = fmt.Errorf("%w: %s", storage.ErrDestinationExists, u.objectKey)
+		}
+		return
+	}
+
+	file := &smallFile{info: info}
+	if output != nil {
+		file.object = storage.FinalizedObject{
+			ETag:      aws.ToString(output.ETag),
+			VersionID: aws.ToString(output.VersionId),
+		}
+	}
+	u.store.setSmallFile(u.bucket, u.objectKey, file)
+	*u.info = info
+	if tracker != nil && info.Size > 0 {
+		tracker.AddConfirmedSize(info.Size)
+	}
+	return info.Size, nil
+}
+
+// copySmallFile reads the whole source object and puts it as the small file, since the
+// content of an object put whole cannot be copied into a part (see Destination.CopyFileFrom).
+func (u *s3Upload) copySmallFile(
+	ctx context.Context,
+	srcBucket, srcKey string,
+	tracker storage.ConfirmedSizeTracker,
+) (n int64, err error) {
+	var res *awss3.GetObjectOutput
+	if res, err = u.client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(srcBucket),
+		Key:    aws.String(srcKey),
+	}); err != nil {
+		return
+	}
+	defer res.Body.Close()
+	return u.putSmallFile(ctx, res.Body, tracker)
+}
+
+// finalizeSmallFile releases the small file once its object has been put.
+func (u *s3Upload) finalizeSmallFile() (object storage.FinalizedObject, err error) {
+	if u.info.Offset != u.info.Size || u.info.FinishTime.IsZero() {
+		err = storage.ErrFileOrObjectCannotFinalize
+		return
+	}
+	if file, ok := u.store.getSmallFile(u.bucket, u.objectKey); ok {
+		object = file.object
+	}
+	u.store.setSmallFile(u.bucket, u.objectKey, nil)
+	return
+}
+
+// deleteSmallFile releases the small file and deletes its object, if it has been put.
+func (u *s3Upload) deleteSmallFile(ctx context.Context) (err error) {
+	u.store.setSmallFile(u.bucket, u.objectKey, nil)
+	if u.info.FinishTime.IsZero() {
+		return
+	}
+	if _, err = u.client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    lo.ToPtr(u.objectKey),
+	}); err != nil && (isAwsError[*types.NoSuchKey](err) || isAwsErrorCode(err, "NoSuchKey")) {
+		err = nil
+	}
+	return
+}
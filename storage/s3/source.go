@@ -1,14 +1,22 @@
 package s3
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/derektruong/fxfer/internal/fileutils"
 	"github.com/derektruong/fxfer/internal/xferfile"
 	"github.com/derektruong/fxfer/protoc"
@@ -24,17 +32,68 @@ type s3Client struct {
 }
 
 type Source struct {
+	// OperationTimeout bounds each S3 operation until its response is received, so that a hung
+	// request fails with context.DeadlineExceeded rather than blocking the transfer as long as
+	// its context lives, the read of the content of an object is not bounded. It must be set
+	// before the first operation of a connection. Default is 0 (no timeout).
+	OperationTimeout time.Duration
+
+	// OperationRecorder records each S3 operation (e.g. HeadObject, GetObject) with its latency and its
+	// error, e.g. to count the calls or to export their latencies (see s3.NewInstrumentedAPI).
+	// It must be set before the first operation of a connection. Default is nil (not recorded).
+	OperationRecorder s3.OperationRecorder
+
 	logger logr.Logger
 
+	// autoRestore is the configuration for restoring archived objects (see WithAutoRestore)
+	autoRestore *autoRestoreConfig
+
+	// partLayout is a flag that indicates whether the part layout is fetched (see WithPartLayout)
+	partLayout bool
+
 	connsMu sync.Mutex
 	conns   map[string]*s3Client
 }
 
-func NewSource(logger logr.Logger) (s *Source) {
+// autoRestoreConfig is the configuration of the restore initiated for archived objects
+type autoRestoreConfig struct {
+	tier types.Tier
+	days int32
+}
+
+// SourceOption is a function that configures the Source
+type SourceOption func(*Source)
+
+// WithAutoRestore initiates a restore (RestoreObject) of an object archived in a storage
+// class requiring restore (e.g. GLACIER, DEEP_ARCHIVE), with the retrieval tier and the
+// number of days the restored copy is kept. The source then fails with
+// storage.ErrObjectRestoreInProgress until the restore completes. By default, the source
+// fails with storage.ErrObjectNeedsRestore.
+func WithAutoRestore(tier types.Tier, days int32) SourceOption {
+	return func(s *Source) {
+		s.autoRestore = &autoRestoreConfig{tier: tier, days: days}
+	}
+}
+
+// WithPartLayout fetches the part layout of multipart-uploaded objects (GetObjectAttributes)
+// and records it in their file info (see storage.PartLayoutMeta), so that the destination
+// uploads the same part boundaries and the composite ETag of the copy matches the one of
+// the source object. The layout is only available for objects uploaded with checksums,
+// other objects are transferred with the part size of the destination.
+func WithPartLayout() SourceOption {
+	return func(s *Source) {
+		s.partLayout = true
+	}
+}
+
+func NewSource(logger logr.Logger, opts ...SourceOption) (s *Source) {
 	s = &Source{
 		logger: logger.WithName("s3.source"),
 		conns:  make(map[string]*s3Client),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	return
 }
 
@@ -58,6 +117,10 @@ func (s *Source) GetFileInfo(
 	}); err != nil {
 		return
 	}
+	if restoreStatus := getRestoreStatus(objInfo); restoreStatus != restoreStatusReadable {
+		err = s.handleArchivedObject(ctx, conn, filePath, restoreStatus)
+		return
+	}
 	var fileName, fileExt string
 	if _, fileName, fileExt, err = fileutils.ExtractFileParts(filePath); err != nil {
 		return
@@ -67,7 +130,28 @@ func (s *Source) GetFileInfo(
 		Size:      lo.FromPtr(objInfo.ContentLength),
 		Name:      fileName,
 		Extension: fileExt,
-		ModTime:   lo.FromPtr(objInfo.LastModified),
+		ModTime:   objectModTime(objInfo.Metadata, objInfo.LastModified),
+	}
+	// the reads of the transfer are conditioned on the ETag (see GetFileFromOffsetIfMatch)
+	if etag := lo.FromPtr(objInfo.ETag); etag != "" {
+		info.Metadata = map[string]string{storage.SourceVersionMeta: etag}
+	}
+	// the declared content type is preserved by the destinations supporting it
+	if contentType := lo.FromPtr(objInfo.ContentType); contentType != "" {
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string)
+		}
+		info.Metadata[storage.ContentTypeMeta] = contentType
+	}
+	// the ETag of a multipart-uploaded object is suffixed with its number of parts (e.g. "<md5>-3")
+	if s.partLayout && strings.Contains(lo.FromPtr(objInfo.ETag), "-") {
+		var partSizes []int64
+		if partSizes, err = s.getPartLayout(ctx, conn, filePath); err != nil {
+			return
+		}
+		if len(partSizes) > 0 {
+			info.Metadata[storage.PartLayoutMeta] = storage.EncodePartLayout(partSizes)
+		}
 	}
 	return
 }
@@ -78,6 +162,35 @@ func (s *Source) GetFileFromOffset(
 	offset int64,
 	cli protoc.Client,
 ) (reader io.ReadCloser, err error) {
+	return s.getObjectFromOffset(ctx, filePath, offset, nil, cli)
+}
+
+// GetFileFromOffsetIfMatch returns the content of the object from the offset only if its ETag is
+// the version (If-Match), storage.ErrSourceChanged is returned if the object has been overwritten
+// since its info was fetched, so that the transfer restarts from the beginning.
+func (s *Source) GetFileFromOffsetIfMatch(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	version string,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	if reader, err = s.getObjectFromOffset(ctx, filePath, offset, aws.String(version), cli); isPreconditionFailed(err) {
+		err = fmt.Errorf("%w: %w", storage.ErrSourceChanged, err)
+	}
+	return
+}
+
+// getObjectFromOffset returns the content of the object from the offset, if its ETag matches
+// ifMatch unless nil.
+func (s *Source) getObjectFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	ifMatch *string,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	defer func() { err = wrapThrottleError(err) }()
 	var conn *s3Client
 	if conn, err = s.checkAndSetClient(cli); err != nil {
 		return
@@ -85,16 +198,244 @@ func (s *Source) GetFileFromOffset(
 	offsetStr := strconv.FormatInt(offset, 10)
 	var objOutput *awss3.GetObjectOutput
 	if objOutput, err = conn.client.GetObject(ctx, &awss3.GetObjectInput{
-		Bucket: aws.String(conn.bucket),
-		Key:    aws.String(filePath),
-		Range:  aws.String(fmt.Sprintf("bytes=%s-", offsetStr)),
+		Bucket:  aws.String(conn.bucket),
+		Key:     aws.String(filePath),
+		Range:   aws.String(fmt.Sprintf("bytes=%s-", offsetStr)),
+		IfMatch: ifMatch,
 	}); err != nil {
+		switch {
+		case isAwsErrorCode(err, "InvalidObjectState"):
+			err = s.handleArchivedObject(ctx, conn, filePath, restoreStatusNeedsRestore)
+		case isRangeNotSatisfiable(err):
+			// the offset is the end of the object (e.g. a fully transferred resume), so there is
+			// nothing to read as seeking a local file to its end
+			reader, err = io.NopCloser(bytes.NewReader(nil)), nil
+		}
 		return
 	}
 	reader = objOutput.Body
 	return
 }
 
+// isRangeNotSatisfiable reports whether the error is the InvalidRange (416) error which S3
+// returns for a range starting at the end of the object.
+func isRangeNotSatisfiable(err error) bool {
+	if isAwsErrorCode(err, "InvalidRange") {
+		return true
+	}
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusRequestedRangeNotSatisfiable
+}
+
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	for info, iterErr := range s.IterFiles(ctx, dirPath, cli) {
+		if err = iterErr; err != nil {
+			return
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
+// IterFiles iterates over the objects under the prefix of the directory, listing a page of
+// objects (ListObjectsV2) only once the previous one is consumed (see storage.FileIterator).
+func (s *Source) IterFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) iter.Seq2[xferfile.Info, error] {
+	return func(yield func(xferfile.Info, error) bool) {
+		conn, err := s.checkAndSetClient(cli)
+		if err != nil {
+			yield(xferfile.Info{}, err)
+			return
+		}
+		prefix := strings.TrimSuffix(dirPath, "/")
+		// the current directory (e.g. the base directory of a pattern) is the root of the bucket
+		if prefix == "." {
+			prefix = ""
+		}
+		if prefix != "" {
+			prefix += "/"
+		}
+		s.iterObjects(ctx, conn, prefix, func(string) bool { return true })(yield)
+	}
+}
+
+// Glob lists the objects under the prefix of the pattern, filtering their keys with path.Match.
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	var conn *s3Client
+	if conn, err = s.checkAndSetClient(cli); err != nil {
+		return
+	}
+	for info, iterErr := range s.iterObjects(ctx, conn, fileutils.GlobPrefix(pattern), func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	}) {
+		if err = iterErr; err != nil {
+			return
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
+// iterObjects iterates over the objects under the prefix whose key matches, across pages,
+// until the context is done.
+func (s *Source) iterObjects(
+	ctx context.Context,
+	conn *s3Client,
+	prefix string,
+	match func(key string) bool,
+) iter.Seq2[xferfile.Info, error] {
+	return func(yield func(xferfile.Info, error) bool) {
+		var continuationToken *string
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(xferfile.Info{}, err)
+				return
+			}
+			listOutput, err := conn.client.ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+				Bucket:            aws.String(conn.bucket),
+				Prefix:            aws.String(prefix),
+				ContinuationToken: continuationToken,
+			})
+			if err != nil {
+				yield(xferfile.Info{}, err)
+				return
+			}
+			for _, obj := range listOutput.Contents {
+				key := lo.FromPtr(obj.Key)
+				// skip the "directory" placeholder objects
+				if strings.HasSuffix(key, "/") || !match(key) {
+					continue
+				}
+				fileName, fileExt := fileutils.SplitFileName(key)
+				if !yield(xferfile.Info{
+					Path:      key,
+					Size:      lo.FromPtr(obj.Size),
+					Name:      fileName,
+					Extension: fileExt,
+					ModTime:   lo.FromPtr(obj.LastModified),
+				}, nil) {
+					return
+				}
+			}
+			if !lo.FromPtr(listOutput.IsTruncated) {
+				return
+			}
+			continuationToken = listOutput.NextContinuationToken
+		}
+	}
+}
+
+// getPartLayout returns the sizes of the parts of a multipart-uploaded object, ordered by
+// part number, or nil if the parts are not listed by S3.
+func (s *Source) getPartLayout(
+	ctx context.Context,
+	conn *s3Client,
+	filePath string,
+) (partSizes []int64, err error) {
+	var partNumberMarker *string
+	for {
+		var attrOutput *awss3.GetObjectAttributesOutput
+		if attrOutput, err = conn.client.GetObjectAttributes(ctx, &awss3.GetObjectAttributesInput{
+			Bucket:           aws.String(conn.bucket),
+			Key:              aws.String(filePath),
+			ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
+			PartNumberMarker: partNumberMarker,
+		}); err != nil {
+			return
+		}
+		objParts := attrOutput.ObjectParts
+		if objParts == nil || len(objParts.Parts) == 0 {
+			s.logger.Info("part layout is not available", "path", filePath)
+			return nil, nil
+		}
+		for _, part := range objParts.Parts {
+			partSizes = append(partSizes, lo.FromPtr(part.Size))
+		}
+		if !lo.FromPtr(objParts.IsTruncated) {
+			break
+		}
+		partNumberMarker = objParts.NextPartNumberMarker
+	}
+	return
+}
+
+// handleArchivedObject returns the error of an archived object, after initiating
+// its restore if the source is configured with WithAutoRestore.
+func (s *Source) handleArchivedObject(
+	ctx context.Context,
+	conn *s3Client,
+	filePath string,
+	status restoreStatus,
+) (err error) {
+	if status == restoreStatusInProgress {
+		return storage.ErrObjectRestoreInProgress
+	}
+	if s.autoRestore == nil {
+		return storage.ErrObjectNeedsRestore
+	}
+
+	s.logger.Info("restoring archived object", "path", filePath,
+		"tier", s.autoRestore.tier, "days", s.autoRestore.days)
+	if _, err = conn.client.RestoreObject(ctx, &awss3.RestoreObjectInput{
+		Bucket: aws.String(conn.bucket),
+		Key:    aws.String(filePath),
+		RestoreRequest: &types.RestoreRequest{
+			Days: aws.Int32(s.autoRestore.days),
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: s.autoRestore.tier,
+			},
+		},
+	}); err != nil && !isAwsErrorCode(err, "RestoreAlreadyInProgress") {
+		return
+	}
+	return storage.ErrObjectRestoreInProgress
+}
+
+// restoreStatus is the status of an object regarding the restore of its storage class
+type restoreStatus int
+
+const (
+	// restoreStatusReadable is the status of an object which can be read
+	restoreStatusReadable restoreStatus = iota
+	// restoreStatusNeedsRestore is the status of an archived object which has not been restored
+	restoreStatusNeedsRestore
+	// restoreStatusInProgress is the status of an archived object being restored
+	restoreStatusInProgress
+)
+
+// getRestoreStatus returns the restore status of the object from its storage class
+// (or archive status) and its Restore header (e.g. `ongoing-request="true"`).
+func getRestoreStatus(objInfo *awss3.HeadObjectOutput) restoreStatus {
+	archived := objInfo.StorageClass == types.StorageClassGlacier ||
+		objInfo.StorageClass == types.StorageClassDeepArchive ||
+		objInfo.ArchiveStatus != ""
+	switch {
+	case !archived:
+		return restoreStatusReadable
+	case objInfo.Restore == nil:
+		return restoreStatusNeedsRestore
+	case strings.Contains(*objInfo.Restore, `ongoing-request="true"`):
+		return restoreStatusInProgress
+	default:
+		return restoreStatusReadable
+	}
+}
+
 func (s *Source) checkAndSetClient(protocol protoc.Client) (conn *s3Client, err error) {
 	s.connsMu.Lock()
 	defer s.connsMu.Unlock()
@@ -110,7 +451,10 @@ func (s *Source) checkAndSetClient(protocol protoc.Client) (conn *s3Client, err
 		}
 		conn = &s3Client{
 			bucket: cred.BucketName,
-			client: client,
+			client: s3.NewInstrumentedAPI(
+				withExpectedBucketOwner(withOperationTimeout(client, s.OperationTimeout), cred.ExpectedBucketOwner),
+				s.OperationRecorder,
+			),
 		}
 		s.conns[connID] = conn
 	}
@@ -2,12 +2,17 @@ package s3
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/derektruong/fxfer/internal/xferfile"
 	localio_protoc "github.com/derektruong/fxfer/protoc/local"
@@ -89,6 +94,21 @@ var _ = Describe("Source", func() {
 			_, err = srcStorage.GetFileInfo(ctx, wrongFilePath, protocS3Client)
 			Expect(err).To(MatchError("file extension is required"))
 		}, NodeTimeout(10*time.Second))
+
+		It("should record the content type of the object", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(13),
+				ContentType:   aws.String("application/vnd.fxfer+xml"),
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Metadata).To(HaveKeyWithValue(storage.ContentTypeMeta, "application/vnd.fxfer+xml"))
+		}, NodeTimeout(10*time.Second))
 	})
 
 	Describe("GetFileFromOffset", func() {
@@ -137,5 +157,521 @@ var _ = Describe("Source", func() {
 			_, err = srcStorage.GetFileFromOffset(ctx, filePath, 0, mockClient)
 			Expect(err).To(MatchError(occurError))
 		}, NodeTimeout(10*time.Second))
+
+		It("should fail a hung request after the operation timeout", func(ctx context.Context) {
+			srcStorage.OperationTimeout = 50 * time.Millisecond
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+
+			mockS3API.EXPECT().GetObject(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(
+					opCtx context.Context,
+					_ *awss3.GetObjectInput,
+					_ ...func(*awss3.Options),
+				) (*awss3.GetObjectOutput, error) {
+					<-opCtx.Done()
+					return nil, opCtx.Err()
+				})
+
+			_, err = srcStorage.GetFileFromOffset(ctx, filePath, 0, mockClient)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(ctx.Err()).ToNot(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+
+		It("should return an empty reader when the offset is the end of the file", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				Return(nil, &smithy.GenericAPIError{Code: "InvalidRange"})
+
+			var reader io.ReadCloser
+			reader, err = srcStorage.GetFileFromOffset(ctx, filePath, 56, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+
+			content, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("GetFileFromOffsetIfMatch", func() {
+		const etag = `"9b2cf535f27731c974343645a3985328"`
+
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return("").AnyTimes()
+			mockClient.EXPECT().GetS3API().Return(mockS3API).AnyTimes()
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient).AnyTimes()
+		})
+
+		It("should record the ETag of the object as its version", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(13),
+				ETag:          aws.String(etag),
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Metadata).To(HaveKeyWithValue(storage.SourceVersionMeta, etag))
+		}, NodeTimeout(10*time.Second))
+
+		It("should read the object from the offset on the condition of its ETag", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(ctx, &awss3.GetObjectInput{
+				Bucket:  aws.String(bucketName),
+				Key:     aws.String(filePath),
+				Range:   aws.String("bytes=4-"),
+				IfMatch: aws.String(etag),
+			}).Return(&awss3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("content"))}, nil)
+
+			reader, err := srcStorage.GetFileFromOffsetIfMatch(ctx, filePath, 4, etag, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEquivalentTo("content"))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the object has been overwritten", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				Return(nil, &smithy.GenericAPIError{Code: "PreconditionFailed"})
+
+			_, err := srcStorage.GetFileFromOffsetIfMatch(ctx, filePath, 4, etag, mockClient)
+			Expect(err).To(MatchError(storage.ErrSourceChanged))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ListFiles", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should list all objects under the prefix across pages", func(ctx context.Context) {
+			modTime := time.Now()
+			gomock.InOrder(
+				mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+					Bucket: aws.String(bucketName),
+					Prefix: aws.String("dt-folder/"),
+				}).Return(&awss3.ListObjectsV2Output{
+					Contents: []types.Object{
+						{Key: aws.String("dt-folder/"), Size: aws.Int64(0)},
+						{Key: aws.String("dt-folder/a.txt"), Size: aws.Int64(10), LastModified: aws.Time(modTime)},
+					},
+					IsTruncated:           aws.Bool(true),
+					NextContinuationToken: aws.String("next-page"),
+				}, nil),
+				mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+					Bucket:            aws.String(bucketName),
+					Prefix:            aws.String("dt-folder/"),
+					ContinuationToken: aws.String("next-page"),
+				}).Return(&awss3.ListObjectsV2Output{
+					Contents: []types.Object{
+						{Key: aws.String("dt-folder/nested/b.mov"), Size: aws.Int64(20), LastModified: aws.Time(modTime)},
+					},
+				}, nil),
+			)
+
+			infos, err := srcStorage.ListFiles(ctx, "dt-folder", mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(HaveExactElements(
+				And(
+					HaveField("Path", "dt-folder/a.txt"),
+					HaveField("Name", "a"),
+					HaveField("Extension", "txt"),
+					HaveField("Size", int64(10)),
+					HaveField("ModTime", modTime),
+				),
+				And(
+					HaveField("Path", "dt-folder/nested/b.mov"),
+					HaveField("Name", "b"),
+					HaveField("Extension", "mov"),
+					HaveField("Size", int64(20)),
+				),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when listing objects failed", func(ctx context.Context) {
+			occurError := gofakeit.Error()
+			mockS3API.EXPECT().ListObjectsV2(ctx, gomock.Any()).Return(nil, occurError)
+
+			_, err = srcStorage.ListFiles(ctx, "dt-folder/", mockClient)
+			Expect(err).To(MatchError(occurError))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("IterFiles", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		// listPage returns a page of the objects from..to (excluded) under dt-folder/, truncated
+		// with the continuation token unless it is empty
+		listPage := func(from, to int, nextToken string) *awss3.ListObjectsV2Output {
+			output := &awss3.ListObjectsV2Output{IsTruncated: aws.Bool(nextToken != "")}
+			if nextToken != "" {
+				output.NextContinuationToken = aws.String(nextToken)
+			}
+			for i := from; i < to; i++ {
+				output.Contents = append(output.Contents, types.Object{
+					Key:  aws.String(fmt.Sprintf("dt-folder/%04d.txt", i)),
+					Size: aws.Int64(int64(i)),
+				})
+			}
+			return output
+		}
+
+		It("should iterate over more than 1000 objects across pages", func(ctx context.Context) {
+			gomock.InOrder(
+				mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+					Bucket: aws.String(bucketName),
+					Prefix: aws.String("dt-folder/"),
+				}).Return(listPage(0, 1000, "page-2"), nil),
+				mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+					Bucket:            aws.String(bucketName),
+					Prefix:            aws.String("dt-folder/"),
+					ContinuationToken: aws.String("page-2"),
+				}).Return(listPage(1000, 2000, "page-3"), nil),
+				mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+					Bucket:            aws.String(bucketName),
+					Prefix:            aws.String("dt-folder/"),
+					ContinuationToken: aws.String("page-3"),
+				}).Return(listPage(2000, 2500, ""), nil),
+			)
+
+			var paths []string
+			for info, err := range srcStorage.IterFiles(ctx, "dt-folder", mockClient) {
+				Expect(err).ToNot(HaveOccurred())
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(HaveLen(2500))
+			Expect(paths[0]).To(Equal("dt-folder/0000.txt"))
+			Expect(paths[2499]).To(Equal("dt-folder/2499.txt"))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not list the next page once the iteration stops", func(ctx context.Context) {
+			mockS3API.EXPECT().ListObjectsV2(ctx, gomock.Any()).Return(listPage(0, 1000, "page-2"), nil)
+
+			for info, err := range srcStorage.IterFiles(ctx, "dt-folder", mockClient) {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Path).To(Equal("dt-folder/0000.txt"))
+				break
+			}
+		}, NodeTimeout(10*time.Second))
+
+		It("should stop once the context is canceled", func(ctx context.Context) {
+			listCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			mockS3API.EXPECT().ListObjectsV2(listCtx, gomock.Any()).Return(listPage(0, 1000, "page-2"), nil)
+
+			var (
+				count   int
+				lastErr error
+			)
+			for _, err := range srcStorage.IterFiles(listCtx, "dt-folder", mockClient) {
+				if err != nil {
+					lastErr = err
+					continue
+				}
+				if count++; count == 1000 {
+					cancel()
+				}
+			}
+			Expect(count).To(Equal(1000))
+			Expect(lastErr).To(MatchError(context.Canceled))
+		}, NodeTimeout(10*time.Second))
+
+		It("should iterate over the whole bucket for the current directory", func(ctx context.Context) {
+			mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+				Bucket: aws.String(bucketName),
+				Prefix: aws.String(""),
+			}).Return(listPage(0, 2, ""), nil)
+
+			var paths []string
+			for info, err := range srcStorage.IterFiles(ctx, ".", mockClient) {
+				Expect(err).ToNot(HaveOccurred())
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(Equal([]string{"dt-folder/0000.txt", "dt-folder/0001.txt"}))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("Glob", func() {
+		It("should list the objects matching the pattern under its prefix", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().ListObjectsV2(ctx, &awss3.ListObjectsV2Input{
+				Bucket: aws.String(bucketName),
+				Prefix: aws.String("logs/2024-"),
+			}).Return(&awss3.ListObjectsV2Output{
+				Contents: []types.Object{
+					{Key: aws.String("logs/2024-01/"), Size: aws.Int64(0)},
+					{Key: aws.String("logs/2024-01/app-1.log"), Size: aws.Int64(10)},
+					{Key: aws.String("logs/2024-01/db-1.log"), Size: aws.Int64(10)},
+					{Key: aws.String("logs/2024-01/nested/app-2.log"), Size: aws.Int64(10)},
+					{Key: aws.String("logs/2024-02/app-3.log"), Size: aws.Int64(20)},
+				},
+			}, nil)
+
+			infos, err := srcStorage.Glob(ctx, "logs/2024-*/app-*.log", mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(HaveExactElements(
+				And(
+					HaveField("Path", "logs/2024-01/app-1.log"),
+					HaveField("Name", "app-1"),
+					HaveField("Extension", "log"),
+					HaveField("Size", int64(10)),
+				),
+				HaveField("Path", "logs/2024-02/app-3.log"),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if the pattern is malformed", func(ctx context.Context) {
+			_, err = srcStorage.Glob(ctx, "logs/[a-", mockClient)
+			Expect(err).To(MatchError(path.ErrBadPattern))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("archived objects", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		DescribeTable("should detect the object needs restore",
+			func(ctx context.Context, output *awss3.HeadObjectOutput, expectedErr error) {
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(output, nil)
+
+				_, err = srcStorage.GetFileInfo(ctx, filePath, mockClient)
+				if expectedErr == nil {
+					Expect(err).ToNot(HaveOccurred())
+					return
+				}
+				Expect(err).To(MatchError(expectedErr))
+			},
+			Entry("glacier object not restored", &awss3.HeadObjectOutput{
+				StorageClass: types.StorageClassGlacier,
+			}, storage.ErrObjectNeedsRestore),
+			Entry("deep archive object being restored", &awss3.HeadObjectOutput{
+				StorageClass: types.StorageClassDeepArchive,
+				Restore:      aws.String(`ongoing-request="true"`),
+			}, storage.ErrObjectRestoreInProgress),
+			Entry("intelligent-tiering archived object", &awss3.HeadObjectOutput{
+				StorageClass:  types.StorageClassIntelligentTiering,
+				ArchiveStatus: types.ArchiveStatusArchiveAccess,
+			}, storage.ErrObjectNeedsRestore),
+			Entry("glacier object restored", &awss3.HeadObjectOutput{
+				StorageClass: types.StorageClassGlacier,
+				Restore:      aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`),
+			}, nil),
+			Entry("standard object", &awss3.HeadObjectOutput{
+				StorageClass: types.StorageClassStandard,
+			}, nil),
+		)
+
+		It("should return error when getting an archived file object", func(ctx context.Context) {
+			mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+				Return(nil, &smithy.GenericAPIError{Code: "InvalidObjectState"})
+
+			_, err = srcStorage.GetFileFromOffset(ctx, filePath, 0, mockClient)
+			Expect(err).To(MatchError(storage.ErrObjectNeedsRestore))
+		}, NodeTimeout(10*time.Second))
+
+		Context("with auto-restore", func() {
+			BeforeEach(func() {
+				srcStorage = NewSource(GinkgoLogr, WithAutoRestore(types.TierBulk, 3))
+			})
+
+			It("should initiate the restore of the archived object", func(ctx context.Context) {
+				gomock.InOrder(
+					mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+						StorageClass: types.StorageClassGlacier,
+					}, nil),
+					mockS3API.EXPECT().RestoreObject(ctx, &awss3.RestoreObjectInput{
+						Bucket: aws.String(bucketName),
+						Key:    aws.String(filePath),
+						RestoreRequest: &types.RestoreRequest{
+							Days: aws.Int32(3),
+							GlacierJobParameters: &types.GlacierJobParameters{
+								Tier: types.TierBulk,
+							},
+						},
+					}).Return(&awss3.RestoreObjectOutput{}, nil),
+				)
+
+				_, err = srcStorage.GetFileInfo(ctx, filePath, mockClient)
+				Expect(err).To(MatchError(storage.ErrObjectRestoreInProgress))
+			}, NodeTimeout(10*time.Second))
+
+			It("should not initiate the restore again while it is in progress", func(ctx context.Context) {
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+					StorageClass: types.StorageClassGlacier,
+					Restore:      aws.String(`ongoing-request="true"`),
+				}, nil)
+
+				_, err = srcStorage.GetFileInfo(ctx, filePath, mockClient)
+				Expect(err).To(MatchError(storage.ErrObjectRestoreInProgress))
+			}, NodeTimeout(10*time.Second))
+
+			It("should fail retryably when getting an archived file object", func(ctx context.Context) {
+				gomock.InOrder(
+					mockS3API.EXPECT().GetObject(ctx, gomock.Any()).
+						Return(nil, &smithy.GenericAPIError{Code: "InvalidObjectState"}),
+					mockS3API.EXPECT().RestoreObject(ctx, gomock.Any()).
+						Return(nil, &smithy.GenericAPIError{Code: "RestoreAlreadyInProgress"}),
+				)
+
+				_, err = srcStorage.GetFileFromOffset(ctx, filePath, 0, mockClient)
+				Expect(err).To(MatchError(storage.ErrObjectRestoreInProgress))
+			}, NodeTimeout(10*time.Second))
+
+			It("should return error when initiating the restore failed", func(ctx context.Context) {
+				occurError := gofakeit.Error()
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+					StorageClass: types.StorageClassDeepArchive,
+				}, nil)
+				mockS3API.EXPECT().RestoreObject(ctx, gomock.Any()).Return(nil, occurError)
+
+				_, err = srcStorage.GetFileInfo(ctx, filePath, mockClient)
+				Expect(err).To(MatchError(occurError))
+			}, NodeTimeout(10*time.Second))
+		})
+	})
+
+	Describe("part layout", func() {
+		BeforeEach(func() {
+			srcStorage = NewSource(GinkgoLogr, WithPartLayout())
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should record the part layout of a multipart-uploaded object", func(ctx context.Context) {
+			gomock.InOrder(
+				mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+					ContentLength: aws.Int64(13),
+					ETag:          aws.String(`"9b2cf535f27731c974343645a3985328-3"`),
+				}, nil),
+				mockS3API.EXPECT().GetObjectAttributes(ctx, &awss3.GetObjectAttributesInput{
+					Bucket:           aws.String(bucketName),
+					Key:              aws.String(filePath),
+					ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
+				}).Return(&awss3.GetObjectAttributesOutput{
+					ObjectParts: &types.GetObjectAttributesParts{
+						Parts: []types.ObjectPart{
+							{PartNumber: aws.Int32(1), Size: aws.Int64(5)},
+							{PartNumber: aws.Int32(2), Size: aws.Int64(5)},
+						},
+						IsTruncated:          aws.Bool(true),
+						NextPartNumberMarker: aws.String("2"),
+					},
+				}, nil),
+				mockS3API.EXPECT().GetObjectAttributes(ctx, &awss3.GetObjectAttributesInput{
+					Bucket:           aws.String(bucketName),
+					Key:              aws.String(filePath),
+					ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
+					PartNumberMarker: aws.String("2"),
+				}).Return(&awss3.GetObjectAttributesOutput{
+					ObjectParts: &types.GetObjectAttributesParts{
+						Parts: []types.ObjectPart{
+							{PartNumber: aws.Int32(3), Size: aws.Int64(3)},
+						},
+					},
+				}, nil),
+			)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Metadata).To(HaveKeyWithValue(storage.PartLayoutMeta, "5,5,3"))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not record the part layout of a single-part object", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(13),
+				ETag:          aws.String(`"9b2cf535f27731c974343645a3985328"`),
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Metadata).ToNot(HaveKey(storage.PartLayoutMeta))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not record the part layout when the parts are not listed", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(13),
+				ETag:          aws.String(`"9b2cf535f27731c974343645a3985328-3"`),
+			}, nil)
+			mockS3API.EXPECT().GetObjectAttributes(ctx, gomock.Any()).Return(&awss3.GetObjectAttributesOutput{
+				ObjectParts: &types.GetObjectAttributesParts{TotalPartsCount: aws.Int32(3)},
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Metadata).ToNot(HaveKey(storage.PartLayoutMeta))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("GetFileInfo of an object with the source modification time", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+		})
+
+		It("should prefer the recorded modification time over LastModified", func(ctx context.Context) {
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(10),
+				LastModified:  aws.Time(time.Now()),
+				Metadata:      map[string]string{"src-modtime": "2024-05-06T00:08:09.00000001Z"},
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.ModTime).To(BeTemporally("==", time.Date(2024, 5, 6, 0, 8, 9, 10, time.UTC)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should fall back to LastModified when none is recorded", func(ctx context.Context) {
+			lastModified := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(&awss3.HeadObjectOutput{
+				ContentLength: aws.Int64(10),
+				LastModified:  aws.Time(lastModified),
+			}, nil)
+
+			info, err := srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.ModTime).To(Equal(lastModified))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("OperationRecorder", func() {
+		It("should record the operations of the source", func(ctx context.Context) {
+			mockClient.EXPECT().GetConnectionID().Return("")
+			mockClient.EXPECT().GetS3API().Return(mockS3API)
+			s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+			mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+			mockS3API.EXPECT().HeadObject(ctx, gomock.Any()).Return(nil, errors.New("head failed"))
+
+			var recorded []string
+			srcStorage.OperationRecorder = func(_ context.Context, operation string, _ time.Duration, err error) {
+				recorded = append(recorded, operation+": "+err.Error())
+			}
+			_, err = srcStorage.GetFileInfo(ctx, filePath, mockClient)
+			Expect(err).To(MatchError("head failed"))
+			Expect(recorded).To(Equal([]string{"HeadObject: head failed"}))
+		}, NodeTimeout(10*time.Second))
 	})
 })
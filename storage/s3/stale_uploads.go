@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/samber/lo"
+)
+
+// maxDeleteObjects is the maximum number of objects deleted by a DeleteObjects request.
+const maxDeleteObjects = 1000
+
+// CleanupStaleUploads aborts the multipart uploads of the bucket initiated more than olderThan
+// ago (ListMultipartUploads), e.g. left behind by the interrupted transfers which are never
+// resumed, and deletes their .info and .part objects, unless a more recent upload of the same
+// object is in progress. The uploads are not told apart from those initiated by other clients
+// of the bucket, olderThan must exceed the longest transfer. It returns the keys of the objects
+// whose uploads are aborted, along with the errors of the uploads which could not be cleaned up.
+func (d *Destination) CleanupStaleUploads(
+	ctx context.Context,
+	protocol protoc.Client,
+	olderThan time.Duration,
+) (aborted []string, err error) {
+	var s3Cli *s3Client
+	if s3Cli, err = d.checkAndSetClient(protocol); err != nil {
+		return
+	}
+	staleBefore := time.Now().Add(-olderThan)
+
+	var stale []types.MultipartUpload
+	inProgress := make(map[string]bool)
+	input := &awss3.ListMultipartUploadsInput{Bucket: aws.String(s3Cli.bucket)}
+	for {
+		var res *awss3.ListMultipartUploadsOutput
+		if res, err = s3Cli.client.ListMultipartUploads(ctx, input); err != nil {
+			return
+		}
+		for _, upload := range res.Uploads {
+			if lo.FromPtr(upload.Initiated).Before(staleBefore) {
+				stale = append(stale, upload)
+			} else {
+				inProgress[lo.FromPtr(upload.Key)] = true
+			}
+		}
+		if !lo.FromPtr(res.IsTruncated) {
+			break
+		}
+		input.KeyMarker, input.UploadIdMarker = res.NextKeyMarker, res.NextUploadIdMarker
+	}
+
+	var errs []error
+	var metadataObjects []types.ObjectIdentifier
+	for _, upload := range stale {
+		key := lo.FromPtr(upload.Key)
+		if _, abortErr := s3Cli.client.AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s3Cli.bucket),
+			Key:      upload.Key,
+			UploadId: upload.UploadId,
+		}); abortErr != nil && !isAwsError[*types.NoSuchUpload](abortErr) {
+			errs = append(errs, abortErr)
+			continue
+		}
+		aborted = append(aborted, key)
+		d.forgetAdaptivePartSize(s3Cli.bucket, key)
+		d.logger.Info("aborted stale multipart upload", "objectKey", key,
+			"initiated", lo.FromPtr(upload.Initiated))
+
+		// the .info and .part objects are shared by the uploads of the object
+		if inProgress[key] {
+			continue
+		}
+		infoKey, infoErr := d.generateInfoKey(key)
+		if infoErr != nil {
+			errs = append(errs, infoErr)
+			continue
+		}
+		metadataObjects = append(metadataObjects,
+			types.ObjectIdentifier{Key: aws.String(infoKey)},
+			types.ObjectIdentifier{Key: aws.String(d.generateMultipartKey(key))},
+		)
+	}
+
+	metadataBucket := cmp.Or(d.MetadataBucket, s3Cli.bucket)
+	for _, objects := range lo.Chunk(lo.UniqBy(metadataObjects, func(object types.ObjectIdentifier) string {
+		return *object.Key
+	}), maxDeleteObjects) {
+		errs = append(errs, d.deleteObjects(ctx, s3Cli.client, metadataBucket, objects)...)
+	}
+	err = errors.Join(errs...)
+	return
+}
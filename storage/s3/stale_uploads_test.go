@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	s3_protoc "github.com/derektruong/fxfer/protoc/s3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("CleanupStaleUploads", func() {
+	var (
+		mockS3API   *mock_protoc.MockS3API
+		mockClient  *mock_protoc.MockClient
+		destStorage *Destination
+	)
+
+	BeforeEach(func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		DeferCleanup(mockCtrl.Finish)
+		mockS3API = mock_protoc.NewMockS3API(mockCtrl)
+		mockClient = mock_protoc.NewMockClient(mockCtrl)
+		mockClient.EXPECT().GetConnectionID().Return("")
+		mockClient.EXPECT().GetS3API().Return(mockS3API)
+		s3ProtocClient := s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+		mockClient.EXPECT().GetCredential().Return(*s3ProtocClient)
+
+		var err error
+		destStorage, err = NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(destStorage.Close)
+	})
+
+	// upload returns a multipart upload of the key initiated the duration ago
+	upload := func(key string, age time.Duration) types.MultipartUpload {
+		return types.MultipartUpload{
+			Key:       aws.String(key),
+			UploadId:  aws.String("upload-" + key),
+			Initiated: aws.Time(time.Now().Add(-age)),
+		}
+	}
+
+	// metadataKeys returns the keys of the .info and .part objects of the object key
+	metadataKeys := func(key string) []string {
+		infoKey, err := destStorage.generateInfoKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		return []string{infoKey, destStorage.generateMultipartKey(key)}
+	}
+
+	It("should abort the stale uploads and delete their info and part objects", func(ctx context.Context) {
+		gomock.InOrder(
+			mockS3API.EXPECT().ListMultipartUploads(ctx, &awss3.ListMultipartUploadsInput{
+				Bucket: aws.String(bucketName),
+			}).Return(&awss3.ListMultipartUploadsOutput{
+				Uploads:            []types.MultipartUpload{upload("a.txt", 48*time.Hour), upload("b.txt", time.Minute)},
+				IsTruncated:        aws.Bool(true),
+				NextKeyMarker:      aws.String("b.txt"),
+				NextUploadIdMarker: aws.String("upload-b.txt"),
+			}, nil),
+			mockS3API.EXPECT().ListMultipartUploads(ctx, &awss3.ListMultipartUploadsInput{
+				Bucket:         aws.String(bucketName),
+				KeyMarker:      aws.String("b.txt"),
+				UploadIdMarker: aws.String("upload-b.txt"),
+			}).Return(&awss3.ListMultipartUploadsOutput{
+				Uploads: []types.MultipartUpload{upload("c/d.txt", 25*time.Hour)},
+			}, nil),
+		)
+		for _, key := range []string{"a.txt", "c/d.txt"} {
+			mockS3API.EXPECT().AbortMultipartUpload(ctx, &awss3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucketName),
+				Key:      aws.String(key),
+				UploadId: aws.String("upload-" + key),
+			}).Return(&awss3.AbortMultipartUploadOutput{}, nil)
+		}
+		var deletedKeys []string
+		mockS3API.EXPECT().DeleteObjects(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *awss3.DeleteObjectsInput, _ ...func(*awss3.Options)) (*awss3.DeleteObjectsOutput, error) {
+				Expect(*input.Bucket).To(Equal(bucketName))
+				for _, object := range input.Delete.Objects {
+					deletedKeys = append(deletedKeys, *object.Key)
+				}
+				return &awss3.DeleteObjectsOutput{}, nil
+			})
+
+		aborted, err := destStorage.CleanupStaleUploads(ctx, mockClient, 24*time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(aborted).To(Equal([]string{"a.txt", "c/d.txt"}))
+		Expect(deletedKeys).To(ConsistOf(append(metadataKeys("a.txt"), metadataKeys("c/d.txt")...)))
+	}, NodeTimeout(10*time.Second))
+
+	It("should keep the info and part objects of an object with a recent upload", func(ctx context.Context) {
+		mockS3API.EXPECT().ListMultipartUploads(ctx, gomock.Any()).Return(&awss3.ListMultipartUploadsOutput{
+			Uploads: []types.MultipartUpload{upload("a.txt", 48*time.Hour), upload("a.txt", time.Minute)},
+		}, nil)
+		mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).Return(&awss3.AbortMultipartUploadOutput{}, nil)
+
+		aborted, err := destStorage.CleanupStaleUploads(ctx, mockClient, 24*time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(aborted).To(Equal([]string{"a.txt"}))
+	}, NodeTimeout(10*time.Second))
+
+	It("should return the error of the uploads which cannot be aborted", func(ctx context.Context) {
+		mockS3API.EXPECT().ListMultipartUploads(ctx, gomock.Any()).Return(&awss3.ListMultipartUploadsOutput{
+			Uploads: []types.MultipartUpload{upload("a.txt", 48*time.Hour)},
+		}, nil)
+		accessDeniedErr := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied."}
+		mockS3API.EXPECT().AbortMultipartUpload(ctx, gomock.Any()).Return(nil, accessDeniedErr)
+
+		aborted, err := destStorage.CleanupStaleUploads(ctx, mockClient, 24*time.Hour)
+		Expect(err).To(MatchError(accessDeniedErr))
+		Expect(aborted).To(BeEmpty())
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// availableDiskSpace returns the number of bytes available in the directory, or -1 if
+// it cannot be determined on this platform, it is replaced in tests.
+var availableDiskSpace = diskSpace
+
+// checkTempDirSpace verifies that the temporary directory has enough available space
+// to buffer MaxBufferedParts parts of PreferredPartSize, or MaxBufferedBytes if less, the
+// parts buffered in memory or in a custom store (see Destination.PartStore) are exempt.
+func (d *Destination) checkTempDirSpace(dir string) (err error) {
+	if dir == TempDirUseMemory || d.PartStore != nil {
+		return
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	var available int64
+	if available, err = availableDiskSpace(dir); err != nil {
+		return fmt.Errorf("unable to check the available space of the temporary directory %s: %w", dir, err)
+	}
+	required := d.PreferredPartSize * d.MaxBufferedParts
+	if d.MaxBufferedBytes > 0 {
+		required = min(required, max(d.MaxBufferedBytes, d.PreferredPartSize))
+	}
+	if available >= 0 && available < required {
+		return fmt.Errorf("%w: %s has %d bytes available, %d bytes required to buffer %d parts",
+			storage.ErrTempDirSpaceInsufficient, dir, available, required, d.MaxBufferedParts)
+	}
+	return
+}
+
+// scratchDirPrefix is the prefix of the name of the scratch directory of a transfer.
+const scratchDirPrefix = "fxfer-scratch-"
+
+// scratchDirectory returns the scratch directory of the transfer of the object under the
+// temporary directory (see Destination.ScratchDirectoryPerTransfer), or "" if it has none.
+// The name of the directory is derived from the object, so that a resumed transfer finds it.
+func (d *Destination) scratchDirectory(bucket, objectKey string) string {
+	if !d.ScratchDirectoryPerTransfer || d.TemporaryDirectory == TempDirUseMemory {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(bucket + "/" + objectKey))
+	return filepath.Join(cmp.Or(d.TemporaryDirectory, os.TempDir()), scratchDirPrefix+hex.EncodeToString(sum[:8]))
+}
+
+// createScratchDirectory creates the scratch directory of the upload if it has one.
+func (u *s3Upload) createScratchDirectory() (err error) {
+	if u.scratchDirectory == "" {
+		return
+	}
+	return os.MkdirAll(u.scratchDirectory, 0700)
+}
+
+// removeScratchDirectory removes the scratch directory of the upload, along with any
+// temporary file left in it, if it has one.
+func (u *s3Upload) removeScratchDirectory() {
+	if u.scratchDirectory == "" {
+		return
+	}
+	if err := os.RemoveAll(u.scratchDirectory); err != nil {
+		u.store.logger.Error(err, "failed to remove scratch directory", "path", u.scratchDirectory)
+	}
+}
+
+// tempFileSet records the temporary files of an upload which have not been removed yet,
+// so that they are removed even if the upload is interrupted (e.g. by a panic).
+// A nil set records nothing.
+type tempFileSet struct {
+	mu    sync.Mutex
+	names map[string]struct{}
+}
+
+func (s *tempFileSet) add(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.names == nil {
+		s.names = make(map[string]struct{})
+	}
+	s.names[name] = struct{}{}
+}
+
+func (s *tempFileSet) remove(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.names, name)
+}
+
+// removeAll removes the recorded files which still exist.
+func (s *tempFileSet) removeAll() (err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.names {
+		if removeErr := os.Remove(name); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			err = errors.Join(err, removeErr)
+		}
+		delete(s.names, name)
+	}
+	return
+}
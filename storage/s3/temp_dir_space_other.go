@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package s3
+
+// diskSpace cannot determine the available space on this platform, the check is skipped.
+func diskSpace(string) (available int64, err error) {
+	return -1, nil
+}
@@ -0,0 +1,13 @@
+//go:build linux || darwin
+
+package s3
+
+import "syscall"
+
+func diskSpace(dir string) (available int64, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(dir, &stat); err != nil {
+		return
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+const tempFilePattern = "file-transfer-s3-tmp-"
+
+// tempFilePool keeps pre-created temporary files for the parts of the uploads, so that
+// a burst of uploads does not create and remove a file for each part (see WithTempFilePrealloc).
+// A file returned to the pool is truncated, the files are only removed once the pool is closed.
+type tempFilePool struct {
+	dir  string
+	size int
+
+	mu     sync.Mutex
+	files  []*os.File
+	closed bool
+}
+
+// newTempFilePool pre-creates size temporary files in the directory.
+func newTempFilePool(dir string, size int) (pool *tempFilePool, err error) {
+	pool = &tempFilePool{
+		dir:   dir,
+		size:  size,
+		files: make([]*os.File, 0, size),
+	}
+	for range size {
+		var file *os.File
+		if file, err = os.CreateTemp(dir, tempFilePattern); err != nil {
+			pool.close()
+			return nil, err
+		}
+		pool.files = append(pool.files, file)
+	}
+	return
+}
+
+// get takes a file from the pool, a new file is created if the pool is exhausted.
+func (p *tempFilePool) get() (file *os.File, err error) {
+	p.mu.Lock()
+	if n := len(p.files); n > 0 {
+		file = p.files[n-1]
+		p.files = p.files[:n-1]
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	return os.CreateTemp(p.dir, tempFilePattern)
+}
+
+// put truncates the file and returns it to the pool, the file is removed instead
+// if it cannot be truncated, if the pool is full or if the pool is closed.
+func (p *tempFilePool) put(file *os.File) (err error) {
+	if err = file.Truncate(0); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed || len(p.files) >= p.size {
+		cleanUpTempFile(file)
+		return
+	}
+	p.files = append(p.files, file)
+	return
+}
+
+// available returns the number of files in the pool.
+func (p *tempFilePool) available() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.files)
+}
+
+// close removes the files of the pool, the files taken from it are removed once returned.
+func (p *tempFilePool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, file := range p.files {
+		cleanUpTempFile(file)
+	}
+	p.files = nil
+}
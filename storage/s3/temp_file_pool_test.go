@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tempFilePool", func() {
+	var (
+		dir  string
+		pool *tempFilePool
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		var err error
+		pool, err = newTempFilePool(dir, 2)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(pool.close)
+	})
+
+	countFiles := func() int {
+		GinkgoHelper()
+		entries, err := os.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		return len(entries)
+	}
+
+	It("should pre-create the files", func() {
+		Expect(pool.available()).To(Equal(2))
+		Expect(countFiles()).To(Equal(2))
+	})
+
+	It("should recycle a returned file truncated", func() {
+		file, err := pool.get()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.available()).To(Equal(1))
+		_, err = file.WriteString("content")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(pool.put(file)).To(Succeed())
+		Expect(pool.available()).To(Equal(2))
+		Expect(countFiles()).To(Equal(2))
+		stat, err := file.Stat()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stat.Size()).To(BeZero())
+
+		recycled, err := pool.get()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recycled.Name()).To(Equal(file.Name()))
+	})
+
+	It("should create a file when exhausted and remove it when full", func() {
+		files := make([]*os.File, 0, 3)
+		for range 3 {
+			file, err := pool.get()
+			Expect(err).ToNot(HaveOccurred())
+			files = append(files, file)
+		}
+		Expect(pool.available()).To(BeZero())
+		Expect(countFiles()).To(Equal(3))
+
+		for _, file := range files {
+			Expect(pool.put(file)).To(Succeed())
+		}
+		Expect(pool.available()).To(Equal(2))
+		Expect(countFiles()).To(Equal(2))
+	})
+
+	It("should remove the files once closed", func() {
+		file, err := pool.get()
+		Expect(err).ToNot(HaveOccurred())
+		pool.close()
+		Expect(countFiles()).To(Equal(1))
+
+		Expect(pool.put(file)).To(Succeed())
+		Expect(countFiles()).To(BeZero())
+	})
+
+	It("should be created by the destination and removed on close", func() {
+		destStorage, err := NewDestination(GinkgoLogr, WithTempFilePrealloc(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(destStorage.tempFiles).ToNot(BeNil())
+		Expect(destStorage.tempFiles.available()).To(Equal(3))
+		names := make([]string, 0, 3)
+		for _, file := range destStorage.tempFiles.files {
+			names = append(names, file.Name())
+		}
+
+		destStorage.Close()
+		for _, name := range names {
+			Expect(name).ToNot(BeAnExistingFile())
+		}
+	})
+})
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// WithTransferLock instructs the Destination to lock each file for the duration of its transfer
+// (see storage.TransferLocker), so that the transfers of the process to the same object of a
+// connection do not corrupt the multipart upload of each other. A transfer to a locked file
+// waits for it to be unlocked, or fails with storage.ErrConcurrentTransfer if failFast is set.
+// Note: the lock is held in memory, it does not protect against the transfers of other processes.
+func WithTransferLock(failFast bool) DestinationOption {
+	return func(d *Destination) {
+		d.transferLocks = make(map[string]chan struct{})
+		d.transferLockFailFast = failFast
+	}
+}
+
+// LockTransfer locks the file for a transfer (see WithTransferLock), it is a no-op unless
+// the transfers are locked.
+func (d *Destination) LockTransfer(
+	ctx context.Context,
+	filePath string,
+	protocol protoc.Client,
+) (unlock func(), err error) {
+	if d.transferLocks == nil {
+		return func() {}, nil
+	}
+	key := protocol.GetConnectionID() + ":" + filePath
+	for {
+		d.transferLocksMu.Lock()
+		held, locked := d.transferLocks[key]
+		if !locked {
+			released := make(chan struct{})
+			d.transferLocks[key] = released
+			d.transferLocksMu.Unlock()
+			unlock = func() {
+				d.transferLocksMu.Lock()
+				delete(d.transferLocks, key)
+				d.transferLocksMu.Unlock()
+				close(released)
+			}
+			return
+		}
+		d.transferLocksMu.Unlock()
+
+		if d.transferLockFailFast {
+			err = storage.ErrConcurrentTransfer
+			return
+		}
+		select {
+		case <-held:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	s3_protoc "github.com/derektruong/fxfer/protoc/s3"
+	"github.com/derektruong/fxfer/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithTransferLock", func() {
+	var s3ProtocClient *s3_protoc.Client
+
+	BeforeEach(func() {
+		s3ProtocClient = s3_protoc.NewClient(endpoint, bucketName, region, accessKey, secretKey)
+	})
+
+	It("should serialize the concurrent transfers to the same file", func(ctx context.Context) {
+		destStorage := destStorageFactory(nil)
+		WithTransferLock(false)(destStorage)
+
+		// uploadIDs is written without synchronization, so that overlapping transfers are
+		// reported by the race detector
+		var (
+			wg                  sync.WaitGroup
+			inFlight, maxFlight atomic.Int32
+			uploadIDs           []int
+		)
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				unlock, err := destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+				Expect(err).ToNot(HaveOccurred())
+				defer unlock()
+
+				n := inFlight.Add(1)
+				for current := maxFlight.Load(); n > current && !maxFlight.CompareAndSwap(current, n); {
+					current = maxFlight.Load()
+				}
+				uploadIDs = append(uploadIDs, i)
+				time.Sleep(time.Millisecond)
+				inFlight.Add(-1)
+			}()
+		}
+		wg.Wait()
+
+		Expect(maxFlight.Load()).To(Equal(int32(1)))
+		Expect(uploadIDs).To(ConsistOf(0, 1, 2, 3, 4, 5, 6, 7))
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail fast the transfer to a locked file", func(ctx context.Context) {
+		destStorage := destStorageFactory(nil)
+		WithTransferLock(true)(destStorage)
+
+		unlock, err := destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+		Expect(err).To(MatchError(storage.ErrConcurrentTransfer))
+
+		By("lock the file once unlocked")
+		unlock()
+		unlock, err = destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+		Expect(err).ToNot(HaveOccurred())
+		unlock()
+	}, NodeTimeout(10*time.Second))
+
+	It("should lock the files per connection and path", func(ctx context.Context) {
+		destStorage := destStorageFactory(nil)
+		WithTransferLock(true)(destStorage)
+		otherClient := s3_protoc.NewClient(endpoint, bucketName+"-other", region, accessKey, secretKey)
+
+		for _, lock := range []struct {
+			filePath string
+			client   *s3_protoc.Client
+		}{
+			{"data/a.txt", s3ProtocClient},
+			{"data/b.txt", s3ProtocClient},
+			{"data/a.txt", otherClient},
+		} {
+			unlock, err := destStorage.LockTransfer(ctx, lock.filePath, lock.client)
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(unlock)
+		}
+	}, NodeTimeout(10*time.Second))
+
+	It("should stop waiting for a locked file once the context is done", func(ctx context.Context) {
+		destStorage := destStorageFactory(nil)
+		WithTransferLock(false)(destStorage)
+
+		unlock, err := destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+		Expect(err).ToNot(HaveOccurred())
+		defer unlock()
+		waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err = destStorage.LockTransfer(waitCtx, "data/a.txt", s3ProtocClient)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not lock the files by default", func(ctx context.Context) {
+		destStorage := destStorageFactory(nil)
+		for range 2 {
+			unlock, err := destStorage.LockTransfer(ctx, "data/a.txt", s3ProtocClient)
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(unlock)
+		}
+	}, NodeTimeout(10*time.Second))
+})
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"io"
+	"iter"
 
 	"github.com/derektruong/fxfer/internal/xferfile"
 	"github.com/derektruong/fxfer/protoc"
@@ -36,6 +37,67 @@ type Source interface {
 	//  - err: the error if any occurred, nil otherwise
 	GetFileFromOffset(ctx context.Context, filePath string, offset int64, client protoc.Client) (reader io.ReadCloser, err error)
 
+	// ListFiles lists all files under the given directory recursively
+	//
+	// Parameters:
+	//  - ctx: the context of the request
+	//  - dirPath: the path of the directory (or the key prefix) you want to list
+	//  - client: the client used to list the files
+	//
+	// Returns:
+	//  - infos: the information of the files, their paths include the dirPath
+	//  - err: the error if any occurred, nil otherwise
+	ListFiles(ctx context.Context, dirPath string, client protoc.Client) (infos []xferfile.Info, err error)
+
+	// Glob lists all files whose path matches the pattern, the syntax of the
+	// pattern is the one of path.Match (e.g. "logs/2024-*/app-*.log")
+	//
+	// Parameters:
+	//  - ctx: the context of the request
+	//  - pattern: the pattern the paths of the files must match
+	//  - client: the client used to list the files
+	//
+	// Returns:
+	//  - infos: the information of the matching files, empty if no file matches
+	//  - err: the error if any occurred (e.g. path.ErrBadPattern), nil otherwise
+	Glob(ctx context.Context, pattern string, client protoc.Client) (infos []xferfile.Info, err error)
+
 	// Close closes the source
 	Close()
 }
+
+// SourceVersionMeta is the metadata key of the version of the source file (e.g. the ETag of an
+// S3 object) recorded in its info by a ConditionalReader when it fetches the info.
+const SourceVersionMeta = "sourceVersion"
+
+// SymlinkTargetMeta is the metadata key of the target of a symbolic link recorded in its info by
+// a Source transferring the links as links (e.g. local.WithRecreateSymlinks), the content of
+// such a file is empty and a destination supporting it recreates the link once finalized.
+const SymlinkTargetMeta = "symlinkTarget"
+
+// ContentTypeMeta is the metadata key of the content type declared for the source file (e.g. the
+// Content-Type of an S3 object) recorded in its info by a Source, a destination supporting it
+// declares the same content type for the file (see fxfer.WithPreserveContentType).
+const ContentTypeMeta = "contentType"
+
+// ConditionalReader can be implemented by a Source to read a file only while it has the version
+// recorded in its info (see SourceVersionMeta), e.g. with an If-Match condition, so that a file
+// changed during a transfer fails the reads rather than mixing the content of both versions.
+type ConditionalReader interface {
+	// GetFileFromOffsetIfMatch fetches the file from the offset (see Source.GetFileFromOffset),
+	// ErrSourceChanged is returned if the file no longer has the version
+	GetFileFromOffsetIfMatch(
+		ctx context.Context,
+		filePath string, offset int64, version string,
+		client protoc.Client,
+	) (reader io.ReadCloser, err error)
+}
+
+// FileIterator can be implemented by a Source to enumerate the files under a directory lazily,
+// e.g. page by page, rather than listing them all at once (see Source.ListFiles).
+type FileIterator interface {
+	// IterFiles iterates over the files under the directory (or the key prefix) recursively,
+	// their paths include the dirPath. The iteration stops at the first error, yielded with a
+	// zero info, e.g. the error of the context once it is done
+	IterFiles(ctx context.Context, dirPath string, client protoc.Client) iter.Seq2[xferfile.Info, error]
+}
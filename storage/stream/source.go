@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/iometer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+// Source represents a source backed by an io.Reader (e.g. os.Stdin), the
+// content can only be read once, so it is not possible to rewind to an
+// offset that has already been consumed.
+type Source struct {
+	logger logr.Logger
+
+	reader  io.Reader
+	size    int64
+	modTime time.Time
+
+	// consumedMu and consumed keep track of the number of bytes already
+	// read from the underlying reader
+	consumedMu sync.Mutex
+	consumed   int64
+}
+
+// NewSource creates a new stream source with the caller-declared size and
+// modification time. A negative size means the size is unknown, the stream
+// is then read until EOF.
+func NewSource(logger logr.Logger, reader io.Reader, size int64, modTime time.Time) (s *Source) {
+	if size < 0 {
+		size = xferfile.SizeUnknown
+	}
+	s = &Source{
+		logger:  logger.WithName("stream.source"),
+		reader:  reader,
+		size:    size,
+		modTime: modTime,
+	}
+	return
+}
+
+func (s *Source) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (info xferfile.Info, err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+	fileName, fileExt := fileutils.SplitFileName(filePath)
+	info = xferfile.Info{
+		Path:      filePath,
+		Name:      fileName,
+		Extension: fileExt,
+		Size:      s.size,
+		ModTime:   s.modTime,
+	}
+	return
+}
+
+func (s *Source) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	if _, ok := cli.GetCredential().(local.IO); !ok {
+		err = storage.ErrLocalProtocolIOInvalid
+		return
+	}
+	s.consumedMu.Lock()
+	defer s.consumedMu.Unlock()
+
+	// hide the io.Closer of the underlying reader, so a retried transfer
+	// can continue reading from the same stream
+	transferReader := iometer.NewTransferReader(struct{ io.Reader }{s.reader}, &s.consumed)
+	if offset < transferReader.TransferredSize() {
+		err = storage.ErrStreamNotRewindable
+		return
+	}
+
+	// skip the bytes which have already been transferred to the destination
+	if skip := offset - transferReader.TransferredSize(); skip > 0 {
+		if _, err = io.CopyN(io.Discard, transferReader, skip); err != nil {
+			return
+		}
+	}
+	reader = transferReader
+	return
+}
+
+// ListFiles is not supported, a stream represents a single file.
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	err = errors.ErrUnsupported
+	return
+}
+
+// Glob is not supported, a stream represents a single file.
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	err = errors.ErrUnsupported
+	return
+}
+
+func (s *Source) Close() {
+	s.logger.Info("closed stream source")
+}
@@ -0,0 +1,101 @@
+package stream_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	s3_protoc "github.com/derektruong/fxfer/protoc/s3"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/stream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	var (
+		srcStorage  *stream.Source
+		testContent string
+		modTime     time.Time
+	)
+
+	BeforeEach(func() {
+		testContent = gofakeit.SentenceSimple()
+		modTime = gofakeit.PastDate()
+		srcStorage = stream.NewSource(GinkgoLogr, strings.NewReader(testContent), int64(len(testContent)), modTime)
+		DeferCleanup(srcStorage.Close)
+	})
+
+	Describe("GetFileInfo", func() {
+		It("should return error if protocol is not local", func(ctx context.Context) {
+			_, err := srcStorage.GetFileInfo(ctx, "test.txt", s3_protoc.NewClient("", "", "", "", ""))
+			Expect(err).To(MatchError(storage.ErrLocalProtocolIOInvalid))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return the caller-declared file info", func(ctx context.Context) {
+			info, err := srcStorage.GetFileInfo(ctx, "prefix/test-abc.txt", local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(And(
+				HaveField("Path", "prefix/test-abc.txt"),
+				HaveField("Name", "test-abc"),
+				HaveField("Extension", "txt"),
+				HaveField("Size", int64(len(testContent))),
+				HaveField("ModTime", modTime),
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return unknown size when the declared size is negative", func(ctx context.Context) {
+			srcStorage = stream.NewSource(GinkgoLogr, strings.NewReader(testContent), -100, modTime)
+			info, err := srcStorage.GetFileInfo(ctx, "stdin", local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Size).To(Equal(xferfile.SizeUnknown))
+			Expect(info.Extension).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("GetFileFromOffset", func() {
+		It("should return the stream content", func(ctx context.Context) {
+			reader, err := srcStorage.GetFileFromOffset(ctx, "test.txt", 0, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			content, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(testContent))
+		}, NodeTimeout(10*time.Second))
+
+		It("should skip the content before the offset", func(ctx context.Context) {
+			reader, err := srcStorage.GetFileFromOffset(ctx, "test.txt", 5, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			content, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(testContent[5:]))
+		}, NodeTimeout(10*time.Second))
+
+		It("should continue the stream after the reader is closed", func(ctx context.Context) {
+			reader, err := srcStorage.GetFileFromOffset(ctx, "test.txt", 0, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadFull(reader, make([]byte, 3))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reader.Close()).To(Succeed())
+
+			reader, err = srcStorage.GetFileFromOffset(ctx, "test.txt", 3, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			content, err := io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(testContent[3:]))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when rewinding to a consumed offset", func(ctx context.Context) {
+			reader, err := srcStorage.GetFileFromOffset(ctx, "test.txt", 0, local_protoc.NewIO())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = srcStorage.GetFileFromOffset(ctx, "test.txt", 0, local_protoc.NewIO())
+			Expect(err).To(MatchError(storage.ErrStreamNotRewindable))
+		}, NodeTimeout(10*time.Second))
+	})
+})
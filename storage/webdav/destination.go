@@ -0,0 +1,428 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+// ErrRangedPutUnsupported is returned when the server does not support the ranged PUTs
+// (Content-Range), the transfer is then restarted from the beginning of the file in a single PUT.
+var ErrRangedPutUnsupported = errors.New("webdav: the server does not support the ranged PUT")
+
+// defaultSegmentSize is the default number of bytes written by each ranged PUT.
+const defaultSegmentSize = 8 << 20 // 8 MiB
+
+// rangedPutSupport is whether the server of a connection supports the ranged PUTs.
+type rangedPutSupport int
+
+const (
+	rangedPutUnknown rangedPutSupport = iota
+	rangedPutSupported
+	rangedPutUnsupported
+)
+
+type Destination struct {
+	logger logr.Logger
+
+	// SegmentSize is the number of bytes written by each ranged PUT, the offset of the info
+	// file is updated after each of them so that an interrupted transfer resumes from it.
+	// The segment is buffered in memory. Default is 8 MiB.
+	SegmentSize int64
+
+	// DisableRangedPut writes the content of a file in a single PUT, for the servers which do not
+	// support the ranged PUTs, an interrupted transfer is then restarted from the beginning. It
+	// is disabled for a connection once its server ignores or rejects a ranged PUT.
+	DisableRangedPut bool
+
+	rangedPutMu sync.Mutex
+	// rangedPut is whether the server of each connection (by connection ID) supports the ranged PUTs
+	rangedPut map[string]rangedPutSupport
+}
+
+func NewDestination(logger logr.Logger) (d *Destination) {
+	d = &Destination{
+		logger:      logger.WithName("webdav.destination"),
+		SegmentSize: defaultSegmentSize,
+		rangedPut:   make(map[string]rangedPutSupport),
+	}
+	return
+}
+
+func (d *Destination) Close() {
+	d.logger.Info("closed webdav destination")
+}
+
+// GetFileInfo returns the info of the file, its offset is the one recorded after the last
+// ranged PUT. The offset of an unfinished file is 0 when the ranged PUTs are not supported,
+// the file is written again from the beginning.
+func (d *Destination) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (info xferfile.Info, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	if info, err = d.readInfo(ctx, conn, filePath); err != nil {
+		return
+	}
+	if info.FinishTime.IsZero() && d.rangedPutSupport(cli) == rangedPutUnsupported {
+		info.Offset = 0
+	}
+	return
+}
+
+func (d *Destination) CreateFile(
+	ctx context.Context,
+	path string, size int64, modTime time.Time,
+	cli protoc.Client,
+) (err error) {
+	return d.CreateFileWithMetadata(ctx, path, size, modTime, nil, cli)
+}
+
+func (d *Destination) CreateFileWithMetadata(
+	ctx context.Context,
+	path string, size int64, modTime time.Time, metadata map[string]string,
+	cli protoc.Client,
+) (err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+
+	var dirPath, fileName, fileExt string
+	if dirPath, fileName, fileExt, err = fileutils.ExtractFileParts(path); err != nil {
+		return
+	}
+	if err = conn.MkdirAll(ctx, dirPath); err != nil {
+		return
+	}
+	if err = conn.Put(ctx, path, nil, 0); err != nil {
+		return
+	}
+
+	if srcExt, ok := metadata[storage.SourceExtensionMeta]; ok {
+		fileExt = srcExt
+	}
+	return d.writeInfo(ctx, conn, path, xferfile.Info{
+		Path:      path,
+		Size:      size,
+		ModTime:   modTime,
+		StartTime: time.Now(),
+		Name:      fileName,
+		Extension: fileExt,
+		Metadata:  metadata,
+	})
+}
+
+// TransferFileChunk writes the content of the reader at the offset in ranged PUTs of
+// SegmentSize bytes, or in a single PUT from the beginning of the file when the ranged PUTs
+// are not supported.
+func (d *Destination) TransferFileChunk(
+	ctx context.Context,
+	filePath string,
+	reader io.Reader,
+	offset int64,
+	cli protoc.Client,
+) (n int64, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	var info xferfile.Info
+	if info, err = d.readInfo(ctx, conn, filePath); err != nil {
+		return
+	}
+	if offset < 0 || offset > info.Offset {
+		err = storage.ErrChunkOffsetOutOfRange
+		return
+	}
+	tracker, _ := reader.(storage.ConfirmedSizeTracker)
+
+	if d.rangedPutSupport(cli) == rangedPutUnsupported {
+		if offset != 0 {
+			err = ErrRangedPutUnsupported
+			return
+		}
+		counter := &countingReader{Reader: reader}
+		if err = conn.Put(ctx, filePath, counter, -1); err != nil {
+			return
+		}
+		n = counter.n
+		info.Offset = n
+		if err = d.writeInfo(ctx, conn, filePath, info); err != nil {
+			return
+		}
+		if tracker != nil {
+			tracker.AddConfirmedSize(n)
+		}
+		return
+	}
+
+	segment := make([]byte, max(d.SegmentSize, 1))
+	for {
+		var segmentSize int
+		segmentSize, err = io.ReadFull(reader, segment)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		}
+		// the bytes read before a failure of the source are written too
+		if segmentSize > 0 {
+			if writeErr := d.writeSegment(ctx, conn, cli, filePath, segment[:segmentSize], offset+n); writeErr != nil {
+				err = writeErr
+				return
+			}
+			n += int64(segmentSize)
+			info.Offset = offset + n
+			if writeErr := d.writeInfo(ctx, conn, filePath, info); writeErr != nil {
+				err = writeErr
+				return
+			}
+			if tracker != nil {
+				tracker.AddConfirmedSize(int64(segmentSize))
+			}
+		}
+		if err != nil || segmentSize < len(segment) {
+			return
+		}
+	}
+}
+
+// ResumeState returns the resumption state of the file (see storage.ResumeStateReporter).
+func (d *Destination) ResumeState(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (state storage.ResumeState, err error) {
+	var info xferfile.Info
+	if info, err = d.GetFileInfo(ctx, filePath, cli); err != nil {
+		return
+	}
+	state = storage.ResumeState{
+		Path:      info.Path,
+		Size:      info.Size,
+		Offset:    info.Offset,
+		StartTime: info.StartTime,
+		Finished:  !info.FinishTime.IsZero(),
+	}
+	return
+}
+
+// FileExists reports whether a file exists on the server at the path, whether or not it has
+// been transferred by the destination (see storage.ExistenceChecker).
+func (d *Destination) FileExists(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (exists bool, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	if _, err = conn.Stat(ctx, filePath); err != nil {
+		if errors.Is(err, webdav.ErrNotFound) {
+			err = nil
+		}
+		return
+	}
+	return true, nil
+}
+
+func (d *Destination) FinalizeTransfer(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	var info xferfile.Info
+	if info, err = d.readInfo(ctx, conn, filePath); err != nil {
+		return
+	}
+	// the size of a stream is only known once it has been fully written
+	if info.Size == xferfile.SizeUnknown {
+		info.Size = info.Offset
+	}
+	if info.Offset != info.Size {
+		err = storage.ErrFileOrObjectCannotFinalize
+		return
+	}
+	info.FinishTime = time.Now()
+	return d.writeInfo(ctx, conn, filePath, info)
+}
+
+func (d *Destination) DeleteFile(ctx context.Context, filePath string, cli protoc.Client) (err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	var infoPath string
+	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+		return
+	}
+	if err = conn.Delete(ctx, filePath); err != nil {
+		if errors.Is(err, webdav.ErrNotFound) {
+			err = xferfile.ErrFileNotExists
+		}
+		return
+	}
+	if err = conn.Delete(ctx, infoPath); err != nil {
+		if errors.Is(err, webdav.ErrNotFound) {
+			err = xferfile.ErrFileNotExists
+		}
+		return
+	}
+	return
+}
+
+// writeSegment writes the segment at the offset of the file, the first segment is written
+// in a PUT of the whole file. The first ranged PUT of a connection is checked against the
+// size of the file, since a server which does not support it may replace the whole file.
+func (d *Destination) writeSegment(
+	ctx context.Context,
+	conn webdav.Client,
+	cli protoc.Client,
+	filePath string,
+	segment []byte,
+	offset int64,
+) (err error) {
+	if offset == 0 {
+		return conn.Put(ctx, filePath, bytes.NewReader(segment), int64(len(segment)))
+	}
+	support := d.rangedPutSupport(cli)
+	if err = conn.PutRange(ctx, filePath, bytes.NewReader(segment), offset, int64(len(segment))); err != nil {
+		var statusErr *webdav.StatusError
+		if errors.As(err, &statusErr) && isRangedPutRejected(statusErr.StatusCode) {
+			err = d.disableRangedPut(ctx, conn, cli, filePath)
+		}
+		return
+	}
+	if support == rangedPutSupported {
+		return
+	}
+	var fileInfo webdav.FileInfo
+	if fileInfo, err = conn.Stat(ctx, filePath); err != nil {
+		return
+	}
+	if fileInfo.Size != offset+int64(len(segment)) {
+		return d.disableRangedPut(ctx, conn, cli, filePath)
+	}
+	d.setRangedPutSupport(cli, rangedPutSupported)
+	return
+}
+
+// disableRangedPut disables the ranged PUTs of the connection and resets the offset of the file,
+// whose content can no longer be trusted, so that the retried transfer writes it from the beginning.
+func (d *Destination) disableRangedPut(
+	ctx context.Context,
+	conn webdav.Client,
+	cli protoc.Client,
+	filePath string,
+) (err error) {
+	d.logger.Info("server does not support the ranged PUT, the file is written in a single PUT",
+		"endpoint", conn.Endpoint, "filePath", filePath)
+	d.setRangedPutSupport(cli, rangedPutUnsupported)
+	var info xferfile.Info
+	if info, err = d.readInfo(ctx, conn, filePath); err != nil {
+		return
+	}
+	info.Offset = 0
+	if err = d.writeInfo(ctx, conn, filePath, info); err != nil {
+		return
+	}
+	return ErrRangedPutUnsupported
+}
+
+// rangedPutSupport returns whether the server of the connection supports the ranged PUTs.
+func (d *Destination) rangedPutSupport(cli protoc.Client) rangedPutSupport {
+	if d.DisableRangedPut {
+		return rangedPutUnsupported
+	}
+	d.rangedPutMu.Lock()
+	defer d.rangedPutMu.Unlock()
+	return d.rangedPut[cli.GetConnectionID()]
+}
+
+func (d *Destination) setRangedPutSupport(cli protoc.Client, support rangedPutSupport) {
+	d.rangedPutMu.Lock()
+	defer d.rangedPutMu.Unlock()
+	d.rangedPut[cli.GetConnectionID()] = support
+}
+
+func (d *Destination) readInfo(ctx context.Context, conn webdav.Client, filePath string) (info xferfile.Info, err error) {
+	var infoPath string
+	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+		return
+	}
+	var reader io.ReadCloser
+	if reader, err = conn.Get(ctx, infoPath, 0); err != nil {
+		if errors.Is(err, webdav.ErrNotFound) {
+			err = xferfile.ErrFileNotExists
+		}
+		return
+	}
+	defer reader.Close()
+	if err = json.NewDecoder(reader).Decode(&info); err != nil {
+		return
+	}
+	// the info file must belong to this file, not to a file sharing its name
+	if info.Path != filePath {
+		err = storage.ErrFileInfoMismatch
+	}
+	return
+}
+
+func (d *Destination) writeInfo(ctx context.Context, conn webdav.Client, filePath string, info xferfile.Info) (err error) {
+	var infoPath string
+	if infoPath, err = xferfile.GenerateInfoPath(filePath); err != nil {
+		return
+	}
+	var infoData []byte
+	if infoData, err = json.Marshal(info); err != nil {
+		return
+	}
+	if err = conn.Put(ctx, infoPath, bytes.NewReader(infoData), int64(len(infoData))); err != nil {
+		return fmt.Errorf("unable to write info file %s: %w", path.Base(infoPath), err)
+	}
+	return
+}
+
+// isRangedPutRejected reports whether the status of a ranged PUT is the rejection of the range.
+func isRangedPutRejected(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusNotImplemented,
+		http.StatusRequestedRangeNotSatisfiable:
+		return true
+	}
+	return false
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.n += int64(n)
+	return
+}
@@ -0,0 +1,189 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Destination", func() {
+	const filePath = "backup/2024/content.txt"
+
+	var (
+		server      *webdavServer
+		destClient  *webdav.Client
+		destStorage *Destination
+		modTime     time.Time
+	)
+
+	BeforeEach(func() {
+		server = newWebDAVServer(true)
+		destClient = server.client()
+		destStorage = NewDestination(GinkgoLogr)
+		destStorage.SegmentSize = 4
+		DeferCleanup(destStorage.Close)
+		modTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+	})
+
+	Describe("CreateFile", func() {
+		It("should create the file and its directories with the info", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(ctx, filePath, 10, modTime, destClient)).To(Succeed())
+
+			info, err := destStorage.GetFileInfo(ctx, filePath, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Path).To(Equal(filePath))
+			Expect(info.Size).To(Equal(int64(10)))
+			Expect(info.Offset).To(BeZero())
+			Expect(info.ModTime).To(BeTemporally("==", modTime))
+			Expect(server.readFile(ctx, "/"+filePath)).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the file info does not exist", func(ctx context.Context) {
+			_, err := destStorage.GetFileInfo(ctx, filePath, destClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the client is not a WebDAV client", func(ctx context.Context) {
+			err := destStorage.CreateFile(ctx, filePath, 10, modTime, local_protoc.NewIO())
+			Expect(err).To(MatchError(storage.ErrWebDAVProtocolClientInvalid))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferFileChunk", func() {
+		BeforeEach(func(ctx context.Context) {
+			Expect(destStorage.CreateFile(ctx, filePath, 10, modTime, destClient)).To(Succeed())
+		})
+
+		It("should write the content in ranged PUTs and resume from the offset", func(ctx context.Context) {
+			n, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("012345"), 0, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(6)))
+			info, err := destStorage.GetFileInfo(ctx, filePath, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(6)))
+
+			n, err = destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("6789"), 6, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(4)))
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, destClient)).To(Succeed())
+
+			Expect(server.readFile(ctx, "/"+filePath)).To(BeEquivalentTo("0123456789"))
+			info, err = destStorage.GetFileInfo(ctx, filePath, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Offset).To(Equal(int64(10)))
+			Expect(info.FinishTime).ToNot(BeZero())
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the offset is beyond the written content", func(ctx context.Context) {
+			_, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("6789"), 6, destClient)
+			Expect(err).To(MatchError(storage.ErrChunkOffsetOutOfRange))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not finalize an incomplete file", func(ctx context.Context) {
+			_, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("012345"), 0, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, destClient)).
+				To(MatchError(storage.ErrFileOrObjectCannotFinalize))
+		}, NodeTimeout(10*time.Second))
+
+		Context("when the server ignores the ranged PUTs", func() {
+			BeforeEach(func() {
+				server.rangedPut = false
+			})
+
+			It("should restart the transfer in a single PUT", func(ctx context.Context) {
+				_, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("0123456789"), 0, destClient)
+				Expect(err).To(MatchError(ErrRangedPutUnsupported))
+				info, err := destStorage.GetFileInfo(ctx, filePath, destClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Offset).To(BeZero())
+
+				n, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("0123456789"), 0, destClient)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int64(10)))
+				Expect(destStorage.FinalizeTransfer(ctx, filePath, destClient)).To(Succeed())
+				Expect(server.readFile(ctx, "/"+filePath)).To(BeEquivalentTo("0123456789"))
+			}, NodeTimeout(10*time.Second))
+		})
+
+		It("should write the content in a single PUT when the ranged PUTs are disabled", func(ctx context.Context) {
+			destStorage.DisableRangedPut = true
+			n, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("0123456789"), 0, destClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(int64(10)))
+			Expect(server.readFile(ctx, "/"+filePath)).To(BeEquivalentTo("0123456789"))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("DeleteFile", func() {
+		It("should delete the file and its info", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(ctx, filePath, 10, modTime, destClient)).To(Succeed())
+			Expect(destStorage.DeleteFile(ctx, filePath, destClient)).To(Succeed())
+
+			Expect(destStorage.FileExists(ctx, filePath, destClient)).To(BeFalse())
+			_, err := destStorage.GetFileInfo(ctx, filePath, destClient)
+			Expect(err).To(MatchError(xferfile.ErrFileNotExists))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("Transfer", func() {
+		It("should transfer a local file to the server", func(ctx context.Context) {
+			content := strings.Repeat("0123456789", 1000)
+			srcPath := filepath.Join(GinkgoT().TempDir(), "content.txt")
+			Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+			srcStorage, err := local.NewSource(GinkgoLogr)
+			Expect(err).ToNot(HaveOccurred())
+			destStorage.SegmentSize = 1024
+
+			tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+			Expect(tfr.Transfer(ctx,
+				fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()},
+				fxfer.DestinationConfig{FilePath: filePath, Storage: destStorage, Client: destClient},
+				func(fxfer.Progress) {},
+			)).To(Succeed())
+
+			Expect(server.readFile(ctx, "/"+filePath)).To(BeEquivalentTo(content))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("with a WebDAV container", Ordered, func() {
+		var containerClient *webdav.Client
+
+		BeforeAll(func(ctx context.Context) {
+			container, endpoint, err := setupWebDAVContainer(ctx)
+			if container != nil {
+				DeferCleanup(container.Terminate, context.Background())
+			}
+			if err != nil {
+				Skip("the WebDAV container cannot be started: " + err.Error())
+			}
+			containerClient = webdav.NewClient(endpoint, webdavUser, webdavPassword)
+		}, NodeTimeout(90*time.Second))
+
+		It("should resume the transfer with the ranged PUTs of the server", func(ctx context.Context) {
+			Expect(destStorage.CreateFile(ctx, filePath, 10, modTime, containerClient)).To(Succeed())
+			_, err := destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("012345"), 0, containerClient)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = destStorage.TransferFileChunk(ctx, filePath, strings.NewReader("6789"), 6, containerClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(destStorage.FinalizeTransfer(ctx, filePath, containerClient)).To(Succeed())
+
+			reader, err := NewSource(GinkgoLogr).GetFileFromOffset(ctx, filePath, 2, containerClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEquivalentTo("23456789"))
+		}, NodeTimeout(30*time.Second))
+	})
+})
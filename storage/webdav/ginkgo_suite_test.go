@@ -0,0 +1,128 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	xwebdav "golang.org/x/net/webdav"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	webdavImage    = "bytemark/webdav:2.4"
+	webdavUser     = "fxfer"
+	webdavPassword = "fxfer"
+	webdavPort     = "80/tcp"
+)
+
+func TestGinkgoSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebDAV Storage tests suite")
+}
+
+// webdavServer is an in-memory WebDAV server, it supports the ranged PUTs (Content-Range) as
+// Apache mod_dav does, unless they are ignored as by a server replacing the whole file.
+type webdavServer struct {
+	*httptest.Server
+	fs        xwebdav.FileSystem
+	rangedPut bool
+}
+
+func newWebDAVServer(rangedPut bool) (server *webdavServer) {
+	GinkgoHelper()
+	server = &webdavServer{fs: xwebdav.NewMemFS(), rangedPut: rangedPut}
+	handler := &xwebdav.Handler{Prefix: "/dav", FileSystem: server.fs, LockSystem: xwebdav.NewMemLS()}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != webdavUser || password != webdavPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if contentRange := r.Header.Get("Content-Range"); r.Method == http.MethodPut && contentRange != "" && server.rangedPut {
+			server.putRange(w, r, contentRange)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	DeferCleanup(server.Close)
+	return
+}
+
+func (s *webdavServer) putRange(w http.ResponseWriter, r *http.Request, contentRange string) {
+	var start, end int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/*", &start, &end); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	file, err := s.fs.OpenFile(r.Context(), r.URL.Path[len("/dav"):], os.O_WRONLY, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	if _, err = file.Seek(start, io.SeekStart); err != nil {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if _, err = io.CopyN(file, r.Body, end-start+1); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readFile returns the content of the file on the server.
+func (s *webdavServer) readFile(ctx context.Context, filePath string) []byte {
+	GinkgoHelper()
+	file, err := s.fs.OpenFile(ctx, filePath, os.O_RDONLY, 0)
+	Expect(err).ToNot(HaveOccurred())
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	Expect(err).ToNot(HaveOccurred())
+	return content
+}
+
+// client returns the WebDAV client of the server.
+func (s *webdavServer) client() *webdav.Client {
+	return webdav.NewClient(s.URL+"/dav", webdavUser, webdavPassword)
+}
+
+// setupWebDAVContainer starts an Apache mod_dav server, which supports the ranged PUTs. The
+// failure to reach Docker is returned rather than panicking, so that the specs are skipped.
+func setupWebDAVContainer(ctx context.Context) (container testcontainers.Container, endpoint string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("docker is unavailable: %v", r)
+		}
+	}()
+	if container, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        webdavImage,
+			ExposedPorts: []string{webdavPort},
+			Env: map[string]string{
+				"AUTH_TYPE": "Basic",
+				"USERNAME":  webdavUser,
+				"PASSWORD":  webdavPassword,
+			},
+			WaitingFor: wait.ForListeningPort(webdavPort).WithStartupTimeout(60 * time.Second),
+		},
+		Started: true,
+	}); err != nil {
+		return
+	}
+	if endpoint, err = container.PortEndpoint(ctx, webdavPort, "http"); err != nil {
+		return
+	}
+	endpoint += "/"
+	return
+}
@@ -0,0 +1,159 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/derektruong/fxfer/internal/fileutils"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc"
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/go-logr/logr"
+)
+
+type Source struct {
+	logger logr.Logger
+}
+
+func NewSource(logger logr.Logger) (s *Source) {
+	s = &Source{
+		logger: logger.WithName("webdav.source"),
+	}
+	return
+}
+
+func (s *Source) GetFileInfo(
+	ctx context.Context,
+	filePath string,
+	cli protoc.Client,
+) (info xferfile.Info, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	var fileInfo webdav.FileInfo
+	if fileInfo, err = conn.Stat(ctx, filePath); err != nil {
+		return
+	}
+	var fileName, fileExt string
+	if _, fileName, fileExt, err = fileutils.ExtractFileParts(filePath); err != nil {
+		return
+	}
+	info = xferfile.Info{
+		Path:      filePath,
+		Name:      fileName,
+		Extension: fileExt,
+		Size:      fileInfo.Size,
+		ModTime:   fileInfo.ModTime,
+	}
+	return
+}
+
+func (s *Source) GetFileFromOffset(
+	ctx context.Context,
+	filePath string,
+	offset int64,
+	cli protoc.Client,
+) (reader io.ReadCloser, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	return conn.Get(ctx, filePath, offset)
+}
+
+func (s *Source) ListFiles(
+	ctx context.Context,
+	dirPath string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	return s.listFiles(ctx, conn, dirPath, func(string) bool { return true })
+}
+
+func (s *Source) Glob(
+	ctx context.Context,
+	pattern string,
+	cli protoc.Client,
+) (infos []xferfile.Info, err error) {
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+	var conn webdav.Client
+	if conn, err = checkClient(cli); err != nil {
+		return
+	}
+	// the files are listed from the deepest directory of the pattern without meta character
+	prefix := fileutils.GlobPrefix(pattern)
+	dirPath := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		dirPath = path.Dir(prefix)
+	}
+	if infos, err = s.listFiles(ctx, conn, dirPath, func(filePath string) bool {
+		matched, _ := path.Match(pattern, filePath)
+		return matched
+	}); errors.Is(err, webdav.ErrNotFound) {
+		err = nil
+	}
+	return
+}
+
+func (s *Source) Close() {
+	s.logger.Info("closed webdav source")
+}
+
+// listFiles lists the files under the directory recursively whose path matches, the
+// directories are read one level at a time (PROPFIND with depth 1) since the servers
+// commonly refuse the infinite depth.
+func (s *Source) listFiles(
+	ctx context.Context,
+	conn webdav.Client,
+	dirPath string,
+	match func(filePath string) bool,
+) (infos []xferfile.Info, err error) {
+	dirPaths := []string{dirPath}
+	for len(dirPaths) > 0 {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var entries []webdav.FileInfo
+		if entries, err = conn.ReadDir(ctx, dirPaths[0]); err != nil {
+			return
+		}
+		dirPaths = dirPaths[1:]
+		for _, entry := range entries {
+			if entry.IsDir {
+				dirPaths = append(dirPaths, entry.Path)
+				continue
+			}
+			if !match(entry.Path) {
+				continue
+			}
+			fileName, fileExt := fileutils.SplitFileName(entry.Path)
+			infos = append(infos, xferfile.Info{
+				Path:      entry.Path,
+				Name:      fileName,
+				Extension: fileExt,
+				Size:      entry.Size,
+				ModTime:   entry.ModTime,
+			})
+		}
+	}
+	return
+}
+
+// checkClient returns the WebDAV client of the protocol client.
+func checkClient(cli protoc.Client) (conn webdav.Client, err error) {
+	var ok bool
+	if conn, ok = cli.GetCredential().(webdav.Client); !ok {
+		err = storage.ErrWebDAVProtocolClientInvalid
+	}
+	return
+}
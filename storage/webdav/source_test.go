@@ -0,0 +1,109 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	protoc_local "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/protoc/webdav"
+	"github.com/derektruong/fxfer/storage"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	var (
+		server    *webdavServer
+		srcClient *webdav.Client
+		source    *Source
+	)
+
+	BeforeEach(func(ctx context.Context) {
+		server = newWebDAVServer(true)
+		srcClient = server.client()
+		source = NewSource(GinkgoLogr)
+		DeferCleanup(source.Close)
+
+		Expect(srcClient.MkdirAll(ctx, "docs/2024")).To(Succeed())
+		for filePath, content := range map[string]string{
+			"docs/readme.txt":        "0123456789",
+			"docs/2024/report.csv":   "a,b,c",
+			"docs/2024/notes 01.txt": "notes",
+		} {
+			Expect(srcClient.Put(ctx, filePath, strings.NewReader(content), int64(len(content)))).To(Succeed())
+		}
+	})
+
+	Describe("GetFileInfo", func() {
+		It("should return the size and the modification time of the file", func(ctx context.Context) {
+			info, err := source.GetFileInfo(ctx, "docs/readme.txt", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Path).To(Equal("docs/readme.txt"))
+			Expect(info.Name).To(Equal("readme"))
+			Expect(info.Extension).To(Equal("txt"))
+			Expect(info.Size).To(Equal(int64(10)))
+			Expect(info.ModTime).To(BeTemporally("~", time.Now(), time.Minute))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the file does not exist", func(ctx context.Context) {
+			_, err := source.GetFileInfo(ctx, "docs/missing.txt", srcClient)
+			Expect(err).To(MatchError(webdav.ErrNotFound))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the client is not a WebDAV client", func(ctx context.Context) {
+			_, err := source.GetFileInfo(ctx, "docs/readme.txt", protoc_local.NewIO())
+			Expect(err).To(MatchError(storage.ErrWebDAVProtocolClientInvalid))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("GetFileFromOffset", func() {
+		It("should return the content of the file from the offset", func(ctx context.Context) {
+			reader, err := source.GetFileFromOffset(ctx, "docs/readme.txt", 4, srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEquivalentTo("456789"))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return an empty content from the end of the file", func(ctx context.Context) {
+			reader, err := source.GetFileFromOffset(ctx, "docs/readme.txt", 10, srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			defer reader.Close()
+			Expect(io.ReadAll(reader)).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("ListFiles", func() {
+		It("should list the files of the directory recursively", func(ctx context.Context) {
+			infos, err := source.ListFiles(ctx, "docs", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			paths := make([]string, 0, len(infos))
+			for _, info := range infos {
+				paths = append(paths, info.Path)
+			}
+			Expect(paths).To(ConsistOf("docs/readme.txt", "docs/2024/report.csv", "docs/2024/notes 01.txt"))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("Glob", func() {
+		It("should list the files matching the pattern", func(ctx context.Context) {
+			infos, err := source.Glob(ctx, "docs/*/*.txt", srcClient)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(infos).To(HaveLen(1))
+			Expect(infos[0].Path).To(Equal("docs/2024/notes 01.txt"))
+			Expect(infos[0].Size).To(Equal(int64(5)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return no file when the directory does not exist", func(ctx context.Context) {
+			Expect(source.Glob(ctx, "logs/*.txt", srcClient)).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	It("should return the authentication failure as is", func(ctx context.Context) {
+		_, err := source.GetFileInfo(ctx, "docs/readme.txt", webdav.NewClient(server.URL+"/dav", "", ""))
+		var statusErr *webdav.StatusError
+		Expect(err).To(BeAssignableToTypeOf(statusErr))
+		Expect(err.(*webdav.StatusError).HTTPStatusCode()).To(Equal(401))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,75 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer a directory with symbolic links", func() {
+	var (
+		tempDir    string
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir = GinkgoT().TempDir()
+		srcDir := filepath.Join(tempDir, "data")
+		Expect(os.MkdirAll(filepath.Join(srcDir, "nested"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("content of a"), 0644)).To(Succeed())
+		Expect(os.Symlink("a.txt", filepath.Join(srcDir, "link-a.txt"))).To(Succeed())
+		Expect(os.Symlink("../a.txt", filepath.Join(srcDir, "nested", "link-up.txt"))).To(Succeed())
+
+		destStorage, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcDir, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "mirror"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should recreate the links within the mirrored tree", func(ctx context.Context) {
+		var err error
+		srcConfig.Storage, err = local.NewSource(GinkgoLogr, local.WithRecreateSymlinks())
+		Expect(err).ToNot(HaveOccurred())
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		mirrorDir := destConfig.FilePath
+		Expect(os.ReadFile(filepath.Join(mirrorDir, "a.txt"))).To(BeEquivalentTo("content of a"))
+		for linkPath, target := range map[string]string{
+			filepath.Join(mirrorDir, "link-a.txt"):            "a.txt",
+			filepath.Join(mirrorDir, "nested", "link-up.txt"): "../a.txt",
+		} {
+			Expect(os.Readlink(linkPath)).To(Equal(target), linkPath)
+		}
+
+		By("assert the links resolve within the mirror")
+		Expect(os.ReadFile(filepath.Join(mirrorDir, "nested", "link-up.txt"))).To(BeEquivalentTo("content of a"))
+		Expect(filepath.EvalSymlinks(filepath.Join(mirrorDir, "link-a.txt"))).
+			To(Equal(filepath.Join(mirrorDir, "a.txt")))
+	}, NodeTimeout(10*time.Second))
+
+	It("should copy the content of the linked files by default", func(ctx context.Context) {
+		var err error
+		srcConfig.Storage, err = local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig.FilePath = filepath.Join(srcConfig.FilePath, "link-a.txt")
+		destConfig.FilePath = filepath.Join(destConfig.FilePath, "link-a.txt")
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(destConfig.FilePath).To(BeARegularFile())
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo("content of a"))
+	}, NodeTimeout(10*time.Second))
+})
@@ -0,0 +1,78 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/derektruong/fxfer/storage"
+)
+
+// throttleController is the cooldown shared by the file transfers of a Transfer (see
+// WithAdaptiveThrottling). The cooldown is doubled each time an attempt is throttled by
+// the storage (storage.ErrThrottled), up to maxCooldown, and decreased by step each time
+// an attempt is not, so that it grows while the storage keeps throttling and decays once
+// the throttling subsides.
+type throttleController struct {
+	step        time.Duration
+	maxCooldown time.Duration
+
+	mu       sync.Mutex
+	cooldown time.Duration
+}
+
+func newThrottleController(step, maxCooldown time.Duration) *throttleController {
+	return &throttleController{step: step, maxCooldown: maxCooldown}
+}
+
+// current returns the current cooldown, 0 if the controller is nil.
+func (c *throttleController) current() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cooldown
+}
+
+// wait waits for the current cooldown, before a new file transfer.
+func (c *throttleController) wait(ctx context.Context) (err error) {
+	cooldown := c.current()
+	if cooldown == 0 {
+		return
+	}
+	timer := time.NewTimer(cooldown)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return
+	}
+}
+
+// observe adapts the cooldown to the result of an attempt.
+func (c *throttleController) observe(err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if errors.Is(err, storage.ErrThrottled) {
+		c.cooldown = min(max(2*c.cooldown, c.step), c.maxCooldown)
+		return
+	}
+	c.cooldown = max(c.cooldown-c.step, 0)
+}
+
+// retryDelay is the delay before retrying a failed attempt (see RetryConfig.backOffDelay),
+// a throttled attempt is not retried before the shared cooldown.
+func (t *transfer) retryDelay(n uint, err error, _ *retry.Config) time.Duration {
+	delay := t.retryConfig.backOffDelay(n)
+	if errors.Is(err, storage.ErrThrottled) {
+		delay = max(delay, t.throttle.current())
+	}
+	return delay
+}
@@ -0,0 +1,121 @@
+package fxfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/internal/xferfile/xferfiletest"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	"github.com/derektruong/fxfer/storage"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("throttleController", func() {
+	It("should double the cooldown up to the max and decrease it by the step", func() {
+		controller := newThrottleController(10*time.Millisecond, 50*time.Millisecond)
+		var cooldowns []time.Duration
+		for range 4 {
+			controller.observe(storage.ErrThrottled)
+			cooldowns = append(cooldowns, controller.current())
+		}
+		controller.observe(fmt.Errorf("%w: slow down", storage.ErrThrottled))
+		cooldowns = append(cooldowns, controller.current())
+		for range 6 {
+			controller.observe(nil)
+			cooldowns = append(cooldowns, controller.current())
+		}
+		Expect(cooldowns).To(Equal([]time.Duration{
+			10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond,
+			50 * time.Millisecond,
+			40 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond, 10 * time.Millisecond, 0, 0,
+		}))
+	})
+
+	It("should do nothing when adaptive throttling is disabled", func(ctx context.Context) {
+		var controller *throttleController
+		controller.observe(storage.ErrThrottled)
+		Expect(controller.current()).To(BeZero())
+		Expect(controller.wait(ctx)).To(Succeed())
+	}, NodeTimeout(10*time.Second))
+
+	It("should share the cooldown between the transfers", func(ctx context.Context) {
+		const step, maxCooldown = 5 * time.Millisecond, 40 * time.Millisecond
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockClient := mock_protoc.NewMockClient(mockCtrl)
+		mockSrcStorage := mock_storage.NewMockSource(mockCtrl)
+		mockDestStorage := mock_storage.NewMockDestination(mockCtrl)
+		tfr := NewTransfer(GinkgoLogr,
+			WithDisabledRetry(),
+			WithAdaptiveThrottling(),
+			WithRetryConfig(RetryConfig{InitialDelay: step, MaxDelay: maxCooldown}),
+		).(*transfer)
+
+		srcInfo := xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size = "src-file.txt", "txt", 10
+		})
+		finishedInfo := xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size, info.Offset = "dest-file.txt", "txt", 10, 10
+			info.ModTime, info.FinishTime = srcInfo.ModTime, time.Now()
+		})
+		src := SourceConfig{FilePath: srcInfo.Path, Storage: mockSrcStorage, Client: mockClient}
+		dest := DestinationConfig{FilePath: finishedInfo.Path, Storage: mockDestStorage, Client: mockClient}
+		mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcInfo.Path, mockClient).Return(srcInfo, nil).AnyTimes()
+		throttledInfo := mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), finishedInfo.Path, mockClient).
+			Return(xferfile.Info{}, fmt.Errorf("%w: SlowDown", storage.ErrThrottled)).Times(6)
+		mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), finishedInfo.Path, mockClient).
+			Return(finishedInfo, nil).After(throttledInfo).AnyTimes()
+		noop := func(Progress) {}
+
+		// the sustained throttling of both transfers grows the shared cooldown
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for range 3 {
+					Expect(tfr.Transfer(ctx, src, dest, noop)).To(MatchError(storage.ErrThrottled))
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(tfr.throttle.current()).To(Equal(maxCooldown))
+
+		// the cooldown decays once the throttling subsides
+		var cooldowns []time.Duration
+		for range 9 {
+			Expect(tfr.Transfer(ctx, src, dest, noop)).To(Succeed())
+			cooldowns = append(cooldowns, tfr.throttle.current())
+		}
+		Expect(cooldowns).To(Equal([]time.Duration{
+			35 * time.Millisecond, 30 * time.Millisecond, 25 * time.Millisecond, 20 * time.Millisecond,
+			15 * time.Millisecond, 10 * time.Millisecond, 5 * time.Millisecond, 0, 0,
+		}))
+	}, NodeTimeout(10*time.Second))
+
+	It("should not retry a throttled attempt before the cooldown", func(ctx context.Context) {
+		tfr := newTransfer(GinkgoLogr)
+		tfr.throttle = newThrottleController(200*time.Millisecond, time.Minute)
+		tfr.throttle.observe(storage.ErrThrottled)
+		retryAfter := func(firstErr error) time.Duration {
+			startTime, attempts := time.Now(), 0
+			Expect(retry.Do(func() error {
+				if attempts++; attempts == 1 {
+					return firstErr
+				}
+				return nil
+			}, retry.Context(ctx), retry.Delay(time.Millisecond), retry.DelayType(tfr.retryDelay))).To(Succeed())
+			return time.Since(startTime)
+		}
+
+		Expect(retryAfter(storage.ErrThrottled)).To(BeNumerically(">=", 200*time.Millisecond))
+		Expect(retryAfter(errRetryable)).To(BeNumerically("<", 200*time.Millisecond))
+	}, NodeTimeout(10*time.Second))
+})
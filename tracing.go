@@ -0,0 +1,51 @@
+package fxfer
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer of the transfers (see WithTracerProvider).
+const tracerName = "github.com/derektruong/fxfer"
+
+// span names of the transfer phases
+const (
+	transferSpanName         = "fxfer.Transfer"
+	getFileInfoSpanName      = "fxfer.GetFileInfo"
+	createFileSpanName       = "fxfer.CreateFile"
+	transferChunkSpanName    = "fxfer.TransferChunk"
+	finalizeTransferSpanName = "fxfer.FinalizeTransfer"
+	verifySpanName           = "fxfer.Verify"
+)
+
+// span attribute keys of the transfer phases
+const (
+	srcPathAttributeKey         = attribute.Key("fxfer.src.path")
+	destPathAttributeKey        = attribute.Key("fxfer.dest.path")
+	sizeAttributeKey            = attribute.Key("fxfer.size")
+	offsetAttributeKey          = attribute.Key("fxfer.offset")
+	transferredSizeAttributeKey = attribute.Key("fxfer.transferred_size")
+	retryCountAttributeKey      = attribute.Key("fxfer.retry_count")
+)
+
+// startSpan starts a span of a transfer phase as a child of the span of the context, the returned
+// context carries the span so that the calls of the storages (e.g. the S3 SDK) nest under it.
+func (t *transfer) startSpan(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks the span as errored if the phase failed, then ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,198 @@
+package fxfer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+var _ = Describe("Transfer with a tracer provider", func() {
+	var (
+		recorder    *spanRecorder
+		destStorage *spanCapturingDestination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		tfr         fxfer.Transfer
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &spanCapturingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		recorder = new(spanRecorder)
+		tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithTracerProvider(recorder))
+	})
+
+	It("should trace each phase of the transfer under a parent span", func(ctx context.Context) {
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		spans := recorder.ended()
+		Expect(spans).To(HaveLen(5))
+		root := spans[len(spans)-1]
+		Expect(root.name).To(Equal("fxfer.Transfer"))
+		Expect(root.parent).To(BeNil())
+		Expect(root.status).To(Equal(codes.Unset))
+		var children []string
+		for _, span := range spans[:len(spans)-1] {
+			Expect(span.parent).To(BeIdenticalTo(root))
+			Expect(span.status).To(Equal(codes.Unset))
+			children = append(children, span.name)
+		}
+		Expect(children).To(Equal([]string{
+			"fxfer.GetFileInfo", "fxfer.CreateFile", "fxfer.TransferChunk", "fxfer.FinalizeTransfer",
+		}))
+
+		By("assert the attributes of the streaming span")
+		chunkSpan := spans[2]
+		Expect(chunkSpan.attributes).To(ContainElements(
+			attribute.Int64("fxfer.size", 1000),
+			attribute.Int64("fxfer.offset", 0),
+			attribute.Int64("fxfer.transferred_size", 1000),
+		))
+
+		By("assert the calls of the destination nest under the streaming span")
+		Expect(destStorage.chunkSpan).To(BeIdenticalTo(chunkSpan))
+	}, NodeTimeout(10*time.Second))
+
+	It("should mark the spans as errored when the transfer fails", func(ctx context.Context) {
+		destStorage.err = errors.New("chunk failed")
+
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).
+			To(MatchError(destStorage.err))
+
+		spans := recorder.ended()
+		Expect(spans).To(HaveLen(4))
+		Expect(spans[2].name).To(Equal("fxfer.TransferChunk"))
+		Expect(spans[2].status).To(Equal(codes.Error))
+		Expect(spans[2].errs).To(ConsistOf(MatchError(destStorage.err)))
+		Expect(spans[3].name).To(Equal("fxfer.Transfer"))
+		Expect(spans[3].status).To(Equal(codes.Error))
+	}, NodeTimeout(10*time.Second))
+})
+
+// spanCapturingDestination is a local destination which records the span of the context
+// of TransferFileChunk, and fails it with err if set.
+type spanCapturingDestination struct {
+	*local.Destination
+	chunkSpan trace.Span
+	err       error
+}
+
+func (d *spanCapturingDestination) TransferFileChunk(
+	ctx context.Context,
+	filePath string,
+	reader io.Reader,
+	offset int64,
+	cli protoc.Client,
+) (n int64, err error) {
+	d.chunkSpan = trace.SpanFromContext(ctx)
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.Destination.TransferFileChunk(ctx, filePath, reader, offset, cli)
+}
+
+// spanRecorder is an in-memory trace.TracerProvider which records the spans in the order
+// they end.
+type spanRecorder struct {
+	embedded.TracerProvider
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{recorder: r}
+}
+
+func (r *spanRecorder) ended() []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*recordedSpan(nil), r.spans...)
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	recorder *spanRecorder
+}
+
+func (t recordingTracer) Start(
+	ctx context.Context,
+	name string,
+	opts ...trace.SpanStartOption,
+) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordedSpan{
+		recorder:   t.recorder,
+		name:       name,
+		attributes: config.Attributes(),
+	}
+	span.parent, _ = trace.SpanFromContext(ctx).(*recordedSpan)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	embedded.Span
+	recorder   *spanRecorder
+	parent     *recordedSpan
+	name       string
+	attributes []attribute.KeyValue
+	status     codes.Code
+	errs       []error
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s)
+}
+
+func (s *recordedSpan) AddEvent(string, ...trace.EventOption) {}
+
+func (s *recordedSpan) AddLink(trace.Link) {}
+
+func (s *recordedSpan) IsRecording() bool { return true }
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext { return trace.SpanContext{} }
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordedSpan) SetName(name string) { s.name = name }
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.attributes = append(s.attributes, kv...)
+}
+
+func (s *recordedSpan) TracerProvider() trace.TracerProvider { return s.recorder }
@@ -1,19 +1,38 @@
 package fxfer
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"iter"
+	"os"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/protoc/local"
 	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"github.com/derektruong/fxfer/storage/stream"
 	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
 var errRetryable = errors.New("retryable error")
 
+// debugLevel is the verbosity of the logs of the per-chunk events of the transfers, their
+// lifecycle transitions are logged at the Info level.
+const debugLevel = 1
+
+// ErrEncryptionMetadataUnsupported is returned when the destination storage cannot
+// record the IV of an encrypted transfer (see storage.MetadataFileCreator)
+var ErrEncryptionMetadataUnsupported = errors.New("encryption: destination does not support file metadata")
+
 // Transfer is the interface for handling file transfers.
 type Transfer interface {
 	// Transfer handles the transfer of a file from a source to a destination,
@@ -29,21 +48,193 @@ type Transfer interface {
 	// Returns:
 	//   - err: if any step in the transfer process fails, nil otherwise
 	Transfer(ctx context.Context, src SourceConfig, dest DestinationConfig, cb ProgressUpdatedCallback) (err error)
+
+	// TransferWithResult handles the transfer of a file from a source to a destination like
+	// Transfer, and reports its outcome.
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfer lifecycle.
+	//   - src: see SourceConfig for more details.
+	//   - dest: see DestinationConfig for more details.
+	//   - cb: the callback function to handle progress updates (see ProgressUpdatedCallback).
+	//
+	// Returns:
+	//   - result: the outcome of the transfer, see TransferResult for more details.
+	//   - err: if any step in the transfer process fails, nil otherwise
+	TransferWithResult(
+		ctx context.Context, src SourceConfig, dest DestinationConfig, cb ProgressUpdatedCallback,
+	) (result TransferResult, err error)
+
+	// TransferStdin streams the content of os.Stdin to the destination, it is
+	// a convenience for pipelines (e.g. `cat file | mytool ...`).
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfer lifecycle.
+	//   - size: the caller-declared size of the content, SizeUnknown (or any negative value)
+	//     streams until EOF.
+	//   - modTime: the caller-declared modification time of the content.
+	//   - dest: see DestinationConfig for more details.
+	//   - cb: the callback function to handle progress updates (see ProgressUpdatedCallback).
+	//
+	// Returns:
+	//   - err: if any step in the transfer process fails, nil otherwise
+	TransferStdin(ctx context.Context, size int64, modTime time.Time, dest DestinationConfig, cb ProgressUpdatedCallback) (err error)
+
+	// TransferDirectory transfers all files under the source directory to the
+	// destination directory recursively, preserving their relative paths. Files
+	// that do not satisfy the file rules are skipped. The files of a source implementing
+	// storage.FileIterator are transferred as they are listed, the totals of the
+	// Progress.BatchProgress then grow along with the listing.
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfer lifecycle.
+	//   - src: see SourceConfig for more details, FilePath is the source directory.
+	//   - dest: see DestinationConfig for more details, FilePath is the destination directory.
+	//   - cb: the callback function to handle progress updates, Progress.BatchProgress
+	//     contains the aggregate progress (see ProgressUpdatedCallback).
+	//
+	// Returns:
+	//   - err: if any file transfer fails, nil otherwise (see WithContinueOnError)
+	TransferDirectory(ctx context.Context, src SourceConfig, dest DestinationConfig, cb ProgressUpdatedCallback) (err error)
+
+	// TransferGlob transfers all files matching the source pattern (e.g. "logs/2024-*/app-*.log")
+	// to the destination directory, preserving their paths relative to the deepest directory
+	// of the pattern without meta character (e.g. "logs"). Files that do not satisfy the file
+	// rules are skipped. The files of a source implementing storage.FileIterator are matched
+	// as they are iterated under that directory (see TransferDirectory).
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfer lifecycle.
+	//   - src: see SourceConfig for more details, FilePath is the pattern (see storage.Source.Glob).
+	//   - dest: see DestinationConfig for more details, FilePath is the destination directory.
+	//   - cb: the callback function to handle progress updates, Progress.BatchProgress
+	//     contains the aggregate progress (see ProgressUpdatedCallback).
+	//
+	// Returns:
+	//   - err: ErrGlobNoMatch if no file matches the pattern, or if any file transfer
+	//     fails, nil otherwise (see WithContinueOnError)
+	TransferGlob(ctx context.Context, src SourceConfig, dest DestinationConfig, cb ProgressUpdatedCallback) (err error)
+
+	// TransferAll transfers the independent pairs of source and destination files, up to
+	// concurrency of them at once. The first failed pair cancels the transfer of the others,
+	// unless WithContinueOnError is set, in which case all the pairs are transferred.
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfers lifecycle.
+	//   - pairs: see TransferPair for more details.
+	//   - concurrency: the maximum number of pairs transferred at once, at least 1.
+	//   - cb: the callback function to handle progress updates of each pair
+	//     (see TransferAllProgressCallback).
+	//
+	// Returns:
+	//   - err: the error of the first failed pair, or the errors of all the failed pairs
+	//     joined (see WithContinueOnError), nil otherwise
+	TransferAll(ctx context.Context, pairs []TransferPair, concurrency int, cb TransferAllProgressCallback) (err error)
+
+	// TransferFanOut transfers a file from a source to several destinations, reading the source
+	// only once. Each destination resumes from its own offset, the source is read from the
+	// lowest of them. The first failed destination cancels the transfer to the others, unless
+	// WithContinueOnError is set, in which case the others are transferred.
+	//
+	// Parameters:
+	//   - ctx: the context for managing the transfer lifecycle.
+	//   - src: see SourceConfig for more details.
+	//   - dests: see DestinationConfig for more details.
+	//   - cb: the callback function to handle progress updates, the progress is the one of
+	//     the slowest destination (see ProgressUpdatedCallback).
+	//
+	// Returns:
+	//   - err: the error of the first failed destination, or the errors of all the failed
+	//     destinations joined (see WithContinueOnError), nil otherwise
+	TransferFanOut(ctx context.Context, src SourceConfig, dests []DestinationConfig, cb ProgressUpdatedCallback) (err error)
+
+	// Verify re-checks a transferred file without transferring it again nor modifying it: the
+	// destination file must be finalized with the size of the source file and, if a checksum
+	// algorithm is set (see WithChecksumAlgorithm), both files are read to compare their checksums.
+	//
+	// Parameters:
+	//   - ctx: the context for managing the verification lifecycle.
+	//   - src: see SourceConfig for more details.
+	//   - dest: see DestinationConfig for more details.
+	//
+	// Returns:
+	//   - ok: true if the destination file matches the source file, false otherwise.
+	//   - err: ErrVerificationUnsupported if the destination file cannot be read back or
+	//     compared, or if any step of the verification fails, nil otherwise
+	Verify(ctx context.Context, src SourceConfig, dest DestinationConfig) (ok bool, err error)
+
+	// Pause quiesces the transferer, e.g. for maintenance: its active transfers stop reading
+	// their source before their next chunk or part, and its new transfers wait before starting,
+	// until Resume is called. The paused transfers keep their resumable state.
+	Pause()
+
+	// Resume lifts Pause, the paused and waiting transfers of the transferer continue.
+	Resume()
 }
 
+// SizeUnknown is the size of a source whose size cannot be determined up front.
+const SizeUnknown = xferfile.SizeUnknown
+
+// FileInfo is the info of a file in a source or destination storage.
+type FileInfo = xferfile.Info
+
 // transfer handles file transfers with configurations
 type transfer struct {
 	logger logr.Logger
 
+	// pauseGate holds the transfers while the transferer is paused
+	pauseGate pauseGate
+
 	// options
 	fileRule                *fileRule
 	refreshProgressInterval time.Duration
 	checksumAlgorithm       ChecksumAlgorithm
 	disabledRetry           bool
 	retryConfig             RetryConfig
+	retryClassifier         RetryClassifier
+	continueOnError         bool
+	dryRun                  bool
+	sourcePreflight         bool
+	modTimeTolerance        time.Duration
+	preserveContentType     bool
+	compressionCodec        CompressionCodec
+	encryptionKey           []byte
+	serverSideCopy          bool
+	validator               Validator
+	enumerationConcurrency  int
+	cancelableProgress      CancelableProgressCallback
+	deleteOnAbort           bool
+	extensionMismatchPolicy ExtensionMismatchPolicy
+	destinationKeyFunc      DestinationKeyFunc
+	collisionSuffixing      bool
+	sourceRoot              string
+	destinationNewerPolicy  DestinationNewerPolicy
+	adaptiveThrottling      bool
+	operationCounting       bool
+	sidecarSuffix           string
+	finalizeConsistency     *RetryConfig
+	fanOutBufferSize        int
+	maxConcurrentFinalizes  int
+	finalizeSlots           chan struct{}
+	commitHook              CommitHook
+	throttle                *throttleController
+	verificationInterval    int64
+	writeBufferSize         int
+	sourceReadRetries       int
+	stallMinBytesPerSec     int64
+	stallWindow             time.Duration
+	heartbeatInterval       time.Duration
+	readerMiddlewares       []ReaderMiddleware
+	writeMiddlewares        []ReaderMiddleware
+	tracer                  trace.Tracer
+	metrics                 *transferMetrics
 }
 
-// NewTransfer creates a new transfer with the optional TransferOption(s).
+// NewTransfer creates a new transfer with the optional TransferOption(s). The lifecycle transitions
+// of the transfers (e.g. started, resumed, retried, finished) are logged at the Info level, and
+// their per-chunk events at the debug level (V(1)), whose logs are only emitted if the verbosity
+// of the logr sink is at least 1 (e.g. funcr.Options.Verbosity or zapr with a debug level). See
+// WithSilent to discard the logs of the transfer.
 func NewTransfer(
 	logger logr.Logger,
 	options ...TransferOption,
@@ -57,11 +248,21 @@ func NewTransfer(
 			MaxRetryAttempts: defaultMaxRetryAttempts,
 			InitialDelay:     defaultInitialDelay,
 			MaxDelay:         defaultMaxDelay,
+			Multiplier:       defaultRetryMultiplier,
 		},
+		retryClassifier: DefaultRetryClassifier,
+		tracer:          otel.GetTracerProvider().Tracer(tracerName),
+		metrics:         newTransferMetrics(otel.GetMeterProvider()),
 	}
 	for _, opt := range options {
 		opt(tr)
 	}
+	if tr.adaptiveThrottling {
+		tr.throttle = newThrottleController(tr.retryConfig.InitialDelay, tr.retryConfig.MaxDelay)
+	}
+	if tr.maxConcurrentFinalizes > 0 {
+		tr.finalizeSlots = make(chan struct{}, tr.maxConcurrentFinalizes)
+	}
 	return tr
 }
 
@@ -71,48 +272,169 @@ func (t *transfer) Transfer(
 	dest DestinationConfig,
 	cb ProgressUpdatedCallback,
 ) (err error) {
+	_, err = t.TransferWithResult(ctx, src, dest, cb)
+	return
+}
+
+func (t *transfer) TransferWithResult(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (result TransferResult, err error) {
+	ctx, span := t.startSpan(ctx, transferSpanName,
+		srcPathAttributeKey.String(src.FilePath), destPathAttributeKey.String(dest.FilePath))
+	defer func() { endSpan(span, err) }()
+
 	if err = src.Validate(ctx); err != nil {
 		return
 	}
 	if err = dest.Validate(ctx); err != nil {
 		return
 	}
-
+	// the info of the source file is counted with the operations of its transfer
+	ctx, src, dest, _ = t.countOperations(ctx, src, dest)
 	var srcInfo xferfile.Info
-	if srcInfo, err = src.Storage.GetFileInfo(
-		ctx,
-		src.FilePath,
-		src.Client,
-	); err != nil {
+	if srcInfo, err = t.getSourceFileInfo(ctx, src); err != nil {
+		return
+	}
+	return t.transferFile(ctx, srcInfo, src, dest, cb)
+}
+
+// getSourceFileInfo fetches the info of the source file in a span (see WithTracerProvider).
+func (t *transfer) getSourceFileInfo(ctx context.Context, src SourceConfig) (srcInfo xferfile.Info, err error) {
+	ctx, span := t.startSpan(ctx, getFileInfoSpanName, srcPathAttributeKey.String(src.FilePath))
+	defer func() { endSpan(span, err) }()
+
+	if srcInfo, err = src.Storage.GetFileInfo(ctx, src.FilePath, src.Client); err != nil {
+		return
+	}
+	span.SetAttributes(sizeAttributeKey.Int64(srcInfo.Size))
+	return
+}
+
+// transferFile transfers the source file whose info has been fetched to the destination.
+func (t *transfer) transferFile(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (result TransferResult, err error) {
+	if t.encryptionKey != nil && len(t.encryptionKey) != crypt.KeySize {
+		err = crypt.ErrInvalidKey
+		return
+	}
+	// the new transfers do not start while the transferer is paused
+	if err = t.pauseGate.wait(ctx); err != nil {
+		return
+	}
+
+	ctx, src, dest, counts := t.countOperations(ctx, src, dest)
+	if counts != nil {
+		defer func() { result.S3Operations = counts.snapshot() }()
+	}
+
+	// the sidecar follows the destination path and the rules of its file
+	if !isSidecarTransfer(ctx) {
+		if dest, err = t.resolveDestination(src, dest); err != nil {
+			return
+		}
+
+		if err = t.fileRule.Check(srcInfo); err != nil {
+			return
+		}
+
+		if err = t.checkExtension(srcInfo, dest); err != nil {
+			return
+		}
+
+		if dest, err = t.avoidCollision(ctx, srcInfo, dest); err != nil {
+			return
+		}
+	}
+
+	if err = t.validate(ctx, srcInfo, dest); err != nil {
+		return
+	}
+
+	if t.dryRun {
+		err = t.processDryRun(ctx, srcInfo, dest, cb)
+		return
+	}
+
+	// the concurrent transfers of the process to the same file are serialized by the destination
+	if locker, ok := dest.Storage.(storage.TransferLocker); ok {
+		var unlock func()
+		if unlock, err = locker.LockTransfer(ctx, dest.FilePath, dest.Client); err != nil {
+			return
+		}
+		defer unlock()
+	}
+
+	// the progress is repeated while the transfer goes quiet, until it returns
+	cb, stopHeartbeat := t.startHeartbeat(srcInfo.Size, cb)
+	defer stopHeartbeat()
+
+	// the new file transfer waits for the storage throttling to cool down
+	if err = t.throttle.wait(ctx); err != nil {
+		return
+	}
+
+	// the sidecar is transferred first, it is finalized along with the file
+	var sc *sidecar
+	if ctx, sc, err = t.transferSidecar(ctx, src, dest); err != nil {
 		return
 	}
+	defer func() {
+		// the sidecar of a skipped file has not been finalized yet
+		if err == nil && ctx.Err() == nil {
+			err = t.finalizeSidecar(ctx, sc)
+		}
+	}()
 
-	if err = t.fileRule.Check(srcInfo); err != nil {
+	attempts := 0
+	sourceChanged := false
+	attempt := func() (err error) {
+		// the source changed during the previous attempt, the info of its new version is fetched
+		if sourceChanged {
+			if srcInfo, err = t.getSourceFileInfo(ctx, src); err != nil {
+				return
+			}
+		}
+		result, err = t.processResumableTransfer(ctx, srcInfo, src, dest, cb)
+		result.RetryAttempts = attempts
+		attempts++
+		sourceChanged = errors.Is(err, storage.ErrSourceChanged)
+		t.throttle.observe(err)
 		return
 	}
 
 	if t.disabledRetry {
-		return t.processResumableTransfer(ctx, srcInfo, src, dest, cb)
+		err = attempt()
+		return
 	}
 
-	if err = retry.Do(
-		func() error {
-			return t.processResumableTransfer(ctx, srcInfo, src, dest, cb)
-		},
+	retryOptions := []retry.Option{
 		retry.Context(ctx),
 		retry.Delay(t.retryConfig.InitialDelay),
 		retry.MaxDelay(t.retryConfig.MaxDelay),
 		retry.Attempts(uint(t.retryConfig.MaxRetryAttempts)),
-		retry.RetryIf(func(err error) bool {
-			return errors.Is(err, errRetryable)
-		}),
+		retry.DelayType(t.retryDelay),
+		retry.RetryIf(retry.RetryIfFunc(t.retryClassifier)),
 		retry.OnRetry(func(n uint, err error) {
+			// the callback also follows the last attempt, which is not retried
+			if n+1 < uint(t.retryConfig.MaxRetryAttempts) {
+				trace.SpanFromContext(ctx).SetAttributes(retryCountAttributeKey.Int(int(n + 1)))
+				t.metrics.recordRetried(ctx, transferAttributes(src, dest))
+			}
 			t.logger.Info("retrying file transfer",
 				"srcPath", src.FilePath, "dstPath", dest.FilePath,
 				"errorMessage", err.Error(),
 				"retryAttempts", n+1)
 		}),
-	); err != nil {
+	}
+	if err = retry.Do(attempt, retryOptions...); err != nil {
 		err = errors.Unwrap(err)
 		if err == nil {
 			return
@@ -126,46 +448,305 @@ func (t *transfer) Transfer(
 	return
 }
 
-func (t *transfer) processResumableTransfer(
+func (t *transfer) TransferStdin(
+	ctx context.Context,
+	size int64,
+	modTime time.Time,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	srcStorage := stream.NewSource(t.logger, os.Stdin, size, modTime)
+	defer srcStorage.Close()
+
+	// the stream has no path of its own, so it is named after its destination
+	return t.Transfer(ctx, SourceConfig{
+		FilePath: dest.FilePath,
+		Storage:  srcStorage,
+		Client:   local.NewIO(),
+	}, dest, cb)
+}
+
+func (t *transfer) TransferDirectory(
 	ctx context.Context,
-	srcInfo xferfile.Info,
 	src SourceConfig,
 	dest DestinationConfig,
 	cb ProgressUpdatedCallback,
 ) (err error) {
-	var destInfo xferfile.Info
-	if destInfo, err = t.getOrCreateDestinationFile(ctx, dest, srcInfo); err != nil {
+	if err = src.Validate(ctx); err != nil {
+		return
+	}
+	if err = dest.Validate(ctx); err != nil {
 		return
 	}
 
-	if destInfo.Offset == srcInfo.Size && !destInfo.FinishTime.IsZero() {
-		t.logger.Info("file transfer is finished, please re-check the destination file",
-			"srcPath", src.FilePath, "dstPath", dest.FilePath)
+	if iterator, ok := src.Storage.(storage.FileIterator); ok {
+		return t.transferIteratedBatch(ctx, iterator.IterFiles(ctx, src.FilePath, src.Client), src.FilePath, src, dest, cb)
+	}
+	var srcInfos []xferfile.Info
+	if srcInfos, err = src.Storage.ListFiles(ctx, src.FilePath, src.Client); err != nil {
 		return
 	}
+	return t.transferBatch(ctx, srcInfos, src.FilePath, src, dest, cb)
+}
 
-	// verify if the source file has been modified
-	if destInfo, err = t.verifyFileChanges(ctx, dest, srcInfo, destInfo); err != nil {
+// batchWindowSize is the number of the iterated source files listed before they are
+// transferred (see transferIteratedBatch).
+const batchWindowSize = 1000
+
+// transferBatch transfers the listed source files which satisfy the file rules, under
+// the destination directory at their path relative to the source base directory
+// (see batchDestinationPath).
+func (t *transfer) transferBatch(
+	ctx context.Context,
+	srcInfos []xferfile.Info,
+	baseDir string,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	var (
+		batchProgress BatchProgress
+		errs          []error
+	)
+	if errs, err = t.transferBatchWindow(ctx, srcInfos, baseDir, src, dest, cb, &batchProgress); err != nil {
 		return
 	}
+	return errors.Join(errs...)
+}
 
-	// if file transfer is not finished, get the file from the offset
-	var reader io.ReadCloser
-	if reader, err = src.Storage.GetFileFromOffset(
-		ctx,
-		src.FilePath,
-		destInfo.Offset,
-		src.Client,
-	); err != nil {
+// transferIteratedBatch transfers the iterated source files as transferBatch does, window by
+// window (see batchWindowSize), so that the files are neither all listed before the first
+// transfer nor all held in memory. The iteration stops at the first error.
+func (t *transfer) transferIteratedBatch(
+	ctx context.Context,
+	srcInfos iter.Seq2[xferfile.Info, error],
+	baseDir string,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (err error) {
+	var (
+		batchProgress    BatchProgress
+		errs, windowErrs []error
+		// the paths of the files whose sidecar may be listed in a later window (see WithSidecar)
+		listedPaths = make(map[string]bool)
+	)
+	window := make([]xferfile.Info, 0, batchWindowSize)
+	for srcInfo, iterErr := range srcInfos {
+		if err = iterErr; err != nil {
+			return
+		}
+		if t.sidecarSuffix != "" {
+			// a sidecar is listed after its file, along with which it is transferred
+			if t.isListedSidecar(srcInfo.Path, listedPaths) {
+				continue
+			}
+			listedPaths[srcInfo.Path] = true
+		}
+		if window = append(window, srcInfo); len(window) < batchWindowSize {
+			continue
+		}
+		if windowErrs, err = t.transferBatchWindow(ctx, window, baseDir, src, dest, cb, &batchProgress); err != nil {
+			return
+		}
+		errs = append(errs, windowErrs...)
+		window = window[:0]
+	}
+	if windowErrs, err = t.transferBatchWindow(ctx, window, baseDir, src, dest, cb, &batchProgress); err != nil {
+		return
+	}
+	return errors.Join(append(errs, windowErrs...)...)
+}
+
+// transferBatchWindow transfers the source files of a window of the batch, adding them to the
+// totals of the batch progress. It returns the errors of the files it continued on (see
+// WithContinueOnError), or the error which stopped the batch.
+func (t *transfer) transferBatchWindow(
+	ctx context.Context,
+	srcInfos []xferfile.Info,
+	baseDir string,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+	batchProgress *BatchProgress,
+) (errs []error, err error) {
+	srcInfos = lo.Filter(t.withoutSidecars(srcInfos), func(info xferfile.Info, _ int) bool {
+		if ruleErr := t.fileRule.Check(info); ruleErr != nil {
+			t.logger.Info("skipping file transfer", "srcPath", info.Path, "reason", ruleErr.Error())
+			return false
+		}
+		return true
+	})
+
+	// the info of the files is fetched up front when it is fetched concurrently,
+	// otherwise it is fetched right before the transfer of each file
+	var enumeratedFiles []enumeratedFile
+	if t.enumerationConcurrency > 1 {
+		enumeratedFiles = t.enumerateFiles(ctx, src, srcInfos)
+	}
+
+	// the sizes of the files are known up front from the listing, or from the enumeration
+	fileSizes := make([]int64, len(srcInfos))
+	batchProgress.TotalFiles += len(srcInfos)
+	for i, srcInfo := range srcInfos {
+		fileSizes[i] = srcInfo.Size
+		if enumeratedFiles != nil && enumeratedFiles[i].err == nil {
+			fileSizes[i] = enumeratedFiles[i].info.Size
+		}
+		batchProgress.TotalBytes += max(fileSizes[i], 0)
+	}
+
+	for i, srcInfo := range srcInfos {
+		fileSrc, fileDest := src, dest
+		fileSrc.FilePath = srcInfo.Path
+		if fileDest.FilePath, err = t.batchDestinationPath(baseDir, srcInfo.Path, dest.FilePath); err != nil {
+			return
+		}
+
+		batchProgress.CurrentFile = srcInfo.Path
+		fileBatchProgress := *batchProgress
+		fileSize := max(fileSizes[i], 0)
+		fileCb := func(progress Progress) {
+			progress.BatchProgress = fileBatchProgress
+			if progress.Status == ProgressStatusFinished {
+				// all the bytes of a finished file are completed, even those transferred before a resume
+				progress.BytesCompleted += fileSize
+			} else {
+				progress.BytesCompleted += progress.TransferredSize
+			}
+			cb(progress)
+		}
+		if enumeratedFiles == nil {
+			err = t.Transfer(ctx, fileSrc, fileDest, fileCb)
+		} else if err = enumeratedFiles[i].err; err == nil {
+			_, err = t.transferFile(ctx, enumeratedFiles[i].info, fileSrc, fileDest, fileCb)
+		}
+		if err != nil {
+			if !t.continueOnError || ctx.Err() != nil {
+				return
+			}
+			errs = append(errs, fmt.Errorf("failed to transfer %s: %w", srcInfo.Path, err))
+			continue
+		}
+		batchProgress.FilesCompleted++
+		batchProgress.BytesCompleted += fileSize
+	}
+	return
+}
+
+// enumeratedFile is the info of a file fetched by enumerateFiles, or the error fetching it.
+type enumeratedFile struct {
+	info xferfile.Info
+	err  error
+}
+
+// enumerateFiles fetches the info of the listed files concurrently (see WithEnumerationConcurrency),
+// in the order of the listing. The error fetching the info of a file does not stop the enumeration
+// of the other files.
+func (t *transfer) enumerateFiles(
+	ctx context.Context,
+	src SourceConfig,
+	listedInfos []xferfile.Info,
+) (files []enumeratedFile) {
+	files = make([]enumeratedFile, len(listedInfos))
+	var eg errgroup.Group
+	eg.SetLimit(t.enumerationConcurrency)
+	for i, listedInfo := range listedInfos {
+		eg.Go(func() error {
+			files[i].info, files[i].err = src.Storage.GetFileInfo(ctx, listedInfo.Path, src.Client)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	return
+}
+
+func (t *transfer) processResumableTransfer(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	src SourceConfig,
+	dest DestinationConfig,
+	cb ProgressUpdatedCallback,
+) (result TransferResult, err error) {
+	result.TotalSize = srcInfo.Size
+
+	// the transfer is aborted by canceling its context with the error of the cancelable callback
+	if t.cancelableProgress != nil {
+		var abort context.CancelCauseFunc
+		ctx, abort = context.WithCancelCause(ctx)
+		defer abort(nil)
+		cb = t.withCancelableProgress(cb, abort)
+	}
+	// the stalled transfer is aborted by canceling its context with ErrStalled
+	var stall context.CancelCauseFunc
+	if t.stallWindow > 0 && t.stallMinBytesPerSec > 0 {
+		ctx, stall = context.WithCancelCause(ctx)
+		defer stall(nil)
+	}
+
+	var (
+		destInfo xferfile.Info
+		skip     bool
+	)
+	if destInfo, skip, err = t.prepareDestination(ctx, srcInfo, src, dest); err != nil {
 		return
 	}
+	if skip {
+		cb(skippedProgress(destInfo))
+		result = skippedResult(srcInfo, destInfo)
+		return
+	}
+
+	// if file transfer is not finished, get the file from the offset, unless
+	// the destination copies it from the source by itself
+	copier := t.getServerSideCopier(srcInfo, src, dest)
+	var reader io.ReadCloser = io.NopCloser(bytes.NewReader(nil))
+	if copier == nil {
+		if reader, err = getSourceFromOffset(ctx, src, srcInfo, destInfo.Offset); err != nil {
+			// the archived source object is being restored, it can be read later
+			if errors.Is(err, storage.ErrObjectRestoreInProgress) {
+				err = errors.Join(err, errRetryable)
+			}
+			// the source cannot be read from the offset or has changed since its info was
+			// fetched, the destination file is transferred again from the beginning
+			if errors.Is(err, storage.ErrRangeUnsupported) || errors.Is(err, storage.ErrSourceChanged) {
+				if delErr := dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client); delErr != nil {
+					err = errors.Join(err, delErr)
+					return
+				}
+				err = errors.Join(err, errRetryable)
+			}
+			return
+		}
+		if t.sourceReadRetries > 0 {
+			reader = newReopeningReader(ctx, t.logger, src, srcInfo, reader, destInfo.Offset, t.sourceReadRetries)
+		}
+	}
 	defer reader.Close()
 
+	// the checksum only covers the content read from the beginning
+	var proxySrc io.Reader = reader
+	checksumHash := t.checksumAlgorithm.newHash()
+	hasChecksum := checksumHash != nil && copier == nil && destInfo.Offset == 0
+	if hasChecksum {
+		proxySrc = io.TeeReader(reader, checksumHash)
+	}
+	proxySrc = applyMiddlewares(proxySrc, t.readerMiddlewares)
+	proxySrc = t.pauseGate.reader(ctx, proxySrc)
+
 	// write chunk to destination
 	interruptedChan := make(chan struct{})
 	completedChan := make(chan struct{})
-	proxy := newProxyReader(reader, destInfo.Offset)
+	proxy := newProxyReader(proxySrc, destInfo.Offset)
 	defer proxy.Close()
+	if stall != nil {
+		proxy.stallDetector = t.newStallDetector(time.Now(), destInfo.Offset, stall)
+	}
+	result.StartedAt = destInfo.StartTime
+	result.Resumed = destInfo.Offset > 0
+	defer func() {
+		result.BytesTransferred = proxy.transferReader.TransferredSize() - destInfo.Offset
+	}()
 
 	go proxy.trackProgress(
 		ctx,
@@ -184,7 +765,35 @@ func (t *transfer) processResumableTransfer(
 		)
 	}
 
-	if _, err = dest.Storage.TransferFileChunk(ctx, dest.FilePath, proxy, destInfo.Offset, dest.Client); err != nil {
+	metricAttrs := transferAttributes(src, dest)
+	t.metrics.recordStarted(ctx, metricAttrs)
+	startAt := time.Now()
+	defer func() {
+		t.metrics.recordFinished(ctx, metricAttrs, startAt, proxy.transferReader.TransferredSize()-destInfo.Offset, err)
+	}()
+
+	chunkCtx, chunkSpan := t.startSpan(ctx, transferChunkSpanName,
+		sizeAttributeKey.Int64(srcInfo.Size), offsetAttributeKey.Int64(destInfo.Offset))
+	if copier != nil {
+		_, err = copier.CopyFileFrom(
+			chunkCtx,
+			dest.FilePath, src.FilePath, src.Client,
+			destInfo.Offset,
+			dest.Client,
+			copiedSizeTracker{proxy, &t.pauseGate},
+		)
+	} else {
+		err = t.transferChunk(chunkCtx, dest, destInfo, proxy)
+	}
+	chunkSpan.SetAttributes(transferredSizeAttributeKey.Int64(proxy.transferReader.TransferredSize() - destInfo.Offset))
+	endSpan(chunkSpan, err)
+	if err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, ErrStalled) {
+			err = cause
+		} else if t.isAborted(cause) {
+			err = t.abortTransfer(ctx, src, dest, cause)
+			return
+		}
 		if errors.Is(err, context.Canceled) {
 			err = nil
 			t.logger.Info("file transfer is canceled in the middle",
@@ -192,29 +801,68 @@ func (t *transfer) processResumableTransfer(
 			return
 		}
 		cb(Progress{
-			Error:    err,
-			Status:   ProgressStatusInError,
-			Duration: time.Since(destInfo.StartTime),
+			Error:           err,
+			Status:          ProgressStatusInError,
+			TotalSize:       srcInfo.Size,
+			TransferredSize: proxy.transferReader.TransferredSize(),
+			ConfirmedSize:   proxy.ConfirmedSize(),
+			Offset:          destInfo.Offset,
+			Duration:        time.Since(destInfo.StartTime),
 		})
 		close(interruptedChan)
-		return errors.Join(err, errRetryable)
+		// the corrupted destination file cannot be resumed, it is transferred again from the beginning
+		if errors.Is(err, ErrVerificationMismatch) {
+			if delErr := dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client); delErr != nil {
+				err = errors.Join(err, delErr)
+				return
+			}
+		}
+		err = errors.Join(err, errRetryable)
+		return
 	}
 
-	// finalize the transfer
-	if err = dest.Storage.FinalizeTransfer(ctx, dest.FilePath, dest.Client); err != nil {
+	// finalize the transfer, the file of an eventually-consistent destination is awaited
+	if result.DestinationObject, err = t.finalizeTransfer(ctx, dest); err == nil && !isSidecarTransfer(ctx) {
+		err = t.awaitFinalizedFile(ctx, dest, t.finalizedFileSize(srcInfo))
+	}
+	if err != nil {
 		if errors.Is(err, storage.ErrFileOrObjectCannotFinalize) {
 			if proxy.transferReader.TransferredSize() < srcInfo.Size {
 				close(interruptedChan)
-				return errors.Join(err, errRetryable)
+				err = errors.Join(err, errRetryable)
+				return
 			}
 			if delErr := dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client); delErr != nil {
 				return
 			}
 		}
 		cb(Progress{
-			Error:    err,
-			Status:   ProgressStatusInError,
-			Duration: time.Since(destInfo.StartTime),
+			Error:           err,
+			Status:          ProgressStatusInError,
+			TotalSize:       srcInfo.Size,
+			TransferredSize: proxy.transferReader.TransferredSize(),
+			ConfirmedSize:   proxy.ConfirmedSize(),
+			Offset:          destInfo.Offset,
+			Duration:        time.Since(destInfo.StartTime),
+		})
+		return
+	}
+	result.FinishedAt = time.Now()
+	if hasChecksum {
+		result.Checksum = checksumHash.Sum(nil)
+	}
+
+	// the transfer is only complete once committed, it is rolled back otherwise
+	if err = t.commit(ctx, dest, result); err != nil {
+		close(interruptedChan)
+		cb(Progress{
+			Error:           err,
+			Status:          ProgressStatusInError,
+			TotalSize:       srcInfo.Size,
+			TransferredSize: proxy.transferReader.TransferredSize(),
+			ConfirmedSize:   proxy.ConfirmedSize(),
+			Offset:          destInfo.Offset,
+			Duration:        time.Since(destInfo.StartTime),
 		})
 		return
 	}
@@ -223,9 +871,9 @@ func (t *transfer) processResumableTransfer(
 	// notify the progress is finished
 	cb(Progress{
 		Status:     ProgressStatusFinished,
-		Duration:   time.Since(destInfo.StartTime),
+		Duration:   result.FinishedAt.Sub(destInfo.StartTime),
 		StartAt:    destInfo.StartTime,
-		FinishAt:   time.Now(),
+		FinishAt:   result.FinishedAt,
 		Percentage: finishedProgress,
 	})
 
@@ -234,20 +882,218 @@ func (t *transfer) processResumableTransfer(
 	return
 }
 
+// prepareDestination gets or creates the destination file and verifies that it can be resumed
+// from the source file, skip reports whether the destination file is kept as is.
+func (t *transfer) prepareDestination(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	src SourceConfig,
+	dest DestinationConfig,
+) (destInfo xferfile.Info, skip bool, err error) {
+	if destInfo, err = t.getOrCreateDestinationFile(ctx, src, dest, srcInfo); err != nil {
+		return
+	}
+
+	// the destination file is identical to the source file, the source file is not read again
+	if t.isDestinationFinished(srcInfo, destInfo) {
+		t.logger.Info("file transfer is finished, skipping the identical destination file",
+			"srcPath", src.FilePath, "dstPath", dest.FilePath)
+		skip = true
+		return
+	}
+
+	// the destination file is newer than the source file, e.g. it has been updated by another writer
+	if skip, err = t.checkDestinationNewer(srcInfo, destInfo); err != nil {
+		return
+	}
+	if skip {
+		t.logger.Info("destination file is newer than the source file, skipping it",
+			"srcPath", src.FilePath, "dstPath", dest.FilePath,
+			"srcModTime", srcInfo.ModTime, "dstModTime", destInfo.ModTime)
+		return
+	}
+
+	// verify if the source file has been modified
+	if destInfo, err = t.verifyFileChanges(ctx, dest, srcInfo, destInfo); err != nil {
+		return
+	}
+
+	// verify if the destination file can be resumed with the compression codec
+	if destInfo, err = t.verifyCompression(ctx, dest, srcInfo, destInfo); err != nil {
+		return
+	}
+
+	// verify if the destination file can be resumed with the encryption
+	destInfo, err = t.verifyEncryption(ctx, dest, srcInfo, destInfo)
+	return
+}
+
+// finalizeTransfer finalizes the destination file along with its sidecar (see WithSidecar), the
+// finalization of a sidecar is deferred until its file is finalized. Neither is kept if the
+// sidecar cannot be finalized after the file.
+func (t *transfer) finalizeTransfer(
+	ctx context.Context,
+	dest DestinationConfig,
+) (object storage.FinalizedObject, err error) {
+	sc := sidecarFromContext(ctx)
+	if sc != nil && sc.deferring {
+		sc.dest = &dest
+		return
+	}
+	if object, err = t.finalizeFile(ctx, dest); err != nil {
+		return
+	}
+	if err = t.finalizeSidecar(ctx, sc); err != nil {
+		object = storage.FinalizedObject{}
+		err = errors.Join(err,
+			dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client),
+			sc.dest.Storage.DeleteFile(ctx, sc.dest.FilePath, sc.dest.Client))
+	}
+	return
+}
+
+// finalizeFile finalizes the destination file in a span (see WithTracerProvider), the
+// finalized object is referenced if the destination supports it (see storage.ObjectFinalizer).
+// It waits for a slot while the concurrent finalizations are bounded (see WithMaxConcurrentFinalizes).
+func (t *transfer) finalizeFile(
+	ctx context.Context,
+	dest DestinationConfig,
+) (object storage.FinalizedObject, err error) {
+	if t.finalizeSlots != nil {
+		select {
+		case t.finalizeSlots <- struct{}{}:
+			defer func() { <-t.finalizeSlots }()
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+	ctx, span := t.startSpan(ctx, finalizeTransferSpanName, destPathAttributeKey.String(dest.FilePath))
+	defer func() { endSpan(span, err) }()
+	if finalizer, ok := dest.Storage.(storage.ObjectFinalizer); ok {
+		return finalizer.FinalizeTransferWithObject(ctx, dest.FilePath, dest.Client)
+	}
+	err = dest.Storage.FinalizeTransfer(ctx, dest.FilePath, dest.Client)
+	return
+}
+
+// transferChunk streams the content of the proxy reader to the destination file from the offset,
+// compressing, encrypting and transforming it (see WithWriteMiddleware) when configured.
+func (t *transfer) transferChunk(
+	ctx context.Context,
+	dest DestinationConfig,
+	destInfo xferfile.Info,
+	proxy *proxyReader,
+) (err error) {
+	var destReader io.Reader = proxy
+	if t.compressionCodec != NoneCompressionCodec {
+		var compressReader *compressReader
+		if compressReader, err = newCompressReader(proxy, t.compressionCodec); err != nil {
+			return
+		}
+		defer compressReader.Close()
+		destReader = compressReader
+	}
+	if t.encryptionKey != nil {
+		var iv []byte
+		if iv, err = crypt.DecodeIV(destInfo.Metadata); err != nil {
+			return
+		}
+		tracker := destReader.(storage.ConfirmedSizeTracker)
+		if destReader, err = crypt.NewReader(destReader, t.encryptionKey, iv, destInfo.Offset); err != nil {
+			return
+		}
+		// CTR keeps the ciphertext the size of the plaintext, so the confirmed bytes map 1:1.
+		destReader = trackedReader{Reader: destReader, tracker: tracker}
+	}
+	destReader = applyMiddlewares(destReader, t.writeMiddlewares)
+	if t.writeBufferSize > 0 {
+		destReader = newWriteBufferReader(destReader, t.writeBufferSize)
+	}
+	if rangeReader, ok := dest.Storage.(storage.RangeReader); ok && t.verificationInterval > 0 {
+		return t.transferVerifiedChunks(ctx, dest, rangeReader, destInfo.Offset, destReader)
+	}
+	var n int64
+	if n, err = dest.Storage.TransferFileChunk(ctx, dest.FilePath, destReader, destInfo.Offset, dest.Client); err != nil {
+		return
+	}
+	t.logger.V(debugLevel).Info("file chunk is transferred",
+		"dstPath", dest.FilePath, "offset", destInfo.Offset, "size", n)
+	return
+}
+
+// getServerSideCopier returns the destination storage if it can copy the source file server-side
+// (see WithServerSideCopy), nil otherwise.
+func (t *transfer) getServerSideCopier(
+	srcInfo xferfile.Info,
+	src SourceConfig,
+	dest DestinationConfig,
+) storage.ServerSideCopier {
+	if !t.serverSideCopy || srcInfo.Size == xferfile.SizeUnknown ||
+		t.compressionCodec != NoneCompressionCodec || t.encryptionKey != nil ||
+		len(t.readerMiddlewares) > 0 || len(t.writeMiddlewares) > 0 {
+		return nil
+	}
+	copier, ok := dest.Storage.(storage.ServerSideCopier)
+	if !ok || !copier.CanCopyFrom(src.Storage, src.Client, dest.Client) {
+		return nil
+	}
+	return copier
+}
+
+// withCancelableProgress returns the progress callback also calling the cancelable callback
+// (see WithCancelableProgress), the transfer is aborted with the error it returns.
+func (t *transfer) withCancelableProgress(
+	cb ProgressUpdatedCallback,
+	abort context.CancelCauseFunc,
+) ProgressUpdatedCallback {
+	return func(progress Progress) {
+		cb(progress)
+		if err := t.cancelableProgress(progress); err != nil {
+			abort(err)
+		}
+	}
+}
+
+// isAborted reports whether the cause of the context cancellation is the error of the cancelable callback.
+func (t *transfer) isAborted(cause error) bool {
+	return t.cancelableProgress != nil && cause != nil &&
+		!errors.Is(cause, context.Canceled) && !errors.Is(cause, context.DeadlineExceeded)
+}
+
+// abortTransfer stops the transfer aborted by the cancelable callback, deleting the
+// partial destination file if configured (see WithDeleteOnAbort).
+func (t *transfer) abortTransfer(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+	abortErr error,
+) (err error) {
+	t.logger.Info("file transfer is aborted by the progress callback",
+		"srcPath", src.FilePath, "dstPath", dest.FilePath, "reason", abortErr.Error())
+	if t.deleteOnAbort {
+		// the context of the transfer is canceled, the deletion must outlive it
+		if err = dest.Storage.DeleteFile(context.WithoutCancel(ctx), dest.FilePath, dest.Client); err != nil {
+			return errors.Join(abortErr, err)
+		}
+	}
+	return abortErr
+}
+
 // getOrCreateDestinationFile gets the destination file info or creates it if it does not exist.
 func (t *transfer) getOrCreateDestinationFile(
 	ctx context.Context,
+	src SourceConfig,
 	dest DestinationConfig,
 	srcInfo xferfile.Info,
 ) (destInfo xferfile.Info, err error) {
 	if destInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
 		// if file does not exist, create it
 		if errors.Is(err, xferfile.ErrFileNotExists) {
-			if err = dest.Storage.CreateFile(
-				ctx,
-				dest.FilePath, srcInfo.Size, srcInfo.ModTime,
-				dest.Client,
-			); err != nil {
+			if err = t.preflightSource(ctx, src, srcInfo); err != nil {
+				return
+			}
+			if err = t.createDestinationFile(ctx, dest, srcInfo); err != nil {
 				return
 			}
 		} else {
@@ -261,6 +1107,19 @@ func (t *transfer) getOrCreateDestinationFile(
 	return
 }
 
+// preflightSource opens and closes the source file when the preflight is enabled (see
+// WithSourcePreflight), so that an unreadable source fails before the destination file is created.
+func (t *transfer) preflightSource(ctx context.Context, src SourceConfig, srcInfo xferfile.Info) (err error) {
+	if !t.sourcePreflight {
+		return
+	}
+	var reader io.ReadCloser
+	if reader, err = getSourceFromOffset(ctx, src, srcInfo, 0); err != nil {
+		return
+	}
+	return reader.Close()
+}
+
 // verifyFileChanges verifies if the source file has been modified and re-creates the destination file.
 func (t *transfer) verifyFileChanges(
 	ctx context.Context,
@@ -269,25 +1128,231 @@ func (t *transfer) verifyFileChanges(
 	destInfo xferfile.Info,
 ) (updatedInfo xferfile.Info, err error) {
 	updatedInfo = destInfo
-	if srcInfo.ModTime.UTC().Equal(destInfo.ModTime.UTC()) {
+	if !t.isSourceModified(srcInfo, destInfo) {
+		if modTimeDifference(srcInfo, destInfo) > 0 {
+			t.logger.Info("source and destination modification times differ within the tolerance, resuming destination file",
+				"srcModTime", srcInfo.ModTime, "dstModTime", destInfo.ModTime, "tolerance", t.modTimeTolerance)
+		}
 		return
 	}
 	t.logger.Info("source file has been modified, re-creating destination file",
 		"srcModTime", srcInfo.ModTime, "dstModTime", destInfo.ModTime,
+		"srcSize", srcInfo.Size, "dstSize", destInfo.Size,
+	)
+	return t.recreateDestinationFile(ctx, dest, srcInfo)
+}
+
+// verifyCompression verifies if the destination file can be resumed with the compression codec
+// and re-creates the destination file otherwise. The offsets of a compressed destination file do
+// not match the offsets of the source file, so a compressed transfer always restarts from the
+// beginning, as well as a destination file compressed with another codec.
+func (t *transfer) verifyCompression(
+	ctx context.Context,
+	dest DestinationConfig,
+	srcInfo xferfile.Info,
+	destInfo xferfile.Info,
+) (updatedInfo xferfile.Info, err error) {
+	updatedInfo = destInfo
+	if t.isResumableCompression(destInfo) {
+		return
+	}
+	t.logger.Info("destination file cannot be resumed with the compression codec, re-creating destination file",
+		"codec", t.compressionCodec.String(), "dstCodec", destInfo.Metadata[compressionMeta],
+		"dstOffset", destInfo.Offset,
 	)
+	return t.recreateDestinationFile(ctx, dest, srcInfo)
+}
+
+// isResumableCompression reports whether the destination file can be resumed with the compression codec.
+func (t *transfer) isResumableCompression(destInfo xferfile.Info) bool {
+	destCodec := destInfo.Metadata[compressionMeta]
+	if destCodec != "" && destCodec != t.compressionCodec.String() {
+		return false
+	}
+	return destInfo.Offset == 0 || (t.compressionCodec == NoneCompressionCodec && destCodec == "")
+}
+
+// verifyEncryption verifies if the destination file can be resumed with the encryption and
+// re-creates the destination file otherwise, the keystream can only be continued with the IV
+// recorded in the info of an encrypted destination file.
+func (t *transfer) verifyEncryption(
+	ctx context.Context,
+	dest DestinationConfig,
+	srcInfo xferfile.Info,
+	destInfo xferfile.Info,
+) (updatedInfo xferfile.Info, err error) {
+	updatedInfo = destInfo
+	if t.isResumableEncryption(destInfo) {
+		return
+	}
+	t.logger.Info("destination file cannot be resumed with the encryption, re-creating destination file",
+		"encrypted", t.encryptionKey != nil, "dstOffset", destInfo.Offset,
+	)
+	return t.recreateDestinationFile(ctx, dest, srcInfo)
+}
+
+// isResumableEncryption reports whether the destination file can be resumed with the encryption.
+func (t *transfer) isResumableEncryption(destInfo xferfile.Info) bool {
+	if t.encryptionKey != nil {
+		_, err := crypt.DecodeIV(destInfo.Metadata)
+		return err == nil
+	}
+	_, encrypted := destInfo.Metadata[crypt.IVMeta]
+	return !encrypted
+}
+
+// recreateDestinationFile deletes and creates the destination file again.
+func (t *transfer) recreateDestinationFile(
+	ctx context.Context,
+	dest DestinationConfig,
+	srcInfo xferfile.Info,
+) (destInfo xferfile.Info, err error) {
 	if err = dest.Storage.DeleteFile(ctx, dest.FilePath, dest.Client); err != nil {
 		return
 	}
-	if err = dest.Storage.CreateFile(
-		ctx,
-		dest.FilePath, srcInfo.Size, srcInfo.ModTime,
-		dest.Client,
-	); err != nil {
+	if err = t.createDestinationFile(ctx, dest, srcInfo); err != nil {
 		return
 	}
 	// get the file info again
-	if updatedInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
+	if destInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
 		return
 	}
 	return
 }
+
+// createDestinationFile creates the destination file, recording the compression codec, the
+// encryption IV, the part layout, the version, the link target and the content type of the source
+// in its info when the destination supports metadata (see storage.MetadataFileCreator).
+func (t *transfer) createDestinationFile(
+	ctx context.Context,
+	dest DestinationConfig,
+	srcInfo xferfile.Info,
+) (err error) {
+	ctx, span := t.startSpan(ctx, createFileSpanName,
+		destPathAttributeKey.String(dest.FilePath), sizeAttributeKey.Int64(srcInfo.Size))
+	defer func() { endSpan(span, err) }()
+
+	size := srcInfo.Size
+	metadata := make(map[string]string)
+	// the part layout of the source only applies to its content as is
+	if partLayout, ok := srcInfo.Metadata[storage.PartLayoutMeta]; ok &&
+		t.compressionCodec == NoneCompressionCodec && t.encryptionKey == nil {
+		metadata[storage.PartLayoutMeta] = partLayout
+	}
+	if version := srcInfo.Metadata[storage.SourceVersionMeta]; version != "" {
+		metadata[storage.SourceVersionMeta] = version
+	}
+	if target, ok := srcInfo.Metadata[storage.SymlinkTargetMeta]; ok {
+		metadata[storage.SymlinkTargetMeta] = target
+	}
+	if contentType := srcInfo.Metadata[storage.ContentTypeMeta]; t.preserveContentType && contentType != "" {
+		metadata[storage.ContentTypeMeta] = contentType
+	}
+	if srcExt, ok := sourceExtension(srcInfo, dest); ok {
+		metadata[storage.SourceExtensionMeta] = srcExt
+	}
+	if t.compressionCodec != NoneCompressionCodec {
+		// the size of the compressed content is only known once it has been fully written
+		size = xferfile.SizeUnknown
+		metadata[compressionMeta] = t.compressionCodec.String()
+	}
+	if t.encryptionKey != nil {
+		var iv []byte
+		if iv, err = crypt.NewIV(); err != nil {
+			return
+		}
+		metadata[crypt.IVMeta] = crypt.EncodeIV(iv)
+	}
+
+	creator, ok := dest.Storage.(storage.MetadataFileCreator)
+	if len(metadata) == 0 || !ok {
+		if t.encryptionKey != nil {
+			return ErrEncryptionMetadataUnsupported
+		}
+		return dest.Storage.CreateFile(
+			ctx,
+			dest.FilePath, size, srcInfo.ModTime,
+			dest.Client,
+		)
+	}
+	return creator.CreateFileWithMetadata(
+		ctx,
+		dest.FilePath, size, srcInfo.ModTime, metadata,
+		dest.Client,
+	)
+}
+
+// skippedProgress returns the progress of a transfer skipped since the destination file is kept as is.
+func skippedProgress(destInfo xferfile.Info) Progress {
+	return Progress{
+		Status:     ProgressStatusFinished,
+		Skipped:    true,
+		Duration:   destInfo.FinishTime.Sub(destInfo.StartTime),
+		StartAt:    destInfo.StartTime,
+		FinishAt:   destInfo.FinishTime,
+		Percentage: finishedProgress,
+	}
+}
+
+// skippedResult returns the result of a transfer skipped since the destination file is kept as is.
+func skippedResult(srcInfo xferfile.Info, destInfo xferfile.Info) TransferResult {
+	return TransferResult{
+		TotalSize:  srcInfo.Size,
+		StartedAt:  destInfo.StartTime,
+		FinishedAt: destInfo.FinishTime,
+		Skipped:    true,
+	}
+}
+
+// isDestinationFinished reports whether the destination file has already been transferred
+// from the source file, as it is now.
+func (t *transfer) isDestinationFinished(srcInfo xferfile.Info, destInfo xferfile.Info) bool {
+	if destInfo.FinishTime.IsZero() || !t.isResumableEncryption(destInfo) || t.isSourceModified(srcInfo, destInfo) {
+		return false
+	}
+	// the size of a compressed destination file does not match the size of the source file
+	if t.compressionCodec != NoneCompressionCodec {
+		return destInfo.Metadata[compressionMeta] == t.compressionCodec.String()
+	}
+	return destInfo.Offset == srcInfo.Size
+}
+
+// modTimeDifference returns the absolute difference between the modification times of the source
+// file and of the destination file.
+func modTimeDifference(srcInfo xferfile.Info, destInfo xferfile.Info) time.Duration {
+	diff := srcInfo.ModTime.Sub(destInfo.ModTime)
+	return max(diff, -diff)
+}
+
+// getSourceFromOffset returns the content of the source file from the offset, read only while the
+// file has the version recorded in its info when the source supports it (see storage.ConditionalReader).
+func getSourceFromOffset(
+	ctx context.Context,
+	src SourceConfig,
+	srcInfo xferfile.Info,
+	offset int64,
+) (reader io.ReadCloser, err error) {
+	conditionalReader, ok := src.Storage.(storage.ConditionalReader)
+	if version := srcInfo.Metadata[storage.SourceVersionMeta]; ok && version != "" {
+		return conditionalReader.GetFileFromOffsetIfMatch(ctx, src.FilePath, offset, version, src.Client)
+	}
+	return src.Storage.GetFileFromOffset(ctx, src.FilePath, offset, src.Client)
+}
+
+// isSourceModified reports whether the source file has been modified since the destination file was created,
+// the size is also compared since the modification time recorded in a stale info file can still match.
+func (t *transfer) isSourceModified(srcInfo xferfile.Info, destInfo xferfile.Info) bool {
+	if modTimeDifference(srcInfo, destInfo) > t.modTimeTolerance {
+		return true
+	}
+	// the version is compared when both are recorded, e.g. an object overwritten within the same second
+	srcVersion, destVersion := srcInfo.Metadata[storage.SourceVersionMeta], destInfo.Metadata[storage.SourceVersionMeta]
+	if srcVersion != "" && destVersion != "" && srcVersion != destVersion {
+		return true
+	}
+	// the size of a compressed destination file does not match the size of the source file
+	if destInfo.Metadata[compressionMeta] != "" {
+		return false
+	}
+	return xferfile.IsSizeChanged(srcInfo.Size, destInfo.Size)
+}
@@ -0,0 +1,63 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// TransferPair is a source file and its destination, transferred by Transfer.TransferAll.
+type TransferPair struct {
+	// Source: see SourceConfig
+	Source SourceConfig `json:"source" yaml:"source"`
+	// Destination: see DestinationConfig
+	Destination DestinationConfig `json:"destination" yaml:"destination"`
+}
+
+// TransferAllProgressCallback is a function that is called when the progress of
+// the transfer of a pair is updated, with the index of the pair in the transferred pairs.
+type TransferAllProgressCallback func(pairIndex int, progress Progress)
+
+func (t *transfer) TransferAll(
+	ctx context.Context,
+	pairs []TransferPair,
+	concurrency int,
+	cb TransferAllProgressCallback,
+) (err error) {
+	// the first failed pair cancels the others, unless the transfer continues on error
+	pairCtx := ctx
+	eg := new(errgroup.Group)
+	if !t.continueOnError {
+		eg, pairCtx = errgroup.WithContext(ctx)
+	}
+	eg.SetLimit(max(concurrency, 1))
+
+	errs := make([]error, len(pairs))
+	for i, pair := range pairs {
+		if pairCtx.Err() != nil {
+			break
+		}
+		eg.Go(func() (err error) {
+			if err = pairCtx.Err(); err != nil {
+				return
+			}
+			pairCb := func(progress Progress) {
+				cb(i, progress)
+			}
+			if err = t.Transfer(pairCtx, pair.Source, pair.Destination, pairCb); err != nil {
+				err = fmt.Errorf("failed to transfer %s: %w", pair.Source.FilePath, err)
+				errs[i] = err
+			}
+			if t.continueOnError {
+				return nil
+			}
+			return
+		})
+	}
+	if err = eg.Wait(); err != nil {
+		return
+	}
+	return errors.Join(append(errs, ctx.Err())...)
+}
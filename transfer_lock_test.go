@@ -0,0 +1,83 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// lockingDestination is a local destination locking its files for the transfers, lockErr fails
+// the lock as if another transfer held it.
+type lockingDestination struct {
+	*local.Destination
+	lockErr       error
+	locked        bool
+	lockedOnFinal bool
+}
+
+func (d *lockingDestination) LockTransfer(context.Context, string, protoc.Client) (unlock func(), err error) {
+	if d.lockErr != nil {
+		return nil, d.lockErr
+	}
+	d.locked = true
+	return func() { d.locked = false }, nil
+}
+
+func (d *lockingDestination) FinalizeTransfer(ctx context.Context, filePath string, cli protoc.Client) error {
+	d.lockedOnFinal = d.locked
+	return d.Destination.FinalizeTransfer(ctx, filePath, cli)
+}
+
+var _ = Describe("Transfer to a destination locking its files", func() {
+	var (
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		destStorage *lockingDestination
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(strings.Repeat("0123456789", 100)), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &lockingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should hold the lock of the file until the transfer returns", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})).To(Succeed())
+
+		Expect(destStorage.lockedOnFinal).To(BeTrue())
+		Expect(destStorage.locked).To(BeFalse())
+		Expect(os.ReadFile(destConfig.FilePath)).To(HaveLen(1000))
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail the transfer to a file locked by another transfer", func(ctx context.Context) {
+		destStorage.lockErr = storage.ErrConcurrentTransfer
+		tfr := fxfer.NewTransfer(GinkgoLogr)
+		err := tfr.Transfer(ctx, srcConfig, destConfig, func(fxfer.Progress) {})
+		Expect(err).To(MatchError(storage.ErrConcurrentTransfer))
+		Expect(filepath.Dir(destConfig.FilePath)).ToNot(BeADirectory())
+	}, NodeTimeout(10*time.Second))
+})
@@ -4,10 +4,19 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/derektruong/fxfer"
 	"github.com/derektruong/fxfer/internal/xferfile"
@@ -18,6 +27,7 @@ import (
 	mock_storage "github.com/derektruong/fxfer/storage/mock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gstruct"
 	"go.uber.org/mock/gomock"
 )
 
@@ -66,28 +76,29 @@ var _ = Describe("transfer", func() {
 			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
 				i.Size = int64(1000)
 				i.Offset = int64(1000)
+				i.ModTime = srcInfo.ModTime
 			})
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(xferfile.Info{}, xferfile.ErrFileNotExists),
 				mockDestStorage.EXPECT().CreateFile(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					srcInfo.Size,
 					srcInfo.ModTime,
 					mockClient,
 				).Return(nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
@@ -96,6 +107,91 @@ var _ = Describe("transfer", func() {
 			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
 		}, NodeTimeout(10*time.Second))
 
+		It("should skip the transfer if the destination is identical", func(ctx context.Context) {
+			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+				i.Size = int64(1000)
+			})
+			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+				i.Size = int64(1000)
+				i.Offset = int64(1000)
+				i.ModTime = srcInfo.ModTime
+			})
+			var progresses []fxfer.Progress
+			callback = func(progress fxfer.Progress) {
+				progresses = append(progresses, progress)
+			}
+
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					srcConfig.FilePath,
+					mockClient,
+				).Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					mockClient,
+				).Return(destInfo, nil),
+			)
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(progresses).To(HaveExactElements(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Status":     Equal(fxfer.ProgressStatusFinished),
+				"Skipped":    BeTrue(),
+				"Percentage": Equal(100),
+				"StartAt":    Equal(destInfo.StartTime),
+				"FinishAt":   Equal(destInfo.FinishTime),
+			})))
+		}, NodeTimeout(10*time.Second))
+
+		It("should report the bytes transferred before the chunk failed", func(ctx context.Context) {
+			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+				i.Size = int64(774)
+			})
+			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+				i.Size = int64(774)
+				i.Offset = int64(700)
+				i.ModTime = srcInfo.ModTime
+			})
+			var (
+				mu              sync.Mutex
+				errorProgresses []fxfer.Progress
+			)
+			callback = func(progress fxfer.Progress) {
+				if progress.Status == fxfer.ProgressStatusInError {
+					mu.Lock()
+					defer mu.Unlock()
+					errorProgresses = append(errorProgresses, progress)
+				}
+			}
+			chunkErr := gofakeit.Error()
+
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).Return(destInfo, nil),
+				mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcConfig.FilePath, int64(700), mockClient).
+					Return(io.NopCloser(strings.NewReader(strings.Repeat("x", 74))), nil),
+				mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), destConfig.FilePath, gomock.Any(), int64(700), mockClient).
+					DoAndReturn(func(_ context.Context, _ string, reader io.Reader, _ int64, _ protoc.Client) (int64, error) {
+						n, err := io.CopyN(io.Discard, reader, 30)
+						Expect(err).ToNot(HaveOccurred())
+						return n, chunkErr
+					}),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(chunkErr))
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(errorProgresses).To(HaveExactElements(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Error":           MatchError(chunkErr),
+				"TotalSize":       Equal(int64(774)),
+				"TransferredSize": Equal(int64(730)),
+				"ConfirmedSize":   Equal(int64(700)),
+				"Offset":          Equal(int64(700)),
+			})))
+		}, NodeTimeout(10*time.Second))
+
 		It("should start over the transfer if the destination modification time is different", func(ctx context.Context) {
 			modTime := time.Now()
 			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
@@ -110,22 +206,22 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockDestStorage.EXPECT().DeleteFile(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(nil),
 				mockDestStorage.EXPECT().CreateFile(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					srcInfo.Size,
 					srcInfo.ModTime,
@@ -133,7 +229,7 @@ var _ = Describe("transfer", func() {
 				).Return(nil),
 				mockDestStorage.EXPECT().
 					GetFileInfo(
-						gomock.AssignableToTypeOf(ctx),
+						gomock.AssignableToTypeOf(contextType),
 						destConfig.FilePath,
 						mockClient,
 					).
@@ -166,29 +262,29 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(xferfile.Info{}, xferfile.ErrFileNotExists),
 				mockDestStorage.EXPECT().CreateFile(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					srcInfo.Size,
 					srcInfo.ModTime,
 					mockClient,
 				).Return(nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockSrcStorage.EXPECT().GetFileFromOffset(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					int64(0),
 					mockClient,
@@ -201,14 +297,14 @@ var _ = Describe("transfer", func() {
 					return readCloser, nil
 				}),
 				mockDestStorage.EXPECT().TransferFileChunk(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					gomock.Any(),
 					int64(0),
 					mockClient,
 				).Return(int64(1000), nil),
 				mockDestStorage.EXPECT().FinalizeTransfer(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(nil),
@@ -220,11 +316,11 @@ var _ = Describe("transfer", func() {
 		It("should transfer the file from the offset successfully", func(ctx context.Context) {
 			modTime := time.Now()
 			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.ModTime = modTime
 			})
 			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.Offset = int64(700)
 				i.ModTime = modTime
 			})
@@ -236,17 +332,17 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockSrcStorage.EXPECT().GetFileFromOffset(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					int64(700),
 					mockClient,
@@ -259,14 +355,14 @@ var _ = Describe("transfer", func() {
 					return readCloser, nil
 				}),
 				mockDestStorage.EXPECT().TransferFileChunk(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					gomock.Any(),
 					int64(700),
 					mockClient,
 				).Return(int64(300), nil),
 				mockDestStorage.EXPECT().FinalizeTransfer(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(nil),
@@ -278,11 +374,11 @@ var _ = Describe("transfer", func() {
 		It("should delete file when transfer cannot finish", func(ctx context.Context) {
 			modTime := time.Now()
 			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(74)
+				i.Size = int64(774)
 				i.ModTime = modTime
 			})
 			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.Offset = int64(700)
 				i.ModTime = modTime
 			})
@@ -294,17 +390,17 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockSrcStorage.EXPECT().GetFileFromOffset(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					int64(700),
 					mockClient,
@@ -317,7 +413,7 @@ var _ = Describe("transfer", func() {
 					return readCloser, nil
 				}),
 				mockDestStorage.EXPECT().TransferFileChunk(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					gomock.Any(),
 					int64(700),
@@ -328,12 +424,12 @@ var _ = Describe("transfer", func() {
 					Expect(err).NotTo(HaveOccurred())
 				}).Return(int64(300), nil),
 				mockDestStorage.EXPECT().FinalizeTransfer(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(storage.ErrFileOrObjectCannotFinalize),
 				mockDestStorage.EXPECT().DeleteFile(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(nil),
@@ -344,6 +440,931 @@ var _ = Describe("transfer", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 
+	Describe("TransferStdin", func() {
+		var stdinContent string
+
+		BeforeEach(func() {
+			stdinContent = gofakeit.Sentence(50)
+			stdinReader, stdinWriter, err := os.Pipe()
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer stdinWriter.Close()
+				_, _ = io.Copy(stdinWriter, bytes.NewBufferString(stdinContent))
+			}()
+
+			originalStdin := os.Stdin
+			os.Stdin = stdinReader
+			DeferCleanup(func() {
+				os.Stdin = originalStdin
+				_ = stdinReader.Close()
+			})
+		})
+
+		It("should stream the content of unknown size to the destination", func(ctx context.Context) {
+			modTime := time.Now()
+			var received bytes.Buffer
+			var lastProgress fxfer.Progress
+			callback = func(progress fxfer.Progress) {
+				lastProgress = progress
+			}
+
+			gomock.InOrder(
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					mockClient,
+				).Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+				mockDestStorage.EXPECT().CreateFile(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					fxfer.SizeUnknown,
+					modTime,
+					mockClient,
+				).Return(nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					mockClient,
+				).Return(xferfile.Info{
+					Path:    destConfig.FilePath,
+					Size:    fxfer.SizeUnknown,
+					ModTime: modTime,
+				}, nil),
+				mockDestStorage.EXPECT().TransferFileChunk(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					gomock.Any(),
+					int64(0),
+					mockClient,
+				).DoAndReturn(func(
+					ctx context.Context,
+					path string,
+					reader io.Reader,
+					offset int64,
+					client protoc.Client,
+				) (int64, error) {
+					return io.Copy(&received, reader)
+				}),
+				mockDestStorage.EXPECT().FinalizeTransfer(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					mockClient,
+				).Return(nil),
+			)
+
+			Expect(tfr.TransferStdin(ctx, fxfer.SizeUnknown, modTime, destConfig, callback)).To(Succeed())
+			Expect(received.String()).To(Equal(stdinContent))
+			Expect(lastProgress.Status).To(Equal(fxfer.ProgressStatusFinished))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferDirectory", func() {
+		var srcFiles []xferfile.Info
+
+		BeforeEach(func() {
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(),
+				fxfer.WithExtensionBlacklist("exe"),
+			)
+			srcConfig.FilePath = "src-dir"
+			destConfig.FilePath = "dest-dir"
+			modTime := time.Now()
+			srcFiles = []xferfile.Info{
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "src-dir/nested/b.txt", "txt", 74, modTime
+				}),
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "src-dir/c.exe", "exe", 74, modTime
+				}),
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "src-dir/a.txt", "txt", 74, modTime
+				}),
+			}
+		})
+
+		It("should stop at the first failed file", func(ctx context.Context) {
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().ListFiles(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir",
+					mockClient,
+				).Return(srcFiles, nil),
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir/nested/b.txt",
+					mockClient,
+				).Return(xferfile.Info{}, errors.New("source is unavailable")),
+			)
+
+			Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, callback)).
+				To(MatchError("source is unavailable"))
+		}, NodeTimeout(10*time.Second))
+
+		It("should continue past failed files and preserve relative paths", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(),
+				fxfer.WithExtensionBlacklist("exe"),
+				fxfer.WithContinueOnError(),
+			)
+			var finishedProgress fxfer.Progress
+			callback = func(progress fxfer.Progress) {
+				if progress.Status == fxfer.ProgressStatusFinished {
+					finishedProgress = progress
+				}
+			}
+			destInfo = srcFiles[2]
+			destInfo.Path, destInfo.Offset, destInfo.FinishTime = "dest-dir/a.txt", 0, time.Time{}
+
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().ListFiles(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir",
+					mockClient,
+				).Return(srcFiles, nil),
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir/nested/b.txt",
+					mockClient,
+				).Return(xferfile.Info{}, errors.New("source is unavailable")),
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir/a.txt",
+					mockClient,
+				).Return(srcFiles[2], nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"dest-dir/a.txt",
+					mockClient,
+				).Return(destInfo, nil),
+				mockSrcStorage.EXPECT().GetFileFromOffset(
+					gomock.AssignableToTypeOf(contextType),
+					"src-dir/a.txt",
+					int64(0),
+					mockClient,
+				).Return(io.NopCloser(strings.NewReader(
+					"Lorem Ipsum is simply dummy text of the printing and typesetting industry.",
+				)), nil),
+				mockDestStorage.EXPECT().TransferFileChunk(
+					gomock.AssignableToTypeOf(contextType),
+					"dest-dir/a.txt",
+					gomock.Any(),
+					int64(0),
+					mockClient,
+				).Return(int64(74), nil),
+				mockDestStorage.EXPECT().FinalizeTransfer(
+					gomock.AssignableToTypeOf(contextType),
+					"dest-dir/a.txt",
+					mockClient,
+				).Return(nil),
+			)
+
+			err := tfr.TransferDirectory(ctx, srcConfig, destConfig, callback)
+			Expect(err).To(MatchError(ContainSubstring("failed to transfer src-dir/nested/b.txt")))
+			Expect(finishedProgress.BatchProgress).To(Equal(fxfer.BatchProgress{
+				CurrentFile:    "src-dir/a.txt",
+				FilesCompleted: 0,
+				TotalFiles:     2,
+				BytesCompleted: 74,
+				TotalBytes:     148,
+			}))
+		}, NodeTimeout(10*time.Second))
+
+		It("should fetch the file infos concurrently and collect their errors", func(ctx context.Context) {
+			const concurrency = 4
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(),
+				fxfer.WithDryRun(),
+				fxfer.WithContinueOnError(),
+				fxfer.WithEnumerationConcurrency(concurrency),
+			)
+			var dryRunFiles atomic.Int64
+			callback = func(progress fxfer.Progress) {
+				if progress.Status == fxfer.ProgressStatusDryRun {
+					dryRunFiles.Add(1)
+				}
+			}
+
+			modTime := time.Now()
+			srcFiles = make([]xferfile.Info, 20)
+			for i := range srcFiles {
+				srcFiles[i] = xferfiletest.InfoFactory(func(info *xferfile.Info) {
+					info.Path, info.Extension, info.Size, info.ModTime = fmt.Sprintf("src-dir/%02d.txt", i), "txt", 74, modTime
+				})
+			}
+
+			var inFlight, maxInFlight atomic.Int64
+			mockSrcStorage.EXPECT().ListFiles(gomock.Any(), "src-dir", mockClient).Return(srcFiles, nil)
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				DoAndReturn(func(ctx context.Context, path string, client protoc.Client) (xferfile.Info, error) {
+					n := inFlight.Add(1)
+					defer inFlight.Add(-1)
+					for {
+						if m := maxInFlight.Load(); n <= m || maxInFlight.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+
+					var i int
+					_, err := fmt.Sscanf(path, "src-dir/%02d.txt", &i)
+					Expect(err).ToNot(HaveOccurred())
+					if i%5 == 0 {
+						return xferfile.Info{}, errors.New("source is unavailable")
+					}
+					return srcFiles[i], nil
+				}).Times(len(srcFiles))
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists).Times(16)
+
+			err := tfr.TransferDirectory(ctx, srcConfig, destConfig, callback)
+			Expect(maxInFlight.Load()).To(BeNumerically("<=", concurrency))
+			Expect(maxInFlight.Load()).To(BeNumerically(">", 1))
+			for _, i := range []int{0, 5, 10, 15} {
+				Expect(err).To(MatchError(ContainSubstring("failed to transfer src-dir/%02d.txt: source is unavailable", i)))
+			}
+			Expect(dryRunFiles.Load()).To(Equal(int64(16)))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferDirectory with files of varying sizes", func() {
+		It("should report the files and the bytes completed", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDryRun())
+			srcConfig.FilePath = "src-dir"
+			destConfig.FilePath = "dest-dir"
+			modTime := time.Now()
+			srcFiles := make([]xferfile.Info, 0, 3)
+			for i, size := range []int64{10, 1000, 90} {
+				srcFiles = append(srcFiles, xferfiletest.InfoFactory(func(info *xferfile.Info) {
+					info.Path, info.Extension, info.Size, info.ModTime = fmt.Sprintf("src-dir/%d.txt", i), "txt", size, modTime
+				}))
+			}
+			var batchProgresses []fxfer.BatchProgress
+			callback = func(progress fxfer.Progress) {
+				batchProgresses = append(batchProgresses, progress.BatchProgress)
+			}
+
+			mockSrcStorage.EXPECT().ListFiles(gomock.Any(), "src-dir", mockClient).Return(srcFiles, nil)
+			for _, srcFile := range srcFiles {
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcFile.Path, mockClient).Return(srcFile, nil)
+			}
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists).Times(len(srcFiles))
+
+			Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(batchProgresses).To(HaveExactElements(
+				fxfer.BatchProgress{CurrentFile: "src-dir/0.txt", TotalFiles: 3, TotalBytes: 1100},
+				fxfer.BatchProgress{
+					CurrentFile: "src-dir/1.txt", FilesCompleted: 1, TotalFiles: 3,
+					BytesCompleted: 10, TotalBytes: 1100,
+				},
+				fxfer.BatchProgress{
+					CurrentFile: "src-dir/2.txt", FilesCompleted: 2, TotalFiles: 3,
+					BytesCompleted: 1010, TotalBytes: 1100,
+				},
+			))
+			Expect(batchProgresses[1].WeightedPercentage()).To(Equal(17))
+			Expect(batchProgresses[2].WeightedPercentage()).To(Equal(79))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferGlob", func() {
+		BeforeEach(func() {
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(),
+				fxfer.WithDryRun(),
+				fxfer.WithExtensionBlacklist("gz"),
+			)
+			srcConfig.FilePath = "logs/2024-*/app-*"
+			destConfig.FilePath = "dest-dir"
+		})
+
+		It("should transfer the matching files preserving their relative paths", func(ctx context.Context) {
+			modTime := time.Now()
+			srcFiles := []xferfile.Info{
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "logs/2024-01/app-1.log", "log", 74, modTime
+				}),
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "logs/2024-01/app-0.gz", "gz", 74, modTime
+				}),
+				xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Path, i.Extension, i.Size, i.ModTime = "logs/2024-02/app-2.log", "log", 74, modTime
+				}),
+			}
+			var batchProgresses []fxfer.BatchProgress
+			callback = func(progress fxfer.Progress) {
+				batchProgresses = append(batchProgresses, progress.BatchProgress)
+			}
+
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().Glob(
+					gomock.AssignableToTypeOf(contextType),
+					"logs/2024-*/app-*",
+					mockClient,
+				).Return(srcFiles, nil),
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"logs/2024-01/app-1.log",
+					mockClient,
+				).Return(srcFiles[0], nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"dest-dir/2024-01/app-1.log",
+					mockClient,
+				).Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"logs/2024-02/app-2.log",
+					mockClient,
+				).Return(srcFiles[2], nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					"dest-dir/2024-02/app-2.log",
+					mockClient,
+				).Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+			)
+
+			Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(batchProgresses).To(HaveExactElements(
+				fxfer.BatchProgress{
+					CurrentFile: "logs/2024-01/app-1.log", FilesCompleted: 0, TotalFiles: 2, TotalBytes: 148,
+				},
+				fxfer.BatchProgress{
+					CurrentFile: "logs/2024-02/app-2.log", FilesCompleted: 1, TotalFiles: 2,
+					BytesCompleted: 74, TotalBytes: 148,
+				},
+			))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if no file matches the pattern", func(ctx context.Context) {
+			mockSrcStorage.EXPECT().Glob(gomock.AssignableToTypeOf(contextType), "logs/2024-*/app-*", mockClient).
+				Return(nil, nil)
+
+			Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, callback)).
+				To(MatchError(fxfer.ErrGlobNoMatch))
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error if the pattern is malformed", func(ctx context.Context) {
+			mockSrcStorage.EXPECT().Glob(gomock.AssignableToTypeOf(contextType), "logs/2024-*/app-*", mockClient).
+				Return(nil, path.ErrBadPattern)
+
+			Expect(tfr.TransferGlob(ctx, srcConfig, destConfig, callback)).
+				To(MatchError(path.ErrBadPattern))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Describe("TransferAll", func() {
+		var (
+			pairs      []fxfer.TransferPair
+			pairSrcs   []xferfile.Info
+			pairErr    = errors.New("source is unavailable")
+			dryRunIdxs sync.Map
+			allCb      fxfer.TransferAllProgressCallback
+		)
+
+		BeforeEach(func() {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDryRun())
+			modTime := time.Now()
+			pairs = make([]fxfer.TransferPair, 10)
+			pairSrcs = make([]xferfile.Info, len(pairs))
+			for i := range pairs {
+				pairs[i] = fxfer.TransferPair{Source: srcConfig, Destination: destConfig}
+				pairs[i].Source.FilePath = fmt.Sprintf("src-dir/%02d.txt", i)
+				pairs[i].Destination.FilePath = fmt.Sprintf("dest-dir/%02d.txt", i)
+				pairSrcs[i] = xferfiletest.InfoFactory(func(info *xferfile.Info) {
+					info.Path, info.Extension, info.Size, info.ModTime = pairs[i].Source.FilePath, "txt", 74, modTime
+				})
+			}
+			dryRunIdxs = sync.Map{}
+			allCb = func(pairIndex int, progress fxfer.Progress) {
+				if progress.Status == fxfer.ProgressStatusDryRun {
+					dryRunIdxs.Store(pairIndex, struct{}{})
+				}
+			}
+		})
+
+		dryRunPairs := func() (idxs []int) {
+			dryRunIdxs.Range(func(key, value any) bool {
+				idxs = append(idxs, key.(int))
+				return true
+			})
+			return
+		}
+
+		It("should transfer up to concurrency pairs at once", func(ctx context.Context) {
+			const concurrency = 3
+			var inFlight, maxInFlight atomic.Int64
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				DoAndReturn(func(ctx context.Context, path string, client protoc.Client) (xferfile.Info, error) {
+					n := inFlight.Add(1)
+					defer inFlight.Add(-1)
+					for {
+						if m := maxInFlight.Load(); n <= m || maxInFlight.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+
+					var i int
+					_, err := fmt.Sscanf(path, "src-dir/%02d.txt", &i)
+					Expect(err).ToNot(HaveOccurred())
+					return pairSrcs[i], nil
+				}).Times(len(pairs))
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists).Times(len(pairs))
+
+			Expect(tfr.TransferAll(ctx, pairs, concurrency, allCb)).To(Succeed())
+			Expect(maxInFlight.Load()).To(BeNumerically("<=", concurrency))
+			Expect(maxInFlight.Load()).To(BeNumerically(">", 1))
+			Expect(dryRunPairs()).To(ConsistOf(0, 1, 2, 3, 4, 5, 6, 7, 8, 9))
+		}, NodeTimeout(10*time.Second))
+
+		It("should stop at the first failed pair", func(ctx context.Context) {
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), "src-dir/00.txt", mockClient).
+				Return(xferfile.Info{}, pairErr)
+
+			err := tfr.TransferAll(ctx, pairs, 1, allCb)
+			Expect(err).To(MatchError(pairErr))
+			Expect(err).To(MatchError(ContainSubstring("failed to transfer src-dir/00.txt")))
+			Expect(dryRunPairs()).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+
+		It("should continue past failed pairs and collect their errors", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(),
+				fxfer.WithDryRun(),
+				fxfer.WithContinueOnError(),
+			)
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				DoAndReturn(func(ctx context.Context, path string, client protoc.Client) (xferfile.Info, error) {
+					var i int
+					_, err := fmt.Sscanf(path, "src-dir/%02d.txt", &i)
+					Expect(err).ToNot(HaveOccurred())
+					if i%4 == 0 {
+						return xferfile.Info{}, pairErr
+					}
+					return pairSrcs[i], nil
+				}).Times(len(pairs))
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), gomock.Any(), mockClient).
+				Return(xferfile.Info{}, xferfile.ErrFileNotExists).Times(7)
+
+			err := tfr.TransferAll(ctx, pairs, 4, allCb)
+			for _, i := range []int{0, 4, 8} {
+				Expect(err).To(MatchError(ContainSubstring("failed to transfer src-dir/%02d.txt: source is unavailable", i)))
+			}
+			Expect(dryRunPairs()).To(ConsistOf(1, 2, 3, 5, 6, 7, 9))
+		}, NodeTimeout(10*time.Second))
+
+		It("should not transfer any pair when the context is canceled", func(ctx context.Context) {
+			canceledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			Expect(tfr.TransferAll(canceledCtx, pairs, 4, allCb)).To(MatchError(context.Canceled))
+			Expect(dryRunPairs()).To(BeEmpty())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Context("Transfer with dry-run", func() {
+		var lastProgress fxfer.Progress
+
+		BeforeEach(func() {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDryRun())
+			callback = func(progress fxfer.Progress) {
+				lastProgress = progress
+			}
+			srcInfo.Size = 1000
+		})
+
+		DescribeTable("should report the would-be action without writing to the destination",
+			func(ctx context.Context, editDestFn func(*xferfile.Info), destErr error, action fxfer.DryRunAction, offset int64) {
+				destInfo.Size = srcInfo.Size
+				destInfo.ModTime = srcInfo.ModTime
+				if editDestFn != nil {
+					editDestFn(&destInfo)
+				}
+				// any call to the write methods of the destination fails the test
+				gomock.InOrder(
+					mockSrcStorage.EXPECT().GetFileInfo(
+						gomock.AssignableToTypeOf(contextType),
+						srcConfig.FilePath,
+						mockClient,
+					).Return(srcInfo, nil),
+					mockDestStorage.EXPECT().GetFileInfo(
+						gomock.AssignableToTypeOf(contextType),
+						destConfig.FilePath,
+						mockClient,
+					).Return(destInfo, destErr),
+				)
+
+				Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+				Expect(lastProgress.Status).To(Equal(fxfer.ProgressStatusDryRun))
+				Expect(lastProgress.TotalSize).To(Equal(srcInfo.Size))
+				Expect(lastProgress.TransferredSize).To(Equal(offset))
+				Expect(lastProgress.DryRun).To(gstruct.PointTo(gstruct.MatchAllFields(gstruct.Fields{
+					"Action":          Equal(action),
+					"DestinationPath": Equal(destConfig.FilePath),
+					"TotalSize":       Equal(srcInfo.Size),
+					"Offset":          Equal(offset),
+				})))
+			},
+			Entry("destination does not exist", nil, xferfile.ErrFileNotExists, fxfer.DryRunActionStart, int64(0)),
+			Entry("destination is incomplete", func(i *xferfile.Info) {
+				i.Offset = 700
+				i.FinishTime = time.Time{}
+			}, nil, fxfer.DryRunActionResume, int64(700)),
+			Entry("source has been modified", func(i *xferfile.Info) {
+				i.Offset = 700
+				i.ModTime = i.ModTime.Add(-time.Minute)
+			}, nil, fxfer.DryRunActionRestart, int64(0)),
+			Entry("source size has changed", func(i *xferfile.Info) {
+				i.Offset = 700
+				i.Size = 800
+				i.FinishTime = time.Time{}
+			}, nil, fxfer.DryRunActionRestart, int64(0)),
+			Entry("destination is finished", func(i *xferfile.Info) {
+				i.Offset = 1000
+			}, nil, fxfer.DryRunActionSkip, int64(1000)),
+		)
+
+		It("should return error if the destination info cannot be fetched", func(ctx context.Context) {
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					srcConfig.FilePath,
+					mockClient,
+				).Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(
+					gomock.AssignableToTypeOf(contextType),
+					destConfig.FilePath,
+					mockClient,
+				).Return(xferfile.Info{}, errors.New("destination is unavailable")),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).
+				To(MatchError("destination is unavailable"))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Context("Transfer with server-side copy", func() {
+		var mockCopier *mock_storage.MockServerSideCopier
+
+		BeforeEach(func() {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithServerSideCopy())
+			mockCopier = mock_storage.NewMockServerSideCopier(mockCtrl)
+			destConfig.Storage = copierDestination{mockDestStorage, mockCopier}
+			modTime := time.Now()
+			srcInfo.Size, srcInfo.ModTime = 1000, modTime
+			destInfo.Size, destInfo.ModTime, destInfo.Offset = 1000, modTime, 700
+		})
+
+		It("should copy the file server-side and report the copied bytes", func(ctx context.Context) {
+			var transferredSize, confirmedSize atomic.Int64
+			callback = func(progress fxfer.Progress) {
+				if progress.TransferredSize > 0 {
+					transferredSize.Store(progress.TransferredSize)
+					confirmedSize.Store(progress.ConfirmedSize)
+				}
+			}
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDisabledRetry(), fxfer.WithServerSideCopy(),
+				fxfer.WithProgressRefreshInterval(10*time.Millisecond))
+
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(destInfo, nil),
+				mockCopier.EXPECT().CanCopyFrom(mockSrcStorage, mockClient, mockClient).Return(true),
+				mockCopier.EXPECT().CopyFileFrom(
+					gomock.Any(),
+					destConfig.FilePath, srcConfig.FilePath, mockClient,
+					int64(700),
+					mockClient,
+					gomock.Any(),
+				).DoAndReturn(func(
+					ctx context.Context,
+					filePath, srcPath string, srcClient protoc.Client,
+					offset int64,
+					client protoc.Client,
+					tracker storage.ConfirmedSizeTracker,
+				) (int64, error) {
+					tracker.AddConfirmedSize(200)
+					tracker.AddConfirmedSize(100)
+					return 300, nil
+				}),
+				mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(nil),
+			)
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Eventually(transferredSize.Load).Should(Equal(int64(1000)))
+			Expect(confirmedSize.Load()).To(Equal(int64(1000)))
+		}, NodeTimeout(10*time.Second))
+
+		It("should hold the server-side copy between its parts while paused", func(ctx context.Context) {
+			var copiedParts atomic.Int32
+			firstCopied, paused := make(chan struct{}), make(chan struct{})
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(destInfo, nil),
+				mockCopier.EXPECT().CanCopyFrom(mockSrcStorage, mockClient, mockClient).Return(true),
+				mockCopier.EXPECT().CopyFileFrom(
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+				).DoAndReturn(func(
+					ctx context.Context,
+					filePath, srcPath string, srcClient protoc.Client,
+					offset int64,
+					client protoc.Client,
+					tracker storage.ConfirmedSizeTracker,
+				) (int64, error) {
+					// the destination copies 3 parts, the transferer is paused after the first one
+					pauser, ok := tracker.(storage.CopyPauser)
+					Expect(ok).To(BeTrue())
+					for part := range 3 {
+						if err := pauser.WaitToCopy(ctx); err != nil {
+							return 0, err
+						}
+						copiedParts.Add(1)
+						tracker.AddConfirmedSize(100)
+						if part == 0 {
+							close(firstCopied)
+							<-paused
+						}
+					}
+					return 300, nil
+				}),
+				mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(nil),
+			)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- tfr.Transfer(ctx, srcConfig, destConfig, callback)
+			}()
+			Eventually(firstCopied).Should(BeClosed())
+			tfr.Pause()
+			close(paused)
+			Consistently(copiedParts.Load, 200*time.Millisecond).Should(BeEquivalentTo(1))
+
+			tfr.Resume()
+			Eventually(done).Should(Receive(BeNil()))
+			Expect(copiedParts.Load()).To(BeEquivalentTo(3))
+		}, NodeTimeout(10*time.Second))
+
+		It("should stream the file when the destination cannot copy from the source", func(ctx context.Context) {
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(destInfo, nil),
+				mockCopier.EXPECT().CanCopyFrom(mockSrcStorage, mockClient, mockClient).Return(false),
+				mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcConfig.FilePath, int64(700), mockClient).
+					Return(io.NopCloser(strings.NewReader("content")), nil),
+				mockDestStorage.EXPECT().TransferFileChunk(
+					gomock.Any(), destConfig.FilePath, gomock.Any(), int64(700), mockClient,
+				).Return(int64(300), nil),
+				mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(nil),
+			)
+			mockCopier.EXPECT().CopyFileFrom(
+				gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+			).Times(0)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Context("Transfer with validator", func() {
+		var (
+			validatedInfo       xferfile.Info
+			validatedDestExists bool
+			validatorErr        error
+		)
+
+		BeforeEach(func() {
+			validatorErr = nil
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithValidator(
+				func(ctx context.Context, srcInfo fxfer.FileInfo, destExists bool) error {
+					validatedInfo, validatedDestExists = srcInfo, destExists
+					return validatorErr
+				},
+			))
+		})
+
+		It("should abort the transfer with the error of the validator", func(ctx context.Context) {
+			validatorErr = errors.New("file name collides with an existing file")
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(validatorErr))
+			Expect(validatedInfo).To(Equal(srcInfo))
+			Expect(validatedDestExists).To(BeFalse())
+		}, NodeTimeout(10*time.Second))
+
+		It("should continue the transfer when the validator passes", func(ctx context.Context) {
+			destInfo.Size, destInfo.Offset, destInfo.ModTime = srcInfo.Size, srcInfo.Size, srcInfo.ModTime
+			destInfo.FinishTime = time.Now()
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(destInfo, nil).Times(2),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+			Expect(validatedInfo).To(Equal(srcInfo))
+			Expect(validatedDestExists).To(BeTrue())
+		}, NodeTimeout(10*time.Second))
+
+		It("should not run the validator when the file rules are not satisfied", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithMaxFileSize(1), fxfer.WithValidator(
+				func(ctx context.Context, srcInfo fxfer.FileInfo, destExists bool) error {
+					Fail("validator should not run")
+					return nil
+				},
+			))
+			srcInfo.Size = 2
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+				Return(srcInfo, nil)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Context("Transfer with destination key function", func() {
+		var destPath string
+
+		BeforeEach(func() {
+			keyFunc := func(srcPath string) string {
+				return path.Join("2026-10-15", strings.ToLower(srcPath))
+			}
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithDestinationKeyFunc(keyFunc))
+			srcConfig.FilePath = "Src-Dir/Report.TXT"
+			destPath = "2026-10-15/src-dir/report.txt"
+			srcInfo.Path, srcInfo.Extension, srcInfo.Size = srcConfig.FilePath, "TXT", 74
+			destInfo.Path, destInfo.Extension, destInfo.Size = destPath, "txt", 74
+			destInfo.ModTime, destInfo.Offset, destInfo.FinishTime = srcInfo.ModTime, 0, time.Time{}
+		})
+
+		It("should use the derived path for every operation on the destination", func(ctx context.Context) {
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destPath, mockClient).
+					Return(xferfile.Info{}, xferfile.ErrFileNotExists),
+				mockDestStorage.EXPECT().CreateFile(gomock.Any(), destPath, srcInfo.Size, srcInfo.ModTime, mockClient).
+					Return(nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destPath, mockClient).
+					Return(destInfo, nil),
+				mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcConfig.FilePath, int64(0), mockClient).
+					Return(io.NopCloser(strings.NewReader(
+						"Lorem Ipsum is simply dummy text of the printing and typesetting industry.",
+					)), nil),
+				mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), destPath, gomock.Any(), int64(0), mockClient).
+					Return(int64(74), nil),
+				mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), destPath, mockClient).
+					Return(nil),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should re-create the destination file at the derived path", func(ctx context.Context) {
+			destInfo.ModTime = srcInfo.ModTime.Add(-time.Minute)
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destPath, mockClient).
+					Return(destInfo, nil),
+				mockDestStorage.EXPECT().DeleteFile(gomock.Any(), destPath, mockClient).
+					Return(nil),
+				mockDestStorage.EXPECT().CreateFile(gomock.Any(), destPath, srcInfo.Size, srcInfo.ModTime, mockClient).
+					Return(errors.New("error for skipping all other calls, just in test")),
+			)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(HaveOccurred())
+		}, NodeTimeout(10*time.Second))
+
+		It("should derive the path of each file of a directory", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr,
+				fxfer.WithDryRun(),
+				fxfer.WithDestinationKeyFunc(func(srcPath string) string {
+					return "archive/" + path.Base(srcPath)
+				}),
+			)
+			srcConfig.FilePath = "src-dir"
+			srcFiles := []xferfile.Info{
+				xferfiletest.InfoFactory(func(i *xferfile.Info) { i.Path, i.Extension = "src-dir/a.txt", "txt" }),
+				xferfiletest.InfoFactory(func(i *xferfile.Info) { i.Path, i.Extension = "src-dir/nested/b.txt", "txt" }),
+			}
+			mockSrcStorage.EXPECT().ListFiles(gomock.Any(), "src-dir", mockClient).Return(srcFiles, nil)
+			for _, srcFile := range srcFiles {
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcFile.Path, mockClient).Return(srcFile, nil)
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), "archive/"+path.Base(srcFile.Path), mockClient).
+					Return(xferfile.Info{}, xferfile.ErrFileNotExists)
+			}
+
+			Expect(tfr.TransferDirectory(ctx, srcConfig, destConfig, callback)).To(Succeed())
+		}, NodeTimeout(10*time.Second))
+
+		It("should return error when the derived path is empty", func(ctx context.Context) {
+			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithDestinationKeyFunc(func(string) string { return "" }))
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+				Return(srcInfo, nil)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(fxfer.ErrDestinationKeyEmpty))
+		}, NodeTimeout(10*time.Second))
+	})
+
+	Context("Transfer with cancelable progress", func() {
+		var (
+			abortErr   error
+			content    string
+			chunkReads atomic.Int64
+		)
+
+		BeforeEach(func() {
+			abortErr = errors.New("canceled by the user")
+			content = strings.Repeat("0123456789", 100)
+			chunkReads.Store(0)
+			srcInfo.Size, destInfo.Size = int64(len(content)), int64(len(content))
+			destInfo.ModTime, destInfo.Offset = srcInfo.ModTime, 0
+		})
+
+		newCancelableTransfer := func(opts ...fxfer.TransferOption) fxfer.Transfer {
+			return fxfer.NewTransfer(GinkgoLogr, append([]fxfer.TransferOption{
+				fxfer.WithProgressRefreshInterval(5 * time.Millisecond),
+				fxfer.WithCancelableProgress(func(progress fxfer.Progress) error {
+					if progress.Percentage >= 50 {
+						return abortErr
+					}
+					return nil
+				}),
+			}, opts...)...)
+		}
+
+		expectTransferUntilAborted := func(ctx context.Context) {
+			GinkgoHelper()
+			gomock.InOrder(
+				mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).
+					Return(srcInfo, nil),
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(destInfo, nil),
+				mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcConfig.FilePath, int64(0), mockClient).
+					Return(io.NopCloser(strings.NewReader(content)), nil),
+				mockDestStorage.EXPECT().TransferFileChunk(
+					gomock.Any(), destConfig.FilePath, gomock.Any(), int64(0), mockClient,
+				).DoAndReturn(func(
+					ctx context.Context,
+					filePath string,
+					reader io.Reader,
+					offset int64,
+					client protoc.Client,
+				) (n int64, err error) {
+					// read slowly, so that the progress is reported along the way
+					buf := make([]byte, 10)
+					for {
+						var read int
+						read, err = reader.Read(buf)
+						n += int64(read)
+						chunkReads.Add(1)
+						if err != nil {
+							return
+						}
+						time.Sleep(2 * time.Millisecond)
+					}
+				}),
+			)
+			mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		}
+
+		It("should stop the transfer promptly with the error of the callback", func(ctx context.Context) {
+			tfr = newCancelableTransfer()
+			expectTransferUntilAborted(ctx)
+			mockDestStorage.EXPECT().DeleteFile(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(abortErr))
+			Expect(chunkReads.Load()).To(BeNumerically("<", 100))
+		}, NodeTimeout(10*time.Second))
+
+		It("should delete the partial destination file when configured", func(ctx context.Context) {
+			tfr = newCancelableTransfer(fxfer.WithDeleteOnAbort())
+			expectTransferUntilAborted(ctx)
+			mockDestStorage.EXPECT().DeleteFile(gomock.Any(), destConfig.FilePath, mockClient).Return(nil)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(abortErr))
+			Expect(chunkReads.Load()).To(BeNumerically("<", 100))
+		}, NodeTimeout(10*time.Second))
+	})
+
 	Context("Transfer with retry", func() {
 		BeforeEach(func() {
 			tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithRetryConfig(fxfer.RetryConfig{
@@ -356,11 +1377,11 @@ var _ = Describe("transfer", func() {
 		It("should retry the transfer when it fails while chunking", func(ctx context.Context) {
 			modTime := time.Now()
 			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.ModTime = modTime
 			})
 			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.Offset = int64(700)
 				i.ModTime = modTime
 			})
@@ -372,17 +1393,17 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockSrcStorage.EXPECT().GetFileFromOffset(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					int64(700),
 					mockClient,
@@ -395,14 +1416,14 @@ var _ = Describe("transfer", func() {
 					return readCloser, nil
 				}),
 				mockDestStorage.EXPECT().TransferFileChunk(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					gomock.Any(),
 					int64(700),
 					mockClient,
 				).Return(int64(0), gofakeit.Error()),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(xferfile.Info{}, gofakeit.Error()),
@@ -411,14 +1432,104 @@ var _ = Describe("transfer", func() {
 			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(HaveOccurred())
 		}, NodeTimeout(10*time.Second))
 
+		Describe("classifying the errors", func() {
+			var chunkErr error
+
+			BeforeEach(func(ctx context.Context) {
+				modTime := time.Now()
+				srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Size = int64(774)
+					i.ModTime = modTime
+				})
+				destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+					i.Size = int64(774)
+					i.Offset = int64(700)
+					i.ModTime = modTime
+				})
+				gomock.InOrder(
+					mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcConfig.FilePath, mockClient).Return(srcInfo, nil),
+					mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).Return(destInfo, nil),
+					mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcConfig.FilePath, int64(700), mockClient).
+						Return(io.NopCloser(strings.NewReader("Lorem Ipsum")), nil),
+					mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), destConfig.FilePath, gomock.Any(), int64(700), mockClient).
+						DoAndReturn(func(context.Context, string, io.Reader, int64, protoc.Client) (int64, error) {
+							return 0, chunkErr
+						}),
+				)
+			})
+
+			It("should not retry the transfer when the request is forbidden", func(ctx context.Context) {
+				chunkErr = &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+					Err:      errors.New("forbidden"),
+				}
+
+				Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(chunkErr))
+			}, NodeTimeout(10*time.Second))
+
+			It("should retry the transfer when the network times out", func(ctx context.Context) {
+				chunkErr = &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+				mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destConfig.FilePath, mockClient).
+					Return(xferfile.Info{}, gofakeit.Error())
+
+				Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(HaveOccurred())
+			}, NodeTimeout(10*time.Second))
+
+			It("should consult the retry classifier", func(ctx context.Context) {
+				tfr = fxfer.NewTransfer(GinkgoLogr,
+					fxfer.WithRetryConfig(fxfer.RetryConfig{
+						MaxRetryAttempts: 2,
+						InitialDelay:     50 * time.Millisecond,
+						MaxDelay:         100 * time.Millisecond,
+					}),
+					fxfer.WithRetryClassifier(func(err error) bool {
+						return !errors.Is(err, os.ErrDeadlineExceeded) && fxfer.DefaultRetryClassifier(err)
+					}),
+				)
+				chunkErr = &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+
+				Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(MatchError(os.ErrDeadlineExceeded))
+			}, NodeTimeout(10*time.Second))
+		})
+
+		It("should retry the transfer while the source object is being restored", func(ctx context.Context) {
+			modTime := time.Now()
+			srcInfo.ModTime = modTime
+			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
+				i.Size = srcInfo.Size
+				i.Offset = 0
+				i.ModTime = modTime
+			})
+
+			mockSrcStorage.EXPECT().GetFileInfo(
+				gomock.AssignableToTypeOf(contextType),
+				srcConfig.FilePath,
+				mockClient,
+			).Return(srcInfo, nil)
+			mockDestStorage.EXPECT().GetFileInfo(
+				gomock.AssignableToTypeOf(contextType),
+				destConfig.FilePath,
+				mockClient,
+			).Return(destInfo, nil).Times(2)
+			mockSrcStorage.EXPECT().GetFileFromOffset(
+				gomock.AssignableToTypeOf(contextType),
+				srcConfig.FilePath,
+				int64(0),
+				mockClient,
+			).Return(nil, storage.ErrObjectRestoreInProgress).Times(2)
+
+			Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).
+				To(MatchError(storage.ErrObjectRestoreInProgress))
+		}, NodeTimeout(10*time.Second))
+
 		It("should retry the transfer when it fails while finalizing", func(ctx context.Context) {
 			modTime := time.Now()
 			srcInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.ModTime = modTime
 			})
 			destInfo = xferfiletest.InfoFactory(func(i *xferfile.Info) {
-				i.Size = int64(1000)
+				i.Size = int64(774)
 				i.Offset = int64(700)
 				i.ModTime = modTime
 			})
@@ -430,17 +1541,17 @@ var _ = Describe("transfer", func() {
 
 			gomock.InOrder(
 				mockSrcStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					mockClient,
 				).Return(srcInfo, nil),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(destInfo, nil),
 				mockSrcStorage.EXPECT().GetFileFromOffset(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					srcConfig.FilePath,
 					int64(700),
 					mockClient,
@@ -453,19 +1564,19 @@ var _ = Describe("transfer", func() {
 					return readCloser, nil
 				}),
 				mockDestStorage.EXPECT().TransferFileChunk(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					gomock.Any(),
 					int64(700),
 					mockClient,
 				).Return(int64(300), nil),
 				mockDestStorage.EXPECT().FinalizeTransfer(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(storage.ErrFileOrObjectCannotFinalize),
 				mockDestStorage.EXPECT().GetFileInfo(
-					gomock.AssignableToTypeOf(ctx),
+					gomock.AssignableToTypeOf(contextType),
 					destConfig.FilePath,
 					mockClient,
 				).Return(xferfile.Info{}, gofakeit.Error()),
@@ -475,3 +1586,13 @@ var _ = Describe("transfer", func() {
 		}, NodeTimeout(10*time.Second))
 	})
 })
+
+// contextType is the type of the contexts passed to the storages, they carry the spans of the
+// transfer (see fxfer.WithTracerProvider) rather than being the context of the spec.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// copierDestination is a destination storage that can copy files server-side.
+type copierDestination struct {
+	*mock_storage.MockDestination
+	*mock_storage.MockServerSideCopier
+}
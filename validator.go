@@ -0,0 +1,41 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+)
+
+// Validator is a user-defined predicate gating the transfer of a file beyond the
+// built-in file rules (see WithValidator), e.g. to reject a file whose name collides
+// with an existing destination file or whose metadata fails a business check.
+//
+// Parameters:
+//   - ctx: the context of the transfer.
+//   - srcInfo: the info of the source file.
+//   - destExists: whether the destination file already exists.
+//
+// Returns:
+//   - err: a non-nil error aborts the transfer with this error
+type Validator func(ctx context.Context, srcInfo FileInfo, destExists bool) (err error)
+
+// validate runs the validator (if any) against the source file info.
+func (t *transfer) validate(
+	ctx context.Context,
+	srcInfo xferfile.Info,
+	dest DestinationConfig,
+) (err error) {
+	if t.validator == nil {
+		return
+	}
+
+	destExists := true
+	if _, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
+		if !errors.Is(err, xferfile.ErrFileNotExists) {
+			return
+		}
+		destExists = false
+	}
+	return t.validator(ctx, srcInfo, destExists)
+}
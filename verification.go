@@ -0,0 +1,100 @@
+package fxfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// ErrVerificationMismatch is returned when a checkpoint read back from the destination does
+// not match the bytes read from the source (see WithPeriodicVerification).
+var ErrVerificationMismatch = errors.New("verification: the destination does not match the source")
+
+// transferVerifiedChunks writes the content of the reader to the destination file from the offset
+// in checkpoints of t.verificationInterval bytes, each checkpoint is read back and compared with
+// the bytes written before the next one is written.
+func (t *transfer) transferVerifiedChunks(
+	ctx context.Context,
+	dest DestinationConfig,
+	rangeReader storage.RangeReader,
+	offset int64,
+	reader io.Reader,
+) (err error) {
+	tracker, _ := reader.(storage.ConfirmedSizeTracker)
+	for {
+		checkpoint := checkpointReader{
+			Reader:  io.LimitReader(reader, t.verificationInterval),
+			hash:    crc32.NewIEEE(),
+			tracker: tracker,
+		}
+		var n int64
+		if n, err = dest.Storage.TransferFileChunk(ctx, dest.FilePath, &checkpoint, offset, dest.Client); err != nil {
+			return
+		}
+		if n == 0 {
+			return
+		}
+		if err = t.verifyCheckpoint(ctx, dest, rangeReader, offset, n, checkpoint.hash.Sum32()); err != nil {
+			return
+		}
+		t.logger.V(debugLevel).Info("file chunk is transferred and verified",
+			"dstPath", dest.FilePath, "offset", offset, "size", n)
+		offset += n
+		if n < t.verificationInterval {
+			return
+		}
+	}
+}
+
+// verifyCheckpoint reads back the length bytes of the destination file from the offset
+// and compares their checksum with the checksum of the bytes written.
+func (t *transfer) verifyCheckpoint(
+	ctx context.Context,
+	dest DestinationConfig,
+	rangeReader storage.RangeReader,
+	offset, length int64,
+	writtenSum uint32,
+) (err error) {
+	var reader io.ReadCloser
+	if reader, err = rangeReader.ReadRange(ctx, dest.FilePath, offset, length, dest.Client); err != nil {
+		return
+	}
+	defer reader.Close()
+
+	hash := crc32.NewIEEE()
+	var n int64
+	if n, err = io.Copy(hash, reader); err != nil {
+		return
+	}
+	if n != length || hash.Sum32() != writtenSum {
+		return fmt.Errorf("%w: bytes %d-%d of %s", ErrVerificationMismatch, offset, offset+length-1, dest.FilePath)
+	}
+	return
+}
+
+// checkpointReader reads a checkpoint of the content written to the destination, and
+// computes the checksum of the bytes read.
+type checkpointReader struct {
+	io.Reader
+	hash    hash.Hash32
+	tracker storage.ConfirmedSizeTracker
+}
+
+func (r *checkpointReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	_, _ = r.hash.Write(p[:n])
+	return
+}
+
+// AddConfirmedSize implements the storage.ConfirmedSizeTracker interface, the confirmed
+// bytes are reported to the tracker of the content (may be nil).
+func (r *checkpointReader) AddConfirmedSize(n int64) {
+	if r.tracker != nil {
+		r.tracker.AddConfirmedSize(n)
+	}
+}
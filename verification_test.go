@@ -0,0 +1,139 @@
+package fxfer_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derektruong/fxfer"
+	"github.com/derektruong/fxfer/protoc"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transfer with periodic verification", func() {
+	const checkpointSize = 1000
+
+	var (
+		content     string
+		destStorage *corruptingDestination
+		srcConfig   fxfer.SourceConfig
+		destConfig  fxfer.DestinationConfig
+		statuses    func() []fxfer.ProgressStatus
+		callback    fxfer.ProgressUpdatedCallback
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 1000)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err := local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		destStorage = &corruptingDestination{Destination: localDest}
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  destStorage,
+			Client:   local_protoc.NewIO(),
+		}
+		// the progress is also reported by a goroutine, so it is recorded per spec under a lock
+		var mu sync.Mutex
+		var recorded []fxfer.ProgressStatus
+		callback = func(progress fxfer.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if progress.Status != fxfer.ProgressStatusInProgress {
+				recorded = append(recorded, progress.Status)
+			}
+		}
+		statuses = func() []fxfer.ProgressStatus {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]fxfer.ProgressStatus(nil), recorded...)
+		}
+	})
+
+	It("should transfer the content in verified checkpoints", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithPeriodicVerification(checkpointSize))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+
+		Expect(destStorage.chunks).To(BeNumerically(">=", len(content)/checkpointSize))
+		data, err := os.ReadFile(destConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail at the corrupted checkpoint rather than at the end", func(ctx context.Context) {
+		destStorage.corruptChunk = 2
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithPeriodicVerification(checkpointSize))
+
+		err := tfr.Transfer(ctx, srcConfig, destConfig, callback)
+		Expect(err).To(MatchError(fxfer.ErrVerificationMismatch))
+		Expect(err).To(MatchError(ContainSubstring("bytes 1000-1999")))
+		Expect(destStorage.chunks).To(Equal(2))
+		Expect(statuses()).To(Equal([]fxfer.ProgressStatus{fxfer.ProgressStatusInError}))
+
+		By("assert the corrupted destination file is deleted")
+		_, err = os.Stat(destConfig.FilePath)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	}, NodeTimeout(10*time.Second))
+
+	It("should transfer the content again after a corrupted checkpoint", func(ctx context.Context) {
+		destStorage.corruptChunk = 2
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithPeriodicVerification(checkpointSize),
+			fxfer.WithRetryConfig(fxfer.RetryConfig{
+				MaxRetryAttempts: 2,
+				InitialDelay:     time.Millisecond,
+				MaxDelay:         time.Millisecond,
+			}),
+		)
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, callback)).To(Succeed())
+
+		data, err := os.ReadFile(destConfig.FilePath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+		Expect(len(statuses())).To(BeNumerically(">=", 2))
+		Expect(statuses()[0]).To(Equal(fxfer.ProgressStatusInError))
+		Expect(statuses()[1:]).To(HaveEach(fxfer.ProgressStatusFinished))
+	}, NodeTimeout(10*time.Second))
+})
+
+// corruptingDestination is a local destination which silently flips the first byte
+// of the chunk written by its corruptChunk-th call of TransferFileChunk.
+type corruptingDestination struct {
+	*local.Destination
+	corruptChunk int
+	chunks       int
+}
+
+func (d *corruptingDestination) TransferFileChunk(
+	ctx context.Context,
+	filePath string,
+	reader io.Reader,
+	offset int64,
+	cli protoc.Client,
+) (n int64, err error) {
+	d.chunks++
+	if d.chunks == d.corruptChunk {
+		var data []byte
+		if data, err = io.ReadAll(reader); err != nil {
+			return
+		}
+		data[0] ^= 0xff
+		reader = bytes.NewReader(data)
+	}
+	return d.Destination.TransferFileChunk(ctx, filePath, reader, offset, cli)
+}
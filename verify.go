@@ -0,0 +1,134 @@
+package fxfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrVerificationUnsupported is returned when the destination file cannot be verified against
+// the source file, i.e. it cannot be read back (see storage.RangeReader) or its content has been
+// compressed or encrypted with an unknown key.
+var ErrVerificationUnsupported = errors.New("verification: the destination cannot be verified")
+
+func (t *transfer) Verify(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+) (ok bool, err error) {
+	ctx, span := t.startSpan(ctx, verifySpanName,
+		srcPathAttributeKey.String(src.FilePath), destPathAttributeKey.String(dest.FilePath))
+	defer func() { endSpan(span, err) }()
+
+	if err = src.Validate(ctx); err != nil {
+		return
+	}
+	if err = dest.Validate(ctx); err != nil {
+		return
+	}
+	if dest, err = t.resolveDestination(src, dest); err != nil {
+		return
+	}
+
+	var srcInfo, destInfo xferfile.Info
+	if srcInfo, err = t.getSourceFileInfo(ctx, src); err != nil {
+		return
+	}
+	if destInfo, err = dest.Storage.GetFileInfo(ctx, dest.FilePath, dest.Client); err != nil {
+		return
+	}
+	if _, compressed := destInfo.Metadata[compressionMeta]; compressed {
+		err = ErrVerificationUnsupported
+		return
+	}
+
+	// the destination file must be finalized with the size of the source file
+	if destInfo.FinishTime.IsZero() || destInfo.Size != srcInfo.Size || destInfo.Offset != destInfo.Size {
+		t.logger.Info("destination file does not match the size of the source file",
+			"srcPath", src.FilePath, "dstPath", dest.FilePath,
+			"srcSize", srcInfo.Size, "dstSize", destInfo.Size, "dstOffset", destInfo.Offset)
+		return
+	}
+	checksumAlgorithm := t.checksumAlgorithm
+	if checksumAlgorithm == NoneChecksumAlgorithm {
+		return true, nil
+	}
+
+	var srcSum, destSum []byte
+	if srcSum, destSum, err = t.verifyChecksums(ctx, src, dest, destInfo); err != nil {
+		return
+	}
+	if ok = bytes.Equal(srcSum, destSum); !ok {
+		t.logger.Info("destination file does not match the checksum of the source file",
+			"srcPath", src.FilePath, "dstPath", dest.FilePath, "checksumAlgorithm", checksumAlgorithm)
+	}
+	return
+}
+
+// verifyChecksums streams the source and destination files at once and computes their checksums,
+// the source content is encrypted as the destination content if the destination is encrypted.
+func (t *transfer) verifyChecksums(
+	ctx context.Context,
+	src SourceConfig,
+	dest DestinationConfig,
+	destInfo xferfile.Info,
+) (srcSum, destSum []byte, err error) {
+	rangeReader, ok := dest.Storage.(storage.RangeReader)
+	if !ok {
+		err = ErrVerificationUnsupported
+		return
+	}
+	var iv []byte
+	if _, encrypted := destInfo.Metadata[crypt.IVMeta]; encrypted {
+		if t.encryptionKey == nil {
+			err = ErrVerificationUnsupported
+			return
+		}
+		if iv, err = crypt.DecodeIV(destInfo.Metadata); err != nil {
+			return
+		}
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.Go(func() (err error) {
+		var reader io.ReadCloser
+		if reader, err = src.Storage.GetFileFromOffset(egCtx, src.FilePath, 0, src.Client); err != nil {
+			return
+		}
+		defer reader.Close()
+		var srcReader io.Reader = reader
+		if iv != nil {
+			if srcReader, err = crypt.NewReader(reader, t.encryptionKey, iv, 0); err != nil {
+				return
+			}
+		}
+		srcSum, err = checksum(t.checksumAlgorithm.newHash(), srcReader)
+		return
+	})
+	eg.Go(func() (err error) {
+		var reader io.ReadCloser
+		if reader, err = rangeReader.ReadRange(egCtx, dest.FilePath, 0, destInfo.Size, dest.Client); err != nil {
+			return
+		}
+		defer reader.Close()
+		destSum, err = checksum(t.checksumAlgorithm.newHash(), reader)
+		return
+	})
+	err = eg.Wait()
+	return
+}
+
+// checksum returns the checksum of the content of the reader.
+func checksum(hash hash.Hash, reader io.Reader) (sum []byte, err error) {
+	if _, err = io.Copy(hash, reader); err != nil {
+		return
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,110 @@
+package fxfer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/derektruong/fxfer"
+	local_protoc "github.com/derektruong/fxfer/protoc/local"
+	"github.com/derektruong/fxfer/storage"
+	"github.com/derektruong/fxfer/storage/crypt"
+	"github.com/derektruong/fxfer/storage/local"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verify", func() {
+	noopCallback := func(fxfer.Progress) {}
+
+	var (
+		content    string
+		localDest  *local.Destination
+		srcConfig  fxfer.SourceConfig
+		destConfig fxfer.DestinationConfig
+	)
+
+	BeforeEach(func() {
+		tempDir := GinkgoT().TempDir()
+		content = strings.Repeat("0123456789", 1000)
+		srcPath := filepath.Join(tempDir, "src", "content.txt")
+		Expect(os.MkdirAll(filepath.Dir(srcPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(srcPath, []byte(content), 0644)).To(Succeed())
+
+		srcStorage, err := local.NewSource(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		localDest, err = local.NewDestination(GinkgoLogr)
+		Expect(err).ToNot(HaveOccurred())
+		srcConfig = fxfer.SourceConfig{FilePath: srcPath, Storage: srcStorage, Client: local_protoc.NewIO()}
+		destConfig = fxfer.DestinationConfig{
+			FilePath: filepath.Join(tempDir, "dest", "content.txt"),
+			Storage:  localDest,
+			Client:   local_protoc.NewIO(),
+		}
+	})
+
+	It("should verify the transferred file with its checksum", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmSHA256))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeTrue())
+	}, NodeTimeout(10*time.Second))
+
+	It("should not verify a destination of the same size with another checksum", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmCRC32))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+		corrupted := strings.Replace(content, "5", "x", 1)
+		Expect(os.WriteFile(destConfig.FilePath, []byte(corrupted), 0644)).To(Succeed())
+
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeFalse())
+		Expect(os.ReadFile(destConfig.FilePath)).To(BeEquivalentTo(corrupted))
+	}, NodeTimeout(10*time.Second))
+
+	It("should compare the sizes only without checksum algorithm", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry())
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+		Expect(os.WriteFile(destConfig.FilePath, []byte(strings.ToUpper(content)), 0644)).To(Succeed())
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeTrue())
+
+		Expect(os.WriteFile(destConfig.FilePath, []byte(content[1:]), 0644)).To(Succeed())
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeFalse())
+	}, NodeTimeout(10*time.Second))
+
+	It("should not verify an unfinished destination", func(ctx context.Context) {
+		Expect(localDest.CreateFile(ctx, destConfig.FilePath, int64(len(content)), fileModTime(srcConfig.FilePath),
+			destConfig.Client)).To(Succeed())
+		_, err := localDest.TransferFileChunk(ctx, destConfig.FilePath, strings.NewReader(content), 0,
+			destConfig.Client)
+		Expect(err).ToNot(HaveOccurred())
+
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmMD5))
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeFalse())
+	}, NodeTimeout(10*time.Second))
+
+	It("should verify an encrypted destination with the encryption key", func(ctx context.Context) {
+		key := []byte(gofakeit.LetterN(crypt.KeySize))
+		tfr := fxfer.NewTransfer(GinkgoLogr, fxfer.WithDisabledRetry(), fxfer.WithClientEncryption(key),
+			fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmSHA256))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+		Expect(tfr.Verify(ctx, srcConfig, destConfig)).To(BeTrue())
+
+		tfr = fxfer.NewTransfer(GinkgoLogr, fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmSHA256))
+		_, err := tfr.Verify(ctx, srcConfig, destConfig)
+		Expect(err).To(MatchError(fxfer.ErrVerificationUnsupported))
+	}, NodeTimeout(10*time.Second))
+
+	It("should fail to verify a destination which cannot be read back", func(ctx context.Context) {
+		tfr := fxfer.NewTransfer(GinkgoLogr,
+			fxfer.WithDisabledRetry(), fxfer.WithChecksumAlgorithm(fxfer.ChecksumAlgorithmSHA256))
+		Expect(tfr.Transfer(ctx, srcConfig, destConfig, noopCallback)).To(Succeed())
+
+		destConfig.Storage = struct{ storage.Destination }{localDest}
+		_, err := tfr.Verify(ctx, srcConfig, destConfig)
+		Expect(err).To(MatchError(fxfer.ErrVerificationUnsupported))
+	}, NodeTimeout(10*time.Second))
+})
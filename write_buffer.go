@@ -0,0 +1,49 @@
+package fxfer
+
+import (
+	"errors"
+	"io"
+
+	"github.com/derektruong/fxfer/storage"
+)
+
+// writeBufferReader accumulates the small reads of the content into reads of the size of its
+// buffer (see WithWriteBuffer), so that the destination writes the content in larger writes.
+type writeBufferReader struct {
+	reader     io.Reader
+	buf        []byte
+	start, end int
+	err        error
+}
+
+func newWriteBufferReader(reader io.Reader, size int) *writeBufferReader {
+	return &writeBufferReader{reader: reader, buf: make([]byte, size)}
+}
+
+func (r *writeBufferReader) Read(p []byte) (n int, err error) {
+	if r.start == r.end {
+		if r.err != nil {
+			return 0, r.err
+		}
+		// the buffer is filled up unless the content ends or fails
+		r.start = 0
+		r.end, r.err = io.ReadFull(r.reader, r.buf)
+		if errors.Is(r.err, io.ErrUnexpectedEOF) {
+			r.err = io.EOF
+		}
+		if r.end == 0 {
+			return 0, r.err
+		}
+	}
+	n = copy(p, r.buf[r.start:r.end])
+	r.start += n
+	return
+}
+
+// AddConfirmedSize implements the storage.ConfirmedSizeTracker interface, the confirmed
+// bytes are reported to the content if it tracks them.
+func (r *writeBufferReader) AddConfirmedSize(n int64) {
+	if tracker, ok := r.reader.(storage.ConfirmedSizeTracker); ok {
+		tracker.AddConfirmedSize(n)
+	}
+}
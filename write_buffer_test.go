@@ -0,0 +1,101 @@
+package fxfer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	"github.com/derektruong/fxfer/internal/xferfile"
+	"github.com/derektruong/fxfer/internal/xferfile/xferfiletest"
+	"github.com/derektruong/fxfer/protoc"
+	mock_protoc "github.com/derektruong/fxfer/protoc/mock"
+	mock_storage "github.com/derektruong/fxfer/storage/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("writeBufferReader", func() {
+	content := strings.Repeat("0123456789", 100)
+
+	It("should coalesce the drip-fed reads into fewer writes", func() {
+		unbuffered := &writeCounter{}
+		_, err := io.Copy(unbuffered, iotest.OneByteReader(strings.NewReader(content)))
+		Expect(err).ToNot(HaveOccurred())
+
+		buffered := &writeCounter{}
+		_, err = io.Copy(buffered, newWriteBufferReader(iotest.OneByteReader(strings.NewReader(content)), 64))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(unbuffered.writes).To(Equal(len(content)))
+		Expect(buffered.writes).To(Equal(16)) // 15 writes of 64 bytes and the remaining 40 bytes
+		Expect(buffered.content.String()).To(Equal(content))
+	})
+
+	It("should pass the bytes read before the source fails", func() {
+		reader := newWriteBufferReader(io.MultiReader(
+			strings.NewReader("0123"),
+			iotest.ErrReader(iotest.ErrTimeout),
+		), 64)
+
+		data, err := io.ReadAll(reader)
+		Expect(err).To(MatchError(iotest.ErrTimeout))
+		Expect(string(data)).To(Equal("0123"))
+	})
+
+	It("should report the confirmed bytes to the content", func() {
+		proxy := newProxyReader(io.NopCloser(strings.NewReader(content)), 0)
+		defer proxy.Close()
+
+		newWriteBufferReader(proxy, 64).AddConfirmedSize(100)
+		Expect(proxy.ConfirmedSize()).To(Equal(int64(100)))
+	})
+
+	It("should buffer the content written to the destination", func(ctx context.Context) {
+		mockCtrl := gomock.NewController(GinkgoT())
+		mockClient := mock_protoc.NewMockClient(mockCtrl)
+		mockSrcStorage := mock_storage.NewMockSource(mockCtrl)
+		mockDestStorage := mock_storage.NewMockDestination(mockCtrl)
+		tfr := NewTransfer(GinkgoLogr, WithDisabledRetry(), WithWriteBuffer(256))
+
+		srcInfo := xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size = "src-file.txt", "txt", int64(len(content))
+		})
+		destInfo := xferfiletest.InfoFactory(func(info *xferfile.Info) {
+			info.Path, info.Extension, info.Size, info.Offset = "dest-file.txt", "txt", int64(len(content)), 0
+			info.ModTime, info.FinishTime = srcInfo.ModTime, time.Time{}
+		})
+		written := &writeCounter{}
+		gomock.InOrder(
+			mockSrcStorage.EXPECT().GetFileInfo(gomock.Any(), srcInfo.Path, mockClient).Return(srcInfo, nil),
+			mockDestStorage.EXPECT().GetFileInfo(gomock.Any(), destInfo.Path, mockClient).Return(destInfo, nil),
+			mockSrcStorage.EXPECT().GetFileFromOffset(gomock.Any(), srcInfo.Path, int64(0), mockClient).
+				Return(io.NopCloser(iotest.OneByteReader(strings.NewReader(content))), nil),
+			mockDestStorage.EXPECT().TransferFileChunk(gomock.Any(), destInfo.Path, gomock.Any(), int64(0), mockClient).
+				DoAndReturn(func(_ context.Context, _ string, reader io.Reader, _ int64, _ protoc.Client) (int64, error) {
+					return io.Copy(written, reader)
+				}),
+			mockDestStorage.EXPECT().FinalizeTransfer(gomock.Any(), destInfo.Path, mockClient).Return(nil),
+		)
+
+		src := SourceConfig{FilePath: srcInfo.Path, Storage: mockSrcStorage, Client: mockClient}
+		dest := DestinationConfig{FilePath: destInfo.Path, Storage: mockDestStorage, Client: mockClient}
+		Expect(tfr.Transfer(ctx, src, dest, func(Progress) {})).To(Succeed())
+		Expect(written.writes).To(Equal(4))
+		Expect(written.content.String()).To(Equal(content))
+	}, NodeTimeout(10*time.Second))
+})
+
+// writeCounter records the content written to it and counts the writes.
+type writeCounter struct {
+	content bytes.Buffer
+	writes  int
+}
+
+func (w *writeCounter) Write(p []byte) (n int, err error) {
+	w.writes++
+	return w.content.Write(p)
+}